@@ -10,10 +10,15 @@ import (
 	"time"
 
 	"github.com/appinesshq/caservice/app/services/sales-api/handlers/debug/checkgrp"
+	"github.com/appinesshq/caservice/app/services/sales-api/handlers/jwksgrp"
 	v1 "github.com/appinesshq/caservice/app/services/sales-api/handlers/v1"
+	"github.com/appinesshq/caservice/app/services/sales-api/handlers/v1/usergrp"
 	"github.com/appinesshq/caservice/app/services/sales-api/web/auth"
 	"github.com/appinesshq/caservice/app/services/sales-api/web/v1/mid"
+	entity "github.com/appinesshq/caservice/business/user"
 	"github.com/appinesshq/caservice/data"
+	"github.com/appinesshq/caservice/foundation/encryption"
+	"github.com/appinesshq/caservice/foundation/mail"
 	"github.com/appinesshq/caservice/foundation/web"
 	"github.com/jmoiron/sqlx"
 
@@ -34,11 +39,29 @@ func WithCORS(origin string) func(opts *Options) {
 
 // APIMuxConfig contains all the mandatory systems required by handlers.
 type APIMuxConfig struct {
-	Shutdown            chan os.Signal
-	Log                 *zap.SugaredLogger
-	Auth                *auth.Auth
-	Repositories        *data.Repositories
-	UserSessionDuration time.Duration
+	Shutdown              chan os.Signal
+	Log                   *zap.SugaredLogger
+	Auth                  *auth.Auth
+	KeySet                jwksgrp.KeySet
+	Repositories          *data.Repositories
+	UserSessionDuration   time.Duration
+	RefreshTokenDuration  time.Duration
+	Mailer                mail.Mailer
+	ResetTokenDuration    time.Duration
+	VerificationKey       []byte
+	VerificationURL       string
+	VerificationDuration  time.Duration
+	RequireVerifiedEmail  bool
+	AccountLockout        entity.LockoutPolicy
+	IPLockout             entity.LockoutPolicy
+	TOTPSecrets           *encryption.Box
+	TOTPIssuer            string
+	RequireAdminTOTP      bool
+	OIDC                  usergrp.OIDC
+	OIDCAutoProvision     bool
+	APIKeyMaxDuration     time.Duration
+	PasswordPolicy        entity.PasswordPolicy
+	ImpersonationDuration time.Duration
 }
 
 // APIMux constructs a http.Handler with all application routes defined.
@@ -81,12 +104,37 @@ func APIMux(cfg APIMuxConfig, options ...func(opts *Options)) http.Handler {
 		)
 	}
 
+	// Publish the public keys, if a key set is provided.
+	if cfg.KeySet != nil {
+		jgh := jwksgrp.Handlers{
+			Keys: cfg.KeySet,
+		}
+		app.Handle(http.MethodGet, "", "/.well-known/jwks.json", jgh.JWKS)
+	}
+
 	// Load the v1 routes.
 	v1.Routes(app, v1.Config{
-		Log:                 cfg.Log,
-		Auth:                cfg.Auth,
-		Repositories:        cfg.Repositories,
-		UserSessionDuration: cfg.UserSessionDuration,
+		Log:                   cfg.Log,
+		Auth:                  cfg.Auth,
+		Repositories:          cfg.Repositories,
+		UserSessionDuration:   cfg.UserSessionDuration,
+		RefreshTokenDuration:  cfg.RefreshTokenDuration,
+		Mailer:                cfg.Mailer,
+		ResetTokenDuration:    cfg.ResetTokenDuration,
+		VerificationKey:       cfg.VerificationKey,
+		VerificationURL:       cfg.VerificationURL,
+		VerificationDuration:  cfg.VerificationDuration,
+		RequireVerifiedEmail:  cfg.RequireVerifiedEmail,
+		AccountLockout:        cfg.AccountLockout,
+		IPLockout:             cfg.IPLockout,
+		TOTPSecrets:           cfg.TOTPSecrets,
+		TOTPIssuer:            cfg.TOTPIssuer,
+		RequireAdminTOTP:      cfg.RequireAdminTOTP,
+		OIDC:                  cfg.OIDC,
+		OIDCAutoProvision:     cfg.OIDCAutoProvision,
+		APIKeyMaxDuration:     cfg.APIKeyMaxDuration,
+		PasswordPolicy:        cfg.PasswordPolicy,
+		ImpersonationDuration: cfg.ImpersonationDuration,
 	})
 
 	return app
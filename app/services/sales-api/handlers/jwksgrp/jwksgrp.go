@@ -0,0 +1,28 @@
+// Package jwksgrp maintains the handler for publishing the public keys
+// tokens are signed with.
+package jwksgrp
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/appinesshq/caservice/foundation/keystore"
+	"github.com/appinesshq/caservice/foundation/web"
+)
+
+// KeySet declares the behavior for providing a JSON Web Key Set.
+type KeySet interface {
+	JWKS() keystore.JWKS
+}
+
+// Handlers manages the set of JWKS endpoints.
+type Handlers struct {
+	Keys KeySet
+}
+
+// JWKS returns the public keys of the service as a JSON Web Key Set, so
+// other services can verify tokens.
+func (h Handlers) JWKS(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	return web.Respond(ctx, w, h.Keys.JWKS(), http.StatusOK)
+}
@@ -0,0 +1,89 @@
+// Package auditgrp maintains the group of handlers for audit trail access.
+package auditgrp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/appinesshq/caservice/app/services/sales-api/sys/validate"
+	"github.com/appinesshq/caservice/app/services/sales-api/web/auth"
+	v1Web "github.com/appinesshq/caservice/app/services/sales-api/web/v1"
+	"github.com/appinesshq/caservice/business/audit"
+	usecases "github.com/appinesshq/caservice/business/audit/usecases"
+	"github.com/appinesshq/caservice/foundation/web"
+)
+
+// Handlers manages the set of audit trail endpoints.
+type Handlers struct {
+	Audit usecases.AuditUseCases
+}
+
+// Query returns a page of the audit trail of the organization of the
+// authenticated user, newest first. Entries can be filtered by the actor and
+// entity query parameters, which are ids, and by the since and until query
+// parameters, which are RFC 3339 times.
+func (h Handlers) Query(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	page := web.Param(r, "page")
+	pageNumber, err := strconv.Atoi(page)
+	if err != nil {
+		return v1Web.NewRequestError(fmt.Errorf("invalid page format, page[%s]", page), http.StatusBadRequest)
+	}
+	rows := web.Param(r, "rows")
+	rowsPerPage, err := strconv.Atoi(rows)
+	if err != nil {
+		return v1Web.NewRequestError(fmt.Errorf("invalid rows format, rows[%s]", rows), http.StatusBadRequest)
+	}
+
+	f, err := filter(r)
+	if err != nil {
+		return v1Web.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	entries, err := h.Audit.Query(ctx, f, pageNumber, rowsPerPage)
+	if err != nil {
+		if errors.Is(err, usecases.ErrUnauthorized) {
+			return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+		}
+		return fmt.Errorf("unable to query audit trail: %w", err)
+	}
+
+	return web.Respond(ctx, w, entries, http.StatusOK)
+}
+
+// filter returns the filter of the query parameters of the request.
+func filter(r *http.Request) (audit.Filter, error) {
+	q := r.URL.Query()
+
+	f := audit.Filter{
+		ActorID:  q.Get("actor"),
+		EntityID: q.Get("entity"),
+	}
+	if f.ActorID != "" {
+		if err := validate.CheckID(f.ActorID); err != nil {
+			return audit.Filter{}, fmt.Errorf("invalid actor: %w", err)
+		}
+	}
+	if f.EntityID != "" {
+		if err := validate.CheckID(f.EntityID); err != nil {
+			return audit.Filter{}, fmt.Errorf("invalid entity: %w", err)
+		}
+	}
+
+	var err error
+	if since := q.Get("since"); since != "" {
+		if f.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			return audit.Filter{}, fmt.Errorf("invalid since format, since[%s]", since)
+		}
+	}
+	if until := q.Get("until"); until != "" {
+		if f.Until, err = time.Parse(time.RFC3339, until); err != nil {
+			return audit.Filter{}, fmt.Errorf("invalid until format, until[%s]", until)
+		}
+	}
+
+	return f, nil
+}
@@ -0,0 +1,154 @@
+// Package productgrp maintains the group of handlers for product access.
+package productgrp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/appinesshq/caservice/app/services/sales-api/sys/validate"
+	"github.com/appinesshq/caservice/app/services/sales-api/web/auth"
+	v1Web "github.com/appinesshq/caservice/app/services/sales-api/web/v1"
+	product "github.com/appinesshq/caservice/business/product/usecases"
+	fctx "github.com/appinesshq/caservice/foundation/context"
+	"github.com/appinesshq/caservice/foundation/web"
+)
+
+// Handlers manages the set of product endpoints.
+type Handlers struct {
+	Product product.ProductUseCases
+}
+
+// Create adds a new product to the system.
+func (h Handlers) Create(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := fctx.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var np product.NewProduct
+	if err := web.Decode(r, &np); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+	if err := validate.Check(np); err != nil {
+		return fmt.Errorf("validating data: %w", err)
+	}
+
+	prd, err := h.Product.Create(ctx, np, v.Now)
+	if err != nil {
+		return fmt.Errorf("creating new product, np[%+v]: %w", np, err)
+	}
+
+	return web.Respond(ctx, w, prd, http.StatusCreated)
+}
+
+// Update updates a product in the system.
+func (h Handlers) Update(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := fctx.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	id := web.Param(r, "id")
+	if err := validate.CheckID(id); err != nil {
+		return v1Web.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	var upd product.UpdateProduct
+	if err := web.Decode(r, &upd); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+	if err := validate.Check(upd); err != nil {
+		return fmt.Errorf("validating data: %w", err)
+	}
+
+	if _, err := h.Product.Update(ctx, id, upd, v.Now); err != nil {
+		switch {
+		case errors.Is(err, product.ErrUnauthorized):
+			return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+		case errors.Is(err, product.ErrNotFound):
+			return v1Web.NewRequestError(err, http.StatusNotFound)
+		case errors.Is(err, product.ErrConflict):
+			return v1Web.NewRequestError(err, http.StatusConflict)
+		default:
+			return fmt.Errorf("ID[%s] Product[%+v]: %w", id, &upd, err)
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Delete removes a product from the system.
+func (h Handlers) Delete(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := web.Param(r, "id")
+	if err := validate.CheckID(id); err != nil {
+		return v1Web.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	if err := h.Product.Delete(ctx, id); err != nil {
+		if errors.Is(err, product.ErrUnauthorized) {
+			return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+		}
+		return fmt.Errorf("ID[%s]: %w", id, err)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Query returns a list of products with paging.
+func (h Handlers) Query(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	page := web.Param(r, "page")
+	pageNumber, err := strconv.Atoi(page)
+	if err != nil {
+		return v1Web.NewRequestError(fmt.Errorf("invalid page format, page[%s]", page), http.StatusBadRequest)
+	}
+	rows := web.Param(r, "rows")
+	rowsPerPage, err := strconv.Atoi(rows)
+	if err != nil {
+		return v1Web.NewRequestError(fmt.Errorf("invalid rows format, rows[%s]", rows), http.StatusBadRequest)
+	}
+
+	products, err := h.Product.Query(ctx, pageNumber, rowsPerPage)
+	if err != nil {
+		return fmt.Errorf("unable to query for products: %w", err)
+	}
+
+	return web.Respond(ctx, w, products, http.StatusOK)
+}
+
+// QueryByID returns a product by its ID.
+func (h Handlers) QueryByID(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := web.Param(r, "id")
+	if err := validate.CheckID(id); err != nil {
+		return v1Web.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	prd, err := h.Product.QueryByID(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, product.ErrNotFound):
+			return v1Web.NewRequestError(err, http.StatusNotFound)
+		default:
+			return fmt.Errorf("ID[%s]: %w", id, err)
+		}
+	}
+
+	return web.Respond(ctx, w, prd, http.StatusOK)
+}
+
+// QueryByUserID returns the products listed by a user.
+func (h Handlers) QueryByUserID(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := web.Param(r, "id")
+	if err := validate.CheckID(id); err != nil {
+		return v1Web.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	products, err := h.Product.QueryByUserID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("userID[%s]: %w", id, err)
+	}
+
+	return web.Respond(ctx, w, products, http.StatusOK)
+}
@@ -0,0 +1,118 @@
+// Package salegrp maintains the group of handlers for sale access.
+package salegrp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/appinesshq/caservice/app/services/sales-api/sys/validate"
+	"github.com/appinesshq/caservice/app/services/sales-api/web/auth"
+	v1Web "github.com/appinesshq/caservice/app/services/sales-api/web/v1"
+	sale "github.com/appinesshq/caservice/business/sale/usecases"
+	fctx "github.com/appinesshq/caservice/foundation/context"
+	"github.com/appinesshq/caservice/foundation/web"
+)
+
+// Handlers manages the set of sale endpoints.
+type Handlers struct {
+	Sale sale.SaleUseCases
+}
+
+// Create records the purchase of a product by the authenticated user.
+func (h Handlers) Create(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := fctx.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	productID := web.Param(r, "id")
+	if err := validate.CheckID(productID); err != nil {
+		return v1Web.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	var ns sale.NewSale
+	if err := web.Decode(r, &ns); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+	if err := validate.Check(ns); err != nil {
+		return fmt.Errorf("validating data: %w", err)
+	}
+
+	sl, err := h.Sale.Create(ctx, productID, ns, v.Now)
+	if err != nil {
+		switch {
+		case errors.Is(err, sale.ErrProductNotFound):
+			return v1Web.NewRequestError(err, http.StatusNotFound)
+		case errors.Is(err, sale.ErrInsufficientStock):
+			return v1Web.NewRequestError(err, http.StatusConflict)
+		default:
+			return fmt.Errorf("productID[%s] sale[%+v]: %w", productID, ns, err)
+		}
+	}
+
+	return web.Respond(ctx, w, sl, http.StatusCreated)
+}
+
+// QueryByID returns a sale by its ID.
+func (h Handlers) QueryByID(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := web.Param(r, "id")
+	if err := validate.CheckID(id); err != nil {
+		return v1Web.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	sl, err := h.Sale.QueryByID(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sale.ErrUnauthorized):
+			return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+		case errors.Is(err, sale.ErrNotFound):
+			return v1Web.NewRequestError(err, http.StatusNotFound)
+		default:
+			return fmt.Errorf("ID[%s]: %w", id, err)
+		}
+	}
+
+	return web.Respond(ctx, w, sl, http.StatusOK)
+}
+
+// QueryByProductID returns the sales of a product.
+func (h Handlers) QueryByProductID(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := web.Param(r, "id")
+	if err := validate.CheckID(id); err != nil {
+		return v1Web.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	sales, err := h.Sale.QueryByProductID(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sale.ErrUnauthorized):
+			return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+		case errors.Is(err, sale.ErrProductNotFound):
+			return v1Web.NewRequestError(err, http.StatusNotFound)
+		default:
+			return fmt.Errorf("productID[%s]: %w", id, err)
+		}
+	}
+
+	return web.Respond(ctx, w, sales, http.StatusOK)
+}
+
+// QueryByUserID returns the purchases of a user.
+func (h Handlers) QueryByUserID(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := web.Param(r, "id")
+	if err := validate.CheckID(id); err != nil {
+		return v1Web.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	sales, err := h.Sale.QueryByUserID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sale.ErrUnauthorized) {
+			return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+		}
+		return fmt.Errorf("userID[%s]: %w", id, err)
+	}
+
+	return web.Respond(ctx, w, sales, http.StatusOK)
+}
@@ -0,0 +1,112 @@
+package usergrp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/appinesshq/caservice/app/services/sales-api/sys/validate"
+	"github.com/appinesshq/caservice/app/services/sales-api/web/auth"
+	v1Web "github.com/appinesshq/caservice/app/services/sales-api/web/v1"
+	entity "github.com/appinesshq/caservice/business/user"
+	user "github.com/appinesshq/caservice/business/user/usecases"
+	fctx "github.com/appinesshq/caservice/foundation/context"
+	"github.com/appinesshq/caservice/foundation/web"
+)
+
+// CreateAPIKey creates an API key for a user. The key is only part of this
+// response, it cannot be retrieved later.
+func (h Handlers) CreateAPIKey(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := fctx.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	id := web.Param(r, "id")
+	if err := validate.CheckID(id); err != nil {
+		return v1Web.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	var nk user.NewAPIKey
+	if err := web.Decode(r, &nk); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+	if err := validate.Check(nk); err != nil {
+		return fmt.Errorf("validating data: %w", err)
+	}
+
+	ak, key, err := h.User.CreateAPIKey(ctx, id, nk, v.Now)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrAPIKeysDisabled):
+			return v1Web.NewRequestError(err, http.StatusNotFound)
+		case errors.Is(err, user.ErrUnauthorized):
+			return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+		case errors.Is(err, user.ErrNotFound):
+			return v1Web.NewRequestError(err, http.StatusNotFound)
+		case errors.Is(err, user.ErrInvalidScope), errors.Is(err, user.ErrInvalidExpiry):
+			return v1Web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("ID[%s]: %w", id, err)
+		}
+	}
+
+	resp := struct {
+		entity.APIKey
+		Key string
+	}{
+		APIKey: ak,
+		Key:    key,
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusCreated)
+}
+
+// QueryAPIKeys returns the API keys of a user.
+func (h Handlers) QueryAPIKeys(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := web.Param(r, "id")
+	if err := validate.CheckID(id); err != nil {
+		return v1Web.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	keys, err := h.User.QueryAPIKeys(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrAPIKeysDisabled):
+			return v1Web.NewRequestError(err, http.StatusNotFound)
+		case errors.Is(err, user.ErrUnauthorized):
+			return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+		default:
+			return fmt.Errorf("ID[%s]: %w", id, err)
+		}
+	}
+
+	return web.Respond(ctx, w, keys, http.StatusOK)
+}
+
+// RevokeAPIKey revokes an API key of a user.
+func (h Handlers) RevokeAPIKey(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := web.Param(r, "id")
+	if err := validate.CheckID(id); err != nil {
+		return v1Web.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	keyID := web.Param(r, "keyid")
+	if err := validate.CheckID(keyID); err != nil {
+		return v1Web.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	if err := h.User.RevokeAPIKey(ctx, id, keyID); err != nil {
+		switch {
+		case errors.Is(err, user.ErrAPIKeysDisabled), errors.Is(err, user.ErrAPIKeyNotFound):
+			return v1Web.NewRequestError(err, http.StatusNotFound)
+		case errors.Is(err, user.ErrUnauthorized):
+			return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+		default:
+			return fmt.Errorf("ID[%s] KeyID[%s]: %w", id, keyID, err)
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
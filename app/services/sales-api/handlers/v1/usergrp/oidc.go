@@ -0,0 +1,188 @@
+package usergrp
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+
+	v1Web "github.com/appinesshq/caservice/app/services/sales-api/web/v1"
+	user "github.com/appinesshq/caservice/business/user/usecases"
+	fctx "github.com/appinesshq/caservice/foundation/context"
+	"github.com/appinesshq/caservice/foundation/encryption"
+	"github.com/appinesshq/caservice/foundation/oidc"
+	"github.com/appinesshq/caservice/foundation/web"
+)
+
+// oidcCookie is the name of the cookie that keeps the state of a sign in
+// with the OpenID Connect provider between the login and callback requests.
+const oidcCookie = "oidc_state"
+
+// oidcStateDuration is how long a sign in at the provider may take.
+const oidcStateDuration = 10 * time.Minute
+
+// OIDC contains the OpenID Connect provider users can sign in with.
+type OIDC struct {
+	Provider *oidc.Provider
+
+	// Name identifies the provider in the identities of users.
+	Name string
+
+	// State encrypts the state cookie, so it cannot be read or forged.
+	State *encryption.Box
+}
+
+// oidcState is the content of the state cookie.
+type oidcState struct {
+	State    string    `json:"state"`
+	Nonce    string    `json:"nonce"`
+	Verifier string    `json:"verifier"`
+	Expires  time.Time `json:"expires"`
+}
+
+// OIDCLogin redirects the user to the OpenID Connect provider to sign in.
+func (h Handlers) OIDCLogin(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := fctx.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	if h.OIDC.Provider == nil {
+		return v1Web.NewRequestError(user.ErrExternalIdentitiesDisabled, http.StatusNotFound)
+	}
+
+	var st oidcState
+	for _, s := range []*string{&st.State, &st.Nonce, &st.Verifier} {
+		if *s, err = oidc.RandomString(); err != nil {
+			return err
+		}
+	}
+	st.Expires = v.Now.Add(oidcStateDuration)
+
+	b, err := json.Marshal(st)
+	if err != nil {
+		return fmt.Errorf("encoding state: %w", err)
+	}
+	sealed, err := h.OIDC.State.Encrypt(b)
+	if err != nil {
+		return fmt.Errorf("encrypting state: %w", err)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookie,
+		Value:    base64.RawURLEncoding.EncodeToString(sealed),
+		Path:     path.Dir(r.URL.Path),
+		Expires:  st.Expires,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, h.OIDC.Provider.AuthCodeURL(st.State, st.Nonce, st.Verifier), http.StatusFound)
+	fctx.SetStatusCode(ctx, http.StatusFound)
+
+	return nil
+}
+
+// OIDCCallback provides an API token for the user coming back from the
+// OpenID Connect provider.
+func (h Handlers) OIDCCallback(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := fctx.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	if h.OIDC.Provider == nil {
+		return v1Web.NewRequestError(user.ErrExternalIdentitiesDisabled, http.StatusNotFound)
+	}
+
+	st, err := h.oidcState(r, v.Now)
+	if err != nil {
+		return v1Web.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	// The state can only be used once.
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookie,
+		Path:     path.Dir(r.URL.Path),
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+
+	q := r.URL.Query()
+	if subtle.ConstantTimeCompare([]byte(q.Get("state")), []byte(st.State)) != 1 {
+		return v1Web.NewRequestError(errors.New("state mismatch"), http.StatusBadRequest)
+	}
+	if e := q.Get("error"); e != "" {
+		return v1Web.NewRequestError(fmt.Errorf("sign in failed: %s", e), http.StatusUnauthorized)
+	}
+
+	claims, err := h.OIDC.Provider.Exchange(ctx, q.Get("code"), st.Verifier, st.Nonce)
+	if err != nil {
+		switch {
+		case errors.Is(err, oidc.ErrCodeRejected), errors.Is(err, oidc.ErrInvalidIDToken):
+			return v1Web.NewRequestError(err, http.StatusUnauthorized)
+		default:
+			return fmt.Errorf("exchanging code: %w", err)
+		}
+	}
+
+	ext := user.ExternalIdentity{
+		Provider:      h.OIDC.Name,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+	}
+	session, err := h.User.AuthenticateExternal(ctx, ext, v.Now)
+	if err != nil {
+		var challenge *user.ChallengeError
+		switch {
+		case errors.As(err, &challenge):
+			resp := challengeResponse{Challenge: challenge.Challenge, Expires: challenge.Expires}
+			return web.Respond(ctx, w, resp, http.StatusOK)
+		case errors.Is(err, user.ErrExternalIdentitiesDisabled), errors.Is(err, user.ErrTOTPDisabled):
+			return v1Web.NewRequestError(err, http.StatusNotFound)
+		case errors.Is(err, user.ErrIdentityNotLinked), errors.Is(err, user.ErrUnverified):
+			return v1Web.NewRequestError(err, http.StatusForbidden)
+		case errors.Is(err, user.ErrAuthenticationFailed):
+			return v1Web.NewRequestError(err, http.StatusUnauthorized)
+		default:
+			return fmt.Errorf("authenticating external identity: %w", err)
+		}
+	}
+
+	return h.respondTokens(ctx, w, r, session, v.Now)
+}
+
+// oidcState returns the state stored in the cookie of the request.
+func (h Handlers) oidcState(r *http.Request, now time.Time) (oidcState, error) {
+	invalid := errors.New("missing or invalid sign in state")
+
+	c, err := r.Cookie(oidcCookie)
+	if err != nil {
+		return oidcState{}, invalid
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(c.Value)
+	if err != nil {
+		return oidcState{}, invalid
+	}
+
+	b, err := h.OIDC.State.Decrypt(sealed)
+	if err != nil {
+		return oidcState{}, invalid
+	}
+
+	var st oidcState
+	if err := json.Unmarshal(b, &st); err != nil || now.After(st.Expires) {
+		return oidcState{}, invalid
+	}
+
+	return st, nil
+}
@@ -0,0 +1,208 @@
+package usergrp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/appinesshq/caservice/app/services/sales-api/sys/validate"
+	"github.com/appinesshq/caservice/app/services/sales-api/web/auth"
+	v1Web "github.com/appinesshq/caservice/app/services/sales-api/web/v1"
+	user "github.com/appinesshq/caservice/business/user/usecases"
+	fctx "github.com/appinesshq/caservice/foundation/context"
+	"github.com/appinesshq/caservice/foundation/web"
+)
+
+// CreateOrg adds a new organization to the system, with the authenticated
+// user as its first admin.
+func (h Handlers) CreateOrg(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := fctx.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var no user.NewOrg
+	if err := web.Decode(r, &no); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+	if err := validate.Check(no); err != nil {
+		return fmt.Errorf("validating data: %w", err)
+	}
+
+	o, err := h.User.CreateOrg(ctx, no, v.Now)
+	if err != nil {
+		if errors.Is(err, user.ErrUnauthorized) {
+			return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+		}
+		return fmt.Errorf("org[%+v]: %w", &no, err)
+	}
+
+	return web.Respond(ctx, w, o, http.StatusCreated)
+}
+
+// QueryOrgs returns the organizations the authenticated user is a member of.
+func (h Handlers) QueryOrgs(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	orgs, err := h.User.QueryOrgs(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to query organizations: %w", err)
+	}
+
+	return web.Respond(ctx, w, orgs, http.StatusOK)
+}
+
+// SwitchOrg responds with tokens for the authenticated user in another
+// organization the user is a member of.
+func (h Handlers) SwitchOrg(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := fctx.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	id := web.Param(r, "id")
+	if err := validate.CheckID(id); err != nil {
+		return v1Web.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	session, err := h.User.SwitchOrg(ctx, id, v.Now)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrUnauthorized), errors.Is(err, user.ErrAuthenticationFailed):
+			return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+		default:
+			return fmt.Errorf("ID[%s]: %w", id, err)
+		}
+	}
+
+	return h.respondTokens(ctx, w, r, session, v.Now)
+}
+
+// QueryMembers returns the members of the organization of the authenticated
+// user.
+func (h Handlers) QueryMembers(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	members, err := h.User.QueryMembers(ctx)
+	if err != nil {
+		if errors.Is(err, user.ErrUnauthorized) {
+			return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+		}
+		return fmt.Errorf("unable to query members: %w", err)
+	}
+
+	return web.Respond(ctx, w, members, http.StatusOK)
+}
+
+// InviteMember invites an existing user to become a member of the
+// organization of the authenticated user.
+func (h Handlers) InviteMember(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := fctx.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var nm user.NewMember
+	if err := web.Decode(r, &nm); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+	if err := validate.Check(nm); err != nil {
+		return fmt.Errorf("validating data: %w", err)
+	}
+
+	i, err := h.User.InviteMember(ctx, nm, v.Now)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrUnauthorized):
+			return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+		case errors.Is(err, user.ErrNotFound):
+			return v1Web.NewRequestError(err, http.StatusNotFound)
+		case errors.Is(err, user.ErrUniqueMembership), errors.Is(err, user.ErrUniqueInvitation):
+			return v1Web.NewRequestError(err, http.StatusConflict)
+		default:
+			return fmt.Errorf("member[%+v]: %w", &nm, err)
+		}
+	}
+
+	return web.Respond(ctx, w, i, http.StatusCreated)
+}
+
+// QueryInvitations returns the open invitations of the authenticated user.
+func (h Handlers) QueryInvitations(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	invitations, err := h.User.QueryInvitations(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to query invitations: %w", err)
+	}
+
+	return web.Respond(ctx, w, invitations, http.StatusOK)
+}
+
+// AcceptInvitation makes the authenticated user a member of the organization
+// it was invited to.
+func (h Handlers) AcceptInvitation(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := fctx.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	id := web.Param(r, "id")
+	if err := validate.CheckID(id); err != nil {
+		return v1Web.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	m, err := h.User.AcceptInvitation(ctx, id, v.Now)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrUnauthorized):
+			return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+		case errors.Is(err, user.ErrInvitationNotFound):
+			return v1Web.NewRequestError(err, http.StatusNotFound)
+		case errors.Is(err, user.ErrUniqueMembership):
+			return v1Web.NewRequestError(err, http.StatusConflict)
+		default:
+			return fmt.Errorf("InvitationID[%s]: %w", id, err)
+		}
+	}
+
+	return web.Respond(ctx, w, m, http.StatusOK)
+}
+
+// DeclineInvitation removes an invitation of the authenticated user.
+func (h Handlers) DeclineInvitation(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := web.Param(r, "id")
+	if err := validate.CheckID(id); err != nil {
+		return v1Web.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	if err := h.User.DeclineInvitation(ctx, id); err != nil {
+		switch {
+		case errors.Is(err, user.ErrUnauthorized):
+			return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+		case errors.Is(err, user.ErrInvitationNotFound):
+			return v1Web.NewRequestError(err, http.StatusNotFound)
+		default:
+			return fmt.Errorf("InvitationID[%s]: %w", id, err)
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// RemoveMember ends the membership of a user in the organization of the
+// authenticated user.
+func (h Handlers) RemoveMember(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := web.Param(r, "userid")
+	if err := validate.CheckID(id); err != nil {
+		return v1Web.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	if err := h.User.RemoveMember(ctx, id); err != nil {
+		switch {
+		case errors.Is(err, user.ErrUnauthorized):
+			return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+		case errors.Is(err, user.ErrMembershipNotFound):
+			return v1Web.NewRequestError(err, http.StatusNotFound)
+		default:
+			return fmt.Errorf("ID[%s]: %w", id, err)
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
@@ -0,0 +1,114 @@
+package usergrp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/appinesshq/caservice/app/services/sales-api/sys/validate"
+	"github.com/appinesshq/caservice/app/services/sales-api/web/auth"
+	v1Web "github.com/appinesshq/caservice/app/services/sales-api/web/v1"
+	entity "github.com/appinesshq/caservice/business/user"
+	user "github.com/appinesshq/caservice/business/user/usecases"
+	fctx "github.com/appinesshq/caservice/foundation/context"
+	"github.com/appinesshq/caservice/foundation/web"
+)
+
+// sessionResponse is a login session of the authenticated user. Current
+// marks the login session the request was authenticated with.
+type sessionResponse struct {
+	entity.LoginSession
+	Current bool
+}
+
+// QuerySessions returns the active login sessions of the authenticated user.
+func (h Handlers) QuerySessions(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := fctx.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	session, err := entity.GetSession(ctx)
+	if err != nil {
+		return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	sessions, err := h.User.QuerySessions(ctx, session.User.ID, v.Now)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrLoginSessionsDisabled):
+			return v1Web.NewRequestError(err, http.StatusNotFound)
+		case errors.Is(err, user.ErrUnauthorized):
+			return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+		default:
+			return fmt.Errorf("ID[%s]: %w", session.User.ID, err)
+		}
+	}
+
+	resp := make([]sessionResponse, len(sessions))
+	for i, ls := range sessions {
+		resp[i] = sessionResponse{LoginSession: ls, Current: ls.ID == session.LoginSessionID}
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// RevokeSession ends a login session of the authenticated user.
+func (h Handlers) RevokeSession(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := web.Param(r, "id")
+	if err := validate.CheckID(id); err != nil {
+		return v1Web.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	session, err := entity.GetSession(ctx)
+	if err != nil {
+		return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	if err := h.User.RevokeSession(ctx, session.User.ID, id); err != nil {
+		switch {
+		case errors.Is(err, user.ErrLoginSessionsDisabled), errors.Is(err, user.ErrLoginSessionNotFound):
+			return v1Web.NewRequestError(err, http.StatusNotFound)
+		case errors.Is(err, user.ErrUnauthorized):
+			return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+		default:
+			return fmt.Errorf("ID[%s] SessionID[%s]: %w", session.User.ID, id, err)
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// RevokeSessions ends all login sessions of the authenticated user,
+// including the one the request was authenticated with.
+func (h Handlers) RevokeSessions(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := fctx.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	session, err := entity.GetSession(ctx)
+	if err != nil {
+		return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	if err := h.User.RevokeSessions(ctx, session.User.ID); err != nil {
+		switch {
+		case errors.Is(err, user.ErrLoginSessionsDisabled):
+			return v1Web.NewRequestError(err, http.StatusNotFound)
+		case errors.Is(err, user.ErrUnauthorized):
+			return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+		default:
+			return fmt.Errorf("ID[%s]: %w", session.User.ID, err)
+		}
+	}
+
+	// Tokens issued before login sessions were tracked have no login
+	// session to refuse them.
+	if err := h.revokeAll(ctx, session.User.ID, v.Now); err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
@@ -6,10 +6,13 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/appinesshq/caservice/app/services/sales-api/sys/validate"
 	"github.com/appinesshq/caservice/app/services/sales-api/web/auth"
 	v1Web "github.com/appinesshq/caservice/app/services/sales-api/web/v1"
+	entity "github.com/appinesshq/caservice/business/user"
 	user "github.com/appinesshq/caservice/business/user/usecases"
 	fctx "github.com/appinesshq/caservice/foundation/context"
 	"github.com/appinesshq/caservice/foundation/web"
@@ -20,6 +23,7 @@ import (
 type Handlers struct {
 	User user.UserUseCases
 	Auth *auth.Auth
+	OIDC OIDC
 }
 
 // Token provides an API token for the authenticated user.
@@ -35,18 +39,503 @@ func (h Handlers) Token(ctx context.Context, w http.ResponseWriter, r *http.Requ
 		return v1Web.NewRequestError(err, http.StatusUnauthorized)
 	}
 
-	session, err := h.User.Authenticate(ctx, email, pass, v.Now)
+	session, err := h.User.Authenticate(ctx, email, pass, v1Web.ClientIP(r), v.Now)
 	if err != nil {
+		var locked *user.LockedError
+		var challenge *user.ChallengeError
 		switch {
-		case errors.Is(err, user.ErrNotFound):
+		case errors.As(err, &challenge):
+			resp := challengeResponse{Challenge: challenge.Challenge, Expires: challenge.Expires}
+			return web.Respond(ctx, w, resp, http.StatusOK)
+		case errors.As(err, &locked):
+			w.Header().Set("Retry-After", retryAfter(locked.RetryAfter(v.Now)))
+			return v1Web.NewRequestError(err, http.StatusTooManyRequests)
+		case errors.Is(err, user.ErrNotFound), errors.Is(err, user.ErrTOTPDisabled):
 			return v1Web.NewRequestError(err, http.StatusNotFound)
 		case errors.Is(err, user.ErrAuthenticationFailed):
 			return v1Web.NewRequestError(err, http.StatusUnauthorized)
+		case errors.Is(err, user.ErrUnverified):
+			return v1Web.NewRequestError(err, http.StatusForbidden)
 		default:
 			return fmt.Errorf("authenticating: %w", err)
 		}
 	}
 
+	return h.respondTokens(ctx, w, r, session, v.Now)
+}
+
+// CompleteChallenge provides an API token for a user with two-factor
+// authentication, after checking the code for the challenge returned by Token.
+func (h Handlers) CompleteChallenge(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := fctx.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var req struct {
+		Challenge string `json:"challenge" validate:"required"`
+		Code      string `json:"code" validate:"required"`
+	}
+	if err := web.Decode(r, &req); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+	if err := validate.Check(req); err != nil {
+		return fmt.Errorf("validating data: %w", err)
+	}
+
+	session, err := h.User.CompleteChallenge(ctx, req.Challenge, req.Code, v1Web.ClientIP(r), v.Now)
+	if err != nil {
+		var locked *user.LockedError
+		switch {
+		case errors.As(err, &locked):
+			w.Header().Set("Retry-After", retryAfter(locked.RetryAfter(v.Now)))
+			return v1Web.NewRequestError(err, http.StatusTooManyRequests)
+		case errors.Is(err, user.ErrTOTPDisabled):
+			return v1Web.NewRequestError(err, http.StatusNotFound)
+		case errors.Is(err, user.ErrInvalidChallenge), errors.Is(err, user.ErrInvalidTOTPCode):
+			return v1Web.NewRequestError(err, http.StatusUnauthorized)
+		default:
+			return fmt.Errorf("completing challenge: %w", err)
+		}
+	}
+
+	return h.respondTokens(ctx, w, r, session, v.Now)
+}
+
+// respondTokens responds with an API token for the session and a refresh
+// token, if refresh tokens are enabled. Sessions of users that just
+// authenticated start a new login session, if login sessions are tracked.
+func (h Handlers) respondTokens(ctx context.Context, w http.ResponseWriter, r *http.Request, session entity.Session, now time.Time) error {
+	var err error
+	if session.LoginSessionID == "" {
+		session, err = h.User.StartSession(ctx, session, v1Web.ClientIP(r), r.UserAgent(), now)
+		if err != nil {
+			return fmt.Errorf("starting session: %w", err)
+		}
+	}
+
+	var tkn tokenResponse
+	tkn.Token, err = h.accessToken(session)
+	if err != nil {
+		return err
+	}
+
+	if h.User.RefreshTokens != nil {
+		tkn.RefreshToken, err = h.User.IssueRefreshToken(ctx, session, now)
+		if err != nil {
+			return fmt.Errorf("issuing refresh token: %w", err)
+		}
+	}
+
+	return web.Respond(ctx, w, tkn, http.StatusOK)
+}
+
+// Refresh exchanges a refresh token for a new API token and refresh token.
+func (h Handlers) Refresh(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := fctx.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var req struct {
+		RefreshToken string `json:"refresh_token" validate:"required"`
+	}
+	if err := web.Decode(r, &req); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+	if err := validate.Check(req); err != nil {
+		return fmt.Errorf("validating data: %w", err)
+	}
+
+	session, refreshToken, err := h.User.Refresh(ctx, req.RefreshToken, v1Web.ClientIP(r), v.Now)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrRefreshTokensDisabled):
+			return v1Web.NewRequestError(err, http.StatusNotFound)
+		case errors.Is(err, user.ErrAuthenticationFailed), errors.Is(err, user.ErrRefreshTokenReused):
+			return v1Web.NewRequestError(err, http.StatusUnauthorized)
+		default:
+			return fmt.Errorf("refreshing token: %w", err)
+		}
+	}
+
+	tkn := tokenResponse{RefreshToken: refreshToken}
+	tkn.Token, err = h.accessToken(session)
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, w, tkn, http.StatusOK)
+}
+
+// RequestPasswordReset sends a password reset token to the email address of
+// a user. The response is the same for unknown email addresses.
+func (h Handlers) RequestPasswordReset(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := fctx.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var req struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+	if err := web.Decode(r, &req); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+	if err := validate.Check(req); err != nil {
+		return fmt.Errorf("validating data: %w", err)
+	}
+
+	if err := h.User.RequestPasswordReset(ctx, req.Email, v.Now); err != nil {
+		if errors.Is(err, user.ErrPasswordResetDisabled) {
+			return v1Web.NewRequestError(err, http.StatusNotFound)
+		}
+		return fmt.Errorf("requesting password reset: %w", err)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// ResetPassword sets a new password with a password reset token. All API
+// tokens of the user are revoked.
+func (h Handlers) ResetPassword(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := fctx.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var req struct {
+		Token           string `json:"token" validate:"required"`
+		Password        string `json:"password" validate:"required"`
+		PasswordConfirm string `json:"password_confirm" validate:"eqfield=Password"`
+	}
+	if err := web.Decode(r, &req); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+	if err := validate.Check(req); err != nil {
+		return fmt.Errorf("validating data: %w", err)
+	}
+
+	usr, err := h.User.ResetPassword(ctx, req.Token, req.Password, v.Now)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrPasswordResetDisabled):
+			return v1Web.NewRequestError(err, http.StatusNotFound)
+		case errors.Is(err, user.ErrInvalidResetToken):
+			return v1Web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("resetting password: %w", err)
+		}
+	}
+
+	if err := h.revokeAll(ctx, usr.ID, v.Now); err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// EnrollTOTP generates a new second factor for the authenticated user and
+// returns the otpauth:// URI for authenticator apps.
+func (h Handlers) EnrollTOTP(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := fctx.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	uri, err := h.User.EnrollTOTP(ctx, v.Now)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrTOTPDisabled):
+			return v1Web.NewRequestError(err, http.StatusNotFound)
+		case errors.Is(err, user.ErrUnauthorized):
+			return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+		case errors.Is(err, user.ErrTOTPAlreadyEnabled):
+			return v1Web.NewRequestError(err, http.StatusConflict)
+		default:
+			return fmt.Errorf("enrolling totp: %w", err)
+		}
+	}
+
+	resp := struct {
+		URI string `json:"uri"`
+	}{
+		URI: uri,
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// EnableTOTP enables two-factor authentication for the authenticated user
+// with the first code of the enrolled second factor. The response holds the
+// recovery codes of the user.
+func (h Handlers) EnableTOTP(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := fctx.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var req struct {
+		Code string `json:"code" validate:"required"`
+	}
+	if err := web.Decode(r, &req); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+	if err := validate.Check(req); err != nil {
+		return fmt.Errorf("validating data: %w", err)
+	}
+
+	codes, err := h.User.EnableTOTP(ctx, req.Code, v.Now)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrTOTPDisabled):
+			return v1Web.NewRequestError(err, http.StatusNotFound)
+		case errors.Is(err, user.ErrUnauthorized):
+			return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+		case errors.Is(err, user.ErrTOTPAlreadyEnabled), errors.Is(err, user.ErrTOTPNotEnrolled):
+			return v1Web.NewRequestError(err, http.StatusConflict)
+		case errors.Is(err, user.ErrInvalidTOTPCode):
+			return v1Web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("enabling totp: %w", err)
+		}
+	}
+
+	resp := struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}{
+		RecoveryCodes: codes,
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// DisableTOTP disables two-factor authentication for the authenticated user
+// with a code of the second factor or a recovery code.
+func (h Handlers) DisableTOTP(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := fctx.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var req struct {
+		Code string `json:"code" validate:"required"`
+	}
+	if err := web.Decode(r, &req); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+	if err := validate.Check(req); err != nil {
+		return fmt.Errorf("validating data: %w", err)
+	}
+
+	if err := h.User.DisableTOTP(ctx, req.Code, v.Now); err != nil {
+		switch {
+		case errors.Is(err, user.ErrTOTPDisabled):
+			return v1Web.NewRequestError(err, http.StatusNotFound)
+		case errors.Is(err, user.ErrUnauthorized):
+			return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+		case errors.Is(err, user.ErrTOTPNotEnrolled):
+			return v1Web.NewRequestError(err, http.StatusConflict)
+		case errors.Is(err, user.ErrInvalidTOTPCode):
+			return v1Web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("disabling totp: %w", err)
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// VerifyEmail verifies the email address of a user with the token of a
+// verification link.
+func (h Handlers) VerifyEmail(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := fctx.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		return v1Web.NewRequestError(errors.New("missing verification token"), http.StatusBadRequest)
+	}
+
+	if _, err := h.User.VerifyEmail(ctx, token, v.Now); err != nil {
+		switch {
+		case errors.Is(err, user.ErrVerificationDisabled):
+			return v1Web.NewRequestError(err, http.StatusNotFound)
+		case errors.Is(err, user.ErrInvalidVerificationToken):
+			return v1Web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("verifying email: %w", err)
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// RequestVerification sends a new verification link to a user.
+func (h Handlers) RequestVerification(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := fctx.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var req struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+	if err := web.Decode(r, &req); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+	if err := validate.Check(req); err != nil {
+		return fmt.Errorf("validating data: %w", err)
+	}
+
+	if err := h.User.RequestVerification(ctx, req.Email, v.Now); err != nil {
+		if errors.Is(err, user.ErrVerificationDisabled) {
+			return v1Web.NewRequestError(err, http.StatusNotFound)
+		}
+		return fmt.Errorf("requesting verification: %w", err)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Logout revokes the API token the request was authenticated with and ends
+// its login session, if login sessions are tracked.
+func (h Handlers) Logout(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	// Requests authenticated with an API key have no token to revoke.
+	if claims.ID == "" {
+		err := errors.New("api keys must be revoked with their own endpoint")
+		return v1Web.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	session, err := entity.GetSession(ctx)
+	if err != nil {
+		return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+	if session.LoginSessionID != "" {
+		if err := h.User.RevokeSession(ctx, session.User.ID, session.LoginSessionID); err != nil {
+			return fmt.Errorf("logout: %w", err)
+		}
+	}
+
+	// A revoked login session refuses its tokens without token revocation.
+	if err := h.Auth.Revoke(ctx, claims); err != nil {
+		if !errors.Is(err, auth.ErrRevocationDisabled) {
+			return fmt.Errorf("logout: %w", err)
+		}
+		if session.LoginSessionID == "" {
+			return v1Web.NewRequestError(err, http.StatusNotFound)
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// RevokeTokens revokes all API tokens and refresh tokens of a user.
+func (h Handlers) RevokeTokens(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := fctx.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	id := web.Param(r, "id")
+	if err := validate.CheckID(id); err != nil {
+		return v1Web.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	if err := h.User.RevokeTokens(ctx, id, v.Now); err != nil {
+		switch {
+		case errors.Is(err, user.ErrTokenRevocationDisabled), errors.Is(err, user.ErrNotFound):
+			return v1Web.NewRequestError(err, http.StatusNotFound)
+		case errors.Is(err, user.ErrUnauthorized):
+			return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+		default:
+			return fmt.Errorf("ID[%s]: %w", id, err)
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Impersonate returns a short-lived API token for another user, on behalf of
+// the authenticated admin. The token carries the admin as actor and can't be
+// refreshed.
+func (h Handlers) Impersonate(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := fctx.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	id := web.Param(r, "id")
+	if err := validate.CheckID(id); err != nil {
+		return v1Web.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	session, err := h.User.Impersonate(ctx, id, v1Web.ClientIP(r), r.UserAgent(), v.Now)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrImpersonationDisabled):
+			return v1Web.NewRequestError(err, http.StatusNotFound)
+		case errors.Is(err, user.ErrUnauthorized):
+			return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+		case errors.Is(err, user.ErrNotFound), errors.Is(err, user.ErrAuthenticationFailed):
+			return v1Web.NewRequestError(user.ErrNotFound, http.StatusNotFound)
+		default:
+			return fmt.Errorf("ID[%s]: %w", id, err)
+		}
+	}
+
+	var tkn tokenResponse
+	tkn.Token, err = h.accessToken(session)
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, w, tkn, http.StatusOK)
+}
+
+// revokeAll revokes all API tokens of a user, if token revocation is enabled.
+// It is used when tokens carry outdated claims, like roles that were changed.
+func (h Handlers) revokeAll(ctx context.Context, id string, now time.Time) error {
+	if err := h.Auth.RevokeAll(ctx, id, now); err != nil && !errors.Is(err, auth.ErrRevocationDisabled) {
+		return fmt.Errorf("ID[%s]: %w", id, err)
+	}
+	return nil
+}
+
+// retryAfter formats a duration as the value of a Retry-After header,
+// which is a whole number of seconds.
+func retryAfter(d time.Duration) string {
+	secs := int64((d + time.Second - 1) / time.Second)
+	if secs < 1 {
+		secs = 1
+	}
+	return strconv.FormatInt(secs, 10)
+}
+
+// tokenResponse is the response of the token endpoints.
+type tokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+// challengeResponse is the response of the token endpoint for users with
+// two-factor authentication.
+type challengeResponse struct {
+	Challenge string    `json:"challenge"`
+	Expires   time.Time `json:"expires"`
+}
+
+// accessToken generates a signed API token for the user of the session,
+// which expires together with the session and is valid within the
+// organization of the session only.
+func (h Handlers) accessToken(session entity.Session) (string, error) {
 	claims := auth.Claims{
 		RegisteredClaims: jwt.RegisteredClaims{
 			Subject:   session.User.ID,
@@ -54,18 +543,20 @@ func (h Handlers) Token(ctx context.Context, w http.ResponseWriter, r *http.Requ
 			ExpiresAt: jwt.NewNumericDate(session.Expires),
 			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
 		},
-		Roles: session.User.Roles,
+		Roles:     session.User.Roles,
+		TenantID:  session.TenantID,
+		SessionID: session.LoginSessionID,
 	}
-
-	var tkn struct {
-		Token string `json:"token"`
+	if session.ImpersonatorID != "" {
+		claims.Actor = &auth.ActorClaim{Subject: session.ImpersonatorID}
 	}
-	tkn.Token, err = h.Auth.GenerateToken(claims)
+
+	token, err := h.Auth.GenerateToken(claims)
 	if err != nil {
-		return fmt.Errorf("generating token: %w", err)
+		return "", fmt.Errorf("generating token: %w", err)
 	}
 
-	return web.Respond(ctx, w, tkn, http.StatusOK)
+	return token, nil
 }
 
 // Register adds a new user to the system.
@@ -105,11 +596,297 @@ func (h Handlers) Create(ctx context.Context, w http.ResponseWriter, r *http.Req
 
 	usr, err := h.User.Create(ctx, nu, v.Now)
 	if err != nil {
-		if errors.Is(err, user.ErrUniqueEmail) {
+		switch {
+		case errors.Is(err, user.ErrUnauthorized):
+			return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+		case errors.Is(err, user.ErrUniqueEmail):
 			return v1Web.NewRequestError(err, http.StatusConflict)
+		default:
+			return fmt.Errorf("user[%+v]: %w", &usr, err)
 		}
-		return fmt.Errorf("user[%+v]: %w", &usr, err)
 	}
 
 	return web.Respond(ctx, w, usr, http.StatusCreated)
 }
+
+// Query returns a list of users with paging.
+func (h Handlers) Query(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	page := web.Param(r, "page")
+	pageNumber, err := strconv.Atoi(page)
+	if err != nil {
+		return v1Web.NewRequestError(fmt.Errorf("invalid page format, page[%s]", page), http.StatusBadRequest)
+	}
+	rows := web.Param(r, "rows")
+	rowsPerPage, err := strconv.Atoi(rows)
+	if err != nil {
+		return v1Web.NewRequestError(fmt.Errorf("invalid rows format, rows[%s]", rows), http.StatusBadRequest)
+	}
+
+	users, err := h.User.Query(ctx, entity.QueryFilter{}, pageNumber, rowsPerPage)
+	if err != nil {
+		if errors.Is(err, user.ErrUnauthorized) {
+			return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+		}
+		return fmt.Errorf("unable to query for users: %w", err)
+	}
+
+	return web.Respond(ctx, w, users, http.StatusOK)
+}
+
+// defaultRowsPerPage is the number of users listed per page if the rows
+// query parameter is missing.
+const defaultRowsPerPage = 20
+
+// userPage is a page of a listing of users, with the total number of users
+// in the listing.
+type userPage struct {
+	Items       []entity.User `json:"items"`
+	Total       int           `json:"total"`
+	Page        int           `json:"page"`
+	RowsPerPage int           `json:"rows"`
+}
+
+// List returns a page of users, with the total number of users so clients
+// can page through them. The page and rows query parameters select the page.
+// Users can be filtered by the name, email and role query parameters and by
+// the since and until query parameters, which are RFC 3339 times of their
+// creation. The order_by and direction query parameters order the users.
+func (h Handlers) List(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	q := r.URL.Query()
+
+	pageNumber := 1
+	if page := q.Get("page"); page != "" {
+		var err error
+		if pageNumber, err = strconv.Atoi(page); err != nil || pageNumber < 1 {
+			return v1Web.NewRequestError(fmt.Errorf("invalid page format, page[%s]", page), http.StatusBadRequest)
+		}
+	}
+	rowsPerPage := defaultRowsPerPage
+	if rows := q.Get("rows"); rows != "" {
+		var err error
+		if rowsPerPage, err = strconv.Atoi(rows); err != nil || rowsPerPage < 1 {
+			return v1Web.NewRequestError(fmt.Errorf("invalid rows format, rows[%s]", rows), http.StatusBadRequest)
+		}
+	}
+
+	f, err := filter(r)
+	if err != nil {
+		return v1Web.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	users, err := h.User.Query(ctx, f, pageNumber, rowsPerPage)
+	if err != nil {
+		if errors.Is(err, user.ErrUnauthorized) {
+			return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+		}
+		return fmt.Errorf("unable to query for users: %w", err)
+	}
+
+	total, err := h.User.Count(ctx, f)
+	if err != nil {
+		return fmt.Errorf("unable to count users: %w", err)
+	}
+
+	p := userPage{
+		Items:       users,
+		Total:       total,
+		Page:        pageNumber,
+		RowsPerPage: rowsPerPage,
+	}
+
+	return web.Respond(ctx, w, p, http.StatusOK)
+}
+
+// filter returns the user filter of the query parameters of the request.
+func filter(r *http.Request) (entity.QueryFilter, error) {
+	q := r.URL.Query()
+
+	f := entity.QueryFilter{
+		Name:      q.Get("name"),
+		Email:     q.Get("email"),
+		Role:      q.Get("role"),
+		OrderBy:   q.Get("order_by"),
+		Direction: q.Get("direction"),
+	}
+
+	var err error
+	if since := q.Get("since"); since != "" {
+		if f.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			return entity.QueryFilter{}, fmt.Errorf("invalid since format, since[%s]", since)
+		}
+	}
+	if until := q.Get("until"); until != "" {
+		if f.Until, err = time.Parse(time.RFC3339, until); err != nil {
+			return entity.QueryFilter{}, fmt.Errorf("invalid until format, until[%s]", until)
+		}
+	}
+
+	return f, nil
+}
+
+// QueryDeleted returns a list of deleted users with paging.
+func (h Handlers) QueryDeleted(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	page := web.Param(r, "page")
+	pageNumber, err := strconv.Atoi(page)
+	if err != nil {
+		return v1Web.NewRequestError(fmt.Errorf("invalid page format, page[%s]", page), http.StatusBadRequest)
+	}
+	rows := web.Param(r, "rows")
+	rowsPerPage, err := strconv.Atoi(rows)
+	if err != nil {
+		return v1Web.NewRequestError(fmt.Errorf("invalid rows format, rows[%s]", rows), http.StatusBadRequest)
+	}
+
+	users, err := h.User.QueryDeleted(ctx, pageNumber, rowsPerPage)
+	if err != nil {
+		if errors.Is(err, user.ErrUnauthorized) {
+			return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+		}
+		return fmt.Errorf("unable to query for deleted users: %w", err)
+	}
+
+	return web.Respond(ctx, w, users, http.StatusOK)
+}
+
+// QueryByID returns a user by its ID.
+func (h Handlers) QueryByID(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := web.Param(r, "id")
+	if err := validate.CheckID(id); err != nil {
+		return v1Web.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	usr, err := h.User.QueryByID(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrUnauthorized):
+			return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+		case errors.Is(err, user.ErrNotFound):
+			return v1Web.NewRequestError(err, http.StatusNotFound)
+		default:
+			return fmt.Errorf("ID[%s]: %w", id, err)
+		}
+	}
+
+	w.Header().Set("ETag", etag(usr.Version))
+	return web.Respond(ctx, w, usr, http.StatusOK)
+}
+
+// Update updates a user in the system. With an If-Match header holding the
+// ETag of the user, the user is only updated if it did not change since.
+func (h Handlers) Update(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := fctx.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	id := web.Param(r, "id")
+	if err := validate.CheckID(id); err != nil {
+		return v1Web.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	var upd user.UpdateUser
+	if err := web.Decode(r, &upd); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+	if err := validate.Check(upd); err != nil {
+		return fmt.Errorf("validating data: %w", err)
+	}
+
+	var version int
+	if match := r.Header.Get("If-Match"); match != "" && match != "*" {
+		if version, err = parseETag(match); err != nil {
+			return v1Web.NewRequestError(err, http.StatusBadRequest)
+		}
+	}
+
+	usr, err := h.User.Update(ctx, id, upd, version, v.Now)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrUnauthorized):
+			return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+		case errors.Is(err, user.ErrNotFound):
+			return v1Web.NewRequestError(err, http.StatusNotFound)
+		case errors.Is(err, user.ErrUniqueEmail):
+			return v1Web.NewRequestError(err, http.StatusConflict)
+		case errors.Is(err, user.ErrConflict) && version != 0:
+			return v1Web.NewRequestError(err, http.StatusPreconditionFailed)
+		case errors.Is(err, user.ErrConflict):
+			return v1Web.NewRequestError(err, http.StatusConflict)
+		default:
+			return fmt.Errorf("ID[%s] User[%+v]: %w", id, &upd, err)
+		}
+	}
+
+	w.Header().Set("ETag", etag(usr.Version))
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// etag returns the ETag of a version of a user.
+func etag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// parseETag returns the version of a user in an ETag.
+func parseETag(tag string) (int, error) {
+	s, err := strconv.Unquote(tag)
+	if err != nil {
+		return 0, fmt.Errorf("invalid etag format, etag[%s]", tag)
+	}
+	version, err := strconv.Atoi(s)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("invalid etag format, etag[%s]", tag)
+	}
+	return version, nil
+}
+
+// Delete marks a user as deleted and signs it out everywhere.
+func (h Handlers) Delete(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := fctx.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	id := web.Param(r, "id")
+	if err := validate.CheckID(id); err != nil {
+		return v1Web.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	if err := h.User.Delete(ctx, id, v.Now); err != nil {
+		switch {
+		case errors.Is(err, user.ErrUnauthorized):
+			return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+		case errors.Is(err, user.ErrNotFound):
+			return v1Web.NewRequestError(err, http.StatusNotFound)
+		default:
+			return fmt.Errorf("ID[%s]: %w", id, err)
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Restore undoes the deletion of a user.
+func (h Handlers) Restore(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := fctx.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	id := web.Param(r, "id")
+	if err := validate.CheckID(id); err != nil {
+		return v1Web.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	if err := h.User.Restore(ctx, id, v.Now); err != nil {
+		switch {
+		case errors.Is(err, user.ErrUnauthorized):
+			return v1Web.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+		case errors.Is(err, user.ErrNotFound):
+			return v1Web.NewRequestError(err, http.StatusNotFound)
+		default:
+			return fmt.Errorf("ID[%s]: %w", id, err)
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
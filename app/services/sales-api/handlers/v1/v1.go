@@ -6,51 +6,177 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/appinesshq/caservice/app/services/sales-api/handlers/v1/auditgrp"
+	"github.com/appinesshq/caservice/app/services/sales-api/handlers/v1/productgrp"
+	"github.com/appinesshq/caservice/app/services/sales-api/handlers/v1/salegrp"
 	"github.com/appinesshq/caservice/app/services/sales-api/handlers/v1/usergrp"
 	"github.com/appinesshq/caservice/app/services/sales-api/web/auth"
-	"github.com/appinesshq/caservice/data"
-
-	// "github.com/appinesshq/caservice/app/services/sales-api/web/v1/mid"
+	"github.com/appinesshq/caservice/app/services/sales-api/web/v1/mid"
+	audit "github.com/appinesshq/caservice/business/audit/usecases"
+	"github.com/appinesshq/caservice/business/authz"
+	product "github.com/appinesshq/caservice/business/product/usecases"
+	sale "github.com/appinesshq/caservice/business/sale/usecases"
+	entity "github.com/appinesshq/caservice/business/user"
 	user "github.com/appinesshq/caservice/business/user/usecases"
+	"github.com/appinesshq/caservice/data"
+	"github.com/appinesshq/caservice/foundation/encryption"
+	"github.com/appinesshq/caservice/foundation/mail"
 	"github.com/appinesshq/caservice/foundation/web"
 	"go.uber.org/zap"
 )
 
 // Config contains all the mandatory systems required by handlers.
 type Config struct {
-	Log                 *zap.SugaredLogger
-	Auth                *auth.Auth
-	Repositories        *data.Repositories
-	UserSessionDuration time.Duration
+	Log                   *zap.SugaredLogger
+	Auth                  *auth.Auth
+	Repositories          *data.Repositories
+	UserSessionDuration   time.Duration
+	RefreshTokenDuration  time.Duration
+	Mailer                mail.Mailer
+	ResetTokenDuration    time.Duration
+	VerificationKey       []byte
+	VerificationURL       string
+	VerificationDuration  time.Duration
+	RequireVerifiedEmail  bool
+	AccountLockout        entity.LockoutPolicy
+	IPLockout             entity.LockoutPolicy
+	TOTPSecrets           *encryption.Box
+	TOTPIssuer            string
+	RequireAdminTOTP      bool
+	OIDC                  usergrp.OIDC
+	OIDCAutoProvision     bool
+	APIKeyMaxDuration     time.Duration
+	PasswordPolicy        entity.PasswordPolicy
+	ImpersonationDuration time.Duration
 }
 
 // Routes binds all the version 1 routes.
 func Routes(app *web.App, cfg Config) {
 	const version = "v1"
 
-	// authen := mid.Authenticate(cfg.Auth)
-	// admin := mid.Authorize(auth.RoleAdmin)
+	// Changes are recorded in the audit trail, if there is a repository for it.
+	var auditUseCases audit.AuditUseCases
+	var userOptions []func(*user.UserUseCases)
+	var productOptions []func(*product.ProductUseCases)
+	var saleOptions []func(*sale.SaleUseCases)
+	if cfg.Repositories.AuditRepo != nil {
+		auditUseCases = audit.New(cfg.Log, cfg.Repositories.AuditRepo)
+		userOptions = append(userOptions, user.WithAudit(auditUseCases))
+		productOptions = append(productOptions, product.WithAudit(auditUseCases))
+		saleOptions = append(saleOptions, sale.WithAudit(auditUseCases))
+	}
+
+	if cfg.Repositories.RefreshTokenRepo != nil {
+		userOptions = append(userOptions, user.WithRefreshTokens(cfg.Repositories.RefreshTokenRepo, cfg.RefreshTokenDuration))
+	}
+	if cfg.Repositories.OneTimeTokenRepo != nil && cfg.Mailer != nil {
+		userOptions = append(userOptions, user.WithPasswordReset(cfg.Repositories.OneTimeTokenRepo, cfg.Mailer, cfg.ResetTokenDuration))
+	}
+	if len(cfg.VerificationKey) > 0 && cfg.Mailer != nil {
+		userOptions = append(userOptions, user.WithEmailVerification(cfg.Mailer, cfg.VerificationKey, cfg.VerificationURL, cfg.VerificationDuration))
+	}
+	if cfg.Repositories.LoginAttemptRepo != nil {
+		userOptions = append(userOptions, user.WithLockout(cfg.Repositories.LoginAttemptRepo, cfg.AccountLockout, cfg.IPLockout))
+	}
+	if cfg.TOTPSecrets != nil && cfg.Repositories.OneTimeTokenRepo != nil {
+		userOptions = append(userOptions, user.WithTOTP(cfg.Repositories.OneTimeTokenRepo, cfg.TOTPSecrets, cfg.TOTPIssuer))
+	}
+	if cfg.RequireAdminTOTP {
+		userOptions = append(userOptions, user.WithRequireAdminTOTP())
+	}
+	if cfg.OIDC.Provider != nil && cfg.Repositories.IdentityRepo != nil {
+		userOptions = append(userOptions, user.WithExternalIdentities(cfg.Repositories.IdentityRepo, cfg.OIDCAutoProvision))
+	}
+	if cfg.Repositories.LoginSessionRepo != nil {
+		userOptions = append(userOptions, user.WithLoginSessions(cfg.Repositories.LoginSessionRepo))
+	}
+	if cfg.Repositories.APIKeyRepo != nil {
+		userOptions = append(userOptions, user.WithAPIKeys(cfg.Repositories.APIKeyRepo, cfg.APIKeyMaxDuration))
+	}
+	if cfg.ImpersonationDuration > 0 {
+		userOptions = append(userOptions, user.WithImpersonation(cfg.ImpersonationDuration))
+	}
+	if cfg.Auth.RevocationEnabled() {
+		userOptions = append(userOptions, user.WithTokenRevocation(cfg.Auth))
+	}
+	if cfg.RequireVerifiedEmail {
+		userOptions = append(userOptions, user.WithRequireVerified())
+	}
+	userOptions = append(userOptions, user.WithPasswordPolicy(cfg.PasswordPolicy))
+	userUseCases := user.New(cfg.Log, cfg.Repositories.UserRepo, cfg.Repositories.OrgRepo, cfg.UserSessionDuration, userOptions...)
+
+	authen := mid.Authenticate(cfg.Auth, userUseCases)
 
 	// Register user management and authentication endpoints.
 	ugh := usergrp.Handlers{
-		User: user.New(cfg.Log, cfg.Repositories.UserRepo, cfg.UserSessionDuration),
+		User: userUseCases,
 		Auth: cfg.Auth,
+		OIDC: cfg.OIDC,
 	}
 	app.Handle(http.MethodGet, version, "/users/token", ugh.Token)
+	app.Handle(http.MethodPost, version, "/users/token/refresh", ugh.Refresh)
+	app.Handle(http.MethodPost, version, "/users/token/2fa", ugh.CompleteChallenge)
+	app.Handle(http.MethodGet, version, "/users/oidc/login", ugh.OIDCLogin)
+	app.Handle(http.MethodGet, version, "/users/oidc/callback", ugh.OIDCCallback)
+	app.Handle(http.MethodPost, version, "/users/2fa/enroll", ugh.EnrollTOTP, authen)
+	app.Handle(http.MethodPost, version, "/users/2fa/enable", ugh.EnableTOTP, authen)
+	app.Handle(http.MethodPost, version, "/users/2fa/disable", ugh.DisableTOTP, authen)
+	app.Handle(http.MethodPost, version, "/users/logout", ugh.Logout, authen)
+	app.Handle(http.MethodGet, version, "/users/me/sessions", ugh.QuerySessions, authen)
+	app.Handle(http.MethodDelete, version, "/users/me/sessions", ugh.RevokeSessions, authen)
+	app.Handle(http.MethodDelete, version, "/users/me/sessions/:id", ugh.RevokeSession, authen)
+	app.Handle(http.MethodGet, version, "/users/me/invitations", ugh.QueryInvitations, authen)
+	app.Handle(http.MethodPost, version, "/users/me/invitations/:id/accept", ugh.AcceptInvitation, authen)
+	app.Handle(http.MethodDelete, version, "/users/me/invitations/:id", ugh.DeclineInvitation, authen)
+	app.Handle(http.MethodPost, version, "/users/password-reset", ugh.RequestPasswordReset)
+	app.Handle(http.MethodPost, version, "/users/password-reset/confirm", ugh.ResetPassword)
+	app.Handle(http.MethodGet, version, "/users/verify", ugh.VerifyEmail)
+	app.Handle(http.MethodPost, version, "/users/verify", ugh.RequestVerification)
 	app.Handle(http.MethodPost, version, "/users/register", ugh.Register)
-	// app.Handle(http.MethodPost, version, "/users", ugh.Create, authen, admin)
-	// app.Handle(http.MethodGet, version, "/users/:page/:rows", ugh.Query, authen, admin)
-	// app.Handle(http.MethodGet, version, "/users/:id", ugh.QueryByID, authen)
-	// app.Handle(http.MethodPut, version, "/users/:id", ugh.Update, authen, admin)
-	// app.Handle(http.MethodDelete, version, "/users/:id", ugh.Delete, authen, admin)
+	app.Handle(http.MethodPost, version, "/users", ugh.Create, authen, mid.Authorize(authz.ActionUserCreate))
+	app.Handle(http.MethodGet, version, "/users", ugh.List, authen, mid.Authorize(authz.ActionUserQuery))
+	app.Handle(http.MethodGet, version, "/users/:page/:rows", ugh.Query, authen, mid.Authorize(authz.ActionUserQuery))
+	app.Handle(http.MethodGet, version, "/users/deleted/:page/:rows", ugh.QueryDeleted, authen, mid.Authorize(authz.ActionUserQuery))
+	app.Handle(http.MethodGet, version, "/users/:id", ugh.QueryByID, authen)
+	app.Handle(http.MethodPut, version, "/users/:id", ugh.Update, authen)
+	app.Handle(http.MethodDelete, version, "/users/:id", ugh.Delete, authen)
+	app.Handle(http.MethodPost, version, "/users/:id/restore", ugh.Restore, authen, mid.Authorize(authz.ActionUserRestore))
+	app.Handle(http.MethodDelete, version, "/users/:id/tokens", ugh.RevokeTokens, authen)
+	app.Handle(http.MethodPost, version, "/users/:id/impersonate", ugh.Impersonate, authen, mid.Authorize(authz.ActionUserImpersonate))
+	app.Handle(http.MethodPost, version, "/users/:id/apikeys", ugh.CreateAPIKey, authen)
+	app.Handle(http.MethodGet, version, "/users/:id/apikeys", ugh.QueryAPIKeys, authen)
+	app.Handle(http.MethodDelete, version, "/users/:id/apikeys/:keyid", ugh.RevokeAPIKey, authen)
+	app.Handle(http.MethodPost, version, "/orgs", ugh.CreateOrg, authen, mid.Authorize(authz.ActionOrgCreate))
+	app.Handle(http.MethodGet, version, "/orgs", ugh.QueryOrgs, authen)
+	app.Handle(http.MethodGet, version, "/orgs/members", ugh.QueryMembers, authen, mid.Authorize(authz.ActionOrgQueryMembers))
+	app.Handle(http.MethodPost, version, "/orgs/invitations", ugh.InviteMember, authen, mid.Authorize(authz.ActionOrgInviteMember))
+	app.Handle(http.MethodDelete, version, "/orgs/members/:userid", ugh.RemoveMember, authen)
+	app.Handle(http.MethodGet, version, "/orgs/:id/token", ugh.SwitchOrg, authen, mid.Authorize(authz.ActionOrgSwitch))
 
 	// Register product and sale endpoints.
-	// pgh := productgrp.Handlers{
-	// 	Product: product.NewCore(cfg.Log, cfg.DB),
-	// }
-	// app.Handle(http.MethodGet, version, "/products/:page/:rows", pgh.Query, authen)
-	// app.Handle(http.MethodGet, version, "/products/:id", pgh.QueryByID, authen)
-	// app.Handle(http.MethodPost, version, "/products", pgh.Create, authen)
-	// app.Handle(http.MethodPut, version, "/products/:id", pgh.Update, authen)
-	// app.Handle(http.MethodDelete, version, "/products/:id", pgh.Delete, authen)
+	pgh := productgrp.Handlers{
+		Product: product.New(cfg.Log, cfg.Repositories.ProductRepo, productOptions...),
+	}
+	app.Handle(http.MethodGet, version, "/products/:page/:rows", pgh.Query, authen)
+	app.Handle(http.MethodGet, version, "/products/:id", pgh.QueryByID, authen)
+	app.Handle(http.MethodGet, version, "/users/:id/products", pgh.QueryByUserID, authen)
+	app.Handle(http.MethodPost, version, "/products", pgh.Create, authen)
+	app.Handle(http.MethodPut, version, "/products/:id", pgh.Update, authen)
+	app.Handle(http.MethodDelete, version, "/products/:id", pgh.Delete, authen)
+
+	sgh := salegrp.Handlers{
+		Sale: sale.New(cfg.Log, cfg.Repositories.SaleRepo, cfg.Repositories.ProductRepo, saleOptions...),
+	}
+	app.Handle(http.MethodPost, version, "/products/:id/sales", sgh.Create, authen)
+	app.Handle(http.MethodGet, version, "/products/:id/sales", sgh.QueryByProductID, authen)
+	app.Handle(http.MethodGet, version, "/users/:id/sales", sgh.QueryByUserID, authen)
+	app.Handle(http.MethodGet, version, "/sales/:id", sgh.QueryByID, authen)
+
+	// Register audit trail endpoints.
+	if cfg.Repositories.AuditRepo != nil {
+		agh := auditgrp.Handlers{
+			Audit: auditUseCases,
+		}
+		app.Handle(http.MethodGet, version, "/audit/:page/:rows", agh.Query, authen, mid.Authorize(authz.ActionAuditQuery))
+	}
 }
@@ -5,7 +5,9 @@ import (
 	"errors"
 	"expvar" // Calls init function.
 	"fmt"
+	"net"
 	"net/http"
+	"net/smtp"
 	"os"
 	"os/signal"
 	"runtime"
@@ -13,13 +15,28 @@ import (
 	"time"
 
 	"github.com/appinesshq/caservice/app/services/sales-api/handlers"
+	"github.com/appinesshq/caservice/app/services/sales-api/handlers/v1/usergrp"
 	"github.com/appinesshq/caservice/app/services/sales-api/web/auth"
+	entity "github.com/appinesshq/caservice/business/user"
 	"github.com/appinesshq/caservice/data"
+	"github.com/appinesshq/caservice/data/audit"
+	auditpg "github.com/appinesshq/caservice/data/audit/pg"
 	database "github.com/appinesshq/caservice/data/core/pg"
+	loginattemptpg "github.com/appinesshq/caservice/data/loginattempt/pg"
+	"github.com/appinesshq/caservice/data/product"
+	productpg "github.com/appinesshq/caservice/data/product/pg"
+	revocationpg "github.com/appinesshq/caservice/data/revocation/pg"
+	"github.com/appinesshq/caservice/data/sale"
+	salepg "github.com/appinesshq/caservice/data/sale/pg"
 	"github.com/appinesshq/caservice/data/user"
 	"github.com/appinesshq/caservice/data/user/pg"
+	"github.com/appinesshq/caservice/foundation/breached"
+	"github.com/appinesshq/caservice/foundation/encryption"
 	"github.com/appinesshq/caservice/foundation/keystore"
 	"github.com/appinesshq/caservice/foundation/logger"
+	"github.com/appinesshq/caservice/foundation/mail"
+	"github.com/appinesshq/caservice/foundation/oidc"
+	"github.com/appinesshq/caservice/foundation/passhash"
 	"github.com/ardanlabs/conf/v3"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -81,9 +98,63 @@ func run(log *zap.SugaredLogger) error {
 			DebugHost       string        `conf:"default:0.0.0.0:4000"`
 		}
 		Auth struct {
-			KeysFolder          string        `conf:"default:zarf/keys/"`
-			ActiveKID           string        `conf:"default:54bb2165-71e1-41a6-af3e-7da4a0e1e2c1"`
-			UserSessionDuration time.Duration `conf:"default:1h"`
+			KeysFolder            string        `conf:"default:zarf/keys/"`
+			ActiveKID             string        `conf:"default:54bb2165-71e1-41a6-af3e-7da4a0e1e2c1"`
+			KeysReloadInterval    time.Duration `conf:"default:1m"`
+			Algorithms            []string      `conf:"default:RS256;ES256;EdDSA"`
+			UserSessionDuration   time.Duration `conf:"default:15m"`
+			RefreshTokenDuration  time.Duration `conf:"default:720h"`
+			ResetTokenDuration    time.Duration `conf:"default:1h"`
+			VerificationKey       string        `conf:"mask"`
+			VerificationURL       string        `conf:"default:http://localhost:3000/v1/users/verify"`
+			VerificationDuration  time.Duration `conf:"default:72h"`
+			RequireVerifiedEmail  bool          `conf:"default:false"`
+			TOTPKey               string        `conf:"mask"`
+			TOTPIssuer            string        `conf:"default:sales-api"`
+			RequireAdminTOTP      bool          `conf:"default:false"`
+			APIKeyMaxDuration     time.Duration `conf:"default:8760h"`
+			ImpersonationDuration time.Duration `conf:"default:15m"`
+		}
+		OIDC struct {
+			Issuer        string
+			ClientID      string
+			ClientSecret  string `conf:"mask"`
+			RedirectURL   string `conf:"default:http://localhost:3000/v1/users/oidc/callback"`
+			Name          string `conf:"default:oidc"`
+			AutoProvision bool   `conf:"default:false"`
+			StateKey      string `conf:"mask"`
+		}
+		Lockout struct {
+			Disabled            bool          `conf:"default:false"`
+			AccountFreeAttempts int           `conf:"default:5"`
+			AccountDelay        time.Duration `conf:"default:1s"`
+			AccountMaxDelay     time.Duration `conf:"default:15m"`
+			IPFreeAttempts      int           `conf:"default:20"`
+			IPDelay             time.Duration `conf:"default:1s"`
+			IPMaxDelay          time.Duration `conf:"default:15m"`
+			ResetAfter          time.Duration `conf:"default:24h"`
+		}
+		Passwords struct {
+			Algorithm   string `conf:"default:argon2id"`
+			Memory      uint32 `conf:"default:19456"`
+			Iterations  uint32 `conf:"default:2"`
+			Parallelism uint8  `conf:"default:1"`
+			BcryptCost  int    `conf:"default:10"`
+
+			MinLength      int  `conf:"default:8"`
+			RequireLower   bool `conf:"default:false"`
+			RequireUpper   bool `conf:"default:false"`
+			RequireDigit   bool `conf:"default:false"`
+			RequireSymbol  bool `conf:"default:false"`
+			RejectPersonal bool `conf:"default:true"`
+			BreachedFile   string
+		}
+		Mail struct {
+			From     string `conf:"default:no-reply@example.com"`
+			SMTPHost string
+			User     string
+			Password string `conf:"mask"`
+			Outbox   string
 		}
 		DB struct {
 			User         string `conf:"default:postgres"`
@@ -131,20 +202,37 @@ func run(log *zap.SugaredLogger) error {
 	expvar.NewString("build").Set(build)
 
 	// =========================================================================
-	// Initialize authentication
-
-	log.Infow("startup", "status", "initializing authentication support")
-
-	// Construct a key store based on the key files stored in
-	// the specified directory.
-	ks, err := keystore.NewFS(os.DirFS(cfg.Auth.KeysFolder))
+	// Initialize password hashing
+
+	// Hashes of another algorithm or with other parameters keep working and
+	// are replaced the next time their users authenticate.
+	hasher, err := entity.NewPasswordHasher(cfg.Passwords.Algorithm, passhash.Argon2idParams{
+		Memory:      cfg.Passwords.Memory,
+		Iterations:  cfg.Passwords.Iterations,
+		Parallelism: cfg.Passwords.Parallelism,
+		SaltLength:  entity.DefaultArgon2idParams.SaltLength,
+		KeyLength:   entity.DefaultArgon2idParams.KeyLength,
+	}, cfg.Passwords.BcryptCost)
 	if err != nil {
-		return fmt.Errorf("reading keys: %w", err)
+		return fmt.Errorf("initializing password hashing: %w", err)
 	}
-
-	auth, err := auth.New(cfg.Auth.ActiveKID, ks)
-	if err != nil {
-		return fmt.Errorf("constructing auth: %w", err)
+	entity.DefaultPasswordHasher = hasher
+
+	passwordPolicy := entity.PasswordPolicy{
+		MinLength:      cfg.Passwords.MinLength,
+		RequireLower:   cfg.Passwords.RequireLower,
+		RequireUpper:   cfg.Passwords.RequireUpper,
+		RequireDigit:   cfg.Passwords.RequireDigit,
+		RequireSymbol:  cfg.Passwords.RequireSymbol,
+		RejectPersonal: cfg.Passwords.RejectPersonal,
+	}
+	if cfg.Passwords.BreachedFile != "" {
+		list, err := breached.LoadFile(cfg.Passwords.BreachedFile)
+		if err != nil {
+			return fmt.Errorf("loading breached passwords: %w", err)
+		}
+		log.Infow("startup", "status", "breached passwords loaded", "hashes", list.Len())
+		passwordPolicy.Breached = list
 	}
 
 	// =========================================================================
@@ -170,7 +258,166 @@ func run(log *zap.SugaredLogger) error {
 		db.Close()
 	}()
 
-	repos := data.Repositories{UserRepo: user.UserRepository{Storage: pg.NewStore(log, db)}}
+	userStore := pg.NewStore(log, db)
+	repos := data.Repositories{
+		UserRepo:         user.UserRepository{Storage: userStore},
+		OrgRepo:          user.OrgRepository{Storage: userStore},
+		RefreshTokenRepo: user.RefreshTokenRepository{Storage: userStore},
+		OneTimeTokenRepo: user.OneTimeTokenRepository{Storage: userStore},
+		IdentityRepo:     user.IdentityRepository{Storage: userStore},
+		APIKeyRepo:       user.APIKeyRepository{Storage: userStore},
+		LoginSessionRepo: user.LoginSessionRepository{Storage: userStore},
+		ProductRepo:      product.ProductRepository{Storage: productpg.NewStore(log, db)},
+		SaleRepo:         sale.SaleRepository{Storage: salepg.NewStore(log, db)},
+		AuditRepo:        audit.AuditRepository{Storage: auditpg.NewStore(log, db)},
+	}
+	if !cfg.Lockout.Disabled {
+		repos.LoginAttemptRepo = loginattemptpg.NewStore(log, db)
+	}
+
+	// =========================================================================
+	// Initialize mail
+
+	// Emails are sent through an SMTP server, or written to an outbox in
+	// development. Without either, password resets and email verification
+	// are disabled, so their tokens never end up in the logs.
+	var mailer mail.Mailer
+	switch {
+	case cfg.Mail.SMTPHost != "":
+		log.Infow("startup", "status", "initializing mail support", "host", cfg.Mail.SMTPHost)
+
+		var smtpAuth smtp.Auth
+		if cfg.Mail.User != "" {
+			host, _, _ := net.SplitHostPort(cfg.Mail.SMTPHost)
+			smtpAuth = smtp.PlainAuth("", cfg.Mail.User, cfg.Mail.Password, host)
+		}
+		mailer = mail.NewSMTP(cfg.Mail.SMTPHost, cfg.Mail.From, smtpAuth)
+
+	case cfg.Mail.Outbox != "":
+		log.Infow("startup", "status", "initializing mail outbox", "file", cfg.Mail.Outbox)
+
+		outbox, err := os.OpenFile(cfg.Mail.Outbox, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return fmt.Errorf("opening mail outbox: %w", err)
+		}
+		defer outbox.Close()
+		mailer = mail.NewOutbox(outbox, cfg.Mail.From)
+
+	default:
+		log.Infow("startup", "status", "mail disabled, password resets and email verification are unavailable")
+	}
+
+	// Verification links are signed with the verification key. Without a key
+	// no user can verify an email address, so none could authenticate.
+	if cfg.Auth.RequireVerifiedEmail && cfg.Auth.VerificationKey == "" {
+		return errors.New("requiring verified email addresses needs a verification key")
+	}
+	if cfg.Auth.RequireVerifiedEmail && mailer == nil {
+		return errors.New("requiring verified email addresses needs an smtp host or outbox")
+	}
+
+	// Secrets of second factors are stored encrypted with a key derived from
+	// the TOTP key. Two-factor authentication is disabled without a key.
+	var totpSecrets *encryption.Box
+	if cfg.Auth.TOTPKey != "" {
+		totpSecrets, err = encryption.NewFromPassphrase(cfg.Auth.TOTPKey)
+		if err != nil {
+			return fmt.Errorf("constructing totp encryption: %w", err)
+		}
+	}
+	if cfg.Auth.RequireAdminTOTP && totpSecrets == nil {
+		return errors.New("requiring two-factor authentication for admins needs a totp key")
+	}
+
+	// =========================================================================
+	// Initialize authentication
+
+	log.Infow("startup", "status", "initializing authentication support")
+
+	// Construct a key store based on the key files stored in
+	// the specified directory.
+	ks, err := keystore.NewFS(os.DirFS(cfg.Auth.KeysFolder))
+	if err != nil {
+		return fmt.Errorf("reading keys: %w", err)
+	}
+
+	// The active file in the keys folder takes precedence over the
+	// configured active key.
+	if ks.ActiveKID() == "" {
+		if err := ks.SetActive(cfg.Auth.ActiveKID); err != nil {
+			return fmt.Errorf("activating key: %w", err)
+		}
+	}
+
+	// Revoked tokens are stored in the database.
+	revocations := revocationpg.NewStore(log, db)
+
+	auth, err := auth.New(ks.ActiveKID(), ks,
+		auth.WithAlgorithms(cfg.Auth.Algorithms...),
+		auth.WithRevocationStore(revocations),
+	)
+	if err != nil {
+		return fmt.Errorf("constructing auth: %w", err)
+	}
+
+	// Reload the keys folder on an interval or SIGHUP, so keys can be
+	// rotated without a restart.
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	stopReload := make(chan struct{})
+	defer close(stopReload)
+
+	go func() {
+		var tick <-chan time.Time
+		if cfg.Auth.KeysReloadInterval > 0 {
+			ticker := time.NewTicker(cfg.Auth.KeysReloadInterval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+
+		for {
+			select {
+			case <-tick:
+			case <-reload:
+				log.Infow("keys", "status", "reload requested")
+			case <-stopReload:
+				return
+			}
+			reloadKeys(log, ks, auth)
+		}
+	}()
+
+	// =========================================================================
+	// Initialize OpenID Connect
+
+	// Users can sign in with an OpenID Connect provider if an issuer is
+	// configured. The provider is discovered once at startup.
+	var oidcCfg usergrp.OIDC
+	if cfg.OIDC.Issuer != "" {
+		log.Infow("startup", "status", "initializing openid connect support", "issuer", cfg.OIDC.Issuer)
+
+		if cfg.OIDC.StateKey == "" {
+			return errors.New("openid connect needs a state key")
+		}
+		oidcCfg.State, err = encryption.NewFromPassphrase(cfg.OIDC.StateKey)
+		if err != nil {
+			return fmt.Errorf("constructing oidc state encryption: %w", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		oidcCfg.Provider, err = oidc.NewProvider(ctx, oidc.Config{
+			Issuer:       cfg.OIDC.Issuer,
+			ClientID:     cfg.OIDC.ClientID,
+			ClientSecret: cfg.OIDC.ClientSecret,
+			RedirectURL:  cfg.OIDC.RedirectURL,
+		}, nil)
+		if err != nil {
+			return fmt.Errorf("discovering oidc provider: %w", err)
+		}
+		oidcCfg.Name = cfg.OIDC.Name
+	}
 
 	// =========================================================================
 	// Initialize Tracing
@@ -218,11 +465,39 @@ func run(log *zap.SugaredLogger) error {
 
 	// Construct the mux for the API calls.
 	apiMux := handlers.APIMux(handlers.APIMuxConfig{
-		Shutdown:            shutdown,
-		Log:                 log,
-		Auth:                auth,
-		Repositories:        &repos,
-		UserSessionDuration: cfg.Auth.UserSessionDuration,
+		Shutdown:             shutdown,
+		Log:                  log,
+		Auth:                 auth,
+		KeySet:               ks,
+		Repositories:         &repos,
+		UserSessionDuration:  cfg.Auth.UserSessionDuration,
+		RefreshTokenDuration: cfg.Auth.RefreshTokenDuration,
+		Mailer:               mailer,
+		ResetTokenDuration:   cfg.Auth.ResetTokenDuration,
+		VerificationKey:      []byte(cfg.Auth.VerificationKey),
+		VerificationURL:      cfg.Auth.VerificationURL,
+		VerificationDuration: cfg.Auth.VerificationDuration,
+		RequireVerifiedEmail: cfg.Auth.RequireVerifiedEmail,
+		AccountLockout: entity.LockoutPolicy{
+			FreeAttempts: cfg.Lockout.AccountFreeAttempts,
+			Delay:        cfg.Lockout.AccountDelay,
+			MaxDelay:     cfg.Lockout.AccountMaxDelay,
+			ResetAfter:   cfg.Lockout.ResetAfter,
+		},
+		IPLockout: entity.LockoutPolicy{
+			FreeAttempts: cfg.Lockout.IPFreeAttempts,
+			Delay:        cfg.Lockout.IPDelay,
+			MaxDelay:     cfg.Lockout.IPMaxDelay,
+			ResetAfter:   cfg.Lockout.ResetAfter,
+		},
+		TOTPSecrets:           totpSecrets,
+		TOTPIssuer:            cfg.Auth.TOTPIssuer,
+		RequireAdminTOTP:      cfg.Auth.RequireAdminTOTP,
+		OIDC:                  oidcCfg,
+		OIDCAutoProvision:     cfg.OIDC.AutoProvision,
+		APIKeyMaxDuration:     cfg.Auth.APIKeyMaxDuration,
+		PasswordPolicy:        passwordPolicy,
+		ImpersonationDuration: cfg.Auth.ImpersonationDuration,
 	})
 
 	// Construct a server to service the requests against the mux.
@@ -271,6 +546,27 @@ func run(log *zap.SugaredLogger) error {
 	return nil
 }
 
+// =============================================================================
+// reloadKeys reloads the key store and switches the signing key of auth when
+// the active key changed.
+func reloadKeys(log *zap.SugaredLogger, ks *keystore.KeyStore, a *auth.Auth) {
+	if err := ks.Reload(); err != nil {
+		log.Errorw("keys", "status", "reloading keys", "ERROR", err)
+		return
+	}
+
+	kid := ks.ActiveKID()
+	if kid == "" || kid == a.ActiveKID() {
+		return
+	}
+
+	if err := a.SetActiveKID(kid); err != nil {
+		log.Errorw("keys", "status", "switching active key", "kid", kid, "ERROR", err)
+		return
+	}
+	log.Infow("keys", "status", "active key switched", "kid", kid)
+}
+
 // =============================================================================
 // startTracing configure open telemetry to be used with zipkin.
 func startTracing(serviceName string, reporterURI string, probability float64) (*trace.TracerProvider, error) {
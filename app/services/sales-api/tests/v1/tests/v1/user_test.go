@@ -10,7 +10,6 @@ import (
 	"testing"
 
 	"github.com/appinesshq/caservice/app/services/sales-api/handlers"
-	"github.com/appinesshq/caservice/app/services/sales-api/web/auth"
 	v1Web "github.com/appinesshq/caservice/app/services/sales-api/web/v1"
 	"github.com/appinesshq/caservice/business/user"
 	uc "github.com/appinesshq/caservice/business/user/usecases"
@@ -41,10 +40,13 @@ func Test_Users(t *testing.T) {
 	shutdown := make(chan os.Signal, 1)
 	tests := UserTests{
 		app: handlers.APIMux(handlers.APIMuxConfig{
-			Shutdown:     shutdown,
-			Log:          test.Log,
-			Auth:         test.Auth,
-			Repositories: &data.Repositories{UserRepo: pg.NewStore(test.Log, test.DB)},
+			Shutdown: shutdown,
+			Log:      test.Log,
+			Auth:     test.Auth,
+			Repositories: &data.Repositories{
+				UserRepo: pg.NewStore(test.Log, test.DB),
+				OrgRepo:  pg.NewStore(test.Log, test.DB),
+			},
 		}),
 		userToken:  test.Token("user@example.com", "gophers"),
 		adminToken: test.Token("admin@example.com", "gophers"),
@@ -58,7 +60,7 @@ func Test_Users(t *testing.T) {
 	t.Run("getUser400", tests.getUser400)
 	t.Run("getUser403", tests.getUser403)
 	t.Run("getUser404", tests.getUser404)
-	t.Run("deleteUserNotFound", tests.deleteUserNotFound)
+	t.Run("deleteUser404", tests.deleteUser404)
 	t.Run("putUser404", tests.putUser404)
 	t.Run("crudUsers", tests.crudUser)
 }
@@ -334,8 +336,8 @@ func (ut *UserTests) getUser404(t *testing.T) {
 	}
 }
 
-// deleteUserNotFound validates deleting a user that does not exist is not a failure.
-func (ut *UserTests) deleteUserNotFound(t *testing.T) {
+// deleteUser404 validates deleting a user that does not exist.
+func (ut *UserTests) deleteUser404(t *testing.T) {
 	id := "a71f77b2-b1ae-4964-a847-f9eecba09d74"
 
 	r := httptest.NewRequest(http.MethodDelete, "/v1/users/"+id, nil)
@@ -349,10 +351,10 @@ func (ut *UserTests) deleteUserNotFound(t *testing.T) {
 		testID := 0
 		t.Logf("\tTest %d:\tWhen using the new user %s.", testID, id)
 		{
-			if w.Code != http.StatusNoContent {
-				t.Fatalf("\t%s\tTest %d:\tShould receive a status code of 204 for the response : %v", dbtest.Failed, testID, w.Code)
+			if w.Code != http.StatusNotFound {
+				t.Fatalf("\t%s\tTest %d:\tShould receive a status code of 404 for the response : %v", dbtest.Failed, testID, w.Code)
 			}
-			t.Logf("\t%s\tTest %d:\tShould receive a status code of 204 for the response.", dbtest.Success, testID)
+			t.Logf("\t%s\tTest %d:\tShould receive a status code of 404 for the response.", dbtest.Success, testID)
 		}
 	}
 }
@@ -414,7 +416,7 @@ func (ut *UserTests) postUser201(t *testing.T) user.User {
 	nu := uc.NewUser{
 		Name:            "John Doe",
 		Email:           "john@example.com",
-		Roles:           []string{auth.RoleAdmin},
+		Roles:           []string{user.RoleAdmin},
 		Password:        "gophers",
 		PasswordConfirm: "gophers",
 	}
@@ -452,7 +454,7 @@ func (ut *UserTests) postUser201(t *testing.T) user.User {
 			exp := got
 			exp.Name = "John Doe"
 			exp.Email = "john@example.com"
-			exp.Roles = []string{auth.RoleAdmin}
+			exp.Roles = []string{user.RoleAdmin}
 
 			if diff := cmp.Diff(got, exp); diff != "" {
 				t.Fatalf("\t%s\tTest %d:\tShould get the expected result. Diff:\n%s", dbtest.Failed, testID, diff)
@@ -549,7 +551,7 @@ func (ut *UserTests) getUser200(t *testing.T, id string) {
 			exp.ID = id
 			exp.Name = "John Doe"
 			exp.Email = "john@example.com"
-			exp.Roles = []string{auth.RoleAdmin}
+			exp.Roles = []string{user.RoleAdmin}
 
 			if diff := cmp.Diff(got, exp); diff != "" {
 				t.Fatalf("\t%s\tTest %d:\tShould get the expected result. Diff:\n%s", dbtest.Failed, testID, diff)
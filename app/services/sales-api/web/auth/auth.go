@@ -2,48 +2,136 @@
 package auth
 
 import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rsa"
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 )
 
 // ErrForbidden is returned when a auth issue is identified.
 var ErrForbidden = errors.New("attempted action is not allowed")
 
+// Set of errors returned when revoking tokens.
+var (
+	ErrRevoked            = errors.New("token has been revoked")
+	ErrRevocationDisabled = errors.New("token revocation is not enabled")
+)
+
+// These are the supported algorithms for signing tokens.
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// DefaultAlgorithms are the algorithms tokens are allowed to be signed with,
+// unless configured otherwise.
+var DefaultAlgorithms = []string{AlgorithmRS256, AlgorithmES256, AlgorithmEdDSA}
+
 // KeyLookup declares a method set of behavior for looking up
-// private and public keys for JWT use.
+// private and public keys for JWT use. The keys can be RSA, ECDSA P-256
+// or Ed25519 keys.
 type KeyLookup interface {
-	PrivateKey(kid string) (*rsa.PrivateKey, error)
-	PublicKey(kid string) (*rsa.PublicKey, error)
+	PrivateKey(kid string) (crypto.Signer, error)
+	PublicKey(kid string) (crypto.PublicKey, error)
+}
+
+// Algorithm returns the algorithm for signing tokens with the public key, or
+// the private key that belongs to it.
+func Algorithm(publicKey crypto.PublicKey) (string, error) {
+	switch pk := publicKey.(type) {
+	case *rsa.PublicKey:
+		return AlgorithmRS256, nil
+	case *ecdsa.PublicKey:
+		if pk.Curve != elliptic.P256() {
+			return "", fmt.Errorf("unsupported curve %s", pk.Curve.Params().Name)
+		}
+		return AlgorithmES256, nil
+	case ed25519.PublicKey:
+		return AlgorithmEdDSA, nil
+	default:
+		return "", fmt.Errorf("unsupported key type %T", publicKey)
+	}
+}
+
+// RevocationStore declares a method set of behavior for storing and looking
+// up revoked tokens. Single tokens are revoked by their id (jti), all tokens
+// of a subject are revoked by the time they were issued at.
+type RevocationStore interface {
+	Revoke(ctx context.Context, tokenID string, expires time.Time) error
+	RevokeSubject(ctx context.Context, subject string, before time.Time) error
+	IsRevoked(ctx context.Context, tokenID string, subject string, issuedAt time.Time) (bool, error)
 }
 
 // Auth is used to authenticate clients. It can generate a token for a
 // set of user claims and recreate the claims by parsing the token.
 type Auth struct {
-	activeKID string
-	keyLookup KeyLookup
-	method    jwt.SigningMethod
-	keyFunc   func(t *jwt.Token) (any, error)
-	parser    *jwt.Parser
+	mu          sync.RWMutex
+	activeKID   string
+	keyLookup   KeyLookup
+	revocations RevocationStore
+	algorithms  []string
+	keyFunc     func(t *jwt.Token) (any, error)
+	parser      *jwt.Parser
+}
+
+// WithAlgorithms configures the algorithms tokens are allowed to be signed
+// with. It replaces DefaultAlgorithms.
+func WithAlgorithms(algorithms ...string) func(a *Auth) {
+	return func(a *Auth) {
+		a.algorithms = algorithms
+	}
+}
+
+// WithRevocationStore enables token revocation. Validated tokens are checked
+// against the provided store.
+func WithRevocationStore(rs RevocationStore) func(a *Auth) {
+	return func(a *Auth) {
+		a.revocations = rs
+	}
 }
 
 // New creates an Auth to support authentication/authorization.
-func New(activeKID string, keyLookup KeyLookup) (*Auth, error) {
+// Optional features are enabled with the provided options.
+//
+// An empty activeKID creates an Auth in verify-only mode, which validates
+// tokens but can't generate them. This is used by services that verify
+// tokens with the public keys of a remote key set.
+func New(activeKID string, keyLookup KeyLookup, options ...func(a *Auth)) (*Auth, error) {
+	a := Auth{
+		activeKID:  activeKID,
+		keyLookup:  keyLookup,
+		algorithms: DefaultAlgorithms,
+	}
+	for _, option := range options {
+		option(&a)
+	}
 
-	// The activeKID represents the private key used to signed new tokens.
-	_, err := keyLookup.PrivateKey(activeKID)
-	if err != nil {
-		return nil, errors.New("active KID does not exist in store")
+	for _, algorithm := range a.algorithms {
+		if jwt.GetSigningMethod(algorithm) == nil || !supported(algorithm) {
+			return nil, fmt.Errorf("configuring algorithm %s", algorithm)
+		}
 	}
 
-	method := jwt.GetSigningMethod("RS256")
-	if method == nil {
-		return nil, errors.New("configuring algorithm RS256")
+	// The activeKID represents the private key used to signed new tokens.
+	if activeKID != "" {
+		if _, _, err := a.signer(activeKID); err != nil {
+			return nil, err
+		}
 	}
 
-	keyFunc := func(t *jwt.Token) (any, error) {
+	// The public key must be of the type that belongs to the algorithm of
+	// the token, so a key can't be used with another algorithm.
+	a.keyFunc = func(t *jwt.Token) (any, error) {
 		kid, ok := t.Header["kid"]
 		if !ok {
 			return nil, errors.New("missing key id (kid) in token header")
@@ -52,35 +140,103 @@ func New(activeKID string, keyLookup KeyLookup) (*Auth, error) {
 		if !ok {
 			return nil, errors.New("user token key id (kid) must be string")
 		}
-		return keyLookup.PublicKey(kidID)
+		publicKey, err := keyLookup.PublicKey(kidID)
+		if err != nil {
+			return nil, err
+		}
+		algorithm, err := Algorithm(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		if algorithm != t.Method.Alg() {
+			return nil, fmt.Errorf("token algorithm %s does not match key algorithm %s", t.Method.Alg(), algorithm)
+		}
+		return publicKey, nil
 	}
 
 	// Create the token parser to use. The algorithm used to sign the JWT must be
 	// validated to avoid a critical vulnerability:
 	// https://auth0.com/blog/critical-vulnerabilities-in-json-web-token-libraries/
-	parser := jwt.NewParser(jwt.WithValidMethods([]string{"RS256"}))
+	a.parser = jwt.NewParser(jwt.WithValidMethods(a.algorithms))
 
-	a := Auth{
-		activeKID: activeKID,
-		keyLookup: keyLookup,
-		method:    method,
-		keyFunc:   keyFunc,
-		parser:    parser,
+	return &a, nil
+}
+
+// supported reports whether tokens can be signed with the algorithm.
+func supported(algorithm string) bool {
+	switch algorithm {
+	case AlgorithmRS256, AlgorithmES256, AlgorithmEdDSA:
+		return true
 	}
+	return false
+}
 
-	return &a, nil
+// signer looks up the private key for the kid and the signing method that
+// belongs to it. The algorithm of the key must be allowed.
+func (a *Auth) signer(kid string) (crypto.Signer, jwt.SigningMethod, error) {
+	privateKey, err := a.keyLookup.PrivateKey(kid)
+	if err != nil {
+		return nil, nil, errors.New("active KID does not exist in store")
+	}
+
+	algorithm, err := Algorithm(privateKey.Public())
+	if err != nil {
+		return nil, nil, fmt.Errorf("active KID: %w", err)
+	}
+
+	for _, allowed := range a.algorithms {
+		if algorithm == allowed {
+			return privateKey, jwt.GetSigningMethod(algorithm), nil
+		}
+	}
+
+	return nil, nil, fmt.Errorf("active KID algorithm %s is not allowed", algorithm)
+}
+
+// ActiveKID returns the kid of the private key used to sign new tokens.
+func (a *Auth) ActiveKID() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.activeKID
+}
+
+// SetActiveKID switches the private key used to sign new tokens. Tokens
+// signed before remain valid as long as the key lookup provides the public
+// key of their kid.
+func (a *Auth) SetActiveKID(kid string) error {
+	if _, _, err := a.signer(kid); err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.activeKID = kid
+	return nil
 }
 
 // GenerateToken generates a signed JWT token string representing the user Claims.
+// The token is signed with the algorithm that belongs to the active key.
+// Claims without an id (jti) get a new random id, so the token can be revoked.
 func (a *Auth) GenerateToken(claims Claims) (string, error) {
-	token := jwt.NewWithClaims(a.method, claims)
-	token.Header["kid"] = a.activeKID
+	activeKID := a.ActiveKID()
+	if activeKID == "" {
+		return "", errors.New("no active KID, auth is in verify-only mode")
+	}
 
-	privateKey, err := a.keyLookup.PrivateKey(a.activeKID)
+	if claims.ID == "" {
+		claims.ID = uuid.NewString()
+	}
+
+	privateKey, method, err := a.signer(activeKID)
 	if err != nil {
-		return "", errors.New("kid lookup failed")
+		return "", err
 	}
 
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = activeKID
+
 	str, err := token.SignedString(privateKey)
 	if err != nil {
 		return "", fmt.Errorf("signing token: %w", err)
@@ -90,8 +246,9 @@ func (a *Auth) GenerateToken(claims Claims) (string, error) {
 }
 
 // ValidateToken recreates the Claims that were used to generate a token. It
-// verifies that the token was signed using our key.
-func (a *Auth) ValidateToken(tokenStr string) (Claims, error) {
+// verifies that the token was signed using our key and, if revocation is
+// enabled, that the token has not been revoked.
+func (a *Auth) ValidateToken(ctx context.Context, tokenStr string) (Claims, error) {
 	var claims Claims
 	token, err := a.parser.ParseWithClaims(tokenStr, &claims, a.keyFunc)
 	if err != nil {
@@ -102,5 +259,63 @@ func (a *Auth) ValidateToken(tokenStr string) (Claims, error) {
 		return Claims{}, errors.New("invalid token")
 	}
 
+	if a.revocations != nil {
+		var issuedAt time.Time
+		if claims.IssuedAt != nil {
+			issuedAt = claims.IssuedAt.Time
+		}
+
+		revoked, err := a.revocations.IsRevoked(ctx, claims.ID, claims.Subject, issuedAt)
+		if err != nil {
+			return Claims{}, fmt.Errorf("checking revocation: %w", err)
+		}
+		if revoked {
+			return Claims{}, ErrRevoked
+		}
+	}
+
 	return claims, nil
 }
+
+// RevocationEnabled reports whether tokens can be revoked.
+func (a *Auth) RevocationEnabled() bool {
+	return a.revocations != nil
+}
+
+// Revoke revokes the token the provided claims were recreated from. The token
+// is remembered until it expires.
+func (a *Auth) Revoke(ctx context.Context, claims Claims) error {
+	if a.revocations == nil {
+		return ErrRevocationDisabled
+	}
+
+	if claims.ID == "" {
+		return errors.New("missing token id (jti) in claims")
+	}
+
+	var expires time.Time
+	if claims.ExpiresAt != nil {
+		expires = claims.ExpiresAt.Time
+	}
+
+	if err := a.revocations.Revoke(ctx, claims.ID, expires); err != nil {
+		return fmt.Errorf("revoking token: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeAll revokes all tokens of the subject that were issued up to now.
+// Token timestamps have a precision of one second, so tokens issued within
+// the same second as the revocation are revoked as well.
+func (a *Auth) RevokeAll(ctx context.Context, subject string, now time.Time) error {
+	if a.revocations == nil {
+		return ErrRevocationDisabled
+	}
+
+	if err := a.revocations.RevokeSubject(ctx, subject, now); err != nil {
+		return fmt.Errorf("revoking tokens of subject[%s]: %w", subject, err)
+	}
+
+	return nil
+}
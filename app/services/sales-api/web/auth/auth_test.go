@@ -1,12 +1,24 @@
 package auth_test
 
 import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/appinesshq/caservice/app/services/sales-api/web/auth"
+	"github.com/appinesshq/caservice/business/user"
+	"github.com/appinesshq/caservice/data/revocation/mem"
+	"github.com/appinesshq/caservice/foundation/keystore"
 	"github.com/golang-jwt/jwt/v4"
 )
 
@@ -42,7 +54,7 @@ func Test_Auth(t *testing.T) {
 					ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(time.Hour)),
 					IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
 				},
-				Roles: []string{auth.RoleAdmin},
+				Roles: []string{user.RoleAdmin},
 			}
 
 			token, err := a.GenerateToken(claims)
@@ -51,7 +63,7 @@ func Test_Auth(t *testing.T) {
 			}
 			t.Logf("\t%s\tTest %d:\tShould be able to generate a JWT.", success, testID)
 
-			parsedClaims, err := a.ValidateToken(token)
+			parsedClaims, err := a.ValidateToken(context.Background(), token)
 			if err != nil {
 				t.Fatalf("\t%s\tTest %d:\tShould be able to parse the claims: %v", failed, testID, err)
 			}
@@ -74,16 +86,326 @@ func Test_Auth(t *testing.T) {
 	}
 }
 
+func Test_Revocation(t *testing.T) {
+	t.Log("Given the need to be able to revoke tokens before they expire.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen handling the tokens of a single user.", testID)
+		{
+			const keyID = "54bb2165-71e1-41a6-af3e-7da4a0e1e2c1"
+			privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create a private key: %v", failed, testID, err)
+			}
+
+			a, err := auth.New(keyID, &keyStore{pk: privateKey}, auth.WithRevocationStore(mem.New()))
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create an authenticator: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to create an authenticator with a revocation store.", success, testID)
+
+			ctx := context.Background()
+			now := time.Now().UTC()
+			newToken := func(issuedAt time.Time) string {
+				token, err := a.GenerateToken(auth.Claims{
+					RegisteredClaims: jwt.RegisteredClaims{
+						Subject:   "5cf37266-3473-4006-984f-9325122678b7",
+						ExpiresAt: jwt.NewNumericDate(issuedAt.Add(time.Hour)),
+						IssuedAt:  jwt.NewNumericDate(issuedAt),
+					},
+					Roles: []string{user.RoleUser},
+				})
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to generate a JWT: %v", failed, testID, err)
+				}
+				return token
+			}
+
+			first, second := newToken(now.Add(-2*time.Second)), newToken(now.Add(-2*time.Second))
+			claims, err := a.ValidateToken(ctx, first)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to parse the claims: %v", failed, testID, err)
+			}
+			if claims.ID == "" {
+				t.Fatalf("\t%s\tTest %d:\tShould have a token id (jti).", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould have a token id (jti).", success, testID)
+
+			if err := a.Revoke(ctx, claims); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to revoke the token: %v", failed, testID, err)
+			}
+			if _, err := a.ValidateToken(ctx, first); !errors.Is(err, auth.ErrRevoked) {
+				t.Fatalf("\t%s\tTest %d:\tShould not accept a revoked token, got: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not accept a revoked token.", success, testID)
+
+			if _, err := a.ValidateToken(ctx, second); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould accept other tokens of the user: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould accept other tokens of the user.", success, testID)
+
+			if err := a.RevokeAll(ctx, claims.Subject, now.Add(-time.Second)); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to revoke all tokens: %v", failed, testID, err)
+			}
+			if _, err := a.ValidateToken(ctx, second); !errors.Is(err, auth.ErrRevoked) {
+				t.Fatalf("\t%s\tTest %d:\tShould not accept tokens issued before revoking all, got: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not accept tokens issued before revoking all.", success, testID)
+
+			if _, err := a.ValidateToken(ctx, newToken(now)); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould accept tokens issued after revoking all: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould accept tokens issued after revoking all.", success, testID)
+		}
+	}
+}
+
+func Test_VerifyOnly(t *testing.T) {
+	t.Log("Given the need to verify tokens with the keys of a remote key set.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen handling a token of a remote service.", testID)
+		{
+			const keyID = "54bb2165-71e1-41a6-af3e-7da4a0e1e2c1"
+			privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create a private key: %v", failed, testID, err)
+			}
+
+			ks := keystore.NewMap(map[string]crypto.Signer{keyID: privateKey})
+			issuer, err := auth.New(keyID, ks)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create an authenticator: %v", failed, testID, err)
+			}
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(ks.JWKS())
+			}))
+			defer srv.Close()
+
+			verifier, err := auth.New("", keystore.NewRemote(srv.URL, srv.Client(), time.Hour))
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create a verify-only authenticator: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to create a verify-only authenticator.", success, testID)
+
+			claims := auth.Claims{
+				RegisteredClaims: jwt.RegisteredClaims{
+					Subject:   "5cf37266-3473-4006-984f-9325122678b7",
+					ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(time.Hour)),
+					IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+				},
+				Roles: []string{user.RoleUser},
+			}
+
+			token, err := issuer.GenerateToken(claims)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to generate a JWT: %v", failed, testID, err)
+			}
+
+			parsedClaims, err := verifier.ValidateToken(context.Background(), token)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to validate the token with the remote key set: %v", failed, testID, err)
+			}
+			if parsedClaims.Subject != claims.Subject {
+				t.Fatalf("\t%s\tTest %d:\tShould have the expected subject: %s", failed, testID, parsedClaims.Subject)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to validate the token with the remote key set.", success, testID)
+
+			if _, err := verifier.GenerateToken(claims); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould not be able to generate tokens.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not be able to generate tokens.", success, testID)
+		}
+	}
+}
+
+func Test_Rotation(t *testing.T) {
+	t.Log("Given the need to switch the signing key without a restart.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen switching between two keys of a key store.", testID)
+		{
+			ks := keystore.New()
+			for _, kid := range []string{"old", "new"} {
+				privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to create a private key: %v", failed, testID, err)
+				}
+				ks.Add(privateKey, kid)
+			}
+
+			a, err := auth.New("old", ks)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create an authenticator: %v", failed, testID, err)
+			}
+
+			claims := auth.Claims{
+				RegisteredClaims: jwt.RegisteredClaims{
+					Subject:   "5cf37266-3473-4006-984f-9325122678b7",
+					ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(time.Hour)),
+					IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+				},
+				Roles: []string{user.RoleUser},
+			}
+
+			oldToken, err := a.GenerateToken(claims)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to generate a JWT: %v", failed, testID, err)
+			}
+
+			if err := a.SetActiveKID("unknown"); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould not switch to an unknown key.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not switch to an unknown key.", success, testID)
+
+			if err := a.SetActiveKID("new"); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to switch the active key: %v", failed, testID, err)
+			}
+			newToken, err := a.GenerateToken(claims)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to generate a JWT: %v", failed, testID, err)
+			}
+			token, _, err := jwt.NewParser().ParseUnverified(newToken, &auth.Claims{})
+			if err != nil || token.Header["kid"] != "new" {
+				t.Fatalf("\t%s\tTest %d:\tShould sign new tokens with the new key: %v", failed, testID, token.Header["kid"])
+			}
+			t.Logf("\t%s\tTest %d:\tShould sign new tokens with the new key.", success, testID)
+
+			if _, err := a.ValidateToken(context.Background(), oldToken); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould still accept tokens signed with the old key: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould still accept tokens signed with the old key.", success, testID)
+
+			if err := ks.Retire("old"); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to retire the old key: %v", failed, testID, err)
+			}
+			if _, err := a.ValidateToken(context.Background(), oldToken); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould not accept tokens signed with a retired key.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not accept tokens signed with a retired key.", success, testID)
+		}
+	}
+}
+
+func Test_Algorithms(t *testing.T) {
+	newKey := func(alg string) crypto.Signer {
+		var (
+			pk  crypto.Signer
+			err error
+		)
+		switch alg {
+		case auth.AlgorithmRS256:
+			pk, err = rsa.GenerateKey(rand.Reader, 2048)
+		case auth.AlgorithmES256:
+			pk, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		case auth.AlgorithmEdDSA:
+			_, pk, err = ed25519.GenerateKey(rand.Reader)
+		}
+		if err != nil {
+			t.Fatalf("generating %s key: %v", alg, err)
+		}
+		return pk
+	}
+
+	tt := []struct {
+		name    string
+		alg     string
+		allowed []string
+		valid   bool
+	}{
+		{"rs256", auth.AlgorithmRS256, nil, true},
+		{"es256", auth.AlgorithmES256, nil, true},
+		{"eddsa", auth.AlgorithmEdDSA, nil, true},
+		{"es256-only", auth.AlgorithmES256, []string{auth.AlgorithmES256}, true},
+		{"rs256-not-allowed", auth.AlgorithmRS256, []string{auth.AlgorithmES256, auth.AlgorithmEdDSA}, false},
+	}
+
+	t.Log("Given the need to sign tokens with different algorithms.")
+	{
+		for testID, tst := range tt {
+			tf := func(t *testing.T) {
+				t.Logf("\tTest %d:\tWhen handling a %s key.", testID, tst.alg)
+				{
+					ks := keystore.NewMap(map[string]crypto.Signer{"kid": newKey(tst.alg)})
+
+					var options []func(*auth.Auth)
+					if tst.allowed != nil {
+						options = append(options, auth.WithAlgorithms(tst.allowed...))
+					}
+
+					a, err := auth.New("kid", ks, options...)
+					if !tst.valid {
+						if err == nil {
+							t.Fatalf("\t%s\tTest %d:\tShould not be able to use a key of a disallowed algorithm.", failed, testID)
+						}
+						t.Logf("\t%s\tTest %d:\tShould not be able to use a key of a disallowed algorithm.", success, testID)
+						return
+					}
+					if err != nil {
+						t.Fatalf("\t%s\tTest %d:\tShould be able to create an authenticator: %v", failed, testID, err)
+					}
+
+					claims := auth.Claims{
+						RegisteredClaims: jwt.RegisteredClaims{
+							Subject:   "5cf37266-3473-4006-984f-9325122678b7",
+							ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(time.Hour)),
+							IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+						},
+						Roles: []string{user.RoleUser},
+					}
+
+					token, err := a.GenerateToken(claims)
+					if err != nil {
+						t.Fatalf("\t%s\tTest %d:\tShould be able to generate a JWT: %v", failed, testID, err)
+					}
+
+					parsed, _, err := jwt.NewParser().ParseUnverified(token, &auth.Claims{})
+					if err != nil || parsed.Method.Alg() != tst.alg {
+						t.Fatalf("\t%s\tTest %d:\tShould be signed with %s: %v", failed, testID, tst.alg, err)
+					}
+					t.Logf("\t%s\tTest %d:\tShould be signed with %s.", success, testID, tst.alg)
+
+					if _, err := a.ValidateToken(context.Background(), token); err != nil {
+						t.Fatalf("\t%s\tTest %d:\tShould be able to validate the token: %v", failed, testID, err)
+					}
+					t.Logf("\t%s\tTest %d:\tShould be able to validate the token.", success, testID)
+
+					// A token with the kid of this key, but signed by another key
+					// type, must be rejected.
+					other := auth.AlgorithmEdDSA
+					if tst.alg == auth.AlgorithmEdDSA {
+						other = auth.AlgorithmES256
+					}
+					forged, err := auth.New("kid", keystore.NewMap(map[string]crypto.Signer{"kid": newKey(other)}))
+					if err != nil {
+						t.Fatalf("\t%s\tTest %d:\tShould be able to create an authenticator: %v", failed, testID, err)
+					}
+					token, err = forged.GenerateToken(claims)
+					if err != nil {
+						t.Fatalf("\t%s\tTest %d:\tShould be able to generate a JWT: %v", failed, testID, err)
+					}
+					if _, err := a.ValidateToken(context.Background(), token); err == nil {
+						t.Fatalf("\t%s\tTest %d:\tShould not accept a token signed with another key type.", failed, testID)
+					}
+					t.Logf("\t%s\tTest %d:\tShould not accept a token signed with another key type.", success, testID)
+				}
+			}
+			t.Run(tst.name, tf)
+		}
+	}
+}
+
 // =============================================================================
 
 type keyStore struct {
 	pk *rsa.PrivateKey
 }
 
-func (ks *keyStore) PrivateKey(kid string) (*rsa.PrivateKey, error) {
+func (ks *keyStore) PrivateKey(kid string) (crypto.Signer, error) {
 	return ks.pk, nil
 }
 
-func (ks *keyStore) PublicKey(kid string) (*rsa.PublicKey, error) {
+func (ks *keyStore) PublicKey(kid string) (crypto.PublicKey, error) {
 	return &ks.pk.PublicKey, nil
 }
@@ -7,28 +7,24 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 )
 
-// These are the expected values for Claims.Roles.
-const (
-	RoleAdmin = "ADMIN"
-	RoleUser  = "USER"
-)
-
 // Claims represents the authorization claims transmitted via a JWT.
 type Claims struct {
 	jwt.RegisteredClaims
-	Roles []string `json:"roles"`
+	Roles    []string `json:"roles"`
+	TenantID string   `json:"tenant,omitempty"`
+
+	// SessionID is the id of the login session the token was issued for.
+	SessionID string `json:"sid,omitempty"`
+
+	// Actor is the admin acting as the subject, if the token was issued
+	// for impersonation.
+	Actor *ActorClaim `json:"act,omitempty"`
 }
 
-// Authorized returns true if the claims has at least one of the provided roles.
-func (c Claims) Authorized(roles ...string) bool {
-	for _, has := range c.Roles {
-		for _, want := range roles {
-			if has == want {
-				return true
-			}
-		}
-	}
-	return false
+// ActorClaim identifies the party acting on behalf of the subject of a
+// token, like the "act" claim of RFC 8693.
+type ActorClaim struct {
+	Subject string `json:"sub"`
 }
 
 // ctxKey represents the type of value for the context key.
@@ -6,14 +6,24 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/appinesshq/caservice/app/services/sales-api/web/auth"
 	v1Web "github.com/appinesshq/caservice/app/services/sales-api/web/v1"
+	"github.com/appinesshq/caservice/business/authz"
+	"github.com/appinesshq/caservice/business/user"
+	"github.com/appinesshq/caservice/business/user/usecases"
+	fctx "github.com/appinesshq/caservice/foundation/context"
 	"github.com/appinesshq/caservice/foundation/web"
+	"github.com/golang-jwt/jwt/v4"
 )
 
-// Authenticate validates a JWT from the `Authorization` header.
-func Authenticate(a *auth.Auth) web.Middleware {
+// Authenticate validates a JWT or an API key from the `Authorization`
+// header. The user of the token or key is loaded as a user Session, which is
+// required by the user usecases. Tokens of login sessions that were revoked
+// are refused, as are impersonation tokens of admins that may no longer
+// impersonate the user.
+func Authenticate(a *auth.Auth, uc usecases.UserUseCases) web.Middleware {
 
 	// This is the actual middleware function to be executed.
 	m := func(handler web.Handler) web.Handler {
@@ -21,24 +31,41 @@ func Authenticate(a *auth.Auth) web.Middleware {
 		// Create the handler that will be attached in the middleware chain.
 		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 
-			// Expecting: bearer <token>
+			// If the context is missing this value, request the service
+			// to be shutdown gracefully.
+			v, err := fctx.GetValues(ctx)
+			if err != nil {
+				return web.NewShutdownError("web value missing from context")
+			}
+
+			// Expecting: bearer <token> or apikey <key>
 			authStr := r.Header.Get("authorization")
 
 			// Parse the authorization header.
 			parts := strings.Split(authStr, " ")
-			if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-				err := errors.New("expected authorization header format: bearer <token>")
+			if len(parts) != 2 {
+				err := errors.New("expected authorization header format: bearer <token> or apikey <key>")
 				return v1Web.NewRequestError(err, http.StatusUnauthorized)
 			}
 
-			// Validate the token is signed by us.
-			claims, err := a.ValidateToken(parts[1])
+			var claims auth.Claims
+			var session user.Session
+			switch strings.ToLower(parts[0]) {
+			case "bearer":
+				claims, session, err = authenticateToken(ctx, a, uc, parts[1], v1Web.ClientIP(r), v.Now)
+			case "apikey":
+				claims, session, err = authenticateAPIKey(ctx, uc, parts[1], v.Now)
+			default:
+				err = errors.New("expected authorization header format: bearer <token> or apikey <key>")
+			}
 			if err != nil {
 				return v1Web.NewRequestError(err, http.StatusUnauthorized)
 			}
 
-			// Add claims to the context, so they can be retrieved later.
+			// Add claims and session to the context, so they can be retrieved later.
 			ctx = auth.SetClaims(ctx, claims)
+			ctx = user.ContextWithSession(ctx, session)
+			setIdentity(ctx, session)
 
 			// Call the next handler.
 			return handler(ctx, w, r)
@@ -50,9 +77,67 @@ func Authenticate(a *auth.Auth) web.Middleware {
 	return m
 }
 
-// Authorize validates that an authenticated user has at least one role from a
-// specified list. This method constructs the actual function that is used.
-func Authorize(roles ...string) web.Middleware {
+// authenticateToken validates a JWT and restores the session of the user
+// the token was issued for, as part of its login session.
+func authenticateToken(ctx context.Context, a *auth.Auth, uc usecases.UserUseCases, token, ip string, now time.Time) (auth.Claims, user.Session, error) {
+
+	// Validate the token is signed by us and has not been revoked.
+	claims, err := a.ValidateToken(ctx, token)
+	if err != nil {
+		return auth.Claims{}, user.Session{}, err
+	}
+
+	// Tokens without an expiry result in an invalid session.
+	var expires time.Time
+	if claims.ExpiresAt != nil {
+		expires = claims.ExpiresAt.Time
+	}
+	session, err := uc.Identify(ctx, claims.Subject, claims.TenantID, expires)
+	if err != nil {
+		return auth.Claims{}, user.Session{}, err
+	}
+
+	session, err = uc.ResumeSession(ctx, session, claims.SessionID, ip, now)
+	if err != nil {
+		return auth.Claims{}, user.Session{}, err
+	}
+
+	if claims.Actor != nil {
+		session, err = uc.ResumeImpersonation(ctx, session, claims.Actor.Subject)
+		if err != nil {
+			return auth.Claims{}, user.Session{}, err
+		}
+	}
+
+	return claims, session, nil
+}
+
+// authenticateAPIKey validates an API key and returns claims for the session
+// of its user, so the roles of the key can be authorized like the roles of
+// a token. The claims have no token id, as there is no token.
+func authenticateAPIKey(ctx context.Context, uc usecases.UserUseCases, key string, now time.Time) (auth.Claims, user.Session, error) {
+	session, err := uc.AuthenticateAPIKey(ctx, key, now)
+	if err != nil {
+		return auth.Claims{}, user.Session{}, err
+	}
+
+	claims := auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   session.User.ID,
+			ExpiresAt: jwt.NewNumericDate(session.Expires),
+		},
+		Roles:    session.User.Roles,
+		TenantID: session.TenantID,
+	}
+
+	return claims, session, nil
+}
+
+// Authorize validates that the authenticated user may perform the action,
+// following the authorization policy. The resource is not known yet, so
+// only rules that do not depend on it, like roles, can be met here. Rules
+// like ownership are evaluated by the usecases.
+func Authorize(action authz.Action) web.Middleware {
 
 	// This is the actual middleware function to be executed.
 	m := func(handler web.Handler) web.Handler {
@@ -61,17 +146,17 @@ func Authorize(roles ...string) web.Middleware {
 		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 
 			// If the context is missing this value return failure.
-			claims, err := auth.GetClaims(ctx)
+			session, err := user.GetSession(ctx)
 			if err != nil {
 				return v1Web.NewRequestError(
-					fmt.Errorf("you are not authorized for that action, no claims"),
+					fmt.Errorf("you are not authorized for that action, no session"),
 					http.StatusForbidden,
 				)
 			}
 
-			if !claims.Authorized(roles...) {
+			if err := authz.Authorize(action, authz.SessionActor(session), authz.Resource{}); err != nil {
 				return v1Web.NewRequestError(
-					fmt.Errorf("you are not authorized for that action, claims[%v] roles[%v]", claims.Roles, roles),
+					fmt.Errorf("you are not authorized for that action, roles[%v] action[%s]", session.User.Roles, action),
 					http.StatusForbidden,
 				)
 			}
@@ -2,11 +2,13 @@ package mid
 
 import (
 	"context"
+	"errors"
 	"net/http"
 
 	"github.com/appinesshq/caservice/app/services/sales-api/sys/validate"
 	v1Web "github.com/appinesshq/caservice/app/services/sales-api/web/v1"
 	fctx "github.com/appinesshq/caservice/foundation/context"
+	"github.com/appinesshq/caservice/foundation/validation"
 	"github.com/appinesshq/caservice/foundation/web"
 	"go.uber.org/zap"
 )
@@ -47,6 +49,15 @@ func Errors(log *zap.SugaredLogger) web.Middleware {
 					}
 					status = http.StatusBadRequest
 
+				case validation.IsValidationError(err):
+					var ve validation.ValidationError
+					errors.As(err, &ve)
+					er = v1Web.ErrorResponse{
+						Error:  ve.Err,
+						Fields: ve.Fields,
+					}
+					status = http.StatusBadRequest
+
 				case v1Web.IsRequestError(err):
 					reqErr := v1Web.GetRequestError(err)
 					er = v1Web.ErrorResponse{
@@ -5,13 +5,39 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/appinesshq/caservice/business/user"
 	fctx "github.com/appinesshq/caservice/foundation/context"
 	"github.com/appinesshq/caservice/foundation/web"
 	"go.uber.org/zap"
 )
 
+// identity is who a request was authenticated as. Logger runs before
+// Authenticate, so it stores an empty identity in the context that
+// Authenticate fills in.
+type identity struct {
+	userID         string
+	impersonatorID string
+}
+
+// ctxKey represents the type of value for the context key.
+type ctxKey int
+
+// identityKey is used to store/retrieve an identity from a context.Context.
+const identityKey ctxKey = 1
+
+// setIdentity records the user of the session, and the admin impersonating
+// it, as the identity of the request.
+func setIdentity(ctx context.Context, s user.Session) {
+	if id, ok := ctx.Value(identityKey).(*identity); ok {
+		id.userID = s.User.ID
+		id.impersonatorID = s.ImpersonatorID
+	}
+}
+
 // Logger writes some information about the request to the logs in the
-// format: TraceID : (200) GET /foo -> IP ADDR (latency)
+// format: TraceID : (200) GET /foo -> IP ADDR (latency). Requests of
+// impersonated sessions are logged with both the user and the admin
+// impersonating it.
 func Logger(log *zap.SugaredLogger) web.Middleware {
 
 	// This is the actual middleware function to be executed.
@@ -30,11 +56,21 @@ func Logger(log *zap.SugaredLogger) web.Middleware {
 			log.Infow("request started", "traceid", v.TraceID, "method", r.Method, "path", r.URL.Path,
 				"remoteaddr", r.RemoteAddr)
 
+			var id identity
+			ctx = context.WithValue(ctx, identityKey, &id)
+
 			// Call the next handler.
 			err = handler(ctx, w, r)
 
-			log.Infow("request completed", "traceid", v.TraceID, "method", r.Method, "path", r.URL.Path,
-				"remoteaddr", r.RemoteAddr, "statuscode", v.StatusCode, "since", time.Since(v.Now))
+			kv := []any{"traceid", v.TraceID, "method", r.Method, "path", r.URL.Path,
+				"remoteaddr", r.RemoteAddr, "statuscode", v.StatusCode, "since", time.Since(v.Now)}
+			if id.userID != "" {
+				kv = append(kv, "userid", id.userID)
+			}
+			if id.impersonatorID != "" {
+				kv = append(kv, "impersonatorid", id.impersonatorID)
+			}
+			log.Infow("request completed", kv...)
 
 			// Return the error so it can be handled further up the chain.
 			return err
@@ -1,7 +1,11 @@
 // Package v1 represents types used by the web application for v1.
 package v1
 
-import "errors"
+import (
+	"errors"
+	"net"
+	"net/http"
+)
 
 // ErrorResponse is the form used for API responses from failures in the API.
 type ErrorResponse struct {
@@ -42,3 +46,12 @@ func GetRequestError(err error) *RequestError {
 	}
 	return re
 }
+
+// ClientIP returns the IP address of the client of the request.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
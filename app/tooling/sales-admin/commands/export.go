@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	entity "github.com/appinesshq/caservice/business/user"
+	uc "github.com/appinesshq/caservice/business/user/usecases"
+	database "github.com/appinesshq/caservice/data/core/pg"
+	data "github.com/appinesshq/caservice/data/user"
+	us "github.com/appinesshq/caservice/data/user/pg"
+	"go.uber.org/zap"
+)
+
+// The formats users can be exported and imported in.
+const (
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+)
+
+// csvHeader is the header of exported CSV files. Imports only read the
+// name, email, roles and password columns, so exports can be imported again.
+var csvHeader = []string{"id", "name", "email", "roles", "verified", "date_created", "date_updated"}
+
+// rolesSeparator separates the roles of a user within a CSV column.
+const rolesSeparator = ";"
+
+// exportUser is an exported user. It has the columns of csvHeader, so both
+// formats export the same fields and nothing else, like password hashes.
+type exportUser struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Email       string   `json:"email"`
+	Roles       []string `json:"roles"`
+	Verified    bool     `json:"verified"`
+	DateCreated string   `json:"date_created"`
+	DateUpdated string   `json:"date_updated"`
+}
+
+func toExportUser(u entity.User) exportUser {
+	return exportUser{
+		ID:          u.ID,
+		Name:        u.Name,
+		Email:       u.Email,
+		Roles:       u.Roles,
+		Verified:    u.Verified,
+		DateCreated: u.DateCreated.Format(time.RFC3339),
+		DateUpdated: u.DateUpdated.Format(time.RFC3339),
+	}
+}
+
+// record returns the exported user as a CSV record in the order of
+// csvHeader.
+func (eu exportUser) record() []string {
+	return []string{
+		eu.ID,
+		eu.Name,
+		eu.Email,
+		strings.Join(eu.Roles, rolesSeparator),
+		strconv.FormatBool(eu.Verified),
+		eu.DateCreated,
+		eu.DateUpdated,
+	}
+}
+
+// UsersExport writes the users with one of the comma separated roles, or all
+// users if no roles are provided, to stdout as CSV or NDJSON.
+func UsersExport(log *zap.SugaredLogger, cfg database.Config, format string, roles string) error {
+	if format != formatCSV && format != formatNDJSON {
+		fmt.Println("help: users export <csv|ndjson> [roles]")
+		return ErrHelp
+	}
+
+	var filter []string
+	if roles != "" {
+		filter = strings.Split(roles, ",")
+	}
+
+	db, err := database.Open(cfg)
+	if err != nil {
+		return fmt.Errorf("connect database: %w", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	store := us.NewStore(log, db)
+	user := uc.New(log, data.UserRepository{Storage: store}, data.OrgRepository{Storage: store}, 1*time.Hour)
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+
+	var write func(entity.User) error
+	switch format {
+	case formatCSV:
+		w := csv.NewWriter(out)
+		defer w.Flush()
+		if err := w.Write(csvHeader); err != nil {
+			return fmt.Errorf("writing header: %w", err)
+		}
+		write = func(u entity.User) error {
+			return w.Write(toExportUser(u).record())
+		}
+	case formatNDJSON:
+		enc := json.NewEncoder(out)
+		write = func(u entity.User) error {
+			return enc.Encode(toExportUser(u))
+		}
+	}
+
+	if err := user.ExportUsers(ctx, filter, write); err != nil {
+		return fmt.Errorf("export users: %w", err)
+	}
+
+	return nil
+}
@@ -1,6 +1,10 @@
 package commands
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
@@ -9,12 +13,56 @@ import (
 	"os"
 )
 
-// GenKey creates an x509 private/public key for auth tokens.
-func GenKey() error {
+// GenKey creates an x509 private/public key for auth tokens. The key type
+// is rsa (RS256), ecdsa (ES256) or ed25519 (EdDSA) and defaults to rsa.
+func GenKey(keyType string) error {
+	var (
+		privateKey   crypto.Signer
+		privateBlock pem.Block
+		publicType   string
+		err          error
+	)
 
-	// Generate a new private key.
-	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
-	if err != nil {
+	// Generate a new private key and construct a PEM block for it.
+	switch keyType {
+	case "", "rsa":
+		var pk *rsa.PrivateKey
+		if pk, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+			return fmt.Errorf("generating key: %w", err)
+		}
+		privateKey = pk
+		privateBlock = pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(pk),
+		}
+		publicType = "RSA PUBLIC KEY"
+
+	case "ecdsa":
+		var pk *ecdsa.PrivateKey
+		if pk, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+			return fmt.Errorf("generating key: %w", err)
+		}
+		privateKey = pk
+		privateBlock.Type = "EC PRIVATE KEY"
+		if privateBlock.Bytes, err = x509.MarshalECPrivateKey(pk); err != nil {
+			return fmt.Errorf("marshaling private key: %w", err)
+		}
+		publicType = "PUBLIC KEY"
+
+	case "ed25519":
+		var pk ed25519.PrivateKey
+		if _, pk, err = ed25519.GenerateKey(rand.Reader); err != nil {
+			return fmt.Errorf("generating key: %w", err)
+		}
+		privateKey = pk
+		privateBlock.Type = "PRIVATE KEY"
+		if privateBlock.Bytes, err = x509.MarshalPKCS8PrivateKey(pk); err != nil {
+			return fmt.Errorf("marshaling private key: %w", err)
+		}
+		publicType = "PUBLIC KEY"
+
+	default:
+		fmt.Println("help: genkey [rsa|ecdsa|ed25519]")
 		return ErrHelp
 	}
 
@@ -25,19 +73,13 @@ func GenKey() error {
 	}
 	defer privateFile.Close()
 
-	// Construct a PEM block for the private key.
-	privateBlock := pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
-	}
-
 	// Write the private key to the private key file.
 	if err := pem.Encode(privateFile, &privateBlock); err != nil {
 		return fmt.Errorf("encoding to private file: %w", err)
 	}
 
 	// Marshal the public key from the private key to PKIX.
-	asn1Bytes, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	asn1Bytes, err := x509.MarshalPKIXPublicKey(privateKey.Public())
 	if err != nil {
 		return fmt.Errorf("marshaling public key: %w", err)
 	}
@@ -51,7 +93,7 @@ func GenKey() error {
 
 	// Construct a PEM block for the public key.
 	publicBlock := pem.Block{
-		Type:  "RSA PUBLIC KEY",
+		Type:  publicType,
 		Bytes: asn1Bytes,
 	}
 
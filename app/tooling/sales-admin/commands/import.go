@@ -0,0 +1,289 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	audits "github.com/appinesshq/caservice/business/audit/usecases"
+	"github.com/appinesshq/caservice/business/org"
+	entity "github.com/appinesshq/caservice/business/user"
+	uc "github.com/appinesshq/caservice/business/user/usecases"
+	"github.com/appinesshq/caservice/data/audit"
+	auditpg "github.com/appinesshq/caservice/data/audit/pg"
+	database "github.com/appinesshq/caservice/data/core/pg"
+	data "github.com/appinesshq/caservice/data/user"
+	us "github.com/appinesshq/caservice/data/user/pg"
+	"github.com/appinesshq/caservice/foundation/validation"
+	"go.uber.org/zap"
+)
+
+// importBatchSize is the number of users that are stored in a single
+// transaction.
+const importBatchSize = 100
+
+// importBatchTimeout is how long storing a single batch may take. Hashing
+// the passwords of new users takes most of it.
+const importBatchTimeout = time.Minute
+
+// importLine reports the outcome of importing the user on a line of the
+// input.
+type importLine struct {
+	Line   int               `json:"line"`
+	Email  string            `json:"email,omitempty"`
+	UserID string            `json:"id,omitempty"`
+	Result string            `json:"result"`
+	Error  string            `json:"error,omitempty"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// importSummary reports the outcome of an import.
+type importSummary struct {
+	DryRun  bool `json:"dry_run"`
+	Created int  `json:"created"`
+	Updated int  `json:"updated"`
+	Failed  int  `json:"failed"`
+}
+
+// UsersImport creates or updates the users in the CSV or NDJSON file, or
+// stdin for "-", by email address as members of the default organization.
+// Passwords must meet the policy. The outcome of every line is written to
+// stdout. With dryRun, the users are only validated.
+func UsersImport(log *zap.SugaredLogger, cfg database.Config, policy entity.PasswordPolicy, format string, file string, dryRun bool) error {
+	if (format != formatCSV && format != formatNDJSON) || file == "" {
+		fmt.Println("help: users import <csv|ndjson> <file|-> [dry-run]")
+		return ErrHelp
+	}
+
+	in := os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return fmt.Errorf("opening file: %w", err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var next func() (uc.ImportUser, int, error)
+	switch format {
+	case formatCSV:
+		r, err := newCSVReader(in)
+		if err != nil {
+			return err
+		}
+		next = r.next
+	case formatNDJSON:
+		next = newNDJSONReader(in).next
+	}
+
+	db, err := database.Open(cfg)
+	if err != nil {
+		return fmt.Errorf("connect database: %w", err)
+	}
+	defer db.Close()
+
+	store := us.NewStore(log, db)
+	trail := audits.New(log, audit.AuditRepository{Storage: auditpg.NewStore(log, db)})
+	user := uc.New(log, data.UserRepository{Storage: store}, data.OrgRepository{Storage: store}, 1*time.Hour, uc.WithAudit(trail), uc.WithPasswordPolicy(policy))
+
+	enc := json.NewEncoder(os.Stdout)
+	summary := importSummary{DryRun: dryRun}
+	report := func(l importLine, err error) error {
+		switch {
+		case err != nil:
+			l.Result = "failed"
+			l.Error = err.Error()
+			var ve validation.ValidationError
+			if errors.As(err, &ve) {
+				l.Fields = ve.Fields
+			}
+			summary.Failed++
+		case l.Result == "created":
+			summary.Created++
+		case l.Result == "updated":
+			summary.Updated++
+		}
+		return enc.Encode(l)
+	}
+
+	var batch []uc.ImportUser
+	var lines []int
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), importBatchTimeout)
+		defer cancel()
+
+		results, batchErr := user.ImportUsers(ctx, org.DefaultID, batch, dryRun, time.Now().UTC())
+		for i, res := range results {
+			l := importLine{Line: lines[i], Email: res.Email, UserID: res.UserID, Result: "updated"}
+			if res.Created {
+				l.Result = "created"
+			}
+
+			// A batch that could not be stored fails all users that were
+			// valid on their own.
+			err := res.Err
+			if err == nil {
+				err = batchErr
+			}
+			if err := report(l, err); err != nil {
+				return err
+			}
+		}
+
+		batch, lines = batch[:0], lines[:0]
+		return nil
+	}
+
+	for {
+		iu, line, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var le *lineError
+		switch {
+		case errors.As(err, &le):
+			if err := report(importLine{Line: line}, le.err); err != nil {
+				return err
+			}
+			continue
+		case err != nil:
+			return fmt.Errorf("reading line %d: %w", line, err)
+		}
+
+		batch = append(batch, iu)
+		lines = append(lines, line)
+		if len(batch) == importBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	if err := enc.Encode(summary); err != nil {
+		return err
+	}
+
+	if summary.Failed > 0 {
+		return fmt.Errorf("import users: %d of %d users failed", summary.Failed, summary.Created+summary.Updated+summary.Failed)
+	}
+	return nil
+}
+
+// lineError is an error of a single line of the input, after which the next
+// lines can still be read.
+type lineError struct {
+	err error
+}
+
+// Error implements the error interface.
+func (e *lineError) Error() string {
+	return e.err.Error()
+}
+
+// csvReader reads users from CSV with a header. The name, email, roles and
+// password columns are read, other columns are ignored.
+type csvReader struct {
+	r       *csv.Reader
+	columns map[string]int
+}
+
+func newCSVReader(in io.Reader) (*csvReader, error) {
+	r := csv.NewReader(in)
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, errors.New("reading header: email column is missing")
+	}
+
+	return &csvReader{r: r, columns: columns}, nil
+}
+
+// next returns the user on the next line of the input, or io.EOF.
+func (c *csvReader) next() (uc.ImportUser, int, error) {
+	record, err := c.r.Read()
+	if err != nil {
+		var pe *csv.ParseError
+		if errors.As(err, &pe) {
+			return uc.ImportUser{}, pe.StartLine, &lineError{err: err}
+		}
+		return uc.ImportUser{}, 0, err
+	}
+	line, _ := c.r.FieldPos(0)
+
+	column := func(name string) string {
+		if i, ok := c.columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	iu := uc.ImportUser{
+		Name:     column("name"),
+		Email:    column("email"),
+		Password: column("password"),
+	}
+	if roles := column("roles"); roles != "" {
+		for _, role := range strings.Split(roles, rolesSeparator) {
+			iu.Roles = append(iu.Roles, strings.TrimSpace(role))
+		}
+	}
+
+	return iu, line, nil
+}
+
+// ndjsonReader reads users from newline delimited JSON, one object per line.
+// Empty lines are skipped.
+type ndjsonReader struct {
+	s    *bufio.Scanner
+	line int
+}
+
+func newNDJSONReader(in io.Reader) *ndjsonReader {
+	return &ndjsonReader{s: bufio.NewScanner(in)}
+}
+
+// next returns the user on the next line of the input, or io.EOF.
+func (n *ndjsonReader) next() (uc.ImportUser, int, error) {
+	for n.s.Scan() {
+		n.line++
+		b := n.s.Bytes()
+		if len(strings.TrimSpace(string(b))) == 0 {
+			continue
+		}
+
+		var iu uc.ImportUser
+		if err := json.Unmarshal(b, &iu); err != nil {
+			return uc.ImportUser{}, n.line, &lineError{err: err}
+		}
+		return iu, n.line, nil
+	}
+	if err := n.s.Err(); err != nil {
+		return uc.ImportUser{}, n.line, err
+	}
+
+	return uc.ImportUser{}, n.line, io.EOF
+}
@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	audits "github.com/appinesshq/caservice/business/audit/usecases"
+	uc "github.com/appinesshq/caservice/business/user/usecases"
+	"github.com/appinesshq/caservice/data/audit"
+	auditpg "github.com/appinesshq/caservice/data/audit/pg"
+	database "github.com/appinesshq/caservice/data/core/pg"
+	data "github.com/appinesshq/caservice/data/user"
+	us "github.com/appinesshq/caservice/data/user/pg"
+	"go.uber.org/zap"
+)
+
+// defaultRetention is how long deleted users are kept if no retention
+// period is provided.
+const defaultRetention = 30 * 24 * time.Hour
+
+// Purge removes the users that were deleted longer than the retention period
+// ago from the database, together with their data.
+func Purge(log *zap.SugaredLogger, cfg database.Config, retention string) error {
+	period := defaultRetention
+	if retention != "" {
+		var err error
+		if period, err = time.ParseDuration(retention); err != nil {
+			return fmt.Errorf("parsing retention period: %w", err)
+		}
+	}
+
+	db, err := database.Open(cfg)
+	if err != nil {
+		return fmt.Errorf("connect database: %w", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store := us.NewStore(log, db)
+	trail := audits.New(log, audit.AuditRepository{Storage: auditpg.NewStore(log, db)})
+	user := uc.New(log, data.UserRepository{Storage: store}, data.OrgRepository{Storage: store}, 1*time.Hour, uc.WithAudit(trail))
+
+	ids, err := user.PurgeDeleted(ctx, time.Now().UTC().Add(-period))
+	if err != nil {
+		return fmt.Errorf("purge users: %w", err)
+	}
+
+	return json.NewEncoder(os.Stdout).Encode(ids)
+}
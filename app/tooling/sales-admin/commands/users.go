@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"time"
 
+	entity "github.com/appinesshq/caservice/business/user"
 	uc "github.com/appinesshq/caservice/business/user/usecases"
 	database "github.com/appinesshq/caservice/data/core/pg"
 	data "github.com/appinesshq/caservice/data/user"
@@ -37,9 +38,9 @@ func Users(log *zap.SugaredLogger, cfg database.Config, pageNumber string, rowsP
 	}
 
 	store := us.NewStore(log, db)
-	user := uc.New(log, data.UserRepository{Storage: store}, 1*time.Hour)
+	user := uc.New(log, data.UserRepository{Storage: store}, data.OrgRepository{Storage: store}, 1*time.Hour)
 
-	users, err := user.Query(ctx, page, rows)
+	users, err := user.Query(ctx, entity.QueryFilter{}, page, rows)
 	if err != nil {
 		return fmt.Errorf("retrieve users: %w", err)
 	}
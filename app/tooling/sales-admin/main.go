@@ -7,7 +7,9 @@ import (
 	"os"
 
 	"github.com/appinesshq/caservice/app/tooling/sales-admin/commands"
+	"github.com/appinesshq/caservice/business/user"
 	database "github.com/appinesshq/caservice/data/core/pg"
+	"github.com/appinesshq/caservice/foundation/breached"
 	"github.com/appinesshq/caservice/foundation/logger"
 	"github.com/ardanlabs/conf/v3"
 	"go.uber.org/zap"
@@ -55,6 +57,15 @@ func run(log *zap.SugaredLogger) error {
 			APIHost       string `conf:"default:http://0.0.0.0:3000"`
 			AuthTokenFile string `conf:"default:/tmp/.service/token"`
 		}
+		Passwords struct {
+			MinLength      int  `conf:"default:8"`
+			RequireLower   bool `conf:"default:false"`
+			RequireUpper   bool `conf:"default:false"`
+			RequireDigit   bool `conf:"default:false"`
+			RequireSymbol  bool `conf:"default:false"`
+			RejectPersonal bool `conf:"default:true"`
+			BreachedFile   string
+		}
 	}{
 		Version: conf.Version{
 			Build: build,
@@ -78,6 +89,27 @@ func run(log *zap.SugaredLogger) error {
 	}
 	log.Infow("startup", "config", out)
 
+	// =========================================================================
+	// Password policy
+
+	// Imported passwords must meet the same policy as the API enforces, so
+	// configure both the same.
+	passwordPolicy := user.PasswordPolicy{
+		MinLength:      cfg.Passwords.MinLength,
+		RequireLower:   cfg.Passwords.RequireLower,
+		RequireUpper:   cfg.Passwords.RequireUpper,
+		RequireDigit:   cfg.Passwords.RequireDigit,
+		RequireSymbol:  cfg.Passwords.RequireSymbol,
+		RejectPersonal: cfg.Passwords.RejectPersonal,
+	}
+	if cfg.Passwords.BreachedFile != "" {
+		list, err := breached.LoadFile(cfg.Passwords.BreachedFile)
+		if err != nil {
+			return fmt.Errorf("loading breached passwords: %w", err)
+		}
+		passwordPolicy.Breached = list
+	}
+
 	// =========================================================================
 	// Commands
 
@@ -89,12 +121,12 @@ func run(log *zap.SugaredLogger) error {
 		DisableTLS: cfg.DB.DisableTLS,
 	}
 
-	return processCommands(cfg.Args, dbConfig, cfg.Web.APIHost, cfg.Web.AuthTokenFile, log)
+	return processCommands(cfg.Args, dbConfig, passwordPolicy, cfg.Web.APIHost, cfg.Web.AuthTokenFile, log)
 }
 
 // processCommands handles the execution of the commands specified on
 // the command line.
-func processCommands(args conf.Args, dbConfig database.Config, host string, tokenfile string, log *zap.SugaredLogger) error {
+func processCommands(args conf.Args, dbConfig database.Config, passwordPolicy user.PasswordPolicy, host string, tokenfile string, log *zap.SugaredLogger) error {
 	switch args.Num(0) {
 	case "migrate":
 		if err := commands.Migrate(dbConfig); err != nil {
@@ -112,7 +144,7 @@ func processCommands(args conf.Args, dbConfig database.Config, host string, toke
 		}
 
 	case "genkey":
-		if err := commands.GenKey(); err != nil {
+		if err := commands.GenKey(args.Num(1)); err != nil {
 			return fmt.Errorf("key generation: %w", err)
 		}
 
@@ -122,19 +154,39 @@ func processCommands(args conf.Args, dbConfig database.Config, host string, toke
 		}
 
 	case "users":
-		pageNumber := args.Num(1)
-		rowsPerPage := args.Num(2)
-		if err := commands.Users(log, dbConfig, pageNumber, rowsPerPage); err != nil {
-			return fmt.Errorf("getting users: %w", err)
+		switch args.Num(1) {
+		case "export":
+			if err := commands.UsersExport(log, dbConfig, args.Num(2), args.Num(3)); err != nil {
+				return fmt.Errorf("exporting users: %w", err)
+			}
+		case "import":
+			dryRun := args.Num(4) == "dry-run"
+			if err := commands.UsersImport(log, dbConfig, passwordPolicy, args.Num(2), args.Num(3), dryRun); err != nil {
+				return fmt.Errorf("importing users: %w", err)
+			}
+		default:
+			pageNumber := args.Num(1)
+			rowsPerPage := args.Num(2)
+			if err := commands.Users(log, dbConfig, pageNumber, rowsPerPage); err != nil {
+				return fmt.Errorf("getting users: %w", err)
+			}
+		}
+
+	case "purge":
+		if err := commands.Purge(log, dbConfig, args.Num(1)); err != nil {
+			return fmt.Errorf("purging users: %w", err)
 		}
 
 	default:
 		fmt.Println("migrate: create the schema in the database")
 		fmt.Println("seed: add data to the database")
 		fmt.Printf("authenticate: authenticate a user and store the token in %q\n", tokenfile)
-		fmt.Println("genkey: generate a set of private/public key files")
+		fmt.Println("genkey: generate a set of private/public key files (rsa, ecdsa or ed25519)")
 		fmt.Println("register: register a new user")
 		fmt.Println("users: get a list of users from the database")
+		fmt.Println("users export: write users to stdout as csv or ndjson, optionally only those with one of the comma separated roles")
+		fmt.Println("users import: create or update users by email from a csv or ndjson file, validating only with dry-run")
+		fmt.Println("purge: remove users deleted longer than the retention period ago (default 720h)")
 		fmt.Println("provide a command to get more help.")
 		return commands.ErrHelp
 	}
@@ -0,0 +1,146 @@
+// Package audit provides audit trail (related) entities and business logic.
+// The audit trail records every change of state: who made it, in which
+// usecase, to which entity and what changed.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/appinesshq/caservice/foundation/validation"
+	"github.com/google/uuid"
+)
+
+// Entry is a single change in the audit trail. The actor and organization
+// are empty for changes that were made without a session, like the
+// registration of a user. The impersonator is the admin that acted as the
+// actor, if the change was made in an impersonated session.
+type Entry struct {
+	ID             string `validate:"required,uuid"`
+	OrgID          string
+	ActorID        string
+	ImpersonatorID string
+	Usecase        string `validate:"required"`
+	EntityID       string
+	TraceID        string
+	Changes        map[string]Change
+	DateCreated    time.Time `validate:"required"`
+}
+
+// Change is the value of a field before and after a change. Before is empty
+// for created entities, After for deleted entities.
+type Change struct {
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+func New(id, orgID, actorID, impersonatorID, usecase, entityID, traceID string, changes map[string]Change, now time.Time) (Entry, error) {
+	e := Entry{
+		ID:             id,
+		OrgID:          orgID,
+		ActorID:        actorID,
+		ImpersonatorID: impersonatorID,
+		Usecase:        usecase,
+		EntityID:       entityID,
+		TraceID:        traceID,
+		Changes:        changes,
+		DateCreated:    now,
+	}
+
+	if err := e.Validate(); err != nil {
+		return Entry{}, fmt.Errorf("validation error: %w", err)
+	}
+	return e, nil
+}
+
+func NewWithID(orgID, actorID, impersonatorID, usecase, entityID, traceID string, changes map[string]Change, now time.Time) (Entry, error) {
+	return New(uuid.New().String(), orgID, actorID, impersonatorID, usecase, entityID, traceID, changes, now)
+}
+
+func (e Entry) Validate() error {
+	if err := validation.DefaultValidationProvider.Check(e); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Diff returns the fields that differ between two versions of an entity, as
+// they are encoded to JSON. Fields that are not encoded, like password
+// hashes, never show up. Before is nil for created entities and after is nil
+// for deleted entities.
+func Diff(before, after any) (map[string]Change, error) {
+	b, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]Change)
+	for name, value := range b {
+		if !bytes.Equal(value, a[name]) {
+			changes[name] = Change{Before: value, After: a[name]}
+		}
+	}
+	for name, value := range a {
+		if _, ok := b[name]; !ok {
+			changes[name] = Change{After: value}
+		}
+	}
+
+	return changes, nil
+}
+
+// fields returns the JSON encoded fields of an entity.
+func fields(v any) (map[string]json.RawMessage, error) {
+	if v == nil {
+		return map[string]json.RawMessage{}, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("encoding entity: %w", err)
+	}
+
+	var f map[string]json.RawMessage
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("decoding fields: %w", err)
+	}
+	return f, nil
+}
+
+// Filter selects entries of the audit trail. Empty fields match all entries.
+type Filter struct {
+	ActorID  string
+	EntityID string
+	Since    time.Time
+	Until    time.Time
+}
+
+// Match returns true if the entry is selected by the filter.
+func (f Filter) Match(e Entry) bool {
+	switch {
+	case f.ActorID != "" && e.ActorID != f.ActorID:
+		return false
+	case f.EntityID != "" && e.EntityID != f.EntityID:
+		return false
+	case !f.Since.IsZero() && e.DateCreated.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !e.DateCreated.Before(f.Until):
+		return false
+	}
+	return true
+}
+
+// Recorder records changes in the audit trail. Usecases of any domain use it
+// to record their changes, with the entity as it was before and after the
+// change.
+type Recorder interface {
+	Record(ctx context.Context, usecase, entityID string, before, after any) error
+}
@@ -0,0 +1,96 @@
+package audit_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/appinesshq/caservice/business/audit"
+	"github.com/appinesshq/caservice/foundation/tests"
+	"github.com/google/go-cmp/cmp"
+)
+
+type item struct {
+	Name   string
+	Cost   int
+	Secret string `json:"-"`
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	before := item{Name: "Comic Books", Cost: 50, Secret: "a"}
+	after := item{Name: "Comic Books", Cost: 55, Secret: "b"}
+
+	table := []struct {
+		name   string
+		before any
+		after  any
+		exp    map[string]audit.Change
+	}{
+		{"creating an entity", nil, before, map[string]audit.Change{
+			"Name": {After: json.RawMessage(`"Comic Books"`)},
+			"Cost": {After: json.RawMessage(`50`)},
+		}},
+		{"updating an entity", before, after, map[string]audit.Change{
+			"Cost": {Before: json.RawMessage(`50`), After: json.RawMessage(`55`)},
+		}},
+		{"deleting an entity", after, nil, map[string]audit.Change{
+			"Name": {Before: json.RawMessage(`"Comic Books"`)},
+			"Cost": {Before: json.RawMessage(`55`)},
+		}},
+		{"changing nothing", nil, nil, map[string]audit.Change{}},
+	}
+
+	t.Log("Given the need to record what changed.")
+	{
+		for testID, tt := range table {
+			t.Logf("\tTest %d:\tWhen %s.", testID, tt.name)
+			{
+				got, err := audit.Diff(tt.before, tt.after)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to diff: %v.", tests.Failed, testID, err)
+				}
+
+				if diff := cmp.Diff(got, tt.exp); diff != "" {
+					t.Fatalf("\t%s\tTest %d:\tShould only get the changed, encoded fields. Diff:\n%s", tests.Failed, testID, diff)
+				}
+				t.Logf("\t%s\tTest %d:\tShould only get the changed, encoded fields.", tests.Success, testID)
+			}
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	e := audit.Entry{ActorID: "actor-id", EntityID: "entity-id", DateCreated: now}
+
+	table := []struct {
+		name   string
+		filter audit.Filter
+		match  bool
+	}{
+		{"an empty filter", audit.Filter{}, true},
+		{"the actor", audit.Filter{ActorID: "actor-id"}, true},
+		{"another actor", audit.Filter{ActorID: "other-id"}, false},
+		{"another entity", audit.Filter{EntityID: "other-id"}, false},
+		{"a range around the entry", audit.Filter{Since: now, Until: now.Add(time.Second)}, true},
+		{"a range before the entry", audit.Filter{Until: now}, false},
+		{"a range after the entry", audit.Filter{Since: now.Add(time.Second)}, false},
+	}
+
+	t.Log("Given the need to filter the audit trail.")
+	{
+		for testID, tt := range table {
+			t.Logf("\tTest %d:\tWhen filtering by %s.", testID, tt.name)
+			{
+				if got := tt.filter.Match(e); got != tt.match {
+					t.Fatalf("\t%s\tTest %d:\tShould match: %t, but got %t.", tests.Failed, testID, tt.match, got)
+				}
+				t.Logf("\t%s\tTest %d:\tShould match: %t.", tests.Success, testID, tt.match)
+			}
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package usecases
+
+import (
+	"context"
+
+	"github.com/appinesshq/caservice/business/audit"
+)
+
+// AuditRepository is an interface which is to be implemented by the layer
+// between audit usecases and storages. The audit trail is append-only, so
+// entries can't be updated or deleted.
+//
+// Query returns the entries of the organization of the session in the
+// context that match the filter, newest first.
+type AuditRepository interface {
+	Create(context.Context, audit.Entry) error
+	Query(ctx context.Context, f audit.Filter, pageNumber int, rowsPerPage int) ([]audit.Entry, error)
+}
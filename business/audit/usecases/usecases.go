@@ -0,0 +1,76 @@
+// Package usecases provides audit usecases with application logic.
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/appinesshq/caservice/business/audit"
+	"github.com/appinesshq/caservice/business/authz"
+	"github.com/appinesshq/caservice/business/user"
+	fctx "github.com/appinesshq/caservice/foundation/context"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrUnauthorized = authz.ErrUnauthorized
+)
+
+// AuditUseCases contain application logic for the audit trail.
+type AuditUseCases struct {
+	Log  *zap.SugaredLogger
+	Repo AuditRepository
+}
+
+// New returns an initialized AuditUseCases.
+// Requires a logger and audit repository as input.
+func New(log *zap.SugaredLogger, r AuditRepository) AuditUseCases {
+	return AuditUseCases{Log: log, Repo: r}
+}
+
+// Record appends a change to the audit trail. The user and organization of
+// the session in the context are recorded as the actor of the change, along
+// with the admin impersonating the user, if any. The trace id and time are
+// taken from the request. Before is nil for created
+// entities and after is nil for deleted entities.
+func (uc AuditUseCases) Record(ctx context.Context, usecase, entityID string, before, after any) error {
+	changes, err := audit.Diff(before, after)
+	if err != nil {
+		return fmt.Errorf("diffing %s of entityID[%s]: %w", usecase, entityID, err)
+	}
+
+	var orgID, actorID, impersonatorID string
+	if s, err := user.GetSession(ctx); err == nil {
+		orgID = s.TenantID
+		actorID = s.User.ID
+		impersonatorID = s.ImpersonatorID
+	}
+
+	now := time.Now().UTC()
+	if v, err := fctx.GetValues(ctx); err == nil {
+		now = v.Now
+	}
+
+	e, err := audit.NewWithID(orgID, actorID, impersonatorID, usecase, entityID, fctx.GetTraceID(ctx), changes, now)
+	if err != nil {
+		return err
+	}
+
+	return uc.Repo.Create(ctx, e)
+}
+
+// Query retrieves the entries of the organization of the session that match
+// the filter, newest first.
+func (uc AuditUseCases) Query(ctx context.Context, f audit.Filter, pageNumber int, rowsPerPage int) ([]audit.Entry, error) {
+	s, err := user.GetSession(ctx)
+	if err != nil {
+		return []audit.Entry{}, err
+	}
+
+	if err := authz.Authorize(authz.ActionAuditQuery, authz.SessionActor(s), authz.Resource{}); err != nil {
+		return []audit.Entry{}, err
+	}
+
+	return uc.Repo.Query(ctx, f, pageNumber, rowsPerPage)
+}
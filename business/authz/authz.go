@@ -0,0 +1,211 @@
+// Package authz provides policy based authorization. A policy declares once
+// which actors may perform an action on a resource, so usecases and web
+// middleware evaluate the same rules.
+package authz
+
+import (
+	"errors"
+
+	"github.com/appinesshq/caservice/business/user"
+)
+
+// ErrUnauthorized is returned when an actor may not perform an action.
+var ErrUnauthorized = errors.New("unauthorized")
+
+// Action identifies an operation that is subject to authorization.
+type Action string
+
+// These are the actions the default policy has rules for.
+const (
+	ActionUserCreate       Action = "user:create"
+	ActionUserQuery        Action = "user:query"
+	ActionUserRead         Action = "user:read"
+	ActionUserUpdate       Action = "user:update"
+	ActionUserUpdateRoles  Action = "user:update-roles"
+	ActionUserDelete       Action = "user:delete"
+	ActionUserRestore      Action = "user:restore"
+	ActionUserRevokeTokens Action = "user:revoke-tokens"
+	ActionUserCredentials  Action = "user:credentials"
+	ActionUserManageTOTP   Action = "user:manage-totp"
+	ActionUserImpersonate  Action = "user:impersonate"
+	ActionAPIKeyCreate     Action = "apikey:create"
+	ActionAPIKeyQuery      Action = "apikey:query"
+	ActionAPIKeyRevoke     Action = "apikey:revoke"
+	ActionSessionQuery     Action = "session:query"
+	ActionSessionRevoke    Action = "session:revoke"
+	ActionProductUpdate    Action = "product:update"
+	ActionProductDelete    Action = "product:delete"
+	ActionSaleRead         Action = "sale:read"
+	ActionSaleQuery        Action = "sale:query"
+	ActionOrgCreate        Action = "org:create"
+	ActionOrgSwitch        Action = "org:switch"
+	ActionOrgQueryMembers  Action = "org:query-members"
+	ActionOrgInviteMember  Action = "org:invite-member"
+	ActionOrgAnswerInvite  Action = "org:answer-invite"
+	ActionOrgRemoveMember  Action = "org:remove-member"
+	ActionAuditQuery       Action = "audit:query"
+)
+
+// Actor is the user performing an action, with its roles within the
+// organization of its session. APIKey is set for actors that authenticated
+// with an API key rather than as the user, Impersonated for actors that are
+// impersonated by an admin.
+type Actor struct {
+	ID           string
+	Roles        []string
+	APIKey       bool
+	Impersonated bool
+}
+
+// SessionActor returns the user of the session as actor.
+func SessionActor(s user.Session) Actor {
+	return Actor{
+		ID:           s.User.ID,
+		Roles:        s.User.Roles,
+		APIKey:       s.APIKeyID != "",
+		Impersonated: s.ImpersonatorID != "",
+	}
+}
+
+// HasRole returns true if the actor has one of the provided roles.
+func (a Actor) HasRole(roles ...string) bool {
+	for _, has := range a.Roles {
+		for _, want := range roles {
+			if has == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Resource is what an action is performed on. Owners are the ids of the
+// users the resource belongs to, like the user itself or both the buyer and
+// the seller of a sale. A resource without owners belongs to nobody.
+type Resource struct {
+	Owners []string
+}
+
+// OwnedBy returns a resource that belongs to the users with the provided ids.
+func OwnedBy(ids ...string) Resource {
+	return Resource{Owners: ids}
+}
+
+// Rule decides whether an actor may perform an action on a resource.
+type Rule func(a Actor, r Resource) bool
+
+// Role returns a rule that allows actors with one of the provided roles.
+func Role(roles ...string) Rule {
+	return func(a Actor, r Resource) bool {
+		return a.HasRole(roles...)
+	}
+}
+
+// Owner returns a rule that allows actors that own the resource.
+func Owner() Rule {
+	return func(a Actor, r Resource) bool {
+		if a.ID == "" {
+			return false
+		}
+		for _, id := range r.Owners {
+			if id == a.ID {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Interactive returns a rule that allows actors that did not authenticate
+// with an API key.
+func Interactive() Rule {
+	return func(a Actor, r Resource) bool {
+		return !a.APIKey
+	}
+}
+
+// Personal returns a rule that allows actors that are not impersonated by an
+// admin. It guards sensitive actions, like changing credentials.
+func Personal() Rule {
+	return func(a Actor, r Resource) bool {
+		return !a.Impersonated
+	}
+}
+
+// All returns a rule that allows actors allowed by all of the provided rules.
+func All(rules ...Rule) Rule {
+	return func(a Actor, r Resource) bool {
+		for _, rule := range rules {
+			if !rule(a, r) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Any returns a rule that allows actors allowed by at least one of the
+// provided rules.
+func Any(rules ...Rule) Rule {
+	return func(a Actor, r Resource) bool {
+		for _, rule := range rules {
+			if rule(a, r) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Policy maps actions to the rule that authorizes them. Actions without a
+// rule are denied.
+type Policy map[Action]Rule
+
+// Authorize returns ErrUnauthorized unless the actor may perform the action
+// on the resource.
+func (p Policy) Authorize(action Action, a Actor, r Resource) error {
+	rule, ok := p[action]
+	if !ok || !rule(a, r) {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+// adminOrOwner allows admins and the owners of a resource.
+var adminOrOwner = Any(Role(user.RoleAdmin), Owner())
+
+// Default is the policy of the application.
+var Default = Policy{
+	ActionUserCreate:       Role(user.RoleAdmin),
+	ActionUserQuery:        Role(user.RoleAdmin),
+	ActionUserRead:         adminOrOwner,
+	ActionUserUpdate:       adminOrOwner,
+	ActionUserUpdateRoles:  Role(user.RoleAdmin),
+	ActionUserDelete:       adminOrOwner,
+	ActionUserRestore:      Role(user.RoleAdmin),
+	ActionUserRevokeTokens: adminOrOwner,
+	ActionUserCredentials:  All(Personal(), adminOrOwner),
+	ActionUserManageTOTP:   All(Personal(), Owner()),
+	ActionUserImpersonate:  All(Interactive(), Personal(), Role(user.RoleAdmin)),
+	ActionAPIKeyCreate:     All(Interactive(), Personal(), adminOrOwner),
+	ActionAPIKeyQuery:      adminOrOwner,
+	ActionAPIKeyRevoke:     adminOrOwner,
+	ActionSessionQuery:     All(Interactive(), Owner()),
+	ActionSessionRevoke:    All(Interactive(), Personal(), Owner()),
+	ActionProductUpdate:    adminOrOwner,
+	ActionProductDelete:    adminOrOwner,
+	ActionSaleRead:         adminOrOwner,
+	ActionSaleQuery:        adminOrOwner,
+	ActionOrgCreate:        Interactive(),
+	ActionOrgSwitch:        All(Interactive(), Personal()),
+	ActionOrgQueryMembers:  Role(user.RoleAdmin),
+	ActionOrgInviteMember:  Role(user.RoleAdmin),
+	ActionOrgAnswerInvite:  All(Interactive(), Personal(), Owner()),
+	ActionOrgRemoveMember:  adminOrOwner,
+	ActionAuditQuery:       Role(user.RoleAdmin),
+}
+
+// Authorize evaluates the action with the Default policy.
+func Authorize(action Action, a Actor, r Resource) error {
+	return Default.Authorize(action, a, r)
+}
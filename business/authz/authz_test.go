@@ -0,0 +1,139 @@
+package authz_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/appinesshq/caservice/business/authz"
+	"github.com/appinesshq/caservice/business/user"
+	"github.com/appinesshq/caservice/foundation/tests"
+)
+
+func TestDefaultPolicy(t *testing.T) {
+	t.Parallel()
+
+	admin := authz.Actor{ID: "admin-id", Roles: []string{user.RoleAdmin}}
+	owner := authz.Actor{ID: "owner-id", Roles: []string{user.RoleUser}}
+	other := authz.Actor{ID: "other-id", Roles: []string{user.RoleUser}}
+	ownerKey := authz.Actor{ID: "owner-id", Roles: []string{user.RoleUser}, APIKey: true}
+	adminKey := authz.Actor{ID: "admin-id", Roles: []string{user.RoleAdmin}, APIKey: true}
+	impersonated := authz.Actor{ID: "owner-id", Roles: []string{user.RoleUser}, Impersonated: true}
+	impersonatedAdmin := authz.Actor{ID: "admin-id", Roles: []string{user.RoleAdmin}, Impersonated: true}
+	anonymous := authz.Actor{}
+
+	owned := authz.OwnedBy("owner-id")
+	shared := authz.OwnedBy("seller-id", "owner-id")
+	unowned := authz.Resource{}
+
+	table := []struct {
+		name     string
+		action   authz.Action
+		actor    authz.Actor
+		resource authz.Resource
+		allowed  bool
+	}{
+		{"admin reads any user", authz.ActionUserRead, admin, owned, true},
+		{"owner reads itself", authz.ActionUserRead, owner, owned, true},
+		{"other user reads owner", authz.ActionUserRead, other, owned, false},
+		{"owner updates itself", authz.ActionUserUpdate, owner, owned, true},
+		{"owner deletes itself", authz.ActionUserDelete, owner, owned, true},
+		{"other user deletes owner", authz.ActionUserDelete, other, owned, false},
+		{"owner restores itself", authz.ActionUserRestore, owner, owned, false},
+		{"admin restores user", authz.ActionUserRestore, admin, owned, true},
+		{"owner revokes own tokens", authz.ActionUserRevokeTokens, owner, owned, true},
+		{"owner changes own roles", authz.ActionUserUpdateRoles, owner, owned, false},
+		{"admin changes roles", authz.ActionUserUpdateRoles, admin, owned, true},
+		{"user creates user", authz.ActionUserCreate, owner, unowned, false},
+		{"admin creates user", authz.ActionUserCreate, admin, unowned, true},
+		{"user queries users", authz.ActionUserQuery, owner, unowned, false},
+		{"admin queries users", authz.ActionUserQuery, admin, unowned, true},
+		{"owner reads shared sale", authz.ActionSaleRead, owner, shared, true},
+		{"other user reads shared sale", authz.ActionSaleRead, other, shared, false},
+		{"owner updates product", authz.ActionProductUpdate, owner, owned, true},
+		{"other user updates product", authz.ActionProductUpdate, other, owned, false},
+		{"user without id on unowned resource", authz.ActionUserRead, anonymous, unowned, false},
+		{"owner creates api key", authz.ActionAPIKeyCreate, owner, owned, true},
+		{"api key creates api key", authz.ActionAPIKeyCreate, ownerKey, owned, false},
+		{"admin api key creates api key", authz.ActionAPIKeyCreate, adminKey, owned, false},
+		{"api key queries api keys", authz.ActionAPIKeyQuery, ownerKey, owned, true},
+		{"owner queries own sessions", authz.ActionSessionQuery, owner, owned, true},
+		{"admin queries sessions of user", authz.ActionSessionQuery, admin, owned, false},
+		{"api key revokes sessions", authz.ActionSessionRevoke, ownerKey, owned, false},
+		{"user queries audit trail", authz.ActionAuditQuery, owner, unowned, false},
+		{"admin queries audit trail", authz.ActionAuditQuery, admin, unowned, true},
+		{"owner changes own credentials", authz.ActionUserCredentials, owner, owned, true},
+		{"admin changes credentials of user", authz.ActionUserCredentials, admin, owned, true},
+		{"impersonated owner changes credentials", authz.ActionUserCredentials, impersonated, owned, false},
+		{"impersonated owner reads itself", authz.ActionUserRead, impersonated, owned, true},
+		{"impersonated owner creates api key", authz.ActionAPIKeyCreate, impersonated, owned, false},
+		{"impersonated owner revokes sessions", authz.ActionSessionRevoke, impersonated, owned, false},
+		{"owner manages own totp", authz.ActionUserManageTOTP, owner, owned, true},
+		{"impersonated owner manages totp", authz.ActionUserManageTOTP, impersonated, owned, false},
+		{"admin impersonates user", authz.ActionUserImpersonate, admin, owned, true},
+		{"user impersonates user", authz.ActionUserImpersonate, other, owned, false},
+		{"admin api key impersonates user", authz.ActionUserImpersonate, adminKey, owned, false},
+		{"impersonated admin impersonates user", authz.ActionUserImpersonate, impersonatedAdmin, owned, false},
+		{"admin uses unknown action", authz.Action("user:unknown"), admin, owned, false},
+	}
+
+	t.Log("Given the need to authorize actions with the default policy.")
+	{
+		for testID, tt := range table {
+			t.Logf("\tTest %d:\tWhen %s.", testID, tt.name)
+			{
+				err := authz.Authorize(tt.action, tt.actor, tt.resource)
+				switch {
+				case tt.allowed && err != nil:
+					t.Fatalf("\t%s\tTest %d:\tShould be allowed: %v.", tests.Failed, testID, err)
+				case !tt.allowed && !errors.Is(err, authz.ErrUnauthorized):
+					t.Fatalf("\t%s\tTest %d:\tShould be denied, but got: %v.", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be allowed: %t.", tests.Success, testID, tt.allowed)
+			}
+		}
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	t.Parallel()
+
+	t.Log("Given the need to compose rules into a policy.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen using a custom policy.", testID)
+		{
+			p := authz.Policy{
+				"report:read": authz.Any(authz.Role("AUDITOR"), authz.Owner()),
+				"report:sign": authz.All(authz.Role("AUDITOR"), authz.Interactive()),
+			}
+
+			auditor := authz.Actor{ID: "auditor-id", Roles: []string{"AUDITOR"}}
+			if err := p.Authorize("report:read", auditor, authz.Resource{}); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould allow a role on a resource without owners: %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould allow a role on a resource without owners.", tests.Success, testID)
+
+			auditor.APIKey = true
+			if err := p.Authorize("report:sign", auditor, authz.Resource{}); !errors.Is(err, authz.ErrUnauthorized) {
+				t.Fatalf("\t%s\tTest %d:\tShould require all rules to allow, but got: %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould require all rules to allow.", tests.Success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen creating an actor from a session.", testID)
+		{
+			u, _ := user.NewWithID("Test User", "test@example.com", "gophers", []string{user.RoleUser}, time.Now())
+			s := user.NewSession(u, time.Now().Add(time.Hour))
+			s.APIKeyID = "key-id"
+			s.ImpersonatorID = "admin-id"
+
+			a := authz.SessionActor(s)
+			if a.ID != u.ID || !a.HasRole(user.RoleUser) || !a.APIKey || !a.Impersonated {
+				t.Fatalf("\t%s\tTest %d:\tShould copy the user, API key and impersonator of the session: %+v.", tests.Failed, testID, a)
+			}
+			t.Logf("\t%s\tTest %d:\tShould copy the user, API key and impersonator of the session.", tests.Success, testID)
+		}
+	}
+}
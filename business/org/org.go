@@ -0,0 +1,118 @@
+// Package org provides organization (related) entities and business logic.
+// Organizations are the tenants of the system: users, products and sales
+// are only visible within the organization they belong to.
+package org
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/appinesshq/caservice/foundation/validation"
+	"github.com/google/uuid"
+)
+
+// DefaultID is the id of the organization that existed before the system
+// hosted multiple organizations. Users that sign up on their own become a
+// member of it.
+const DefaultID = "06726073-2051-4458-87e2-da9956ac6531"
+
+// Org is an organization, like a single garage sale community.
+type Org struct {
+	ID          string    `validate:"required,uuid"`
+	Name        string    `validate:"required"`
+	DateCreated time.Time `validate:"required"`
+	DateUpdated time.Time `validate:"required"`
+}
+
+func New(id, name string, now time.Time) (Org, error) {
+	o := Org{
+		ID:          id,
+		Name:        name,
+		DateCreated: now,
+		DateUpdated: now,
+	}
+
+	if err := o.Validate(); err != nil {
+		return Org{}, fmt.Errorf("validation error: %w", err)
+	}
+	return o, nil
+}
+
+func NewWithID(name string, now time.Time) (Org, error) {
+	return New(uuid.New().String(), name, now)
+}
+
+func (o Org) Validate() error {
+	if err := validation.DefaultValidationProvider.Check(o); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Membership makes a user a member of an organization. The roles of a
+// membership are the roles of the user within that organization.
+type Membership struct {
+	OrgID       string    `validate:"required,uuid"`
+	UserID      string    `validate:"required,uuid"`
+	Roles       []string  `validate:"required,min=1"`
+	DateCreated time.Time `validate:"required"`
+}
+
+func NewMembership(orgID, userID string, roles []string, now time.Time) (Membership, error) {
+	m := Membership{
+		OrgID:       orgID,
+		UserID:      userID,
+		Roles:       roles,
+		DateCreated: now,
+	}
+
+	if err := m.Validate(); err != nil {
+		return Membership{}, fmt.Errorf("validation error: %w", err)
+	}
+	return m, nil
+}
+
+func (m Membership) Validate() error {
+	if err := validation.DefaultValidationProvider.Check(m); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Invitation invites a user to become a member of an organization with the
+// roles of the invitation. The membership is only created once the user
+// accepts, so organizations can't add users without their consent.
+type Invitation struct {
+	ID          string    `validate:"required,uuid"`
+	OrgID       string    `validate:"required,uuid"`
+	UserID      string    `validate:"required,uuid"`
+	Roles       []string  `validate:"required,min=1"`
+	InvitedBy   string    `validate:"required,uuid"`
+	DateCreated time.Time `validate:"required"`
+}
+
+func NewInvitation(orgID, userID, invitedBy string, roles []string, now time.Time) (Invitation, error) {
+	i := Invitation{
+		ID:          uuid.New().String(),
+		OrgID:       orgID,
+		UserID:      userID,
+		Roles:       roles,
+		InvitedBy:   invitedBy,
+		DateCreated: now,
+	}
+
+	if err := i.Validate(); err != nil {
+		return Invitation{}, fmt.Errorf("validation error: %w", err)
+	}
+	return i, nil
+}
+
+func (i Invitation) Validate() error {
+	if err := validation.DefaultValidationProvider.Check(i); err != nil {
+		return err
+	}
+
+	return nil
+}
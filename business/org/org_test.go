@@ -0,0 +1,128 @@
+package org_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/appinesshq/caservice/business/org"
+	"github.com/appinesshq/caservice/foundation/tests"
+	"github.com/appinesshq/caservice/foundation/validation"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+)
+
+var (
+	id     = uuid.New().String()
+	userID = uuid.New().String()
+	name   = "Garage Sale"
+	roles  = []string{"USER"}
+	now    = time.Now()
+)
+
+func TestOrgEntity(t *testing.T) {
+	t.Parallel()
+
+	t.Log("Given the need to work with Org entities.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen handling a single Org.", testID)
+		{
+			t.Run("testEmptyOrg", testEmptyOrg)
+			t.Run("testValidOrg", testValidOrg)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen handling a single Membership.", testID)
+		{
+			t.Run("testInvalidMembership", testInvalidMembership)
+			t.Run("testValidMembership", testValidMembership)
+		}
+	}
+}
+
+func testEmptyOrg(t *testing.T) {
+	err := org.Org{}.Validate()
+
+	var got validation.ValidationError
+	if !errors.As(err, &got) {
+		t.Fatalf("\t%s\tShould get a validation.ValidationError, but got %T.", tests.Failed, err)
+	}
+	t.Logf("\t%s\tShould get a validation.ValidationError.", tests.Success)
+
+	exp := validation.ValidationError{
+		Err: "data validation error",
+		Fields: map[string]string{
+			"ID":          "ID is a required field",
+			"Name":        "Name is a required field",
+			"DateCreated": "DateCreated is a required field",
+			"DateUpdated": "DateUpdated is a required field"}}
+
+	if diff := cmp.Diff(got, exp); diff != "" {
+		t.Fatalf("\t%s\tShould get the expected result. Diff:\n%s", tests.Failed, diff)
+	}
+	t.Logf("\t%s\tShould get the expected result.", tests.Success)
+}
+
+func testValidOrg(t *testing.T) {
+	got, err := org.New(id, name, now)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to create an org with valid data: %v.", tests.Failed, err)
+	}
+	t.Logf("\t%s\tShould be able to create an org with valid data.", tests.Success)
+
+	exp := org.Org{
+		ID:          id,
+		Name:        name,
+		DateCreated: now,
+		DateUpdated: now,
+	}
+
+	if diff := cmp.Diff(got, exp); diff != "" {
+		t.Fatalf("\t%s\tShould get the expected result. Diff:\n%s", tests.Failed, diff)
+	}
+	t.Logf("\t%s\tShould get the expected result.", tests.Success)
+}
+
+func testInvalidMembership(t *testing.T) {
+	_, err := org.NewMembership("123", "456", []string{}, now)
+
+	var got validation.ValidationError
+	if !errors.As(err, &got) {
+		t.Fatalf("\t%s\tShould get a validation.ValidationError, but got %T.", tests.Failed, err)
+	}
+	t.Logf("\t%s\tShould get a validation.ValidationError.", tests.Success)
+
+	exp := validation.ValidationError{
+		Err: "data validation error",
+		Fields: map[string]string{
+			"OrgID":  "OrgID must be a valid UUID",
+			"UserID": "UserID must be a valid UUID",
+			"Roles":  "Roles must contain at least 1 item",
+		}}
+
+	if diff := cmp.Diff(got, exp); diff != "" {
+		t.Fatalf("\t%s\tShould get the expected result. Diff:\n%s", tests.Failed, diff)
+	}
+	t.Logf("\t%s\tShould get the expected result.", tests.Success)
+}
+
+func testValidMembership(t *testing.T) {
+	got, err := org.NewMembership(id, userID, roles, now)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to create a membership with valid data: %v.", tests.Failed, err)
+	}
+	t.Logf("\t%s\tShould be able to create a membership with valid data.", tests.Success)
+
+	exp := org.Membership{
+		OrgID:       id,
+		UserID:      userID,
+		Roles:       roles,
+		DateCreated: now,
+	}
+
+	if diff := cmp.Diff(got, exp); diff != "" {
+		t.Fatalf("\t%s\tShould get the expected result. Diff:\n%s", tests.Failed, diff)
+	}
+	t.Logf("\t%s\tShould get the expected result.", tests.Success)
+}
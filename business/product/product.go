@@ -0,0 +1,59 @@
+// Package product provides product (related) entities and business logic.
+package product
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/appinesshq/caservice/foundation/validation"
+	"github.com/google/uuid"
+)
+
+// Product is an item for sale, owned by the user who listed it within an
+// organization.
+type Product struct {
+	ID          string    `validate:"required,uuid"`
+	Name        string    `validate:"required"`
+	Cost        int       `validate:"gte=0"`
+	Quantity    int       `validate:"gte=0"`
+	UserID      string    `validate:"required,uuid"`
+	OrgID       string    `validate:"required,uuid"`
+	DateCreated time.Time `validate:"required"`
+	DateUpdated time.Time `validate:"required"`
+}
+
+func New(id, name string, cost, quantity int, userID, orgID string, now time.Time) (Product, error) {
+	p := Product{
+		ID:          id,
+		Name:        name,
+		Cost:        cost,
+		Quantity:    quantity,
+		UserID:      userID,
+		OrgID:       orgID,
+		DateCreated: now,
+		DateUpdated: now,
+	}
+
+	if err := p.Validate(); err != nil {
+		return Product{}, fmt.Errorf("validation error: %w", err)
+	}
+	return p, nil
+}
+
+func NewWithID(name string, cost, quantity int, userID, orgID string, now time.Time) (Product, error) {
+	return New(uuid.New().String(), name, cost, quantity, userID, orgID, now)
+}
+
+func (p Product) Validate() error {
+	if err := validation.DefaultValidationProvider.Check(p); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// IsOwnedBy returns true if the product was listed by
+// the user with the provided id.
+func (p Product) IsOwnedBy(userID string) bool {
+	return p.UserID == userID
+}
@@ -0,0 +1,127 @@
+package product_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/appinesshq/caservice/business/product"
+	"github.com/appinesshq/caservice/foundation/tests"
+	"github.com/appinesshq/caservice/foundation/validation"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+)
+
+var (
+	id       = uuid.New().String()
+	userID   = uuid.New().String()
+	orgID    = uuid.New().String()
+	name     = "Comic Books"
+	cost     = 50
+	quantity = 42
+	now      = time.Now()
+)
+
+func TestProductEntity(t *testing.T) {
+	t.Parallel()
+
+	t.Log("Given the need to work with Product entities.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen handling a single Product.", testID)
+		{
+			t.Run("testEmptyProduct", testEmptyProduct)
+			t.Run("testInvalidProduct", testInvalidProduct)
+			t.Run("testValidProduct", testValidProduct)
+			t.Run("testProductOwner", testProductOwner)
+		}
+	}
+}
+
+func testEmptyProduct(t *testing.T) {
+	err := product.Product{}.Validate()
+
+	var got validation.ValidationError
+	if !errors.As(err, &got) {
+		t.Fatalf("\t%s\tShould get a validation.ValidationError, but got %T.", tests.Failed, err)
+	}
+	t.Logf("\t%s\tShould get a validation.ValidationError.", tests.Success)
+
+	exp := validation.ValidationError{
+		Err: "data validation error",
+		Fields: map[string]string{
+			"ID":          "ID is a required field",
+			"Name":        "Name is a required field",
+			"UserID":      "UserID is a required field",
+			"OrgID":       "OrgID is a required field",
+			"DateCreated": "DateCreated is a required field",
+			"DateUpdated": "DateUpdated is a required field"}}
+
+	if diff := cmp.Diff(got, exp); diff != "" {
+		t.Fatalf("\t%s\tShould get the expected result. Diff:\n%s", tests.Failed, diff)
+	}
+	t.Logf("\t%s\tShould get the expected result.", tests.Success)
+}
+
+func testInvalidProduct(t *testing.T) {
+	_, err := product.New("123", name, -1, -1, "456", "789", now)
+
+	var got validation.ValidationError
+	if !errors.As(err, &got) {
+		t.Fatalf("\t%s\tShould get a validation.ValidationError, but got %T.", tests.Failed, err)
+	}
+	t.Logf("\t%s\tShould get a validation.ValidationError.", tests.Success)
+
+	exp := validation.ValidationError{
+		Err: "data validation error",
+		Fields: map[string]string{
+			"ID":       "ID must be a valid UUID",
+			"Cost":     "Cost must be 0 or greater",
+			"Quantity": "Quantity must be 0 or greater",
+			"UserID":   "UserID must be a valid UUID",
+			"OrgID":    "OrgID must be a valid UUID",
+		}}
+
+	if diff := cmp.Diff(got, exp); diff != "" {
+		t.Fatalf("\t%s\tShould get the expected result. Diff:\n%s", tests.Failed, diff)
+	}
+	t.Logf("\t%s\tShould get the expected result.", tests.Success)
+}
+
+func testValidProduct(t *testing.T) {
+	got, err := product.New(id, name, cost, quantity, userID, orgID, now)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to create a product with valid data: %v.", tests.Failed, err)
+	}
+	t.Logf("\t%s\tShould be able to create a product with valid data.", tests.Success)
+
+	exp := product.Product{
+		ID:          id,
+		Name:        name,
+		Cost:        cost,
+		Quantity:    quantity,
+		UserID:      userID,
+		OrgID:       orgID,
+		DateCreated: now,
+		DateUpdated: now,
+	}
+
+	if diff := cmp.Diff(got, exp); diff != "" {
+		t.Fatalf("\t%s\tShould get the expected result. Diff:\n%s", tests.Failed, diff)
+	}
+	t.Logf("\t%s\tShould get the expected result.", tests.Success)
+}
+
+func testProductOwner(t *testing.T) {
+	p, _ := product.NewWithID(name, cost, quantity, userID, orgID, now)
+
+	if !p.IsOwnedBy(userID) {
+		t.Fatalf("\t%s\tShould be owned by the listing user.", tests.Failed)
+	}
+	t.Logf("\t%s\tShould be owned by the listing user.", tests.Success)
+
+	if p.IsOwnedBy(id) {
+		t.Fatalf("\t%s\tShould not be owned by another user.", tests.Failed)
+	}
+	t.Logf("\t%s\tShould not be owned by another user.", tests.Success)
+}
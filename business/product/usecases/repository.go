@@ -0,0 +1,25 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+
+	"github.com/appinesshq/caservice/business/product"
+)
+
+var (
+	ErrNotFound = errors.New("product not found")
+	ErrUniqueID = errors.New("id already exists")
+	ErrConflict = errors.New("product was changed by someone else")
+)
+
+// ProductRepository is an interface which is to be implemented by the layer
+// between product usecases and storages.
+type ProductRepository interface {
+	Create(context.Context, product.Product) error
+	Query(context.Context, int, int) ([]product.Product, error)
+	QueryByID(context.Context, string) (product.Product, error)
+	QueryByUserID(context.Context, string) ([]product.Product, error)
+	Update(context.Context, product.Product, int) error
+	Delete(context.Context, string) error
+}
@@ -0,0 +1,183 @@
+// Package usecases provides product usecases with application logic.
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/appinesshq/caservice/business/audit"
+	"github.com/appinesshq/caservice/business/authz"
+	"github.com/appinesshq/caservice/business/product"
+	"github.com/appinesshq/caservice/business/user"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrUnauthorized = authz.ErrUnauthorized
+)
+
+// ProductUseCases contain application logic for product entities.
+type ProductUseCases struct {
+	Log   *zap.SugaredLogger
+	Repo  ProductRepository
+	Audit audit.Recorder
+}
+
+// New returns an initialized ProductUseCases.
+// Requires a logger and product repository as input. Optional features are
+// enabled with the provided options.
+func New(log *zap.SugaredLogger, r ProductRepository, options ...func(uc *ProductUseCases)) ProductUseCases {
+	uc := ProductUseCases{Log: log, Repo: r}
+	for _, option := range options {
+		option(&uc)
+	}
+	return uc
+}
+
+// WithAudit records all changes made by the usecases in the audit trail of
+// the provided recorder.
+func WithAudit(r audit.Recorder) func(uc *ProductUseCases) {
+	return func(uc *ProductUseCases) {
+		uc.Audit = r
+	}
+}
+
+// Create inserts a new product at the repository. The product
+// is owned by the user of the session, within its organization.
+func (uc ProductUseCases) Create(ctx context.Context, n NewProduct, now time.Time) (product.Product, error) {
+	s, err := user.GetSession(ctx)
+	if err != nil {
+		return product.Product{}, err
+	}
+
+	p, err := product.NewWithID(n.Name, n.Cost, n.Quantity, s.User.ID, s.TenantID, now)
+	if err != nil {
+		return product.Product{}, err
+	}
+
+	if err := uc.Repo.Create(ctx, p); err != nil {
+		return product.Product{}, err
+	}
+
+	if err := uc.record(ctx, "product.Create", p.ID, nil, p); err != nil {
+		return product.Product{}, err
+	}
+
+	return p, nil
+}
+
+// Query retrieves all products from the repository.
+func (uc ProductUseCases) Query(ctx context.Context, pageNumber int, rowsPerPage int) ([]product.Product, error) {
+	if _, err := user.GetSession(ctx); err != nil {
+		return []product.Product{}, err
+	}
+
+	return uc.Repo.Query(ctx, pageNumber, rowsPerPage)
+}
+
+// QueryByID retrieves a single product from the repository by its id.
+func (uc ProductUseCases) QueryByID(ctx context.Context, id string) (product.Product, error) {
+	if _, err := user.GetSession(ctx); err != nil {
+		return product.Product{}, err
+	}
+
+	return uc.Repo.QueryByID(ctx, id)
+}
+
+// QueryByUserID retrieves the products listed by a user from the repository.
+func (uc ProductUseCases) QueryByUserID(ctx context.Context, userID string) ([]product.Product, error) {
+	if _, err := user.GetSession(ctx); err != nil {
+		return []product.Product{}, err
+	}
+
+	return uc.Repo.QueryByUserID(ctx, userID)
+}
+
+// Update applies the provided changes to a product at the repository.
+// ErrConflict is returned if the stock of the product changed while it was
+// being updated, like when it was sold, so the stock is never overwritten
+// with an outdated quantity.
+func (uc ProductUseCases) Update(ctx context.Context, id string, up UpdateProduct, now time.Time) (product.Product, error) {
+	s, err := user.GetSession(ctx)
+	if err != nil {
+		return product.Product{}, err
+	}
+
+	p, err := uc.Repo.QueryByID(ctx, id)
+	if err != nil {
+		return product.Product{}, err
+	}
+
+	if err := authz.Authorize(authz.ActionProductUpdate, authz.SessionActor(s), authz.OwnedBy(p.UserID)); err != nil {
+		return product.Product{}, err
+	}
+
+	before := p
+
+	if up.Name != nil {
+		p.Name = *up.Name
+	}
+	if up.Cost != nil {
+		p.Cost = *up.Cost
+	}
+	if up.Quantity != nil {
+		p.Quantity = *up.Quantity
+	}
+	p.DateUpdated = now
+
+	if err := p.Validate(); err != nil {
+		return product.Product{}, fmt.Errorf("validation error: %w", err)
+	}
+
+	if err := uc.Repo.Update(ctx, p, before.Quantity); err != nil {
+		return product.Product{}, err
+	}
+
+	if err := uc.record(ctx, "product.Update", p.ID, before, p); err != nil {
+		return product.Product{}, err
+	}
+
+	return p, nil
+}
+
+// Delete removes a product from the repository by its id.
+// Deleting a product that doesn't exist is not an error.
+func (uc ProductUseCases) Delete(ctx context.Context, id string) error {
+	s, err := user.GetSession(ctx)
+	if err != nil {
+		return err
+	}
+
+	p, err := uc.Repo.QueryByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if err := authz.Authorize(authz.ActionProductDelete, authz.SessionActor(s), authz.OwnedBy(p.UserID)); err != nil {
+		return err
+	}
+
+	if err := uc.Repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	return uc.record(ctx, "product.Delete", id, p, nil)
+}
+
+// record appends a change made by a usecase to the audit trail, if auditing
+// is enabled.
+func (uc ProductUseCases) record(ctx context.Context, usecase, entityID string, before, after any) error {
+	if uc.Audit == nil {
+		return nil
+	}
+
+	if err := uc.Audit.Record(ctx, usecase, entityID, before, after); err != nil {
+		return fmt.Errorf("recording %s: %w", usecase, err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+// Package sale provides sale (related) entities and business logic.
+package sale
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/appinesshq/caservice/foundation/validation"
+	"github.com/google/uuid"
+)
+
+// Sale is the purchase of a quantity of a product by a user within an
+// organization.
+type Sale struct {
+	ID          string    `validate:"required,uuid"`
+	UserID      string    `validate:"required,uuid"`
+	ProductID   string    `validate:"required,uuid"`
+	OrgID       string    `validate:"required,uuid"`
+	Quantity    int       `validate:"gte=1"`
+	Paid        int       `validate:"gte=0"`
+	DateCreated time.Time `validate:"required"`
+}
+
+func New(id, userID, productID, orgID string, quantity, paid int, now time.Time) (Sale, error) {
+	s := Sale{
+		ID:          id,
+		UserID:      userID,
+		ProductID:   productID,
+		OrgID:       orgID,
+		Quantity:    quantity,
+		Paid:        paid,
+		DateCreated: now,
+	}
+
+	if err := s.Validate(); err != nil {
+		return Sale{}, fmt.Errorf("validation error: %w", err)
+	}
+	return s, nil
+}
+
+func NewWithID(userID, productID, orgID string, quantity, paid int, now time.Time) (Sale, error) {
+	return New(uuid.New().String(), userID, productID, orgID, quantity, paid, now)
+}
+
+func (s Sale) Validate() error {
+	if err := validation.DefaultValidationProvider.Check(s); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package sale_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/appinesshq/caservice/business/sale"
+	"github.com/appinesshq/caservice/foundation/tests"
+	"github.com/appinesshq/caservice/foundation/validation"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+)
+
+var (
+	id        = uuid.New().String()
+	userID    = uuid.New().String()
+	productID = uuid.New().String()
+	orgID     = uuid.New().String()
+	now       = time.Now()
+)
+
+func TestSaleEntity(t *testing.T) {
+	t.Parallel()
+
+	t.Log("Given the need to work with Sale entities.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen handling a single Sale.", testID)
+		{
+			t.Run("testInvalidSale", testInvalidSale)
+			t.Run("testValidSale", testValidSale)
+		}
+	}
+}
+
+func testInvalidSale(t *testing.T) {
+	_, err := sale.New(id, "", productID, "", 0, -1, now)
+
+	var got validation.ValidationError
+	if !errors.As(err, &got) {
+		t.Fatalf("\t%s\tShould get a validation.ValidationError, but got %T.", tests.Failed, err)
+	}
+	t.Logf("\t%s\tShould get a validation.ValidationError.", tests.Success)
+
+	exp := validation.ValidationError{
+		Err: "data validation error",
+		Fields: map[string]string{
+			"UserID":   "UserID is a required field",
+			"OrgID":    "OrgID is a required field",
+			"Quantity": "Quantity must be 1 or greater",
+			"Paid":     "Paid must be 0 or greater",
+		}}
+
+	if diff := cmp.Diff(got, exp); diff != "" {
+		t.Fatalf("\t%s\tShould get the expected result. Diff:\n%s", tests.Failed, diff)
+	}
+	t.Logf("\t%s\tShould get the expected result.", tests.Success)
+}
+
+func testValidSale(t *testing.T) {
+	got, err := sale.New(id, userID, productID, orgID, 2, 100, now)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to create a sale with valid data: %v.", tests.Failed, err)
+	}
+	t.Logf("\t%s\tShould be able to create a sale with valid data.", tests.Success)
+
+	exp := sale.Sale{
+		ID:          id,
+		UserID:      userID,
+		ProductID:   productID,
+		OrgID:       orgID,
+		Quantity:    2,
+		Paid:        100,
+		DateCreated: now,
+	}
+
+	if diff := cmp.Diff(got, exp); diff != "" {
+		t.Fatalf("\t%s\tShould get the expected result. Diff:\n%s", tests.Failed, diff)
+	}
+	t.Logf("\t%s\tShould get the expected result.", tests.Success)
+}
@@ -0,0 +1,29 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+
+	"github.com/appinesshq/caservice/business/sale"
+)
+
+var (
+	ErrNotFound          = errors.New("sale not found")
+	ErrUniqueID          = errors.New("id already exists")
+	ErrProductNotFound   = errors.New("product not found")
+	ErrInsufficientStock = errors.New("insufficient stock")
+)
+
+// SaleRepository is an interface which is to be implemented by the layer
+// between sale usecases and storages.
+//
+// Create must record the sale and decrement the stock of the sold product
+// atomically. It returns ErrInsufficientStock, and records nothing, when the
+// product has less stock than the sale's quantity. The sale is returned as
+// recorded, paid at the cost of the product when its stock was decremented.
+type SaleRepository interface {
+	Create(context.Context, sale.Sale) (sale.Sale, error)
+	QueryByID(context.Context, string) (sale.Sale, error)
+	QueryByProductID(context.Context, string) ([]sale.Sale, error)
+	QueryByUserID(context.Context, string) ([]sale.Sale, error)
+}
@@ -0,0 +1,6 @@
+package usecases
+
+// NewSale contains information needed to purchase a product.
+type NewSale struct {
+	Quantity int `json:"quantity" validate:"gte=1"`
+}
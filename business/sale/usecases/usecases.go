@@ -0,0 +1,146 @@
+// Package usecases provides sale usecases with application logic.
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/appinesshq/caservice/business/audit"
+	"github.com/appinesshq/caservice/business/authz"
+	products "github.com/appinesshq/caservice/business/product/usecases"
+	"github.com/appinesshq/caservice/business/sale"
+	"github.com/appinesshq/caservice/business/user"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrUnauthorized = authz.ErrUnauthorized
+)
+
+// SaleUseCases contain application logic for sale entities.
+type SaleUseCases struct {
+	Log      *zap.SugaredLogger
+	Repo     SaleRepository
+	Products products.ProductRepository
+	Audit    audit.Recorder
+}
+
+// New returns an initialized SaleUseCases.
+// Requires a logger, sale repository and product repository as input.
+// Optional features are enabled with the provided options.
+func New(log *zap.SugaredLogger, r SaleRepository, p products.ProductRepository, options ...func(uc *SaleUseCases)) SaleUseCases {
+	uc := SaleUseCases{Log: log, Repo: r, Products: p}
+	for _, option := range options {
+		option(&uc)
+	}
+	return uc
+}
+
+// WithAudit records all changes made by the usecases in the audit trail of
+// the provided recorder.
+func WithAudit(r audit.Recorder) func(uc *SaleUseCases) {
+	return func(uc *SaleUseCases) {
+		uc.Audit = r
+	}
+}
+
+// Create records the purchase of a product by the user of the session.
+// The stock of the product is decremented in the same operation, so a
+// product can't be sold more often than it's in stock.
+func (uc SaleUseCases) Create(ctx context.Context, productID string, n NewSale, now time.Time) (sale.Sale, error) {
+	s, err := user.GetSession(ctx)
+	if err != nil {
+		return sale.Sale{}, err
+	}
+
+	// The sale is paid at the cost of the product when its stock is taken,
+	// which the repository sets, so a price change can't slip in between.
+	sl, err := sale.NewWithID(s.User.ID, productID, s.TenantID, n.Quantity, 0, now)
+	if err != nil {
+		return sale.Sale{}, err
+	}
+
+	sl, err = uc.Repo.Create(ctx, sl)
+	if err != nil {
+		return sale.Sale{}, err
+	}
+
+	if uc.Audit != nil {
+		if err := uc.Audit.Record(ctx, "sale.Create", sl.ID, nil, sl); err != nil {
+			return sale.Sale{}, fmt.Errorf("recording sale.Create: %w", err)
+		}
+	}
+
+	return sl, nil
+}
+
+// QueryByID retrieves a single sale from the repository by its id.
+// Only ADMIN, the buyer or the seller of the product can do this action.
+func (uc SaleUseCases) QueryByID(ctx context.Context, id string) (sale.Sale, error) {
+	s, err := user.GetSession(ctx)
+	if err != nil {
+		return sale.Sale{}, err
+	}
+
+	sl, err := uc.Repo.QueryByID(ctx, id)
+	if err != nil {
+		return sale.Sale{}, err
+	}
+
+	// The sale is owned by the buyer and, as long as the product exists,
+	// by the seller.
+	owners := []string{sl.UserID}
+	p, err := uc.Products.QueryByID(ctx, sl.ProductID)
+	switch {
+	case err == nil:
+		owners = append(owners, p.UserID)
+	case !errors.Is(err, products.ErrNotFound):
+		return sale.Sale{}, err
+	}
+
+	if err := authz.Authorize(authz.ActionSaleRead, authz.SessionActor(s), authz.OwnedBy(owners...)); err != nil {
+		return sale.Sale{}, err
+	}
+
+	return sl, nil
+}
+
+// QueryByProductID retrieves the sales of a product from the repository.
+// Only ADMIN or the owner of the product can do this action.
+func (uc SaleUseCases) QueryByProductID(ctx context.Context, productID string) ([]sale.Sale, error) {
+	s, err := user.GetSession(ctx)
+	if err != nil {
+		return []sale.Sale{}, err
+	}
+
+	p, err := uc.Products.QueryByID(ctx, productID)
+	if err != nil {
+		if errors.Is(err, products.ErrNotFound) {
+			return []sale.Sale{}, ErrProductNotFound
+		}
+		return []sale.Sale{}, err
+	}
+
+	if err := authz.Authorize(authz.ActionSaleQuery, authz.SessionActor(s), authz.OwnedBy(p.UserID)); err != nil {
+		return []sale.Sale{}, err
+	}
+
+	return uc.Repo.QueryByProductID(ctx, productID)
+}
+
+// QueryByUserID retrieves the purchases of a user from the repository.
+// Only ADMIN or the buyer can do this action.
+func (uc SaleUseCases) QueryByUserID(ctx context.Context, userID string) ([]sale.Sale, error) {
+	s, err := user.GetSession(ctx)
+	if err != nil {
+		return []sale.Sale{}, err
+	}
+
+	if err := authz.Authorize(authz.ActionSaleQuery, authz.SessionActor(s), authz.OwnedBy(userID)); err != nil {
+		return []sale.Sale{}, err
+	}
+
+	return uc.Repo.QueryByUserID(ctx, userID)
+}
@@ -0,0 +1,207 @@
+package usecases_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/appinesshq/caservice/business/org"
+	"github.com/appinesshq/caservice/business/product"
+	productusecases "github.com/appinesshq/caservice/business/product/usecases"
+	"github.com/appinesshq/caservice/business/sale/usecases"
+	"github.com/appinesshq/caservice/business/user"
+	productmem "github.com/appinesshq/caservice/data/product/mem"
+	salemem "github.com/appinesshq/caservice/data/sale/mem"
+	"github.com/appinesshq/caservice/foundation/tests"
+	"go.uber.org/zap"
+)
+
+func TestConcurrentSales(t *testing.T) {
+	t.Parallel()
+
+	t.Log("Given the need to never sell more than the stock of a product.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen many buyers purchase the same product at once.", testID)
+		{
+			ctx := context.Background()
+			now := time.Now()
+
+			seller, _ := user.NewWithID("Seller", "seller@example.com", "gophers", []string{user.RoleUser}, now)
+			buyer, _ := user.NewWithID("Buyer", "buyer@example.com", "gophers", []string{user.RoleUser}, now)
+
+			const stock = 10
+			p, err := product.NewWithID("Comic Books", 50, stock, seller.ID, org.DefaultID, now)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to create a product: %v.", tests.Failed, err)
+			}
+
+			ss := user.NewSession(seller, now.Add(time.Hour))
+			ss.TenantID = org.DefaultID
+			sctx := user.ContextWithSession(ctx, ss)
+
+			products := productmem.New()
+			if err := products.Create(sctx, p); err != nil {
+				t.Fatalf("\t%s\tShould be able to store a product: %v.", tests.Failed, err)
+			}
+			uc := usecases.New(zap.NewNop().Sugar(), salemem.New(products), products)
+
+			bs := user.NewSession(buyer, now.Add(time.Hour))
+			bs.TenantID = org.DefaultID
+			ctx = user.ContextWithSession(ctx, bs)
+
+			const buyers = 25
+			var wg sync.WaitGroup
+			errs := make(chan error, buyers)
+			wg.Add(buyers)
+			for i := 0; i < buyers; i++ {
+				go func() {
+					defer wg.Done()
+					_, err := uc.Create(ctx, p.ID, usecases.NewSale{Quantity: 1}, now)
+					errs <- err
+				}()
+			}
+			wg.Wait()
+			close(errs)
+
+			var sold, rejected int
+			for err := range errs {
+				switch {
+				case err == nil:
+					sold++
+				case errors.Is(err, usecases.ErrInsufficientStock):
+					rejected++
+				default:
+					t.Fatalf("\t%s\tShould only get insufficient stock errors, but got: %v.", tests.Failed, err)
+				}
+			}
+
+			if sold != stock || rejected != buyers-stock {
+				t.Fatalf("\t%s\tShould sell exactly the stock, but sold %d and rejected %d.", tests.Failed, sold, rejected)
+			}
+			t.Logf("\t%s\tShould sell exactly the stock.", tests.Success)
+
+			sales, err := uc.QueryByUserID(ctx, buyer.ID)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to query the purchases: %v.", tests.Failed, err)
+			}
+
+			got, err := products.QueryByID(ctx, p.ID)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to query the product: %v.", tests.Failed, err)
+			}
+
+			if len(sales) != sold || got.Quantity != 0 {
+				t.Fatalf("\t%s\tShould keep stock and sales in agreement, but got %d sales and %d in stock.", tests.Failed, len(sales), got.Quantity)
+			}
+			t.Logf("\t%s\tShould keep stock and sales in agreement.", tests.Success)
+
+			for _, sl := range sales {
+				if sl.Paid != p.Cost {
+					t.Fatalf("\t%s\tShould pay the cost of the product, but paid %d.", tests.Failed, sl.Paid)
+				}
+			}
+			t.Logf("\t%s\tShould pay the cost of the product.", tests.Success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen the seller updates a product that was sold after it was read.", testID)
+		{
+			ctx := context.Background()
+			now := time.Now()
+
+			seller, _ := user.NewWithID("Seller", "seller@example.com", "gophers", []string{user.RoleUser}, now)
+			buyer, _ := user.NewWithID("Buyer", "buyer@example.com", "gophers", []string{user.RoleUser}, now)
+
+			p, err := product.NewWithID("Comic Books", 50, 10, seller.ID, org.DefaultID, now)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to create a product: %v.", tests.Failed, err)
+			}
+
+			ss := user.NewSession(seller, now.Add(time.Hour))
+			ss.TenantID = org.DefaultID
+			sctx := user.ContextWithSession(ctx, ss)
+
+			products := productmem.New()
+			if err := products.Create(sctx, p); err != nil {
+				t.Fatalf("\t%s\tShould be able to store a product: %v.", tests.Failed, err)
+			}
+			uc := usecases.New(zap.NewNop().Sugar(), salemem.New(products), products)
+
+			bs := user.NewSession(buyer, now.Add(time.Hour))
+			bs.TenantID = org.DefaultID
+			if _, err := uc.Create(user.ContextWithSession(ctx, bs), p.ID, usecases.NewSale{Quantity: 1}, now); err != nil {
+				t.Fatalf("\t%s\tShould be able to buy the product: %v.", tests.Failed, err)
+			}
+
+			upd := p
+			upd.Quantity = 20
+			if err := products.Update(sctx, upd, p.Quantity); !errors.Is(err, productusecases.ErrConflict) {
+				t.Fatalf("\t%s\tShould not update the product with an outdated stock, but got: %v.", tests.Failed, err)
+			}
+
+			got, err := products.QueryByID(sctx, p.ID)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to query the product: %v.", tests.Failed, err)
+			}
+			if got.Quantity != p.Quantity-1 {
+				t.Fatalf("\t%s\tShould keep the stock after the sale, but got %d.", tests.Failed, got.Quantity)
+			}
+			t.Logf("\t%s\tShould not overwrite the stock after a sale.", tests.Success)
+		}
+	}
+}
+
+func TestTenantIsolation(t *testing.T) {
+	t.Parallel()
+
+	t.Log("Given the need to only sell products within an organization.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen a buyer of another organization purchases a product.", testID)
+		{
+			ctx := context.Background()
+			now := time.Now()
+
+			seller, _ := user.NewWithID("Seller", "seller@example.com", "gophers", []string{user.RoleUser}, now)
+			buyer, _ := user.NewWithID("Buyer", "buyer@example.com", "gophers", []string{user.RoleUser}, now)
+			other, _ := org.NewWithID("Other", now)
+
+			p, err := product.NewWithID("Comic Books", 50, 10, seller.ID, org.DefaultID, now)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create a product: %v.", tests.Failed, testID, err)
+			}
+
+			ss := user.NewSession(seller, now.Add(time.Hour))
+			ss.TenantID = org.DefaultID
+			sctx := user.ContextWithSession(ctx, ss)
+
+			products := productmem.New()
+			if err := products.Create(sctx, p); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to store a product: %v.", tests.Failed, testID, err)
+			}
+			uc := usecases.New(zap.NewNop().Sugar(), salemem.New(products), products)
+
+			bs := user.NewSession(buyer, now.Add(time.Hour))
+			bs.TenantID = other.ID
+			bctx := user.ContextWithSession(ctx, bs)
+
+			if _, err := uc.Create(bctx, p.ID, usecases.NewSale{Quantity: 1}, now); !errors.Is(err, usecases.ErrProductNotFound) {
+				t.Fatalf("\t%s\tTest %d:\tShould not find the product, but got: %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not find the product.", tests.Success, testID)
+
+			if _, err := uc.QueryByProductID(sctx, p.ID); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to query sales of the seller: %v.", tests.Failed, testID, err)
+			}
+
+			got, err := products.QueryByID(sctx, p.ID)
+			if err != nil || got.Quantity != 10 {
+				t.Fatalf("\t%s\tTest %d:\tShould keep the stock, but got %d: %v.", tests.Failed, testID, got.Quantity, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould keep the stock.", tests.Success, testID)
+		}
+	}
+}
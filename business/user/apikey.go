@@ -0,0 +1,65 @@
+package user
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/appinesshq/caservice/foundation/validation"
+	"github.com/google/uuid"
+)
+
+// APIKey is an entity for long-lived opaque keys that machine clients use
+// to authenticate on behalf of a user. The scopes of a key are the roles a
+// client gets with it, which are a subset of the roles of the user in the
+// organization of the key. Only a hash of the key is kept.
+type APIKey struct {
+	ID           string   `validate:"required,uuid"`
+	UserID       string   `validate:"required,uuid"`
+	OrgID        string   `validate:"required,uuid"`
+	Name         string   `validate:"required"`
+	KeyHash      string   `json:"-" validate:"required"`
+	Scopes       []string `validate:"required,min=1"`
+	Revoked      bool
+	DateCreated  time.Time `validate:"required"`
+	DateExpires  time.Time `validate:"required,gtfield=DateCreated"`
+	DateLastUsed time.Time
+}
+
+// NewAPIKey returns an API key for the user in the provided organization
+// with the provided name and scopes, together with the opaque key string to
+// hand out to the user.
+func NewAPIKey(userID, orgID, name string, scopes []string, now, expires time.Time) (APIKey, string, error) {
+	key, err := generateToken()
+	if err != nil {
+		return APIKey{}, "", err
+	}
+
+	ak := APIKey{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		OrgID:       orgID,
+		Name:        name,
+		KeyHash:     HashToken(key),
+		Scopes:      scopes,
+		DateCreated: now,
+		DateExpires: expires,
+	}
+
+	if err := ak.Validate(); err != nil {
+		return APIKey{}, "", fmt.Errorf("validation error: %w", err)
+	}
+	return ak, key, nil
+}
+
+func (ak APIKey) Validate() error {
+	if err := validation.DefaultValidationProvider.Check(ak); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// IsExpired returns true if the API key is expired.
+func (ak APIKey) IsExpired(now time.Time) bool {
+	return now.After(ak.DateExpires)
+}
@@ -2,15 +2,22 @@ package user
 
 import (
 	"context"
+	"errors"
 	"fmt"
 )
 
+// ErrNoTenant is returned for sessions that are not scoped to an organization.
+var ErrNoTenant = errors.New("session is not scoped to an organization")
+
 //  ctxKey is the type of the value for a context key.
 type ctxKey int
 
 // key is the key under which values are stored or retrieved.
 const key ctxKey = 534783
 
+// systemKey is the key under which calls made by the system are marked.
+const systemKey ctxKey = 534784
+
 // ContextWithSession returns a new context with the provided session encapsulated.
 func ContextWithSession(ctx context.Context, s Session) context.Context {
 	return context.WithValue(ctx, key, s)
@@ -26,3 +33,33 @@ func GetSession(ctx context.Context) (Session, error) {
 
 	return s, nil
 }
+
+// ContextForSystem returns a new context for calls the system makes itself
+// rather than on behalf of a session, like looking up the user that signs in.
+// Storages don't scope these calls to an organization, so it must only be
+// used where no organization applies yet.
+func ContextForSystem(ctx context.Context) context.Context {
+	return context.WithValue(ctx, systemKey, true)
+}
+
+// IsSystem reports whether the context is marked for calls made by the
+// system itself.
+func IsSystem(ctx context.Context) bool {
+	system, _ := ctx.Value(systemKey).(bool)
+	return system
+}
+
+// GetTenantID returns the id of the organization the Session in the context
+// is scoped to. Storages use it to scope their queries, so data of other
+// organizations can't be reached with the context.
+func GetTenantID(ctx context.Context) (string, error) {
+	s, err := GetSession(ctx)
+	if err != nil {
+		return "", err
+	}
+	if s.TenantID == "" {
+		return "", ErrNoTenant
+	}
+
+	return s.TenantID, nil
+}
@@ -0,0 +1,103 @@
+package user
+
+import (
+	"strings"
+	"time"
+
+	"github.com/appinesshq/caservice/foundation/validation"
+)
+
+// These are the fields users can be ordered by.
+const (
+	OrderByID          = "id"
+	OrderByName        = "name"
+	OrderByEmail       = "email"
+	OrderByDateCreated = "date_created"
+)
+
+// These are the directions users can be ordered in.
+const (
+	DirectionAsc  = "asc"
+	DirectionDesc = "desc"
+)
+
+// QueryFilter selects and orders users. Empty fields match all users. Name
+// and Email match users of which the field contains the value, regardless of
+// case. Role matches users with the role. Since and Until select users
+// created from Since up to Until. Users are ordered by id in ascending
+// direction by default, and by id after any other field.
+type QueryFilter struct {
+	Name      string
+	Email     string
+	Role      string
+	Since     time.Time
+	Until     time.Time
+	OrderBy   string `validate:"omitempty,oneof=id name email date_created"`
+	Direction string `validate:"omitempty,oneof=asc desc"`
+}
+
+func (f QueryFilter) Validate() error {
+	if err := validation.DefaultValidationProvider.Check(f); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Match returns true if the user is selected by the filter.
+func (f QueryFilter) Match(u User) bool {
+	switch {
+	case f.Name != "" && !containsFold(u.Name, f.Name):
+		return false
+	case f.Email != "" && !containsFold(u.Email, f.Email):
+		return false
+	case f.Role != "" && !hasRole(u.Roles, f.Role):
+		return false
+	case !f.Since.IsZero() && u.DateCreated.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !u.DateCreated.Before(f.Until):
+		return false
+	}
+	return true
+}
+
+// Less returns true if user a is ordered before user b by the filter.
+func (f QueryFilter) Less(a, b User) bool {
+	var c int
+	switch f.OrderBy {
+	case OrderByName:
+		c = strings.Compare(a.Name, b.Name)
+	case OrderByEmail:
+		c = strings.Compare(a.Email, b.Email)
+	case OrderByDateCreated:
+		switch {
+		case a.DateCreated.Before(b.DateCreated):
+			c = -1
+		case a.DateCreated.After(b.DateCreated):
+			c = 1
+		}
+	}
+	if c == 0 {
+		c = strings.Compare(a.ID, b.ID)
+	}
+
+	if f.Direction == DirectionDesc {
+		return c > 0
+	}
+	return c < 0
+}
+
+// containsFold reports whether substr is within s, regardless of case.
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// hasRole reports whether the role is one of the roles.
+func hasRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
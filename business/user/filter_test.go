@@ -0,0 +1,101 @@
+package user_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	user "github.com/appinesshq/caservice/business/user"
+	"github.com/appinesshq/caservice/foundation/tests"
+	"github.com/appinesshq/caservice/foundation/validation"
+)
+
+func TestQueryFilter(t *testing.T) {
+	t.Parallel()
+
+	created := time.Date(2022, time.March, 1, 0, 0, 0, 0, time.UTC)
+	u := user.User{
+		ID:          "b",
+		Name:        "Test User",
+		Email:       "test@example.com",
+		Roles:       []string{user.RoleUser},
+		DateCreated: created,
+	}
+
+	table := []struct {
+		name   string
+		filter user.QueryFilter
+		match  bool
+	}{
+		{"empty filter", user.QueryFilter{}, true},
+		{"name regardless of case", user.QueryFilter{Name: "test u"}, true},
+		{"other name", user.QueryFilter{Name: "admin"}, false},
+		{"part of email", user.QueryFilter{Email: "@EXAMPLE"}, true},
+		{"role", user.QueryFilter{Role: user.RoleUser}, true},
+		{"other role", user.QueryFilter{Role: user.RoleAdmin}, false},
+		{"created since", user.QueryFilter{Since: created}, true},
+		{"created before since", user.QueryFilter{Since: created.Add(time.Second)}, false},
+		{"created until", user.QueryFilter{Until: created}, false},
+		{"created before until", user.QueryFilter{Until: created.Add(time.Second)}, true},
+	}
+
+	t.Log("Given the need to select users.")
+	{
+		for testID, tt := range table {
+			tf := func(t *testing.T) {
+				t.Logf("\tTest %d:\tWhen filtering by %s.", testID, tt.name)
+				{
+					if got := tt.filter.Match(u); got != tt.match {
+						t.Fatalf("\t%s\tShould get a match of %t, but got %t.", tests.Failed, tt.match, got)
+					}
+					t.Logf("\t%s\tShould get a match of %t.", tests.Success, tt.match)
+				}
+			}
+			t.Run(tt.name, tf)
+		}
+	}
+
+	t.Log("Given the need to order users.")
+	{
+		a := user.User{ID: "a", Name: "Test User", Email: "z@example.com", DateCreated: created.Add(time.Hour)}
+
+		order := []struct {
+			name   string
+			filter user.QueryFilter
+			less   bool
+		}{
+			{"id by default", user.QueryFilter{}, true},
+			{"id descending", user.QueryFilter{Direction: user.DirectionDesc}, false},
+			{"equal names by id", user.QueryFilter{OrderBy: user.OrderByName}, true},
+			{"email", user.QueryFilter{OrderBy: user.OrderByEmail}, false},
+			{"date created descending", user.QueryFilter{OrderBy: user.OrderByDateCreated, Direction: user.DirectionDesc}, true},
+		}
+
+		for testID, tt := range order {
+			tf := func(t *testing.T) {
+				t.Logf("\tTest %d:\tWhen ordering by %s.", testID, tt.name)
+				{
+					if got := tt.filter.Less(a, u); got != tt.less {
+						t.Fatalf("\t%s\tShould order the users with less %t, but got %t.", tests.Failed, tt.less, got)
+					}
+					t.Logf("\t%s\tShould order the users.", tests.Success)
+				}
+			}
+			t.Run(tt.name, tf)
+		}
+	}
+
+	t.Log("Given the need to validate filters.")
+	{
+		t.Logf("\tTest 0:\tWhen ordering by an unknown field.")
+		{
+			err := user.QueryFilter{OrderBy: "password_hash", Direction: "up"}.Validate()
+
+			var ve validation.ValidationError
+			if !errors.As(err, &ve) || len(ve.Fields) != 2 {
+				t.Fatalf("\t%s\tShould get a validation error for both fields, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould get a validation error for both fields.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package user
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/appinesshq/caservice/foundation/validation"
+	"github.com/google/uuid"
+)
+
+// Identity is an entity that links a user to an account at an external
+// identity provider, like an OpenID Connect provider. The account is
+// identified by the subject, which is unique per provider.
+type Identity struct {
+	ID          string `validate:"required,uuid"`
+	UserID      string `validate:"required,uuid"`
+	Provider    string `validate:"required"`
+	Subject     string `validate:"required"`
+	Email       string
+	DateCreated time.Time `validate:"required"`
+}
+
+// NewIdentity returns an identity linking the user to the subject at
+// the provider.
+func NewIdentity(userID, provider, subject, email string, now time.Time) (Identity, error) {
+	id := Identity{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		Provider:    provider,
+		Subject:     subject,
+		Email:       email,
+		DateCreated: now,
+	}
+
+	if err := id.Validate(); err != nil {
+		return Identity{}, fmt.Errorf("validation error: %w", err)
+	}
+	return id, nil
+}
+
+func (id Identity) Validate() error {
+	if err := validation.DefaultValidationProvider.Check(id); err != nil {
+		return err
+	}
+
+	return nil
+}
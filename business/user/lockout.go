@@ -0,0 +1,33 @@
+package user
+
+import "time"
+
+// LockoutPolicy defines how failed authentication attempts delay further
+// attempts. The first FreeAttempts failures are not delayed. Every failure
+// after that doubles the delay, starting at Delay, until attempts are locked
+// for MaxDelay. Failures are forgotten after ResetAfter without failures.
+type LockoutPolicy struct {
+	FreeAttempts int
+	Delay        time.Duration
+	MaxDelay     time.Duration
+	ResetAfter   time.Duration
+}
+
+// LockedUntil returns the time until which attempts are refused after the
+// provided number of failures, the last of which happened at last. The zero
+// time is returned when attempts are not refused.
+func (p LockoutPolicy) LockedUntil(failures int, last time.Time) time.Time {
+	if failures <= p.FreeAttempts || p.Delay <= 0 {
+		return time.Time{}
+	}
+
+	d := p.Delay
+	for i := p.FreeAttempts + 1; i < failures && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+
+	return last.Add(d)
+}
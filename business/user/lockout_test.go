@@ -0,0 +1,51 @@
+package user_test
+
+import (
+	"testing"
+	"time"
+
+	user "github.com/appinesshq/caservice/business/user"
+	"github.com/appinesshq/caservice/foundation/tests"
+)
+
+func TestLockoutPolicy(t *testing.T) {
+	t.Parallel()
+
+	p := user.LockoutPolicy{FreeAttempts: 3, Delay: time.Second, MaxDelay: 10 * time.Second}
+
+	table := []struct {
+		name     string
+		failures int
+		delay    time.Duration
+	}{
+		{"no failures", 0, 0},
+		{"free attempts", 3, 0},
+		{"first delay", 4, time.Second},
+		{"doubled delay", 5, 2 * time.Second},
+		{"doubled again", 7, 8 * time.Second},
+		{"locked", 8, 10 * time.Second},
+		{"stays locked", 100, 10 * time.Second},
+	}
+
+	t.Log("Given the need to delay attempts after failures.")
+	{
+		for testID, tt := range table {
+			tf := func(t *testing.T) {
+				t.Logf("\tTest %d:\tWhen handling %d failures.", testID, tt.failures)
+				{
+					until := p.LockedUntil(tt.failures, now)
+
+					var delay time.Duration
+					if !until.IsZero() {
+						delay = until.Sub(now)
+					}
+					if delay != tt.delay {
+						t.Fatalf("\t%s\tShould delay %v, but got %v.", tests.Failed, tt.delay, delay)
+					}
+					t.Logf("\t%s\tShould delay %v.", tests.Success, tt.delay)
+				}
+			}
+			t.Run(tt.name, tf)
+		}
+	}
+}
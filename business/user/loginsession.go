@@ -0,0 +1,115 @@
+package user
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/appinesshq/caservice/foundation/validation"
+	"github.com/google/uuid"
+)
+
+// LoginSession is an entity for the stored sessions of users. A login
+// session starts when a user authenticates and lasts as long as its tokens
+// can be refreshed, so users can see where they are logged in and end
+// sessions on other devices. Tokens carry the id of their login session.
+// The refresh tokens of a login session form the family with its id.
+type LoginSession struct {
+	ID           string `validate:"required,uuid"`
+	UserID       string `validate:"required,uuid"`
+	Device       string
+	IP           string
+	UserAgent    string
+	Revoked      bool
+	DateCreated  time.Time `validate:"required"`
+	DateLastSeen time.Time `validate:"required"`
+	DateExpires  time.Time `validate:"required,gtfield=DateCreated"`
+}
+
+// NewLoginSession returns a login session for the user, started from the
+// client with the provided IP address and user agent.
+func NewLoginSession(userID, ip, userAgent string, now, expires time.Time) (LoginSession, error) {
+	ls := LoginSession{
+		ID:           uuid.New().String(),
+		UserID:       userID,
+		Device:       DeviceOf(userAgent),
+		IP:           ip,
+		UserAgent:    userAgent,
+		DateCreated:  now,
+		DateLastSeen: now,
+		DateExpires:  expires,
+	}
+
+	if err := ls.Validate(); err != nil {
+		return LoginSession{}, fmt.Errorf("validation error: %w", err)
+	}
+	return ls, nil
+}
+
+func (ls LoginSession) Validate() error {
+	if err := validation.DefaultValidationProvider.Check(ls); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// IsExpired returns true if the login session is expired.
+func (ls LoginSession) IsExpired(now time.Time) bool {
+	return now.After(ls.DateExpires)
+}
+
+// IsActive returns true if the login session is neither revoked nor expired.
+func (ls LoginSession) IsActive(now time.Time) bool {
+	return !ls.Revoked && !ls.IsExpired(now)
+}
+
+// These are the browsers and operating systems DeviceOf recognizes, in the
+// order they are matched. User agents name several browsers for
+// compatibility, so the most specific ones come first.
+var (
+	browsers = []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+	}
+	systems = []struct{ token, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	}
+)
+
+// DeviceOf returns a short description of the device of a user agent, like
+// "Firefox on Linux", for users to recognize their sessions by.
+func DeviceOf(userAgent string) string {
+	var browser, system string
+	for _, b := range browsers {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	for _, s := range systems {
+		if strings.Contains(userAgent, s.token) {
+			system = s.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	}
+	return "Unknown device"
+}
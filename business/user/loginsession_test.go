@@ -0,0 +1,80 @@
+package user_test
+
+import (
+	"testing"
+	"time"
+
+	user "github.com/appinesshq/caservice/business/user"
+	"github.com/appinesshq/caservice/foundation/tests"
+)
+
+func TestDeviceOf(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		name      string
+		userAgent string
+		device    string
+	}{
+		{"firefox", "Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/118.0", "Firefox on Linux"},
+		{"chrome", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36", "Chrome on Windows"},
+		{"edge", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36 Edg/118.0.2088.46", "Edge on Windows"},
+		{"safari", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1", "Safari on iOS"},
+		{"curl", "curl/8.4.0", "curl"},
+		{"unknown", "", "Unknown device"},
+	}
+
+	t.Log("Given the need to describe the devices of login sessions.")
+	{
+		for testID, tt := range table {
+			tf := func(t *testing.T) {
+				t.Logf("\tTest %d:\tWhen handling a %s user agent.", testID, tt.name)
+				{
+					if got := user.DeviceOf(tt.userAgent); got != tt.device {
+						t.Fatalf("\t%s\tShould describe the device as %q, but got %q.", tests.Failed, tt.device, got)
+					}
+					t.Logf("\t%s\tShould describe the device as %q.", tests.Success, tt.device)
+				}
+			}
+			t.Run(tt.name, tf)
+		}
+	}
+}
+
+func TestLoginSession(t *testing.T) {
+	t.Parallel()
+
+	t.Log("Given the need to track login sessions.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen starting a login session.", testID)
+		{
+			ls, err := user.NewLoginSession(id, "192.0.2.1", "curl/8.4.0", now, now.Add(time.Hour))
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to start a login session: %v", tests.Failed, err)
+			}
+			if ls.Device != "curl" || !ls.DateLastSeen.Equal(now) {
+				t.Fatalf("\t%s\tShould describe the device and be seen now: %+v", tests.Failed, ls)
+			}
+			t.Logf("\t%s\tShould be able to start a login session.", tests.Success)
+
+			if !ls.IsActive(now.Add(time.Minute)) || ls.IsActive(now.Add(2*time.Hour)) {
+				t.Fatalf("\t%s\tShould only be active until it expires.", tests.Failed)
+			}
+			ls.Revoked = true
+			if ls.IsActive(now) {
+				t.Fatalf("\t%s\tShould not be active once revoked.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould only be active until it expires or is revoked.", tests.Success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen starting a login session that expires at once.", testID)
+		{
+			if _, err := user.NewLoginSession(id, "", "", now, now); err == nil {
+				t.Fatalf("\t%s\tShould not be able to start a login session without lifetime.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould not be able to start a login session without lifetime.", tests.Success)
+		}
+	}
+}
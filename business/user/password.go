@@ -0,0 +1,86 @@
+package user
+
+import (
+	"fmt"
+
+	"github.com/appinesshq/caservice/foundation/passhash"
+)
+
+// PasswordHasher hashes the passwords of users. Hashes of any supported
+// algorithm can be verified, whichever hasher made them. NeedsRehash reports
+// whether a hash was made with another algorithm or other parameters than the
+// hasher uses, so it should be replaced by a new hash of the password.
+type PasswordHasher interface {
+	Hash(password string) ([]byte, error)
+	NeedsRehash(hash []byte) bool
+}
+
+// Argon2idHasher hashes passwords with argon2id.
+type Argon2idHasher struct {
+	Params passhash.Argon2idParams
+}
+
+func (h Argon2idHasher) Hash(password string) ([]byte, error) {
+	return passhash.Argon2id(password, h.Params)
+}
+
+func (h Argon2idHasher) NeedsRehash(hash []byte) bool {
+	p, err := passhash.Argon2idParamsOf(hash)
+	if err != nil {
+		return true
+	}
+	return p != h.Params
+}
+
+// BcryptHasher hashes passwords with bcrypt.
+type BcryptHasher struct {
+	Cost int
+}
+
+func (h BcryptHasher) Hash(password string) ([]byte, error) {
+	return passhash.Bcrypt(password, h.Cost)
+}
+
+func (h BcryptHasher) NeedsRehash(hash []byte) bool {
+	if passhash.Algorithm(hash) != passhash.AlgorithmBcrypt {
+		return true
+	}
+	cost, err := passhash.BcryptCostOf(hash)
+	if err != nil {
+		return true
+	}
+	return cost != h.Cost
+}
+
+// DefaultArgon2idParams are the argon2id parameters recommended by OWASP.
+var DefaultArgon2idParams = passhash.Argon2idParams{
+	Memory:      19 * 1024,
+	Iterations:  2,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// DefaultPasswordHasher is the hasher the passwords of users are hashed
+// with. It can be replaced at startup to change the algorithm or its
+// parameters. Existing hashes are replaced the next time their users
+// authenticate.
+var DefaultPasswordHasher PasswordHasher = Argon2idHasher{Params: DefaultArgon2idParams}
+
+// NewPasswordHasher returns the hasher of the algorithm with the parameters.
+// The cost is only used by bcrypt, the other parameters only by argon2id.
+func NewPasswordHasher(algorithm string, p passhash.Argon2idParams, cost int) (PasswordHasher, error) {
+	switch algorithm {
+	case passhash.AlgorithmArgon2id:
+		if p.Memory == 0 || p.Iterations == 0 || p.Parallelism == 0 || p.SaltLength == 0 || p.KeyLength == 0 {
+			return nil, fmt.Errorf("argon2id parameters must not be zero: %+v", p)
+		}
+		return Argon2idHasher{Params: p}, nil
+	case passhash.AlgorithmBcrypt:
+		if cost < passhash.BcryptMinCost || cost > passhash.BcryptMaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d: %d", passhash.BcryptMinCost, passhash.BcryptMaxCost, cost)
+		}
+		return BcryptHasher{Cost: cost}, nil
+	}
+	return nil, fmt.Errorf("unknown password hash algorithm %q", algorithm)
+}
@@ -0,0 +1,47 @@
+package user_test
+
+import (
+	"testing"
+
+	user "github.com/appinesshq/caservice/business/user"
+	"github.com/appinesshq/caservice/foundation/passhash"
+	"github.com/appinesshq/caservice/foundation/tests"
+)
+
+func TestPasswordHasher(t *testing.T) {
+	t.Parallel()
+
+	weak := passhash.Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	argon, _ := user.Argon2idHasher{Params: weak}.Hash("gophers")
+	bcrypt, _ := user.BcryptHasher{Cost: passhash.BcryptMinCost}.Hash("gophers")
+
+	table := []struct {
+		name   string
+		hasher user.PasswordHasher
+		hash   []byte
+		rehash bool
+	}{
+		{"argon2id with the same parameters", user.Argon2idHasher{Params: weak}, argon, false},
+		{"argon2id with other parameters", user.DefaultPasswordHasher, argon, true},
+		{"bcrypt with argon2id", user.DefaultPasswordHasher, bcrypt, true},
+		{"bcrypt with the same cost", user.BcryptHasher{Cost: passhash.BcryptMinCost}, bcrypt, false},
+		{"bcrypt with another cost", user.BcryptHasher{Cost: passhash.BcryptMinCost + 1}, bcrypt, true},
+		{"argon2id with bcrypt", user.BcryptHasher{Cost: passhash.BcryptMinCost}, argon, true},
+	}
+
+	t.Log("Given the need to replace outdated password hashes.")
+	{
+		for testID, tt := range table {
+			tf := func(t *testing.T) {
+				t.Logf("\tTest %d:\tWhen checking %s.", testID, tt.name)
+				{
+					if got := tt.hasher.NeedsRehash(tt.hash); got != tt.rehash {
+						t.Fatalf("\t%s\tShould need a rehash %t, but got %t.", tests.Failed, tt.rehash, got)
+					}
+					t.Logf("\t%s\tShould need a rehash %t.", tests.Success, tt.rehash)
+				}
+			}
+			t.Run(tt.name, tf)
+		}
+	}
+}
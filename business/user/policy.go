@@ -0,0 +1,108 @@
+package user
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/appinesshq/caservice/foundation/validation"
+)
+
+// BreachedPasswords is a list of passwords that were exposed in data
+// breaches, like the lists of the breached package.
+type BreachedPasswords interface {
+	Contains(password string) bool
+}
+
+// minPersonalLength is the shortest part of a name or email address a
+// password is not allowed to contain. Shorter parts, like initials, are too
+// common to reject.
+const minPersonalLength = 3
+
+// PasswordPolicy defines the requirements of the passwords of users.
+// Passwords must be at least MinLength characters long and contain a
+// character of every required class. With RejectPersonal, passwords may not
+// contain the name or email address of the user. Passwords in the Breached
+// list are rejected. The zero policy accepts every password.
+type PasswordPolicy struct {
+	MinLength      int
+	RequireLower   bool
+	RequireUpper   bool
+	RequireDigit   bool
+	RequireSymbol  bool
+	RejectPersonal bool
+	Breached       BreachedPasswords
+}
+
+// Check returns a validation error with the requirements the password of
+// the user with the name and email address does not meet.
+func (p PasswordPolicy) Check(password, name, email string) error {
+	var msgs []string
+
+	if utf8.RuneCountInString(password) < p.MinLength {
+		msgs = append(msgs, fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.RequireLower && !lower {
+		msgs = append(msgs, "must contain a lowercase letter")
+	}
+	if p.RequireUpper && !upper {
+		msgs = append(msgs, "must contain an uppercase letter")
+	}
+	if p.RequireDigit && !digit {
+		msgs = append(msgs, "must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		msgs = append(msgs, "must contain a symbol")
+	}
+
+	if p.RejectPersonal && containsPersonal(password, name, email) {
+		msgs = append(msgs, "must not contain your name or email address")
+	}
+
+	if p.Breached != nil && p.Breached.Contains(password) {
+		msgs = append(msgs, "was found in a data breach")
+	}
+
+	if len(msgs) > 0 {
+		return validation.ValidationError{
+			Err:    "data validation error",
+			Fields: map[string]string{"Password": "Password " + strings.Join(msgs, ", ")},
+		}
+	}
+
+	return nil
+}
+
+// containsPersonal reports whether the password contains the email address,
+// its local part or any part of the name, regardless of case.
+func containsPersonal(password, name, email string) bool {
+	parts := strings.Fields(name)
+	if email != "" {
+		parts = append(parts, email)
+		if i := strings.LastIndexByte(email, '@'); i > 0 {
+			parts = append(parts, email[:i])
+		}
+	}
+
+	for _, part := range parts {
+		if utf8.RuneCountInString(part) >= minPersonalLength && containsFold(password, part) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package user_test
+
+import (
+	"errors"
+	"testing"
+
+	user "github.com/appinesshq/caservice/business/user"
+	"github.com/appinesshq/caservice/foundation/tests"
+	"github.com/appinesshq/caservice/foundation/validation"
+)
+
+// breachedList is a list of breached passwords for testing.
+type breachedList map[string]bool
+
+func (l breachedList) Contains(password string) bool {
+	return l[password]
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	t.Parallel()
+
+	p := user.PasswordPolicy{
+		MinLength:      8,
+		RequireLower:   true,
+		RequireUpper:   true,
+		RequireDigit:   true,
+		RequireSymbol:  true,
+		RejectPersonal: true,
+		Breached:       breachedList{"Passw0rd!": true},
+	}
+
+	table := []struct {
+		name     string
+		password string
+		exp      string
+	}{
+		{"strong", "G0phers-rule", ""},
+		{"short", "G0-r", "Password must be at least 8 characters long"},
+		{"no classes", "        ", "Password must contain a lowercase letter, must contain an uppercase letter, must contain a digit, must contain a symbol"},
+		{"name", "Kennedy-G0phers", "Password must not contain your name or email address"},
+		{"email local part", "b1ll@Example!", "Password must not contain your name or email address"},
+		{"breached", "Passw0rd!", "Password was found in a data breach"},
+	}
+
+	t.Log("Given the need to require strong passwords.")
+	{
+		for testID, tt := range table {
+			tf := func(t *testing.T) {
+				t.Logf("\tTest %d:\tWhen checking a %s password.", testID, tt.name)
+				{
+					err := p.Check(tt.password, "Bill Kennedy", "b1ll@example.com")
+					if tt.exp == "" {
+						if err != nil {
+							t.Fatalf("\t%s\tShould accept the password: %v", tests.Failed, err)
+						}
+						t.Logf("\t%s\tShould accept the password.", tests.Success)
+						return
+					}
+
+					var ve validation.ValidationError
+					if !errors.As(err, &ve) {
+						t.Fatalf("\t%s\tShould get a validation.ValidationError, but got %v.", tests.Failed, err)
+					}
+					if got := ve.Fields["Password"]; got != tt.exp {
+						t.Fatalf("\t%s\tShould reject the password with %q, but got %q.", tests.Failed, tt.exp, got)
+					}
+					t.Logf("\t%s\tShould reject the password.", tests.Success)
+				}
+			}
+			t.Run(tt.name, tf)
+		}
+
+		t.Logf("\tTest %d:\tWhen checking with the zero policy.", len(table))
+		{
+			if err := (user.PasswordPolicy{}).Check("a", "Bill Kennedy", "b1ll@example.com"); err != nil {
+				t.Fatalf("\t%s\tShould accept every password: %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould accept every password.", tests.Success)
+		}
+	}
+}
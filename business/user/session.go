@@ -8,6 +8,22 @@ import (
 type Session struct {
 	User    User
 	Expires time.Time
+
+	// TenantID is the id of the organization the session is scoped to.
+	// The roles of the user are its roles within that organization.
+	TenantID string
+
+	// APIKeyID is the id of the API key the session was authenticated
+	// with, if any.
+	APIKeyID string
+
+	// LoginSessionID is the id of the stored login session the session
+	// belongs to, if login sessions are tracked.
+	LoginSessionID string
+
+	// ImpersonatorID is the id of the admin that acts as the user of the
+	// session, if the session was issued for impersonation.
+	ImpersonatorID string
 }
 
 // NewSession returns an initialized user session.
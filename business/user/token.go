@@ -0,0 +1,144 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/appinesshq/caservice/foundation/validation"
+	"github.com/google/uuid"
+)
+
+// RefreshToken is an entity for opaque refresh tokens, which can be
+// exchanged for a new access token and refresh token. Every refresh token
+// belongs to a family: the chain of tokens that originate from the same
+// authentication. Only a hash of the token is kept. The token refreshes
+// sessions in the organization it was issued for.
+type RefreshToken struct {
+	ID          string `validate:"required,uuid"`
+	FamilyID    string `validate:"required,uuid"`
+	UserID      string `validate:"required,uuid"`
+	OrgID       string `validate:"required,uuid"`
+	TokenHash   string `validate:"required"`
+	Used        bool
+	Revoked     bool
+	DateCreated time.Time `validate:"required"`
+	DateExpires time.Time `validate:"required"`
+}
+
+// NewRefreshToken returns a refresh token for the user in the provided
+// organization and family, together with the opaque token string to hand out
+// to the client. An empty familyID starts a new family.
+func NewRefreshToken(userID, orgID, familyID string, now, expires time.Time) (RefreshToken, string, error) {
+	token, err := generateToken()
+	if err != nil {
+		return RefreshToken{}, "", err
+	}
+
+	if familyID == "" {
+		familyID = uuid.New().String()
+	}
+
+	rt := RefreshToken{
+		ID:          uuid.New().String(),
+		FamilyID:    familyID,
+		UserID:      userID,
+		OrgID:       orgID,
+		TokenHash:   HashToken(token),
+		DateCreated: now,
+		DateExpires: expires,
+	}
+
+	if err := rt.Validate(); err != nil {
+		return RefreshToken{}, "", fmt.Errorf("validation error: %w", err)
+	}
+	return rt, token, nil
+}
+
+// generateToken returns a new random opaque token.
+func generateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the hash under which an opaque token is stored.
+func HashToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
+
+func (rt RefreshToken) Validate() error {
+	if err := validation.DefaultValidationProvider.Check(rt); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// IsExpired returns true if the refresh token is expired.
+func (rt RefreshToken) IsExpired(now time.Time) bool {
+	return now.After(rt.DateExpires)
+}
+
+// These are the purposes a OneTimeToken can be issued for.
+const (
+	PurposePasswordReset = "password_reset"
+	PurposeTOTPChallenge = "totp_challenge"
+)
+
+// OneTimeToken is an entity for opaque tokens that allow a user to perform
+// a single action, like resetting a password, without being authenticated.
+// Only a hash of the token is kept. Attempts counts the attempts to use the
+// token, for tokens that are checked together with a guessable code.
+type OneTimeToken struct {
+	ID          string `validate:"required,uuid"`
+	UserID      string `validate:"required,uuid"`
+	Purpose     string `validate:"required"`
+	TokenHash   string `validate:"required"`
+	Used        bool
+	Attempts    int
+	DateCreated time.Time `validate:"required"`
+	DateExpires time.Time `validate:"required"`
+}
+
+// NewOneTimeToken returns a one-time token for the user and purpose, together
+// with the opaque token string to hand out to the user.
+func NewOneTimeToken(userID, purpose string, now, expires time.Time) (OneTimeToken, string, error) {
+	token, err := generateToken()
+	if err != nil {
+		return OneTimeToken{}, "", err
+	}
+
+	ot := OneTimeToken{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		Purpose:     purpose,
+		TokenHash:   HashToken(token),
+		DateCreated: now,
+		DateExpires: expires,
+	}
+
+	if err := ot.Validate(); err != nil {
+		return OneTimeToken{}, "", fmt.Errorf("validation error: %w", err)
+	}
+	return ot, token, nil
+}
+
+func (ot OneTimeToken) Validate() error {
+	if err := validation.DefaultValidationProvider.Check(ot); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// IsExpired returns true if the one-time token is expired.
+func (ot OneTimeToken) IsExpired(now time.Time) bool {
+	return now.After(ot.DateExpires)
+}
@@ -0,0 +1,64 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"fmt"
+	"strings"
+)
+
+// RecoveryCodeCount is the number of recovery codes a user gets when
+// enabling two-factor authentication.
+const RecoveryCodeCount = 10
+
+// recoveryEncoding encodes recovery codes in lower case base32, which is
+// easy to write down.
+var recoveryEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// NewRecoveryCodes replaces the recovery codes of the user with new codes and
+// returns them. Only hashes of the codes are kept.
+func (u *User) NewRecoveryCodes() ([]string, error) {
+	codes := make([]string, RecoveryCodeCount)
+	hashes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, fmt.Errorf("generating recovery code: %w", err)
+		}
+
+		s := recoveryEncoding.EncodeToString(b)
+		codes[i] = s[:4] + "-" + s[4:8] + "-" + s[8:12] + "-" + s[12:]
+		hashes[i] = HashToken(s)
+	}
+	u.RecoveryCodes = hashes
+
+	return codes, nil
+}
+
+// UseRecoveryCode removes the provided recovery code from the codes of the
+// user. It reports whether the code was one of them, so every code can be
+// used once.
+func (u *User) UseRecoveryCode(code string) bool {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	hash := HashToken(code)
+
+	for i, h := range u.RecoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(h), []byte(hash)) == 1 {
+			codes := make([]string, 0, len(u.RecoveryCodes)-1)
+			codes = append(codes, u.RecoveryCodes[:i]...)
+			u.RecoveryCodes = append(codes, u.RecoveryCodes[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// DisableTOTP removes the second factor and recovery codes of the user.
+func (u *User) DisableTOTP() {
+	u.TOTPEnabled = false
+	u.TOTPSecret = nil
+	u.TOTPLastStep = 0
+	u.RecoveryCodes = nil
+}
@@ -0,0 +1,174 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/appinesshq/caservice/business/authz"
+	"github.com/appinesshq/caservice/business/user"
+)
+
+// apiKeyUsageInterval is how often the last use of an API key is recorded.
+// Clients can make many requests per second, which should not all result in
+// a write to the repository.
+const apiKeyUsageInterval = time.Minute
+
+// CreateAPIKey stores a new API key for the user and returns it together with
+// the opaque key for the client. The key is not stored and cannot be
+// retrieved again.
+//
+// The key belongs to the organization of the session. The scopes must be
+// roles of the user within it and the key must expire within the configured
+// maximum duration. API keys cannot be used to create new keys.
+func (uc UserUseCases) CreateAPIKey(ctx context.Context, userID string, n NewAPIKey, now time.Time) (user.APIKey, string, error) {
+	if uc.APIKeys == nil {
+		return user.APIKey{}, "", ErrAPIKeysDisabled
+	}
+
+	s, err := user.GetSession(ctx)
+	if err != nil {
+		return user.APIKey{}, "", err
+	}
+
+	if err := authz.Authorize(authz.ActionAPIKeyCreate, authz.SessionActor(s), authz.OwnedBy(userID)); err != nil {
+		return user.APIKey{}, "", err
+	}
+
+	if !n.Expires.After(now) || n.Expires.After(now.Add(uc.APIKeyMaxDuration)) {
+		return user.APIKey{}, "", ErrInvalidExpiry
+	}
+
+	u, err := uc.Repo.QueryByID(ctx, userID)
+	if err != nil {
+		return user.APIKey{}, "", err
+	}
+
+	if !hasAllRoles(user.NewSession(u, n.Expires), n.Scopes) {
+		return user.APIKey{}, "", ErrInvalidScope
+	}
+
+	ak, key, err := user.NewAPIKey(u.ID, s.TenantID, n.Name, n.Scopes, now, n.Expires)
+	if err != nil {
+		return user.APIKey{}, "", err
+	}
+
+	if err := uc.APIKeys.CreateAPIKey(ctx, ak); err != nil {
+		return user.APIKey{}, "", err
+	}
+
+	if err := uc.record(ctx, "user.CreateAPIKey", ak.ID, nil, ak); err != nil {
+		return user.APIKey{}, "", err
+	}
+
+	return ak, key, nil
+}
+
+// QueryAPIKeys retrieves all API keys of a user, including revoked and
+// expired keys.
+func (uc UserUseCases) QueryAPIKeys(ctx context.Context, userID string) ([]user.APIKey, error) {
+	if uc.APIKeys == nil {
+		return []user.APIKey{}, ErrAPIKeysDisabled
+	}
+
+	s, err := user.GetSession(ctx)
+	if err != nil {
+		return []user.APIKey{}, err
+	}
+
+	if err := authz.Authorize(authz.ActionAPIKeyQuery, authz.SessionActor(s), authz.OwnedBy(userID)); err != nil {
+		return []user.APIKey{}, err
+	}
+
+	return uc.APIKeys.QueryAPIKeysByUserID(ctx, userID)
+}
+
+// RevokeAPIKey revokes an API key of a user, so it cannot be used anymore.
+func (uc UserUseCases) RevokeAPIKey(ctx context.Context, userID, id string) error {
+	if uc.APIKeys == nil {
+		return ErrAPIKeysDisabled
+	}
+
+	s, err := user.GetSession(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := authz.Authorize(authz.ActionAPIKeyRevoke, authz.SessionActor(s), authz.OwnedBy(userID)); err != nil {
+		return err
+	}
+
+	if err := uc.APIKeys.RevokeAPIKey(ctx, userID, id); err != nil {
+		return err
+	}
+
+	return uc.record(ctx, "user.RevokeAPIKey", id, nil, nil)
+}
+
+// AuthenticateAPIKey returns a Session for the user of an API key in the
+// organization of the key. The session expires together with the key and its
+// user only has the roles that are both scopes of the key and roles of the
+// user within the organization.
+func (uc UserUseCases) AuthenticateAPIKey(ctx context.Context, key string, now time.Time) (user.Session, error) {
+	if uc.APIKeys == nil {
+		return user.Session{}, ErrAPIKeysDisabled
+	}
+	ctx = user.ContextForSystem(ctx)
+
+	ak, err := uc.APIKeys.QueryAPIKeyByHash(ctx, user.HashToken(key))
+	if err != nil {
+		if errors.Is(err, ErrAPIKeyNotFound) {
+			return user.Session{}, ErrAuthenticationFailed
+		}
+		return user.Session{}, err
+	}
+
+	if ak.Revoked || ak.IsExpired(now) {
+		return user.Session{}, ErrAuthenticationFailed
+	}
+
+	u, err := uc.Repo.QueryByID(ctx, ak.UserID)
+	if err != nil {
+		return user.Session{}, ErrAuthenticationFailed
+	}
+
+	if uc.RequireVerified && !u.Verified {
+		return user.Session{}, ErrUnverified
+	}
+
+	// Roles the user lost since the key was created are not granted by it.
+	s, err := uc.newSession(ctx, u, ak.OrgID, ak.DateExpires)
+	if err != nil {
+		return user.Session{}, err
+	}
+	roles := make([]string, 0, len(ak.Scopes))
+	for _, scope := range ak.Scopes {
+		if s.UserHasRole(scope) {
+			roles = append(roles, scope)
+		}
+	}
+	if len(roles) == 0 {
+		return user.Session{}, ErrAuthenticationFailed
+	}
+	s.User.Roles = roles
+	s.APIKeyID = ak.ID
+
+	if now.Sub(ak.DateLastUsed) >= apiKeyUsageInterval {
+		if err := uc.APIKeys.UseAPIKey(ctx, ak.ID, now); err != nil {
+			return user.Session{}, err
+		}
+	}
+
+	return s, nil
+}
+
+// hasAllRoles returns true if the user of the session has all of the
+// provided roles.
+func hasAllRoles(s user.Session, roles []string) bool {
+	for _, role := range roles {
+		if !s.UserHasRole(role) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,193 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/appinesshq/caservice/business/org"
+	"github.com/appinesshq/caservice/business/user"
+)
+
+// exportPageSize is the number of users ExportUsers reads at once.
+const exportPageSize = 500
+
+// ImportResult is the outcome of importing a single user. Err is set if the
+// user was not imported.
+type ImportResult struct {
+	Email   string
+	UserID  string
+	Created bool
+	Err     error
+}
+
+// ImportUsers creates or updates a batch of users by email address and makes
+// them members of the organization with their roles. It is run by the system,
+// not on behalf of a user.
+//
+// Every user is validated on its own and gets a result at the same index as
+// the user. Invalid users are left out, the other users of the batch are
+// stored at once. If storing fails, the returned error applies to all users
+// that were valid. With dryRun, users are only validated and nothing is
+// stored.
+func (uc UserUseCases) ImportUsers(ctx context.Context, orgID string, batch []ImportUser, dryRun bool, now time.Time) ([]ImportResult, error) {
+	ctx = user.ContextForSystem(ctx)
+
+	results := make([]ImportResult, len(batch))
+	befores := make(map[string]user.User, len(batch))
+	emails := make(map[string]bool, len(batch))
+
+	var us []user.User
+	var ms []org.Membership
+	for i, iu := range batch {
+		results[i].Email = iu.Email
+
+		if emails[iu.Email] {
+			results[i].Err = ErrDuplicateImport
+			continue
+		}
+		emails[iu.Email] = true
+
+		before, u, err := uc.importUser(ctx, iu, now)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		m, err := org.NewMembership(orgID, u.ID, u.Roles, now)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		results[i].UserID = u.ID
+		results[i].Created = before == nil
+		if before != nil {
+			befores[u.ID] = *before
+		}
+		us = append(us, u)
+		ms = append(ms, m)
+	}
+
+	if dryRun || len(us) == 0 {
+		return results, nil
+	}
+
+	if err := uc.Repo.Import(ctx, us, ms); err != nil {
+		return results, err
+	}
+
+	for _, u := range us {
+		if before, ok := befores[u.ID]; ok {
+			u.Version = before.Version + 1
+			if err := uc.record(ctx, "user.Import", u.ID, before, u); err != nil {
+				return results, err
+			}
+			continue
+		}
+		if err := uc.record(ctx, "user.Import", u.ID, nil, u); err != nil {
+			return results, err
+		}
+	}
+
+	return results, nil
+}
+
+// importUser returns the user to store for an imported user, with the
+// current state of the user if it exists. Deleted users are not imported,
+// their email address is taken until they are restored or purged.
+func (uc UserUseCases) importUser(ctx context.Context, iu ImportUser, now time.Time) (*user.User, user.User, error) {
+	current, err := uc.Repo.QueryByEmail(ctx, iu.Email)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			return nil, user.User{}, err
+		}
+
+		switch _, err := uc.Repo.QueryDeletedByEmail(ctx, iu.Email); {
+		case err == nil:
+			return nil, user.User{}, ErrDeletedImport
+		case !errors.Is(err, ErrNotFound):
+			return nil, user.User{}, err
+		}
+
+		if err := uc.PasswordPolicy.Check(iu.Password, iu.Name, iu.Email); err != nil {
+			return nil, user.User{}, err
+		}
+
+		u, err := user.NewWithID(iu.Name, iu.Email, iu.Password, iu.Roles, now)
+		if err != nil {
+			return nil, user.User{}, err
+		}
+		return nil, u, nil
+	}
+
+	u := current
+	if iu.Name != "" {
+		u.Name = iu.Name
+	}
+	if len(iu.Roles) > 0 {
+		u.Roles = iu.Roles
+	}
+	if iu.Password != "" {
+		if err := uc.PasswordPolicy.Check(iu.Password, u.Name, u.Email); err != nil {
+			return nil, user.User{}, err
+		}
+		if err := u.SetPassword(iu.Password); err != nil {
+			return nil, user.User{}, err
+		}
+	}
+	u.DateUpdated = now
+
+	if err := u.Validate(); err != nil {
+		return nil, user.User{}, fmt.Errorf("validation error: %w", err)
+	}
+
+	return &current, u, nil
+}
+
+// ExportUsers calls fn for every user that has one of the roles, or for
+// every user if no roles are provided, in the order of their ids. It is run
+// by the system, not on behalf of a user.
+func (uc UserUseCases) ExportUsers(ctx context.Context, roles []string, fn func(user.User) error) error {
+	ctx = user.ContextForSystem(ctx)
+
+	var f user.QueryFilter
+	if len(roles) == 1 {
+		f.Role = roles[0]
+	}
+
+	for page := 1; ; page++ {
+		us, err := uc.Repo.Query(ctx, f, page, exportPageSize)
+		if err != nil {
+			return err
+		}
+
+		for _, u := range us {
+			if !hasAnyRole(u, roles) {
+				continue
+			}
+			if err := fn(u); err != nil {
+				return err
+			}
+		}
+
+		if len(us) < exportPageSize {
+			return nil
+		}
+	}
+}
+
+// hasAnyRole reports whether the user has one of the roles, or true if no
+// roles are provided.
+func hasAnyRole(u user.User, roles []string) bool {
+	if len(roles) == 0 {
+		return true
+	}
+	for _, role := range roles {
+		if (user.QueryFilter{Role: role}).Match(u) {
+			return true
+		}
+	}
+	return false
+}
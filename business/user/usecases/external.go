@@ -0,0 +1,162 @@
+package usecases
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/appinesshq/caservice/business/org"
+	"github.com/appinesshq/caservice/business/user"
+)
+
+// ExternalIdentity represents a user authenticated by an external identity
+// provider, like the claims of an OpenID Connect ID token.
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// AuthenticateExternal returns a Session for a user authenticated by an
+// external identity provider.
+//
+// A subject that is not linked to a user yet is linked to the user with the
+// same email address, but only if the provider verified the email address.
+// Otherwise, if auto provisioning is enabled, a new user with the USER role
+// is created for it, unless the email address belongs to a deleted user, for
+// which ErrAuthenticationFailed is returned until the user is restored. In
+// any other case ErrIdentityNotLinked is returned.
+//
+// Like Authenticate, a *ChallengeError is returned instead of a Session for
+// users with two-factor authentication enabled.
+func (uc UserUseCases) AuthenticateExternal(ctx context.Context, ext ExternalIdentity, now time.Time) (user.Session, error) {
+	if uc.Identities == nil {
+		return user.Session{}, ErrExternalIdentitiesDisabled
+	}
+	ctx = user.ContextForSystem(ctx)
+
+	u, err := uc.externalUser(ctx, ext, now)
+	if err != nil {
+		return user.Session{}, err
+	}
+
+	if uc.RequireVerified && !u.Verified {
+		return user.Session{}, ErrUnverified
+	}
+
+	if u.TOTPEnabled {
+		return user.Session{}, uc.challenge(ctx, u, now)
+	}
+
+	return uc.newSession(ctx, u, "", now.Add(uc.SessionDuration))
+}
+
+// externalUser returns the user linked to the external identity, linking or
+// provisioning one first if needed.
+func (uc UserUseCases) externalUser(ctx context.Context, ext ExternalIdentity, now time.Time) (user.User, error) {
+	id, err := uc.Identities.QueryIdentity(ctx, ext.Provider, ext.Subject)
+	switch {
+	case err == nil:
+		u, err := uc.Repo.QueryByID(ctx, id.UserID)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return user.User{}, ErrIdentityNotLinked
+			}
+			return user.User{}, err
+		}
+		return u, nil
+
+	case !errors.Is(err, ErrIdentityNotFound):
+		return user.User{}, err
+	}
+
+	// An unverified email address could belong to anyone, so it must not
+	// give access to the account of the user with that address.
+	if ext.Email == "" || !ext.EmailVerified {
+		return user.User{}, ErrIdentityNotLinked
+	}
+
+	u, err := uc.Repo.QueryByEmail(ctx, ext.Email)
+	switch {
+	case err == nil:
+		if !u.Verified {
+			u.Verified = true
+			u.DateUpdated = now
+			if err := uc.Repo.Update(ctx, u); err != nil {
+				return user.User{}, err
+			}
+		}
+
+	case errors.Is(err, ErrNotFound) && uc.AutoProvision:
+		if u, err = uc.provision(ctx, ext, now); err != nil {
+			return user.User{}, err
+		}
+
+	case errors.Is(err, ErrNotFound):
+		return user.User{}, ErrIdentityNotLinked
+
+	default:
+		return user.User{}, err
+	}
+
+	id, err = user.NewIdentity(u.ID, ext.Provider, ext.Subject, ext.Email, now)
+	if err != nil {
+		return user.User{}, err
+	}
+
+	if err := uc.Identities.CreateIdentity(ctx, id); err != nil {
+		return user.User{}, err
+	}
+
+	uc.Log.Infow("identity linked", "userid", u.ID, "provider", ext.Provider)
+
+	return u, nil
+}
+
+// provision creates a user with the USER role in the default organization for
+// the external identity. The user gets a random password, which can be
+// replaced by a password reset.
+func (uc UserUseCases) provision(ctx context.Context, ext ExternalIdentity, now time.Time) (user.User, error) {
+	// A deleted user keeps its email address until it is purged.
+	if _, err := uc.Repo.QueryDeletedByEmail(ctx, ext.Email); err == nil {
+		return user.User{}, ErrAuthenticationFailed
+	} else if !errors.Is(err, ErrNotFound) {
+		return user.User{}, err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return user.User{}, fmt.Errorf("generating password: %w", err)
+	}
+
+	name := strings.TrimSpace(ext.Name)
+	if name == "" {
+		name = ext.Email
+	}
+
+	u, err := user.NewWithID(name, ext.Email, base64.RawURLEncoding.EncodeToString(b), []string{user.RoleUser}, now)
+	if err != nil {
+		return user.User{}, err
+	}
+	u.Verified = ext.EmailVerified
+
+	if err := uc.Repo.Create(ctx, u); err != nil {
+		return user.User{}, err
+	}
+
+	if err := uc.join(ctx, org.DefaultID, u, now); err != nil {
+		return user.User{}, err
+	}
+
+	if err := uc.record(ctx, "user.AuthenticateExternal", u.ID, nil, u); err != nil {
+		return user.User{}, err
+	}
+
+	return u, nil
+}
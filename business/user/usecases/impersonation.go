@@ -0,0 +1,91 @@
+package usecases
+
+import (
+	"context"
+	"time"
+
+	"github.com/appinesshq/caservice/business/authz"
+	"github.com/appinesshq/caservice/business/user"
+)
+
+// Impersonate returns a Session for another user in the organization of the
+// session, marked with the admin of the session as impersonator, so support
+// staff can reproduce the problems of the user. Impersonated sessions expire
+// after the impersonation duration and refuse sensitive actions, like
+// changing credentials.
+//
+// With login session tracking, the impersonation is a login session of the
+// user, started from the client of the admin with the provided IP address and
+// user agent. It ends when the user signs out everywhere or changes its
+// password.
+func (uc UserUseCases) Impersonate(ctx context.Context, userID, ip, userAgent string, now time.Time) (user.Session, error) {
+	if uc.ImpersonationDuration == 0 {
+		return user.Session{}, ErrImpersonationDisabled
+	}
+
+	s, err := user.GetSession(ctx)
+	if err != nil {
+		return user.Session{}, err
+	}
+
+	if err := authz.Authorize(authz.ActionUserImpersonate, authz.SessionActor(s), authz.OwnedBy(userID)); err != nil {
+		return user.Session{}, err
+	}
+
+	u, err := uc.Repo.QueryByID(ctx, userID)
+	if err != nil {
+		return user.Session{}, err
+	}
+
+	is, err := uc.newSession(ctx, u, s.TenantID, now.Add(uc.ImpersonationDuration))
+	if err != nil {
+		return user.Session{}, err
+	}
+	is.ImpersonatorID = s.User.ID
+
+	if uc.LoginSessions != nil {
+		if is, err = uc.startSession(ctx, is, ip, userAgent, now, is.Expires); err != nil {
+			return user.Session{}, err
+		}
+	}
+
+	if err := uc.record(ctx, "user.Impersonate", u.ID, nil, nil); err != nil {
+		return user.Session{}, err
+	}
+
+	uc.Log.Infow("impersonation started", "userid", u.ID, "impersonatorid", s.User.ID, "expires", is.Expires)
+
+	return is, nil
+}
+
+// ResumeImpersonation checks that the admin that impersonates the user of a
+// token still may do so. The session of the token is returned marked with the
+// admin as impersonator. Sessions without an impersonator are returned as is.
+func (uc UserUseCases) ResumeImpersonation(ctx context.Context, s user.Session, impersonatorID string) (user.Session, error) {
+	if impersonatorID == "" {
+		return s, nil
+	}
+
+	if uc.ImpersonationDuration == 0 {
+		return user.Session{}, ErrAuthenticationFailed
+	}
+	ctx = user.ContextForSystem(ctx)
+
+	admin, err := uc.Repo.QueryByID(ctx, impersonatorID)
+	if err != nil {
+		return user.Session{}, ErrAuthenticationFailed
+	}
+
+	// Admins that lost their role or left the organization can't keep
+	// impersonating its users.
+	as, err := uc.newSession(ctx, admin, s.TenantID, s.Expires)
+	if err != nil {
+		return user.Session{}, ErrAuthenticationFailed
+	}
+	if err := authz.Authorize(authz.ActionUserImpersonate, authz.SessionActor(as), authz.OwnedBy(s.User.ID)); err != nil {
+		return user.Session{}, ErrAuthenticationFailed
+	}
+
+	s.ImpersonatorID = admin.ID
+	return s, nil
+}
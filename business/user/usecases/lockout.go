@@ -0,0 +1,100 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/appinesshq/caservice/business/user"
+)
+
+// LockedError is returned by Authenticate while an account or a client IP
+// address is locked after too many failed attempts.
+type LockedError struct {
+	Until time.Time
+}
+
+// Error implements the error interface.
+func (e *LockedError) Error() string {
+	return "too many failed authentication attempts"
+}
+
+// RetryAfter returns how long the client has to wait before trying again.
+func (e *LockedError) RetryAfter(now time.Time) time.Duration {
+	return e.Until.Sub(now)
+}
+
+// lockoutKey is a key of the login attempt repository with its policy.
+type lockoutKey struct {
+	key    string
+	policy user.LockoutPolicy
+}
+
+// lockoutKeys returns the keys under which failed attempts of the account
+// and the client IP address are tracked.
+func (uc UserUseCases) lockoutKeys(email, ip string) []lockoutKey {
+	keys := []lockoutKey{{key: "account:" + strings.ToLower(email), policy: uc.AccountLockout}}
+	if ip != "" {
+		keys = append(keys, lockoutKey{key: "ip:" + ip, policy: uc.IPLockout})
+	}
+	return keys
+}
+
+// checkLockout returns a *LockedError if the account or the client IP address
+// is locked.
+func (uc UserUseCases) checkLockout(ctx context.Context, email, ip string, now time.Time) error {
+	if uc.LoginAttempts == nil {
+		return nil
+	}
+
+	var until time.Time
+	for _, lk := range uc.lockoutKeys(email, ip) {
+		failures, last, err := uc.LoginAttempts.QueryLoginFailures(ctx, lk.key)
+		if err != nil {
+			return fmt.Errorf("querying login failures: %w", err)
+		}
+
+		if u := lk.policy.LockedUntil(failures, last); u.After(until) {
+			until = u
+		}
+	}
+
+	if now.Before(until) {
+		return &LockedError{Until: until}
+	}
+
+	return nil
+}
+
+// loginFailed records a failed attempt for the account and the client IP
+// address and returns ErrAuthenticationFailed.
+func (uc UserUseCases) loginFailed(ctx context.Context, email, ip string, now time.Time) error {
+	if uc.LoginAttempts == nil {
+		return ErrAuthenticationFailed
+	}
+
+	for _, lk := range uc.lockoutKeys(email, ip) {
+		since := now.Add(-lk.policy.ResetAfter)
+		if _, err := uc.LoginAttempts.RecordLoginFailure(ctx, lk.key, now, since); err != nil {
+			return fmt.Errorf("recording login failure: %w", err)
+		}
+	}
+
+	return ErrAuthenticationFailed
+}
+
+// loginSucceeded forgets the failed attempts of the account. Failures of the
+// client IP address are kept, so an attacker cannot reset them by
+// authenticating with an account of their own.
+func (uc UserUseCases) loginSucceeded(ctx context.Context, email string) error {
+	if uc.LoginAttempts == nil {
+		return nil
+	}
+
+	if err := uc.LoginAttempts.ResetLoginFailures(ctx, uc.lockoutKeys(email, "")[0].key); err != nil {
+		return fmt.Errorf("resetting login failures: %w", err)
+	}
+
+	return nil
+}
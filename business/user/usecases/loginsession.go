@@ -0,0 +1,183 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/appinesshq/caservice/business/authz"
+	"github.com/appinesshq/caservice/business/user"
+)
+
+// loginSessionSeenInterval is how often the last time a login session was
+// seen is recorded. Every request carries a token of the login session,
+// which should not all result in a write to the repository.
+const loginSessionSeenInterval = time.Minute
+
+// StartSession stores a new login session for the session of a user that
+// just authenticated from a client with the provided IP address and user
+// agent. The returned session carries the id of the login session. Without
+// login session tracking, the session is returned as is.
+func (uc UserUseCases) StartSession(ctx context.Context, s user.Session, ip, userAgent string, now time.Time) (user.Session, error) {
+	if uc.LoginSessions == nil {
+		return s, nil
+	}
+
+	// A login session lasts as long as its tokens can be refreshed.
+	d := uc.SessionDuration
+	if uc.RefreshTokens != nil && uc.RefreshTokenDuration > d {
+		d = uc.RefreshTokenDuration
+	}
+
+	return uc.startSession(ctx, s, ip, userAgent, now, now.Add(d))
+}
+
+// startSession stores a new login session for the session of a user, which
+// expires at the provided time, and returns the session with its id.
+func (uc UserUseCases) startSession(ctx context.Context, s user.Session, ip, userAgent string, now, expires time.Time) (user.Session, error) {
+	ls, err := user.NewLoginSession(s.User.ID, ip, userAgent, now, expires)
+	if err != nil {
+		return user.Session{}, err
+	}
+
+	if err := uc.LoginSessions.CreateLoginSession(ctx, ls); err != nil {
+		return user.Session{}, err
+	}
+
+	s.LoginSessionID = ls.ID
+	return s, nil
+}
+
+// ResumeSession checks that the login session of a token is still active and
+// records that it was seen from a client with the provided IP address. The
+// session of the token is returned with the id of its login session. Tokens
+// without a login session, like those issued before login sessions were
+// tracked, are accepted as is.
+func (uc UserUseCases) ResumeSession(ctx context.Context, s user.Session, loginSessionID, ip string, now time.Time) (user.Session, error) {
+	if uc.LoginSessions == nil || loginSessionID == "" {
+		return s, nil
+	}
+
+	ls, err := uc.LoginSessions.QueryLoginSessionByID(ctx, loginSessionID)
+	if err != nil {
+		if errors.Is(err, ErrLoginSessionNotFound) {
+			return user.Session{}, ErrAuthenticationFailed
+		}
+		return user.Session{}, err
+	}
+
+	if ls.UserID != s.User.ID || !ls.IsActive(now) {
+		return user.Session{}, ErrAuthenticationFailed
+	}
+
+	if now.Sub(ls.DateLastSeen) >= loginSessionSeenInterval || ls.IP != ip {
+		if err := uc.LoginSessions.SeeLoginSession(ctx, ls.ID, ip, now, ls.DateExpires); err != nil {
+			return user.Session{}, err
+		}
+	}
+
+	s.LoginSessionID = ls.ID
+	return s, nil
+}
+
+// QuerySessions retrieves the active login sessions of a user, the session
+// that was seen last first.
+func (uc UserUseCases) QuerySessions(ctx context.Context, userID string, now time.Time) ([]user.LoginSession, error) {
+	if uc.LoginSessions == nil {
+		return []user.LoginSession{}, ErrLoginSessionsDisabled
+	}
+
+	s, err := user.GetSession(ctx)
+	if err != nil {
+		return []user.LoginSession{}, err
+	}
+
+	if err := authz.Authorize(authz.ActionSessionQuery, authz.SessionActor(s), authz.OwnedBy(userID)); err != nil {
+		return []user.LoginSession{}, err
+	}
+
+	all, err := uc.LoginSessions.QueryLoginSessionsByUserID(ctx, userID)
+	if err != nil {
+		return []user.LoginSession{}, err
+	}
+
+	sessions := make([]user.LoginSession, 0, len(all))
+	for _, ls := range all {
+		if ls.IsActive(now) {
+			sessions = append(sessions, ls)
+		}
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession ends a login session of a user. Its tokens are refused from
+// then on and its refresh tokens can't be exchanged anymore.
+func (uc UserUseCases) RevokeSession(ctx context.Context, userID, id string) error {
+	if uc.LoginSessions == nil {
+		return ErrLoginSessionsDisabled
+	}
+
+	s, err := user.GetSession(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := authz.Authorize(authz.ActionSessionRevoke, authz.SessionActor(s), authz.OwnedBy(userID)); err != nil {
+		return err
+	}
+
+	if err := uc.LoginSessions.RevokeLoginSession(ctx, userID, id); err != nil {
+		return err
+	}
+
+	if uc.RefreshTokens != nil {
+		if err := uc.RefreshTokens.RevokeRefreshTokenFamily(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	return uc.record(ctx, "user.RevokeSession", id, nil, nil)
+}
+
+// RevokeSessions ends all login sessions of a user, including the current
+// one, and revokes all of its refresh tokens.
+func (uc UserUseCases) RevokeSessions(ctx context.Context, userID string) error {
+	if uc.LoginSessions == nil {
+		return ErrLoginSessionsDisabled
+	}
+
+	s, err := user.GetSession(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := authz.Authorize(authz.ActionSessionRevoke, authz.SessionActor(s), authz.OwnedBy(userID)); err != nil {
+		return err
+	}
+
+	if err := uc.signOut(ctx, userID); err != nil {
+		return err
+	}
+
+	return uc.record(ctx, "user.RevokeSessions", userID, nil, nil)
+}
+
+// signOut ends all login sessions of a user and revokes all of its refresh
+// tokens, as far as these are enabled, so the user has to authenticate again
+// everywhere.
+func (uc UserUseCases) signOut(ctx context.Context, userID string) error {
+	if uc.LoginSessions != nil {
+		if err := uc.LoginSessions.RevokeUserLoginSessions(ctx, userID); err != nil {
+			return err
+		}
+	}
+
+	if uc.RefreshTokens != nil {
+		if err := uc.RefreshTokens.RevokeUserRefreshTokens(ctx, userID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,286 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/appinesshq/caservice/business/authz"
+	"github.com/appinesshq/caservice/business/org"
+	"github.com/appinesshq/caservice/business/user"
+)
+
+// CreateOrg stores a new organization. The user of the session becomes its
+// first member, as ADMIN and USER.
+func (uc UserUseCases) CreateOrg(ctx context.Context, n NewOrg, now time.Time) (org.Org, error) {
+	s, err := user.GetSession(ctx)
+	if err != nil {
+		return org.Org{}, err
+	}
+
+	if err := authz.Authorize(authz.ActionOrgCreate, authz.SessionActor(s), authz.Resource{}); err != nil {
+		return org.Org{}, err
+	}
+
+	o, err := org.NewWithID(n.Name, now)
+	if err != nil {
+		return org.Org{}, err
+	}
+
+	m, err := org.NewMembership(o.ID, s.User.ID, []string{user.RoleAdmin, user.RoleUser}, now)
+	if err != nil {
+		return org.Org{}, err
+	}
+
+	if err := uc.Orgs.CreateOrg(ctx, o, m); err != nil {
+		return org.Org{}, err
+	}
+
+	if err := uc.record(ctx, "user.CreateOrg", o.ID, nil, o); err != nil {
+		return org.Org{}, err
+	}
+
+	return o, nil
+}
+
+// QueryOrgs retrieves the organizations the user of the session is a member
+// of.
+func (uc UserUseCases) QueryOrgs(ctx context.Context) ([]org.Org, error) {
+	s, err := user.GetSession(ctx)
+	if err != nil {
+		return []org.Org{}, err
+	}
+
+	return uc.Orgs.QueryOrgsByUserID(ctx, s.User.ID)
+}
+
+// SwitchOrg returns a Session for the user of the session in another
+// organization it is a member of. API keys are bound to the organization
+// they were created in and cannot switch.
+func (uc UserUseCases) SwitchOrg(ctx context.Context, orgID string, now time.Time) (user.Session, error) {
+	s, err := user.GetSession(ctx)
+	if err != nil {
+		return user.Session{}, err
+	}
+
+	if err := authz.Authorize(authz.ActionOrgSwitch, authz.SessionActor(s), authz.Resource{}); err != nil {
+		return user.Session{}, err
+	}
+
+	// The roles of the user in the current organization don't carry over,
+	// the new session has the roles of the other membership. It stays part
+	// of the same login session.
+	ns, err := uc.newSession(ctx, s.User, orgID, now.Add(uc.SessionDuration))
+	if err != nil {
+		return user.Session{}, err
+	}
+	ns.LoginSessionID = s.LoginSessionID
+
+	return ns, nil
+}
+
+// QueryMembers retrieves the memberships of the organization of the session.
+func (uc UserUseCases) QueryMembers(ctx context.Context) ([]org.Membership, error) {
+	s, err := user.GetSession(ctx)
+	if err != nil {
+		return []org.Membership{}, err
+	}
+
+	if err := authz.Authorize(authz.ActionOrgQueryMembers, authz.SessionActor(s), authz.Resource{}); err != nil {
+		return []org.Membership{}, err
+	}
+
+	return uc.Orgs.QueryMembershipsByOrgID(ctx, s.TenantID)
+}
+
+// InviteMember invites an existing user to become a member of the
+// organization of the session, with the provided roles. The user only
+// becomes a member after accepting the invitation.
+func (uc UserUseCases) InviteMember(ctx context.Context, n NewMember, now time.Time) (org.Invitation, error) {
+	s, err := user.GetSession(ctx)
+	if err != nil {
+		return org.Invitation{}, err
+	}
+
+	if err := authz.Authorize(authz.ActionOrgInviteMember, authz.SessionActor(s), authz.Resource{}); err != nil {
+		return org.Invitation{}, err
+	}
+
+	if _, err := uc.Orgs.QueryMembership(ctx, s.TenantID, n.UserID); err == nil {
+		return org.Invitation{}, ErrUniqueMembership
+	} else if !errors.Is(err, ErrMembershipNotFound) {
+		return org.Invitation{}, err
+	}
+
+	i, err := org.NewInvitation(s.TenantID, n.UserID, s.User.ID, n.Roles, now)
+	if err != nil {
+		return org.Invitation{}, err
+	}
+
+	if err := uc.Orgs.CreateInvitation(ctx, i); err != nil {
+		return org.Invitation{}, err
+	}
+
+	if err := uc.record(ctx, "user.InviteMember", i.UserID, nil, i); err != nil {
+		return org.Invitation{}, err
+	}
+
+	return i, nil
+}
+
+// QueryInvitations retrieves the open invitations of the user of the
+// session.
+func (uc UserUseCases) QueryInvitations(ctx context.Context) ([]org.Invitation, error) {
+	s, err := user.GetSession(ctx)
+	if err != nil {
+		return []org.Invitation{}, err
+	}
+
+	return uc.Orgs.QueryInvitationsByUserID(ctx, s.User.ID)
+}
+
+// AcceptInvitation makes the user of the session a member of the
+// organization it was invited to, with the roles of the invitation.
+func (uc UserUseCases) AcceptInvitation(ctx context.Context, id string, now time.Time) (org.Membership, error) {
+	i, err := uc.invitation(ctx, id)
+	if err != nil {
+		return org.Membership{}, err
+	}
+
+	m, err := org.NewMembership(i.OrgID, i.UserID, i.Roles, now)
+	if err != nil {
+		return org.Membership{}, err
+	}
+
+	if err := uc.Orgs.AcceptInvitation(ctx, i.ID, m); err != nil {
+		return org.Membership{}, err
+	}
+
+	if err := uc.record(ctx, "user.AcceptInvitation", m.UserID, i, m); err != nil {
+		return org.Membership{}, err
+	}
+
+	return m, nil
+}
+
+// DeclineInvitation removes an invitation of the user of the session
+// without becoming a member.
+func (uc UserUseCases) DeclineInvitation(ctx context.Context, id string) error {
+	i, err := uc.invitation(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.Orgs.DeleteInvitation(ctx, i.ID); err != nil {
+		return err
+	}
+
+	return uc.record(ctx, "user.DeclineInvitation", i.UserID, i, nil)
+}
+
+// invitation returns the invitation with the id if the user of the session
+// may answer it. Invitations of other users are not found.
+func (uc UserUseCases) invitation(ctx context.Context, id string) (org.Invitation, error) {
+	s, err := user.GetSession(ctx)
+	if err != nil {
+		return org.Invitation{}, err
+	}
+
+	i, err := uc.Orgs.QueryInvitationByID(ctx, id)
+	if err != nil {
+		return org.Invitation{}, err
+	}
+	if i.UserID != s.User.ID {
+		return org.Invitation{}, ErrInvitationNotFound
+	}
+
+	if err := authz.Authorize(authz.ActionOrgAnswerInvite, authz.SessionActor(s), authz.OwnedBy(i.UserID)); err != nil {
+		return org.Invitation{}, err
+	}
+
+	return i, nil
+}
+
+// RemoveMember ends the membership of a user in the organization of the
+// session. Admins can remove any member, users can leave themselves.
+func (uc UserUseCases) RemoveMember(ctx context.Context, userID string) error {
+	s, err := user.GetSession(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := authz.Authorize(authz.ActionOrgRemoveMember, authz.SessionActor(s), authz.OwnedBy(userID)); err != nil {
+		return err
+	}
+
+	m, err := uc.Orgs.QueryMembership(ctx, s.TenantID, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.Orgs.DeleteMembership(ctx, s.TenantID, userID); err != nil {
+		return err
+	}
+
+	return uc.record(ctx, "user.RemoveMember", userID, m, nil)
+}
+
+// authorizeAccount returns ErrUnauthorized if the user of the session may
+// not change the account of the user with the id, like its credentials, which
+// are shared by all organizations of the user. Admins may only change the
+// accounts of users that are a member of their organization alone, so they
+// can't take over the accounts of members of other organizations.
+func (uc UserUseCases) authorizeAccount(ctx context.Context, s user.Session, id string) error {
+	if s.User.ID == id {
+		return nil
+	}
+
+	ms, err := uc.Orgs.QueryMembershipsByUserID(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, m := range ms {
+		if m.OrgID != s.TenantID {
+			return ErrUnauthorized
+		}
+	}
+
+	return nil
+}
+
+// join makes the user a member of the organization, with the roles of the
+// user.
+func (uc UserUseCases) join(ctx context.Context, orgID string, u user.User, now time.Time) error {
+	m, err := org.NewMembership(orgID, u.ID, u.Roles, now)
+	if err != nil {
+		return err
+	}
+
+	return uc.Orgs.CreateMembership(ctx, m)
+}
+
+// membership returns the membership of the user in the provided
+// organization, or the membership the user has longest if orgID is empty.
+// ErrAuthenticationFailed is returned if there is no such membership.
+func (uc UserUseCases) membership(ctx context.Context, userID, orgID string) (org.Membership, error) {
+	if orgID != "" {
+		m, err := uc.Orgs.QueryMembership(ctx, orgID, userID)
+		if err != nil {
+			if errors.Is(err, ErrMembershipNotFound) {
+				return org.Membership{}, ErrAuthenticationFailed
+			}
+			return org.Membership{}, err
+		}
+		return m, nil
+	}
+
+	ms, err := uc.Orgs.QueryMembershipsByUserID(ctx, userID)
+	if err != nil {
+		return org.Membership{}, err
+	}
+	if len(ms) == 0 {
+		return org.Membership{}, ErrAuthenticationFailed
+	}
+
+	return ms[0], nil
+}
@@ -0,0 +1,142 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/appinesshq/caservice/business/user"
+	"github.com/appinesshq/caservice/foundation/mail"
+)
+
+// RequestPasswordReset sends a password reset token to the user with the
+// provided email address. To not disclose which email addresses are
+// registered, no error is returned for unknown email addresses.
+func (uc UserUseCases) RequestPasswordReset(ctx context.Context, email string, now time.Time) error {
+	if uc.OneTimeTokens == nil {
+		return ErrPasswordResetDisabled
+	}
+	ctx = user.ContextForSystem(ctx)
+
+	u, err := uc.Repo.QueryByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	expires := now.Add(uc.ResetTokenDuration)
+	ot, token, err := user.NewOneTimeToken(u.ID, user.PurposePasswordReset, now, expires)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.OneTimeTokens.CreateOneTimeToken(ctx, ot); err != nil {
+		return err
+	}
+
+	msg := mail.Message{
+		To:      u.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Use the following token to reset your password. The token can be used once and expires at %s.\n\n"+
+			"%s\n\n"+
+			"If you did not request a password reset, you can ignore this email.\n",
+			u.Name, expires.Format(time.RFC1123), token),
+	}
+	if err := uc.Mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("sending password reset: %w", err)
+	}
+
+	return nil
+}
+
+// ResetPassword sets a new password for the user a password reset token was
+// issued for. The token can only be used once. All login sessions and
+// refresh tokens of the user are revoked, so other sessions must
+// authenticate with the new password. The updated user is returned.
+func (uc UserUseCases) ResetPassword(ctx context.Context, token, password string, now time.Time) (user.User, error) {
+	if uc.OneTimeTokens == nil {
+		return user.User{}, ErrPasswordResetDisabled
+	}
+	ctx = user.ContextForSystem(ctx)
+
+	ot, err := uc.OneTimeTokens.QueryOneTimeTokenByHash(ctx, user.HashToken(token))
+	if err != nil {
+		if errors.Is(err, ErrOneTimeTokenNotFound) {
+			return user.User{}, ErrInvalidResetToken
+		}
+		return user.User{}, err
+	}
+
+	if ot.Purpose != user.PurposePasswordReset || ot.Used || ot.IsExpired(now) {
+		return user.User{}, ErrInvalidResetToken
+	}
+
+	u, err := uc.Repo.QueryByID(ctx, ot.UserID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return user.User{}, ErrInvalidResetToken
+		}
+		return user.User{}, err
+	}
+
+	// The token stays valid for another password if this one is rejected.
+	if err := uc.PasswordPolicy.Check(password, u.Name, u.Email); err != nil {
+		return user.User{}, err
+	}
+
+	// Mark the token as used. This fails if a concurrent request
+	// used the same token first.
+	if err := uc.OneTimeTokens.UseOneTimeToken(ctx, ot.ID); err != nil {
+		if errors.Is(err, ErrOneTimeTokenUsed) {
+			return user.User{}, ErrInvalidResetToken
+		}
+		return user.User{}, err
+	}
+
+	before := u
+	if err := u.SetPassword(password); err != nil {
+		return user.User{}, err
+	}
+	u.DateUpdated = now
+
+	if err := uc.Repo.Update(ctx, u); err != nil {
+		return user.User{}, err
+	}
+
+	if err := uc.record(ctx, "user.ResetPassword", u.ID, before, u); err != nil {
+		return user.User{}, err
+	}
+
+	if err := uc.signOut(ctx, u.ID); err != nil {
+		return user.User{}, err
+	}
+
+	return u, nil
+}
+
+// rehashPassword replaces an outdated password hash of a user that just
+// provided the correct password with a hash of the DefaultPasswordHasher.
+// The user authenticated either way, so failures are only logged.
+func (uc UserUseCases) rehashPassword(ctx context.Context, u user.User, password string) user.User {
+	if !u.PasswordNeedsRehash() {
+		return u
+	}
+
+	rehashed := u
+	if err := rehashed.SetPassword(password); err != nil {
+		uc.Log.Errorw("rehash", "status", "hashing password", "userID", u.ID, "ERROR", err)
+		return u
+	}
+
+	if err := uc.Repo.Update(ctx, rehashed); err != nil {
+		uc.Log.Errorw("rehash", "status", "storing password hash", "userID", u.ID, "ERROR", err)
+		return u
+	}
+	rehashed.Version++
+
+	return rehashed
+}
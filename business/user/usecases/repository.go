@@ -3,23 +3,198 @@ package usecases
 import (
 	"context"
 	"errors"
+	"time"
 
+	"github.com/appinesshq/caservice/business/org"
 	"github.com/appinesshq/caservice/business/user"
 )
 
 var (
-	ErrNotFound    = errors.New("user not found")
-	ErrUniqueEmail = errors.New("email already exists")
-	ErrUniqueID    = errors.New("id already exists")
+	ErrNotFound             = errors.New("user not found")
+	ErrUniqueEmail          = errors.New("email already exists")
+	ErrUniqueID             = errors.New("id already exists")
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrRefreshTokenUsed     = errors.New("refresh token already used")
+	ErrOneTimeTokenNotFound = errors.New("one-time token not found")
+	ErrOneTimeTokenUsed     = errors.New("one-time token already used")
+	ErrIdentityNotFound     = errors.New("identity not found")
+	ErrUniqueIdentity       = errors.New("identity already linked")
+	ErrAPIKeyNotFound       = errors.New("api key not found")
+	ErrMembershipNotFound   = errors.New("membership not found")
+	ErrUniqueMembership     = errors.New("user is already a member")
+	ErrInvitationNotFound   = errors.New("invitation not found")
+	ErrUniqueInvitation     = errors.New("user is already invited")
+	ErrConflict             = errors.New("user was changed by someone else")
+	ErrLoginSessionNotFound = errors.New("login session not found")
 )
 
 // UserRepository is an interface which is to be implemented by the layer
 // between user usecases and storages.
+//
+// Calls with a Session in the context are made on behalf of its user and
+// must be scoped to the members of the organization of the session: other
+// users are not found and users have their roles within the organization.
+// Calls the system makes itself, for example to authenticate a user, carry
+// a context marked with user.ContextForSystem instead and are not scoped.
+// Calls with neither fail with user.ErrNoTenant, so a missing Session never
+// reaches the users of all organizations.
+//
+// Update stores a new version of the user it was read as. It returns
+// ErrConflict if the user was changed since, so concurrent writes don't
+// overwrite each other. Update, Delete and Restore bump the version of the
+// user.
+//
+// Query returns a page of the users selected by the filter, in the order of
+// the filter. Count returns the number of users selected by the filter, so
+// the number of pages is known. CountAll returns the number of users
+// including deleted ones and is not scoped.
+//
+// Delete marks a user as deleted. Deleted users are not returned by Query,
+// QueryByID and QueryByEmail and can't be updated, but keep their data and
+// email address until Purge removes them for good. QueryDeleted and
+// QueryDeletedByEmail return the deleted users instead. Restore returns
+// ErrNotFound if there is no deleted user with the id. Purge removes the
+// users deleted before the provided time, including their products and
+// sales, and returns their ids. It is only called by the system and is not
+// scoped.
+//
+// Import stores a batch of users with their memberships at once. Users are
+// inserted or replaced by id, bumping the version of replaced users, and
+// memberships are inserted or replaced by organization and user. Like
+// Update, users are only replaced if they are still the version they were
+// read as and are not deleted. Either the whole batch is stored or nothing
+// is, for example when ErrUniqueEmail is returned for an email address of
+// another user or ErrConflict for a user that was changed since. Like Purge,
+// it is only called by the system and is not scoped.
 type UserRepository interface {
 	Create(context.Context, user.User) error
-	Query(context.Context, int, int) ([]user.User, error)
+	Query(context.Context, user.QueryFilter, int, int) ([]user.User, error)
+	Count(context.Context, user.QueryFilter) (int, error)
+	CountAll(context.Context) (int, error)
+	QueryDeleted(context.Context, int, int) ([]user.User, error)
 	QueryByID(context.Context, string) (user.User, error)
 	QueryByEmail(context.Context, string) (user.User, error)
+	QueryDeletedByEmail(context.Context, string) (user.User, error)
 	Update(context.Context, user.User) error
-	Delete(context.Context, string) error
+	Delete(ctx context.Context, id string, now time.Time) error
+	Restore(ctx context.Context, id string, now time.Time) error
+	Purge(ctx context.Context, before time.Time) ([]string, error)
+	Import(ctx context.Context, us []user.User, ms []org.Membership) error
+}
+
+// RefreshTokenRepository is an interface which is to be implemented by the
+// layer between user usecases and refresh token storages.
+//
+// UseRefreshToken must mark a token as used atomically. It returns
+// ErrRefreshTokenUsed if the token was used before, so a token can only be
+// exchanged once, even by concurrent requests.
+type RefreshTokenRepository interface {
+	CreateRefreshToken(context.Context, user.RefreshToken) error
+	QueryRefreshTokenByHash(context.Context, string) (user.RefreshToken, error)
+	UseRefreshToken(context.Context, string) error
+	RevokeRefreshTokenFamily(context.Context, string) error
+	RevokeUserRefreshTokens(context.Context, string) error
+}
+
+// OneTimeTokenRepository is an interface which is to be implemented by the
+// layer between user usecases and one-time token storages.
+//
+// UseOneTimeToken must mark a token as used atomically. It returns
+// ErrOneTimeTokenUsed if the token was used before. AttemptOneTimeToken
+// counts an attempt to use a token atomically and returns the number of
+// attempts including this one.
+type OneTimeTokenRepository interface {
+	CreateOneTimeToken(context.Context, user.OneTimeToken) error
+	QueryOneTimeTokenByHash(context.Context, string) (user.OneTimeToken, error)
+	UseOneTimeToken(context.Context, string) error
+	AttemptOneTimeToken(context.Context, string) (int, error)
+}
+
+// LoginAttemptRepository is an interface which is to be implemented by the
+// layer between user usecases and storages of failed authentication
+// attempts. Attempts are tracked by key, like an account or a client IP
+// address.
+//
+// RecordLoginFailure counts a failure at now and returns the number of
+// failures for the key. Failures of the key before since are forgotten first.
+// QueryLoginFailures returns the number of failures and the time of the
+// last failure, or zero values for unknown keys.
+type LoginAttemptRepository interface {
+	RecordLoginFailure(ctx context.Context, key string, now, since time.Time) (int, error)
+	QueryLoginFailures(ctx context.Context, key string) (int, time.Time, error)
+	ResetLoginFailures(ctx context.Context, key string) error
+}
+
+// IdentityRepository is an interface which is to be implemented by the layer
+// between user usecases and storages of identities at external providers.
+//
+// CreateIdentity returns ErrUniqueIdentity if the subject of the provider is
+// already linked to a user.
+type IdentityRepository interface {
+	CreateIdentity(context.Context, user.Identity) error
+	QueryIdentity(ctx context.Context, provider, subject string) (user.Identity, error)
+}
+
+// APIKeyRepository is an interface which is to be implemented by the layer
+// between user usecases and API key storages.
+//
+// RevokeAPIKey returns ErrAPIKeyNotFound if the user has no key with the id.
+// UseAPIKey records the time the key was last used. Like for the
+// UserRepository, calls with a Session in the context are scoped to the keys
+// of the organization of the session.
+type APIKeyRepository interface {
+	CreateAPIKey(context.Context, user.APIKey) error
+	QueryAPIKeyByHash(context.Context, string) (user.APIKey, error)
+	QueryAPIKeysByUserID(context.Context, string) ([]user.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, id string) error
+	UseAPIKey(ctx context.Context, id string, now time.Time) error
+}
+
+// LoginSessionRepository is an interface which is to be implemented by the
+// layer between user usecases and login session storages.
+//
+// SeeLoginSession records the client IP address and time a login session
+// was last seen, and extends it until expires if that is later. An empty IP
+// address keeps the recorded one. RevokeLoginSession returns
+// ErrLoginSessionNotFound if the user has no login session with the id.
+// QueryLoginSessionsByUserID returns the login sessions of a user, including
+// revoked and expired ones, in the order they were last seen, latest first.
+type LoginSessionRepository interface {
+	CreateLoginSession(context.Context, user.LoginSession) error
+	QueryLoginSessionByID(context.Context, string) (user.LoginSession, error)
+	QueryLoginSessionsByUserID(context.Context, string) ([]user.LoginSession, error)
+	SeeLoginSession(ctx context.Context, id, ip string, now, expires time.Time) error
+	RevokeLoginSession(ctx context.Context, userID, id string) error
+	RevokeUserLoginSessions(context.Context, string) error
+}
+
+// OrgRepository is an interface which is to be implemented by the layer
+// between user usecases and storages of organizations and their members.
+//
+// CreateOrg stores the organization together with the membership of its
+// first member. CreateMembership returns ErrNotFound if the user does not
+// exist and ErrUniqueMembership if the user is a member already.
+// DeleteMembership returns ErrMembershipNotFound if the user is not a member.
+// QueryMembershipsByUserID returns the memberships of a user in the order
+// they were created.
+//
+// CreateInvitation returns ErrNotFound if the user does not exist and
+// ErrUniqueInvitation if the user is invited to the organization already.
+// AcceptInvitation stores the membership and deletes the invitation at
+// once. It and the other invitation calls return ErrInvitationNotFound if
+// there is no invitation with the id. QueryInvitationsByUserID returns the
+// invitations of a user in the order they were created.
+type OrgRepository interface {
+	CreateOrg(context.Context, org.Org, org.Membership) error
+	QueryOrgsByUserID(context.Context, string) ([]org.Org, error)
+	CreateMembership(context.Context, org.Membership) error
+	QueryMembership(ctx context.Context, orgID, userID string) (org.Membership, error)
+	QueryMembershipsByUserID(context.Context, string) ([]org.Membership, error)
+	QueryMembershipsByOrgID(context.Context, string) ([]org.Membership, error)
+	DeleteMembership(ctx context.Context, orgID, userID string) error
+	CreateInvitation(context.Context, org.Invitation) error
+	QueryInvitationByID(context.Context, string) (org.Invitation, error)
+	QueryInvitationsByUserID(context.Context, string) ([]org.Invitation, error)
+	AcceptInvitation(ctx context.Context, id string, m org.Membership) error
+	DeleteInvitation(context.Context, string) error
 }
@@ -1,5 +1,7 @@
 package usecases
 
+import "time"
+
 // NewUser contains information needed to create a new User.
 type NewUser struct {
 	Name            string   `json:"name" validate:"required"`
@@ -22,3 +24,32 @@ type UpdateUser struct {
 	Password        *string  `json:"password"`
 	PasswordConfirm *string  `json:"password_confirm" validate:"omitempty,eqfield=Password"`
 }
+
+// ImportUser contains the information of a user to import. Users are
+// matched by email address: unknown addresses are created, known ones are
+// updated. Empty fields keep the values of updated users.
+type ImportUser struct {
+	Name     string   `json:"name"`
+	Email    string   `json:"email"`
+	Roles    []string `json:"roles"`
+	Password string   `json:"password"`
+}
+
+// NewAPIKey contains information needed to create a new APIKey.
+type NewAPIKey struct {
+	Name    string    `json:"name" validate:"required"`
+	Scopes  []string  `json:"scopes" validate:"required,min=1"`
+	Expires time.Time `json:"expires" validate:"required"`
+}
+
+// NewOrg contains information needed to create a new organization.
+type NewOrg struct {
+	Name string `json:"name" validate:"required"`
+}
+
+// NewMember contains information needed to make a user a member of the
+// organization of the session.
+type NewMember struct {
+	UserID string   `json:"user_id" validate:"required,uuid"`
+	Roles  []string `json:"roles" validate:"required,min=1"`
+}
@@ -0,0 +1,323 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/appinesshq/caservice/business/authz"
+	"github.com/appinesshq/caservice/business/user"
+	"github.com/appinesshq/caservice/foundation/totp"
+)
+
+// challengeDuration is how long a challenge can be completed after
+// authenticating with a password.
+const challengeDuration = 5 * time.Minute
+
+// maxChallengeAttempts is the number of codes that can be tried for a single
+// challenge. After that, the user has to authenticate with a password again.
+const maxChallengeAttempts = 5
+
+// totpSkew is the number of time steps codes may be off, to allow for clock
+// drift between the server and authenticator apps.
+const totpSkew = 1
+
+// ChallengeError is returned by Authenticate for users with two-factor
+// authentication enabled. The challenge must be completed with a code by
+// CompleteChallenge to get a Session.
+type ChallengeError struct {
+	Challenge string
+	Expires   time.Time
+}
+
+// Error implements the error interface.
+func (e *ChallengeError) Error() string {
+	return "two-factor authentication required"
+}
+
+// challenge stores a new challenge for the user and returns it as a
+// *ChallengeError.
+func (uc UserUseCases) challenge(ctx context.Context, u user.User, now time.Time) error {
+	if uc.TOTPSecrets == nil {
+		return ErrTOTPDisabled
+	}
+
+	expires := now.Add(challengeDuration)
+	ot, token, err := user.NewOneTimeToken(u.ID, user.PurposeTOTPChallenge, now, expires)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.TOTPChallenges.CreateOneTimeToken(ctx, ot); err != nil {
+		return err
+	}
+
+	return &ChallengeError{Challenge: token, Expires: expires}
+}
+
+// CompleteChallenge returns a Session after checking the code of the second
+// factor for a challenge returned by Authenticate. The code is a code of the
+// authenticator app or one of the recovery codes of the user. The ip is the
+// address of the client, it may be empty if unknown.
+//
+// Wrong codes count as failed attempts. If lockout is enabled, a *LockedError
+// is returned while the account or the client IP address is locked. A
+// challenge is invalid after maxChallengeAttempts codes were tried.
+func (uc UserUseCases) CompleteChallenge(ctx context.Context, challenge, code, ip string, now time.Time) (user.Session, error) {
+	if uc.TOTPSecrets == nil {
+		return user.Session{}, ErrTOTPDisabled
+	}
+	ctx = user.ContextForSystem(ctx)
+
+	ot, err := uc.TOTPChallenges.QueryOneTimeTokenByHash(ctx, user.HashToken(challenge))
+	if err != nil {
+		if errors.Is(err, ErrOneTimeTokenNotFound) {
+			return user.Session{}, ErrInvalidChallenge
+		}
+		return user.Session{}, err
+	}
+
+	if ot.Purpose != user.PurposeTOTPChallenge || ot.Used || ot.IsExpired(now) {
+		return user.Session{}, ErrInvalidChallenge
+	}
+
+	u, err := uc.Repo.QueryByID(ctx, ot.UserID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return user.Session{}, ErrInvalidChallenge
+		}
+		return user.Session{}, err
+	}
+
+	if err := uc.checkLockout(ctx, u.Email, ip, now); err != nil {
+		return user.Session{}, err
+	}
+
+	// Count the attempt before checking the code, so concurrent requests
+	// can't try more codes than allowed.
+	attempts, err := uc.TOTPChallenges.AttemptOneTimeToken(ctx, ot.ID)
+	if err != nil {
+		if errors.Is(err, ErrOneTimeTokenNotFound) {
+			return user.Session{}, ErrInvalidChallenge
+		}
+		return user.Session{}, err
+	}
+	if attempts > maxChallengeAttempts {
+		return user.Session{}, ErrInvalidChallenge
+	}
+
+	ok, err := uc.checkSecondFactor(&u, code, now)
+	if err != nil {
+		return user.Session{}, err
+	}
+	if !ok {
+		if err := uc.loginFailed(ctx, u.Email, ip, now); !errors.Is(err, ErrAuthenticationFailed) {
+			return user.Session{}, err
+		}
+		return user.Session{}, ErrInvalidTOTPCode
+	}
+
+	// Mark the challenge as used. This fails if a concurrent request
+	// completed the same challenge first.
+	if err := uc.TOTPChallenges.UseOneTimeToken(ctx, ot.ID); err != nil {
+		if errors.Is(err, ErrOneTimeTokenUsed) {
+			return user.Session{}, ErrInvalidChallenge
+		}
+		return user.Session{}, err
+	}
+
+	// Store the used time step or recovery code.
+	u.DateUpdated = now
+	if err := uc.Repo.Update(ctx, u); err != nil {
+		return user.Session{}, err
+	}
+
+	if err := uc.loginSucceeded(ctx, u.Email); err != nil {
+		return user.Session{}, err
+	}
+
+	return uc.newSession(ctx, u, "", now.Add(uc.SessionDuration))
+}
+
+// EnrollTOTP generates a new secret for the user of the session and returns
+// the otpauth:// URI to add it to an authenticator app. Two-factor
+// authentication is enabled once the first code is checked by EnableTOTP.
+func (uc UserUseCases) EnrollTOTP(ctx context.Context, now time.Time) (string, error) {
+	u, err := uc.totpUser(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if u.TOTPEnabled {
+		return "", ErrTOTPAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", err
+	}
+
+	before := u
+	u.TOTPSecret, err = uc.TOTPSecrets.Encrypt(secret)
+	if err != nil {
+		return "", fmt.Errorf("encrypting totp secret: %w", err)
+	}
+	u.DateUpdated = now
+
+	if err := uc.Repo.Update(ctx, u); err != nil {
+		return "", err
+	}
+
+	if err := uc.record(ctx, "user.EnrollTOTP", u.ID, before, u); err != nil {
+		return "", err
+	}
+
+	return totp.URI(uc.TOTPIssuer, u.Email, secret), nil
+}
+
+// EnableTOTP enables two-factor authentication for the user of the session
+// after checking the first code of the enrolled secret. The recovery codes
+// of the user are returned, they are not available later on.
+func (uc UserUseCases) EnableTOTP(ctx context.Context, code string, now time.Time) ([]string, error) {
+	u, err := uc.totpUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.TOTPEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	if len(u.TOTPSecret) == 0 {
+		return nil, ErrTOTPNotEnrolled
+	}
+
+	secret, err := uc.TOTPSecrets.Decrypt(u.TOTPSecret)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting totp secret: %w", err)
+	}
+
+	step, ok := totp.Validate(secret, code, now, totpSkew)
+	if !ok {
+		return nil, ErrInvalidTOTPCode
+	}
+
+	before := u
+	codes, err := u.NewRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	u.TOTPEnabled = true
+	u.TOTPLastStep = step
+	u.DateUpdated = now
+
+	if err := uc.Repo.Update(ctx, u); err != nil {
+		return nil, err
+	}
+
+	if err := uc.record(ctx, "user.EnableTOTP", u.ID, before, u); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// DisableTOTP disables two-factor authentication for the user of the session
+// after checking a code of the second factor or a recovery code.
+func (uc UserUseCases) DisableTOTP(ctx context.Context, code string, now time.Time) error {
+	u, err := uc.totpUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !u.TOTPEnabled {
+		return ErrTOTPNotEnrolled
+	}
+
+	before := u
+	ok, err := uc.checkSecondFactor(&u, code, now)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidTOTPCode
+	}
+
+	u.DisableTOTP()
+	u.DateUpdated = now
+
+	if err := uc.Repo.Update(ctx, u); err != nil {
+		return err
+	}
+
+	return uc.record(ctx, "user.DisableTOTP", u.ID, before, u)
+}
+
+// totpUser returns the current state of the user of the session, if it may
+// manage its two-factor authentication.
+func (uc UserUseCases) totpUser(ctx context.Context) (user.User, error) {
+	if uc.TOTPSecrets == nil {
+		return user.User{}, ErrTOTPDisabled
+	}
+
+	s, err := user.GetSession(ctx)
+	if err != nil {
+		return user.User{}, err
+	}
+
+	if err := authz.Authorize(authz.ActionUserManageTOTP, authz.SessionActor(s), authz.OwnedBy(s.User.ID)); err != nil {
+		return user.User{}, err
+	}
+
+	return uc.Repo.QueryByID(ctx, s.User.ID)
+}
+
+// checkSecondFactor reports whether the code is a valid code of the
+// authenticator app or a recovery code of the user. Codes of the app can be
+// used once, the used time step or recovery code is recorded in the user.
+func (uc UserUseCases) checkSecondFactor(u *user.User, code string, now time.Time) (bool, error) {
+	secret, err := uc.TOTPSecrets.Decrypt(u.TOTPSecret)
+	if err != nil {
+		return false, fmt.Errorf("decrypting totp secret: %w", err)
+	}
+
+	if step, ok := totp.Validate(secret, code, now, totpSkew); ok && step > u.TOTPLastStep {
+		u.TOTPLastStep = step
+		return true, nil
+	}
+
+	return u.UseRecoveryCode(code), nil
+}
+
+// newSession returns a Session for the user in the provided organization,
+// or in the organization the user joined first if orgID is empty. The user
+// has its roles within the organization. ErrAuthenticationFailed is returned
+// if the user is not a member of it.
+//
+// If admins must use two-factor authentication, admins without it get the
+// session of a regular user: they can enroll, but not administer.
+func (uc UserUseCases) newSession(ctx context.Context, u user.User, orgID string, expires time.Time) (user.Session, error) {
+	m, err := uc.membership(ctx, u.ID, orgID)
+	if err != nil {
+		return user.Session{}, err
+	}
+	u.Roles = m.Roles
+
+	if uc.RequireAdminTOTP && !u.TOTPEnabled {
+		roles := make([]string, 0, len(u.Roles))
+		for _, role := range u.Roles {
+			if role != user.RoleAdmin {
+				roles = append(roles, role)
+			}
+		}
+		if len(roles) == 0 {
+			roles = append(roles, user.RoleUser)
+		}
+		u.Roles = roles
+	}
+
+	s := user.NewSession(u, expires)
+	s.TenantID = m.OrgID
+
+	return s, nil
+}
@@ -4,58 +4,500 @@ package usecases
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
+	"github.com/appinesshq/caservice/business/audit"
+	"github.com/appinesshq/caservice/business/authz"
+	"github.com/appinesshq/caservice/business/org"
 	"github.com/appinesshq/caservice/business/user"
+	"github.com/appinesshq/caservice/foundation/encryption"
+	"github.com/appinesshq/caservice/foundation/mail"
 	"go.uber.org/zap"
 )
 
 var (
-	ErrUnauthorized         = errors.New("unauthorized")
-	ErrAuthenticationFailed = errors.New("authentication failed")
+	ErrUnauthorized               = authz.ErrUnauthorized
+	ErrAuthenticationFailed       = errors.New("authentication failed")
+	ErrRefreshTokenReused         = errors.New("refresh token reused")
+	ErrRefreshTokensDisabled      = errors.New("refresh tokens are not enabled")
+	ErrPasswordResetDisabled      = errors.New("password reset is not enabled")
+	ErrInvalidResetToken          = errors.New("invalid or expired password reset token")
+	ErrVerificationDisabled       = errors.New("email verification is not enabled")
+	ErrInvalidVerificationToken   = errors.New("invalid or expired verification token")
+	ErrUnverified                 = errors.New("email address is not verified")
+	ErrTOTPDisabled               = errors.New("two-factor authentication is not enabled")
+	ErrTOTPAlreadyEnabled         = errors.New("two-factor authentication is already enabled for the user")
+	ErrTOTPNotEnrolled            = errors.New("two-factor authentication is not enrolled for the user")
+	ErrInvalidTOTPCode            = errors.New("invalid two-factor authentication code")
+	ErrInvalidChallenge           = errors.New("invalid or expired challenge")
+	ErrExternalIdentitiesDisabled = errors.New("external identities are not enabled")
+	ErrIdentityNotLinked          = errors.New("identity is not linked to a user")
+	ErrAPIKeysDisabled            = errors.New("api keys are not enabled")
+	ErrInvalidScope               = errors.New("scopes must be roles of the user")
+	ErrInvalidExpiry              = errors.New("invalid expiry")
+	ErrLoginSessionsDisabled      = errors.New("login sessions are not enabled")
+	ErrImpersonationDisabled      = errors.New("impersonation is not enabled")
+	ErrDuplicateImport            = errors.New("email address is imported more than once")
+	ErrDeletedImport              = errors.New("user with this email address is deleted, restore it first")
+	ErrTokenRevocationDisabled    = errors.New("token revocation is not enabled")
 )
 
+// TokenRevoker is an interface which is to be implemented by the layer that
+// issues access tokens. It revokes all access tokens of a subject that were
+// issued up to the provided time.
+type TokenRevoker interface {
+	RevokeAll(ctx context.Context, subject string, now time.Time) error
+}
+
 // Config is used to configure UserUseCases.
 type Config struct {
 }
 
 // UserUseCases contain application logic for user entities.
 type UserUseCases struct {
-	Log             *zap.SugaredLogger
-	Repo            UserRepository
-	SessionDuration time.Duration
+	Log                   *zap.SugaredLogger
+	Repo                  UserRepository
+	Orgs                  OrgRepository
+	SessionDuration       time.Duration
+	RefreshTokens         RefreshTokenRepository
+	RefreshTokenDuration  time.Duration
+	OneTimeTokens         OneTimeTokenRepository
+	Mailer                mail.Mailer
+	ResetTokenDuration    time.Duration
+	VerificationKey       []byte
+	VerificationURL       string
+	VerificationDuration  time.Duration
+	RequireVerified       bool
+	LoginAttempts         LoginAttemptRepository
+	AccountLockout        user.LockoutPolicy
+	IPLockout             user.LockoutPolicy
+	TOTPChallenges        OneTimeTokenRepository
+	TOTPSecrets           *encryption.Box
+	TOTPIssuer            string
+	RequireAdminTOTP      bool
+	Identities            IdentityRepository
+	AutoProvision         bool
+	APIKeys               APIKeyRepository
+	APIKeyMaxDuration     time.Duration
+	PasswordPolicy        user.PasswordPolicy
+	LoginSessions         LoginSessionRepository
+	ImpersonationDuration time.Duration
+	Tokens                TokenRevoker
+	Audit                 audit.Recorder
 }
 
 // New returns an initialized UserUseCases.
-// Requires a logger, user repository and user session duration as input.
-func New(log *zap.SugaredLogger, r UserRepository, s time.Duration) UserUseCases {
-	return UserUseCases{Log: log, Repo: r, SessionDuration: s}
+// Requires a logger, user repository, organization repository and user
+// session duration as input. Optional features are enabled with the
+// provided options.
+func New(log *zap.SugaredLogger, r UserRepository, o OrgRepository, s time.Duration, options ...func(uc *UserUseCases)) UserUseCases {
+	uc := UserUseCases{Log: log, Repo: r, Orgs: o, SessionDuration: s}
+	for _, option := range options {
+		option(&uc)
+	}
+	return uc
+}
+
+// WithRefreshTokens enables refresh tokens, which are stored in the provided
+// repository and expire after the provided duration.
+func WithRefreshTokens(r RefreshTokenRepository, d time.Duration) func(uc *UserUseCases) {
+	return func(uc *UserUseCases) {
+		uc.RefreshTokens = r
+		uc.RefreshTokenDuration = d
+	}
+}
+
+// WithPasswordReset enables password resets. Reset tokens are stored in the
+// provided repository, sent with the provided mailer and expire after the
+// provided duration.
+func WithPasswordReset(r OneTimeTokenRepository, m mail.Mailer, d time.Duration) func(uc *UserUseCases) {
+	return func(uc *UserUseCases) {
+		uc.OneTimeTokens = r
+		uc.Mailer = m
+		uc.ResetTokenDuration = d
+	}
+}
+
+// WithEmailVerification enables email verification. Verification links
+// point to the provided URL, carry a token signed with the provided key,
+// are sent with the provided mailer and expire after the provided duration.
+func WithEmailVerification(m mail.Mailer, key []byte, url string, d time.Duration) func(uc *UserUseCases) {
+	return func(uc *UserUseCases) {
+		uc.Mailer = m
+		uc.VerificationKey = key
+		uc.VerificationURL = url
+		uc.VerificationDuration = d
+	}
+}
+
+// WithRequireVerified makes Authenticate refuse users whose email address
+// is not verified.
+func WithRequireVerified() func(uc *UserUseCases) {
+	return func(uc *UserUseCases) {
+		uc.RequireVerified = true
+	}
+}
+
+// WithLockout enables brute-force protection for Authenticate. Failed
+// attempts are tracked per account and per client IP address in the provided
+// repository and delay further attempts following the provided policies.
+func WithLockout(r LoginAttemptRepository, account, ip user.LockoutPolicy) func(uc *UserUseCases) {
+	return func(uc *UserUseCases) {
+		uc.LoginAttempts = r
+		uc.AccountLockout = account
+		uc.IPLockout = ip
+	}
+}
+
+// WithPasswordPolicy requires new passwords to meet the provided policy.
+func WithPasswordPolicy(p user.PasswordPolicy) func(uc *UserUseCases) {
+	return func(uc *UserUseCases) {
+		uc.PasswordPolicy = p
+	}
+}
+
+// WithLoginSessions enables tracking of login sessions, which are stored in
+// the provided repository.
+func WithLoginSessions(r LoginSessionRepository) func(uc *UserUseCases) {
+	return func(uc *UserUseCases) {
+		uc.LoginSessions = r
+	}
+}
+
+// WithImpersonation enables admins to impersonate other users. Impersonated
+// sessions expire after the provided duration.
+func WithImpersonation(d time.Duration) func(uc *UserUseCases) {
+	return func(uc *UserUseCases) {
+		uc.ImpersonationDuration = d
+	}
+}
+
+// WithTOTP enables two-factor authentication with time-based one-time
+// passwords. Challenges are stored in the provided repository, the secrets of
+// users are encrypted with the provided box and authenticator apps show the
+// provided issuer.
+func WithTOTP(r OneTimeTokenRepository, b *encryption.Box, issuer string) func(uc *UserUseCases) {
+	return func(uc *UserUseCases) {
+		uc.TOTPChallenges = r
+		uc.TOTPSecrets = b
+		uc.TOTPIssuer = issuer
+	}
+}
+
+// WithRequireAdminTOTP makes sessions of admins without two-factor
+// authentication sessions of regular users, so a password alone does not
+// grant admin privileges.
+func WithRequireAdminTOTP() func(uc *UserUseCases) {
+	return func(uc *UserUseCases) {
+		uc.RequireAdminTOTP = true
+	}
+}
+
+// WithExternalIdentities enables authentication by external identity
+// providers. Identities are linked to users in the provided repository. With
+// auto provisioning, users are created for identities that cannot be linked
+// to an existing user.
+func WithExternalIdentities(r IdentityRepository, autoProvision bool) func(uc *UserUseCases) {
+	return func(uc *UserUseCases) {
+		uc.Identities = r
+		uc.AutoProvision = autoProvision
+	}
+}
+
+// WithAPIKeys enables API keys for machine clients, which are stored in the
+// provided repository and expire at most after the provided duration.
+func WithAPIKeys(r APIKeyRepository, maxDuration time.Duration) func(uc *UserUseCases) {
+	return func(uc *UserUseCases) {
+		uc.APIKeys = r
+		uc.APIKeyMaxDuration = maxDuration
+	}
+}
+
+// WithTokenRevocation enables revoking the access tokens of users with the
+// provided revoker, when they are signed out everywhere.
+func WithTokenRevocation(r TokenRevoker) func(uc *UserUseCases) {
+	return func(uc *UserUseCases) {
+		uc.Tokens = r
+	}
+}
+
+// WithAudit records all changes made by the usecases in the audit trail of
+// the provided recorder.
+func WithAudit(r audit.Recorder) func(uc *UserUseCases) {
+	return func(uc *UserUseCases) {
+		uc.Audit = r
+	}
 }
 
 // Authenticate returns a Session after succesfully authenticating a user by email and password.
-func (uc UserUseCases) Authenticate(ctx context.Context, email, password string, now time.Time) (user.Session, error) {
+// The ip is the address of the client, it may be empty if unknown.
+// When verified email addresses are required, ErrUnverified is returned for
+// users that did not verify their email address yet.
+//
+// If lockout is enabled, a *LockedError is returned without checking the
+// password while the account or the client IP address is locked.
+//
+// For users with two-factor authentication enabled, a *ChallengeError is
+// returned instead of a Session. The challenge must be completed with
+// CompleteChallenge.
+func (uc UserUseCases) Authenticate(ctx context.Context, email, password, ip string, now time.Time) (user.Session, error) {
+	// Nobody is signed in yet, so the user is looked up by the system.
+	ctx = user.ContextForSystem(ctx)
+
+	if err := uc.checkLockout(ctx, email, ip, now); err != nil {
+		return user.Session{}, err
+	}
+
 	u, err := uc.Repo.QueryByEmail(ctx, email)
 	if err != nil {
-		return user.Session{}, ErrAuthenticationFailed
+		if !errors.Is(err, ErrNotFound) {
+			return user.Session{}, err
+		}
+		return user.Session{}, uc.loginFailed(ctx, email, ip, now)
 	}
 
 	if !u.HasPassword(password) {
+		return user.Session{}, uc.loginFailed(ctx, email, ip, now)
+	}
+	u = uc.rehashPassword(ctx, u, password)
+
+	if uc.RequireVerified && !u.Verified {
+		return user.Session{}, ErrUnverified
+	}
+
+	// Failures are only forgotten once the second factor is checked as well,
+	// otherwise the password would allow guessing codes endlessly.
+	if u.TOTPEnabled {
+		return user.Session{}, uc.challenge(ctx, u, now)
+	}
+
+	if err := uc.loginSucceeded(ctx, email); err != nil {
+		return user.Session{}, err
+	}
+
+	return uc.newSession(ctx, u, "", now.Add(uc.SessionDuration))
+}
+
+// Identify returns a Session for an already authenticated user in the
+// provided organization, for example the subject and tenant of a validated
+// token. The user and its membership are loaded from the repositories, so
+// the session reflects the current state of the user.
+func (uc UserUseCases) Identify(ctx context.Context, id, orgID string, expires time.Time) (user.Session, error) {
+	ctx = user.ContextForSystem(ctx)
+
+	u, err := uc.Repo.QueryByID(ctx, id)
+	if err != nil {
+		return user.Session{}, ErrAuthenticationFailed
+	}
+
+	s, err := uc.newSession(ctx, u, orgID, expires)
+	if err != nil {
+		return user.Session{}, err
+	}
+	if !s.IsValid() {
 		return user.Session{}, ErrAuthenticationFailed
 	}
 
-	return user.NewSession(u, now.Add(uc.SessionDuration)), nil
+	return s, nil
 }
 
-// Create inserts the provided user at the repository.
+// IssueRefreshToken stores a new refresh token for the user of the provided
+// session and returns the opaque token for the client. The token starts a
+// new family of refresh tokens, with the id of the login session of the
+// provided session if there is one, and refreshes sessions in the
+// organization of the provided session.
+func (uc UserUseCases) IssueRefreshToken(ctx context.Context, s user.Session, now time.Time) (string, error) {
+	if uc.RefreshTokens == nil {
+		return "", ErrRefreshTokensDisabled
+	}
+
+	rt, token, err := user.NewRefreshToken(s.User.ID, s.TenantID, s.LoginSessionID, now, now.Add(uc.RefreshTokenDuration))
+	if err != nil {
+		return "", err
+	}
+
+	if err := uc.RefreshTokens.CreateRefreshToken(ctx, rt); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Refresh exchanges a refresh token for a new Session and a new refresh
+// token in the same family. Every refresh token can be exchanged once.
+//
+// A token that is presented again after it was exchanged is a sign that it
+// was stolen, so the whole family is revoked and ErrRefreshTokenReused is
+// returned. Both the legitimate client and the attacker must authenticate
+// again.
+//
+// If login sessions are tracked, the token family must belong to an active
+// login session, which is extended and recorded as seen from the client
+// with the provided IP address.
+func (uc UserUseCases) Refresh(ctx context.Context, token, ip string, now time.Time) (user.Session, string, error) {
+	if uc.RefreshTokens == nil {
+		return user.Session{}, "", ErrRefreshTokensDisabled
+	}
+	ctx = user.ContextForSystem(ctx)
+
+	rt, err := uc.RefreshTokens.QueryRefreshTokenByHash(ctx, user.HashToken(token))
+	if err != nil {
+		if errors.Is(err, ErrRefreshTokenNotFound) {
+			return user.Session{}, "", ErrAuthenticationFailed
+		}
+		return user.Session{}, "", err
+	}
+
+	if rt.Revoked {
+		return user.Session{}, "", ErrAuthenticationFailed
+	}
+
+	if rt.Used {
+		return user.Session{}, "", uc.revokeFamily(ctx, rt)
+	}
+
+	if rt.IsExpired(now) {
+		return user.Session{}, "", ErrAuthenticationFailed
+	}
+
+	var ls user.LoginSession
+	if uc.LoginSessions != nil {
+		ls, err = uc.LoginSessions.QueryLoginSessionByID(ctx, rt.FamilyID)
+		switch {
+		case errors.Is(err, ErrLoginSessionNotFound):
+			// The family was started before login sessions were tracked.
+		case err != nil:
+			return user.Session{}, "", err
+		case !ls.IsActive(now):
+			return user.Session{}, "", ErrAuthenticationFailed
+		}
+	}
+
+	// Mark the token as used. This fails if a concurrent request
+	// exchanged the same token first.
+	if err := uc.RefreshTokens.UseRefreshToken(ctx, rt.ID); err != nil {
+		if errors.Is(err, ErrRefreshTokenUsed) {
+			return user.Session{}, "", uc.revokeFamily(ctx, rt)
+		}
+		return user.Session{}, "", err
+	}
+
+	u, err := uc.Repo.QueryByID(ctx, rt.UserID)
+	if err != nil {
+		return user.Session{}, "", ErrAuthenticationFailed
+	}
+
+	// Users that left the organization can't refresh their session in it.
+	s, err := uc.newSession(ctx, u, rt.OrgID, now.Add(uc.SessionDuration))
+	if err != nil {
+		return user.Session{}, "", err
+	}
+
+	next, nextToken, err := user.NewRefreshToken(u.ID, rt.OrgID, rt.FamilyID, now, now.Add(uc.RefreshTokenDuration))
+	if err != nil {
+		return user.Session{}, "", err
+	}
+
+	if err := uc.RefreshTokens.CreateRefreshToken(ctx, next); err != nil {
+		return user.Session{}, "", err
+	}
+
+	if ls.ID != "" {
+		if err := uc.LoginSessions.SeeLoginSession(ctx, ls.ID, ip, now, now.Add(uc.RefreshTokenDuration)); err != nil {
+			return user.Session{}, "", err
+		}
+		s.LoginSessionID = ls.ID
+	}
+
+	return s, nextToken, nil
+}
+
+// revokeFamily revokes all refresh tokens in the family of the provided
+// token, after the token has been reused. The login session of the family
+// is revoked as well, so its access tokens are refused.
+func (uc UserUseCases) revokeFamily(ctx context.Context, rt user.RefreshToken) error {
+	uc.Log.Warnw("refresh token reused", "userid", rt.UserID, "familyid", rt.FamilyID)
+
+	if err := uc.RefreshTokens.RevokeRefreshTokenFamily(ctx, rt.FamilyID); err != nil {
+		return err
+	}
+
+	if uc.LoginSessions != nil {
+		err := uc.LoginSessions.RevokeLoginSession(ctx, rt.UserID, rt.FamilyID)
+		if err != nil && !errors.Is(err, ErrLoginSessionNotFound) {
+			return err
+		}
+	}
+
+	return ErrRefreshTokenReused
+}
+
+// RevokeTokens revokes all access tokens and refresh tokens of a user, so
+// none of them can be used anymore. ErrNotFound is returned for users outside
+// the organization of the session.
+func (uc UserUseCases) RevokeTokens(ctx context.Context, userID string, now time.Time) error {
+	if uc.Tokens == nil {
+		return ErrTokenRevocationDisabled
+	}
+
+	s, err := user.GetSession(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := authz.Authorize(authz.ActionUserRevokeTokens, authz.SessionActor(s), authz.OwnedBy(userID)); err != nil {
+		return err
+	}
+
+	if _, err := uc.Repo.QueryByID(ctx, userID); err != nil {
+		return err
+	}
+	if err := uc.authorizeAccount(ctx, s, userID); err != nil {
+		return err
+	}
+
+	if err := uc.revokeTokens(ctx, userID, now); err != nil {
+		return err
+	}
+
+	return uc.record(ctx, "user.RevokeTokens", userID, nil, nil)
+}
+
+// revokeTokens revokes all access tokens and refresh tokens of a user, as far
+// as these are enabled.
+func (uc UserUseCases) revokeTokens(ctx context.Context, userID string, now time.Time) error {
+	if uc.Tokens != nil {
+		if err := uc.Tokens.RevokeAll(ctx, userID, now); err != nil {
+			return err
+		}
+	}
+
+	if uc.RefreshTokens != nil {
+		if err := uc.RefreshTokens.RevokeUserRefreshTokens(ctx, userID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Create inserts the provided user at the repository and makes it a member
+// of the organization of the session, with the provided roles.
+// If email verification is enabled, a verification link is sent to
+// the email address of the user.
 func (uc UserUseCases) Create(ctx context.Context, n NewUser, now time.Time) (user.User, error) {
 	s, err := user.GetSession(ctx)
 	if err != nil {
 		return user.User{}, err
 	}
 
-	// Only ADMIN can do this action.
-	if !s.UserHasRole(user.RoleAdmin) {
-		return user.User{}, ErrAuthenticationFailed
+	if err := authz.Authorize(authz.ActionUserCreate, authz.SessionActor(s), authz.Resource{}); err != nil {
+		return user.User{}, err
+	}
+
+	if err := uc.PasswordPolicy.Check(n.Password, n.Name, n.Email); err != nil {
+		return user.User{}, err
 	}
 
 	u, err := user.NewWithID(n.Name, n.Email, n.Password, n.Roles, now)
@@ -67,6 +509,16 @@ func (uc UserUseCases) Create(ctx context.Context, n NewUser, now time.Time) (us
 		return user.User{}, err
 	}
 
+	if err := uc.join(ctx, s.TenantID, u, now); err != nil {
+		return user.User{}, err
+	}
+
+	if err := uc.record(ctx, "user.Create", u.ID, nil, u); err != nil {
+		return user.User{}, err
+	}
+
+	uc.sendVerification(ctx, u, now)
+
 	return u, nil
 }
 
@@ -75,18 +527,28 @@ func (uc UserUseCases) Create(ctx context.Context, n NewUser, now time.Time) (us
 // in any other case the user will get only the USER role.
 //
 // Unlike Create, Register requires no admin priviliges. It is meant
-// to register the first admin of the system and user signups.
+// to register the first admin of the system and user signups. Registered
+// users become a member of the default organization.
+//
+// New users start unverified. If email verification is enabled,
+// a verification link is sent to the email address of the user.
 func (uc UserUseCases) Register(ctx context.Context, n NewUser, now time.Time) (user.User, error) {
-	users, err := uc.Repo.Query(ctx, 1, 1)
+	// Deleted users count too, so deleting the first admin doesn't hand
+	// the admin role to whoever registers next.
+	count, err := uc.Repo.CountAll(ctx)
 	if err != nil {
 		return user.User{}, err
 	}
-	if len(users) == 0 {
+	if count == 0 {
 		n.Roles = []string{user.RoleAdmin, user.RoleUser}
 	} else {
 		n.Roles = []string{user.RoleUser}
 	}
 
+	if err := uc.PasswordPolicy.Check(n.Password, n.Name, n.Email); err != nil {
+		return user.User{}, err
+	}
+
 	u, err := user.NewWithID(n.Name, n.Email, n.Password, n.Roles, now)
 	if err != nil {
 		return user.User{}, err
@@ -96,22 +558,55 @@ func (uc UserUseCases) Register(ctx context.Context, n NewUser, now time.Time) (
 		return user.User{}, err
 	}
 
+	if err := uc.join(ctx, org.DefaultID, u, now); err != nil {
+		return user.User{}, err
+	}
+
+	if err := uc.record(ctx, "user.Register", u.ID, nil, u); err != nil {
+		return user.User{}, err
+	}
+
+	uc.sendVerification(ctx, u, now)
+
 	return u, nil
 }
 
-// Query retrieves all users from the repository,
-func (uc UserUseCases) Query(ctx context.Context, pageNumber int, rowsPerPage int) ([]user.User, error) {
+// Query retrieves a page of the users of the organization of the session
+// that are selected by the filter from the repository.
+func (uc UserUseCases) Query(ctx context.Context, f user.QueryFilter, pageNumber int, rowsPerPage int) ([]user.User, error) {
 	s, err := user.GetSession(ctx)
 	if err != nil {
 		return []user.User{}, err
 	}
 
-	// Only ADMIN can do this action.
-	if !s.UserHasRole(user.RoleAdmin) {
-		return []user.User{}, ErrUnauthorized
+	if err := authz.Authorize(authz.ActionUserQuery, authz.SessionActor(s), authz.Resource{}); err != nil {
+		return []user.User{}, err
+	}
+
+	if err := f.Validate(); err != nil {
+		return []user.User{}, fmt.Errorf("validation error: %w", err)
 	}
 
-	return uc.Repo.Query(ctx, pageNumber, rowsPerPage)
+	return uc.Repo.Query(ctx, f, pageNumber, rowsPerPage)
+}
+
+// Count returns the number of users of the organization of the session that
+// are selected by the filter.
+func (uc UserUseCases) Count(ctx context.Context, f user.QueryFilter) (int, error) {
+	s, err := user.GetSession(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := authz.Authorize(authz.ActionUserQuery, authz.SessionActor(s), authz.Resource{}); err != nil {
+		return 0, err
+	}
+
+	if err := f.Validate(); err != nil {
+		return 0, fmt.Errorf("validation error: %w", err)
+	}
+
+	return uc.Repo.Count(ctx, f)
 }
 
 // QueryByID retrieves a single user from the repository by its id.
@@ -121,9 +616,8 @@ func (uc UserUseCases) QueryByID(ctx context.Context, id string) (user.User, err
 		return user.User{}, err
 	}
 
-	// Only ADMIN or owner can do this action.
-	if !s.UserHasRole(user.RoleAdmin) || s.User.ID != id {
-		return user.User{}, ErrUnauthorized
+	if err := authz.Authorize(authz.ActionUserRead, authz.SessionActor(s), authz.OwnedBy(id)); err != nil {
+		return user.User{}, err
 	}
 
 	return uc.Repo.QueryByID(ctx, id)
@@ -136,40 +630,239 @@ func (uc UserUseCases) QueryByEmail(ctx context.Context, email string) (user.Use
 		return user.User{}, err
 	}
 
-	// Only ADMIN or owner can do this action.
-	if !s.UserHasRole(user.RoleAdmin) || s.User.Email != email {
-		return user.User{}, ErrUnauthorized
+	// The user is not loaded before authorization, so the existence of
+	// other email addresses is not revealed.
+	var r authz.Resource
+	if s.User.Email == email {
+		r = authz.OwnedBy(s.User.ID)
+	}
+	if err := authz.Authorize(authz.ActionUserRead, authz.SessionActor(s), r); err != nil {
+		return user.User{}, err
 	}
 
 	return uc.Repo.QueryByEmail(ctx, email)
 }
 
-// Update updates the provided user at the repository.
-func (uc UserUseCases) Update(ctx context.Context, u user.User) error {
+// Update applies the changes of the patch to the user with the id at the
+// repository and returns the updated user. Users can update themselves, but
+// changing roles requires an admin. New roles revoke the access tokens of the
+// user. A new password is hashed and ends all sessions of the user, a new
+// email address must be verified again.
+// Credentials can't be changed in impersonated sessions.
+//
+// If version is not zero, the user is only updated if it still is that
+// version. ErrConflict is returned if it is not, or if the user was changed
+// while it was being updated.
+func (uc UserUseCases) Update(ctx context.Context, id string, upd UpdateUser, version int, now time.Time) (user.User, error) {
+	s, err := user.GetSession(ctx)
+	if err != nil {
+		return user.User{}, err
+	}
+
+	actor := authz.SessionActor(s)
+	if err := authz.Authorize(authz.ActionUserUpdate, actor, authz.OwnedBy(id)); err != nil {
+		return user.User{}, err
+	}
+
+	current, err := uc.Repo.QueryByID(ctx, id)
+	if err != nil {
+		return user.User{}, err
+	}
+	if version != 0 && current.Version != version {
+		return user.User{}, ErrConflict
+	}
+
+	u := current
+	if upd.Name != nil {
+		u.Name = *upd.Name
+	}
+	emailChanged := upd.Email != nil && *upd.Email != current.Email
+	if emailChanged || upd.Password != nil {
+		if err := authz.Authorize(authz.ActionUserCredentials, actor, authz.OwnedBy(id)); err != nil {
+			return user.User{}, err
+		}
+		if err := uc.authorizeAccount(ctx, s, id); err != nil {
+			return user.User{}, err
+		}
+	}
+	if emailChanged {
+		u.Email = *upd.Email
+		u.Verified = false
+	}
+	rolesChanged := upd.Roles != nil && !equalRoles(current.Roles, upd.Roles)
+	if rolesChanged {
+		if err := authz.Authorize(authz.ActionUserUpdateRoles, actor, authz.OwnedBy(id)); err != nil {
+			return user.User{}, err
+		}
+	}
+	if upd.Roles != nil {
+		u.Roles = upd.Roles
+	}
+	if upd.Password != nil {
+		if err := uc.PasswordPolicy.Check(*upd.Password, u.Name, u.Email); err != nil {
+			return user.User{}, err
+		}
+		if err := u.SetPassword(*upd.Password); err != nil {
+			return user.User{}, err
+		}
+	}
+	u.DateUpdated = now
+
+	if err := u.Validate(); err != nil {
+		return user.User{}, fmt.Errorf("validation error: %w", err)
+	}
+
+	if err := uc.Repo.Update(ctx, u); err != nil {
+		return user.User{}, err
+	}
+	u.Version++
+
+	if err := uc.record(ctx, "user.Update", u.ID, current, u); err != nil {
+		return user.User{}, err
+	}
+
+	// Tokens issued before carry the old roles.
+	if rolesChanged && uc.Tokens != nil {
+		if err := uc.Tokens.RevokeAll(ctx, u.ID, now); err != nil {
+			return user.User{}, err
+		}
+	}
+
+	// Sessions that were authenticated with the old password end.
+	if upd.Password != nil {
+		if err := uc.signOut(ctx, u.ID); err != nil {
+			return user.User{}, err
+		}
+	}
+
+	if emailChanged {
+		uc.sendVerification(ctx, u, now)
+	}
+
+	return u, nil
+}
+
+// Delete marks a user as deleted by its id and revokes its tokens. Deleted
+// users can't sign in and are left out of queries, until they are restored
+// or purged. It returns ErrNotFound if there is no user with the id in the
+// organization of the session.
+func (uc UserUseCases) Delete(ctx context.Context, id string, now time.Time) error {
 	s, err := user.GetSession(ctx)
 	if err != nil {
 		return err
 	}
 
-	// Only ADMIN or owner can do this action.
-	if !s.UserHasRole(user.RoleAdmin) || s.User.ID != u.ID {
-		return ErrUnauthorized
+	if err := authz.Authorize(authz.ActionUserDelete, authz.SessionActor(s), authz.OwnedBy(id)); err != nil {
+		return err
+	}
+
+	before, err := uc.Repo.QueryByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := uc.authorizeAccount(ctx, s, id); err != nil {
+		return err
+	}
+
+	if err := uc.Repo.Delete(ctx, id, now); err != nil {
+		return err
+	}
+
+	after := before
+	after.DateDeleted = &now
+	if err := uc.record(ctx, "user.Delete", id, before, after); err != nil {
+		return err
+	}
+
+	return uc.revokeTokens(ctx, id, now)
+}
+
+// QueryDeleted retrieves the deleted users of the organization of the
+// session from the repository.
+func (uc UserUseCases) QueryDeleted(ctx context.Context, pageNumber int, rowsPerPage int) ([]user.User, error) {
+	s, err := user.GetSession(ctx)
+	if err != nil {
+		return []user.User{}, err
+	}
+
+	if err := authz.Authorize(authz.ActionUserQuery, authz.SessionActor(s), authz.Resource{}); err != nil {
+		return []user.User{}, err
 	}
 
-	return uc.Repo.Update(ctx, u)
+	return uc.Repo.QueryDeleted(ctx, pageNumber, rowsPerPage)
 }
 
-// Delete removes a user from the repository by its id.
-func (uc UserUseCases) Delete(ctx context.Context, id string) error {
+// Restore undoes the deletion of a user by its id. It returns ErrNotFound if
+// there is no deleted user with the id.
+func (uc UserUseCases) Restore(ctx context.Context, id string, now time.Time) error {
 	s, err := user.GetSession(ctx)
 	if err != nil {
 		return err
 	}
 
-	// Only ADMIN or owner can do this action.
-	if !s.UserHasRole(user.RoleAdmin) || s.User.ID != id {
-		return ErrUnauthorized
+	if err := authz.Authorize(authz.ActionUserRestore, authz.SessionActor(s), authz.OwnedBy(id)); err != nil {
+		return err
+	}
+	if err := uc.authorizeAccount(ctx, s, id); err != nil {
+		return err
+	}
+
+	if err := uc.Repo.Restore(ctx, id, now); err != nil {
+		return err
+	}
+
+	// The date the user was deleted is gone, so only the mark is recorded.
+	return uc.record(ctx, "user.Restore", id, map[string]bool{"Deleted": true}, map[string]bool{"Deleted": false})
+}
+
+// PurgeDeleted removes the users that were deleted before the provided time
+// for good, together with their data, and returns their ids. It is run by
+// the system after the retention period, not on behalf of a user.
+func (uc UserUseCases) PurgeDeleted(ctx context.Context, before time.Time) ([]string, error) {
+	ctx = user.ContextForSystem(ctx)
+
+	ids, err := uc.Repo.Purge(ctx, before)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		if err := uc.record(ctx, "user.Purge", id, map[string]any{"ID": id}, nil); err != nil {
+			return ids, err
+		}
+	}
+
+	return ids, nil
+}
+
+// record appends a change made by a usecase to the audit trail, if auditing
+// is enabled.
+func (uc UserUseCases) record(ctx context.Context, usecase, entityID string, before, after any) error {
+	if uc.Audit == nil {
+		return nil
+	}
+
+	if err := uc.Audit.Record(ctx, usecase, entityID, before, after); err != nil {
+		return fmt.Errorf("recording %s: %w", usecase, err)
+	}
+	return nil
+}
+
+// equalRoles reports whether both sets of roles are the same.
+func equalRoles(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	roles := make(map[string]bool, len(a))
+	for _, role := range a {
+		roles[role] = true
+	}
+	for _, role := range b {
+		if !roles[role] {
+			return false
+		}
 	}
 
-	return uc.Repo.Delete(ctx, id)
+	return true
 }
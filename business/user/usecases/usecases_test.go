@@ -0,0 +1,1786 @@
+package usecases_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/appinesshq/caservice/business/audit"
+	audits "github.com/appinesshq/caservice/business/audit/usecases"
+	"github.com/appinesshq/caservice/business/org"
+	"github.com/appinesshq/caservice/business/user"
+	"github.com/appinesshq/caservice/business/user/usecases"
+	auditmem "github.com/appinesshq/caservice/data/audit/mem"
+	loginattempt "github.com/appinesshq/caservice/data/loginattempt/mem"
+	"github.com/appinesshq/caservice/data/user/mem"
+	"github.com/appinesshq/caservice/foundation/breached"
+	fctx "github.com/appinesshq/caservice/foundation/context"
+	"github.com/appinesshq/caservice/foundation/encryption"
+	"github.com/appinesshq/caservice/foundation/mail"
+	"github.com/appinesshq/caservice/foundation/passhash"
+	"github.com/appinesshq/caservice/foundation/tests"
+	"github.com/appinesshq/caservice/foundation/totp"
+	"github.com/appinesshq/caservice/foundation/validation"
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+)
+
+var now = time.Now()
+
+func TestRefreshTokens(t *testing.T) {
+	t.Parallel()
+
+	t.Log("Given the need to exchange refresh tokens for new tokens.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen rotating the refresh tokens of a single user.", testID)
+		{
+			ctx := context.Background()
+			store := mem.New()
+			uc := usecases.New(zap.NewNop().Sugar(), store, store, time.Minute, usecases.WithRefreshTokens(store, time.Hour))
+
+			u, _ := user.NewWithID("Test User", "test@example.com", "gophers", []string{user.RoleUser}, now)
+			if err := createMember(ctx, store, u); err != nil {
+				t.Fatalf("\t%s\tShould be able to store a user: %v.", tests.Failed, err)
+			}
+
+			session, err := uc.Authenticate(ctx, u.Email, "gophers", "", now)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to authenticate: %v.", tests.Failed, err)
+			}
+
+			first, err := uc.IssueRefreshToken(ctx, session, now)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to issue a refresh token: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to issue a refresh token.", tests.Success)
+
+			refreshed, second, err := uc.Refresh(ctx, first, "", now.Add(time.Minute))
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to exchange the refresh token: %v.", tests.Failed, err)
+			}
+			if refreshed.User.ID != u.ID || second == first {
+				t.Fatalf("\t%s\tShould get a session for the user and a new refresh token.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould get a session for the user and a new refresh token.", tests.Success)
+
+			if _, _, err := uc.Refresh(ctx, first, "", now.Add(time.Minute)); !errors.Is(err, usecases.ErrRefreshTokenReused) {
+				t.Fatalf("\t%s\tShould detect reuse of a refresh token, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould detect reuse of a refresh token.", tests.Success)
+
+			if _, _, err := uc.Refresh(ctx, second, "", now.Add(time.Minute)); !errors.Is(err, usecases.ErrAuthenticationFailed) {
+				t.Fatalf("\t%s\tShould revoke the whole family after reuse, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould revoke the whole family after reuse.", tests.Success)
+
+			third, err := uc.IssueRefreshToken(ctx, session, now)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to issue a refresh token: %v.", tests.Failed, err)
+			}
+			if _, _, err := uc.Refresh(ctx, third, "", now.Add(2*time.Hour)); !errors.Is(err, usecases.ErrAuthenticationFailed) {
+				t.Fatalf("\t%s\tShould not accept an expired refresh token, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not accept an expired refresh token.", tests.Success)
+		}
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	t.Parallel()
+
+	t.Log("Given the need to reset a forgotten password.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen resetting the password of a single user.", testID)
+		{
+			ctx := context.Background()
+			store := mem.New()
+			mailer := &mailRecorder{}
+			uc := usecases.New(zap.NewNop().Sugar(), store, store, time.Minute, usecases.WithPasswordReset(store, mailer, time.Hour))
+
+			u, _ := user.NewWithID("Test User", "test@example.com", "gophers", []string{user.RoleUser}, now)
+			if err := createMember(ctx, store, u); err != nil {
+				t.Fatalf("\t%s\tShould be able to store a user: %v.", tests.Failed, err)
+			}
+
+			if err := uc.RequestPasswordReset(ctx, "unknown@example.com", now); err != nil || len(mailer.messages) != 0 {
+				t.Fatalf("\t%s\tShould silently ignore unknown email addresses: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould silently ignore unknown email addresses.", tests.Success)
+
+			if err := uc.RequestPasswordReset(ctx, u.Email, now); err != nil {
+				t.Fatalf("\t%s\tShould be able to request a password reset: %v.", tests.Failed, err)
+			}
+			if len(mailer.messages) != 1 || mailer.messages[0].To != u.Email {
+				t.Fatalf("\t%s\tShould send the reset token to the user.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould send the reset token to the user.", tests.Success)
+
+			token := tokenFromBody(mailer.messages[0].Body)
+
+			if _, err := uc.ResetPassword(ctx, token, "new gophers", now.Add(2*time.Hour)); !errors.Is(err, usecases.ErrInvalidResetToken) {
+				t.Fatalf("\t%s\tShould not accept an expired token, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not accept an expired token.", tests.Success)
+
+			if _, err := uc.ResetPassword(ctx, token, "new gophers", now.Add(time.Minute)); err != nil {
+				t.Fatalf("\t%s\tShould be able to reset the password: %v.", tests.Failed, err)
+			}
+			if _, err := uc.Authenticate(ctx, u.Email, "new gophers", "", now); err != nil {
+				t.Fatalf("\t%s\tShould be able to authenticate with the new password: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to authenticate with the new password.", tests.Success)
+
+			if _, err := uc.ResetPassword(ctx, token, "evil gophers", now.Add(time.Minute)); !errors.Is(err, usecases.ErrInvalidResetToken) {
+				t.Fatalf("\t%s\tShould not accept a token twice, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not accept a token twice.", tests.Success)
+		}
+	}
+}
+
+func TestEmailVerification(t *testing.T) {
+	t.Parallel()
+
+	t.Log("Given the need to verify email addresses of new users.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen registering and verifying a single user.", testID)
+		{
+			ctx := context.Background()
+			store := mem.New()
+			mailer := &mailRecorder{}
+			key := []byte("verification key")
+			uc := usecases.New(zap.NewNop().Sugar(), store, store, time.Minute,
+				usecases.WithEmailVerification(mailer, key, "https://example.com/v1/users/verify", time.Hour),
+				usecases.WithRequireVerified(),
+			)
+
+			nu := usecases.NewUser{Name: "Test User", Email: "test@example.com", Password: "gophers", PasswordConfirm: "gophers"}
+			u, err := uc.Register(ctx, nu, now)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to register a user: %v.", tests.Failed, err)
+			}
+			if u.Verified {
+				t.Fatalf("\t%s\tShould start unverified.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould start unverified.", tests.Success)
+
+			if len(mailer.messages) != 1 || mailer.messages[0].To != u.Email {
+				t.Fatalf("\t%s\tShould send a verification link to the user.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould send a verification link to the user.", tests.Success)
+
+			if _, err := uc.Authenticate(ctx, u.Email, "gophers", "", now); !errors.Is(err, usecases.ErrUnverified) {
+				t.Fatalf("\t%s\tShould not authenticate an unverified user, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not authenticate an unverified user.", tests.Success)
+
+			token := linkTokenFromBody(mailer.messages[0].Body)
+
+			if _, err := uc.VerifyEmail(ctx, token+"x", now); !errors.Is(err, usecases.ErrInvalidVerificationToken) {
+				t.Fatalf("\t%s\tShould not accept a tampered token, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not accept a tampered token.", tests.Success)
+
+			if _, err := uc.VerifyEmail(ctx, token, now.Add(2*time.Hour)); !errors.Is(err, usecases.ErrInvalidVerificationToken) {
+				t.Fatalf("\t%s\tShould not accept an expired token, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not accept an expired token.", tests.Success)
+
+			other := usecases.New(zap.NewNop().Sugar(), store, store, time.Minute,
+				usecases.WithEmailVerification(mailer, []byte("other key"), "https://example.com/v1/users/verify", time.Hour),
+			)
+			if _, err := other.VerifyEmail(ctx, token, now); !errors.Is(err, usecases.ErrInvalidVerificationToken) {
+				t.Fatalf("\t%s\tShould not accept a token signed with another key, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not accept a token signed with another key.", tests.Success)
+
+			if _, err := uc.VerifyEmail(ctx, token, now.Add(time.Minute)); err != nil {
+				t.Fatalf("\t%s\tShould be able to verify the email address: %v.", tests.Failed, err)
+			}
+			if _, err := uc.Authenticate(ctx, u.Email, "gophers", "", now); err != nil {
+				t.Fatalf("\t%s\tShould be able to authenticate a verified user: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to authenticate a verified user.", tests.Success)
+
+			if err := uc.RequestVerification(ctx, u.Email, now); err != nil || len(mailer.messages) != 1 {
+				t.Fatalf("\t%s\tShould not send links to verified users: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not send links to verified users.", tests.Success)
+		}
+	}
+}
+
+func TestLockout(t *testing.T) {
+	t.Parallel()
+
+	t.Log("Given the need to throttle failed authentication attempts.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen guessing passwords from a single client.", testID)
+		{
+			ctx := context.Background()
+			store := mem.New()
+			account := user.LockoutPolicy{FreeAttempts: 3, Delay: time.Minute, MaxDelay: 10 * time.Minute, ResetAfter: time.Hour}
+			ip := user.LockoutPolicy{FreeAttempts: 5, Delay: time.Minute, MaxDelay: 10 * time.Minute, ResetAfter: time.Hour}
+			uc := usecases.New(zap.NewNop().Sugar(), store, store, time.Minute, usecases.WithLockout(loginattempt.New(), account, ip))
+
+			u, _ := user.NewWithID("Test User", "test@example.com", "gophers", []string{user.RoleUser}, now)
+			if err := createMember(ctx, store, u); err != nil {
+				t.Fatalf("\t%s\tShould be able to store a user: %v.", tests.Failed, err)
+			}
+
+			for i := 0; i < 4; i++ {
+				if _, err := uc.Authenticate(ctx, u.Email, "guess", "10.0.0.1", now); !errors.Is(err, usecases.ErrAuthenticationFailed) {
+					t.Fatalf("\t%s\tShould fail with a wrong password, but got: %v.", tests.Failed, err)
+				}
+			}
+			t.Logf("\t%s\tShould fail with a wrong password.", tests.Success)
+
+			var locked *usecases.LockedError
+			if _, err := uc.Authenticate(ctx, u.Email, "gophers", "10.0.0.2", now); !errors.As(err, &locked) {
+				t.Fatalf("\t%s\tShould lock the account, but got: %v.", tests.Failed, err)
+			}
+			if locked.RetryAfter(now) != time.Minute {
+				t.Fatalf("\t%s\tShould lock the account for a minute, but got: %v.", tests.Failed, locked.RetryAfter(now))
+			}
+			t.Logf("\t%s\tShould lock the account for a minute.", tests.Success)
+
+			later := now.Add(2 * time.Minute)
+			if _, err := uc.Authenticate(ctx, u.Email, "gophers", "10.0.0.1", later); err != nil {
+				t.Fatalf("\t%s\tShould be able to authenticate after the delay: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to authenticate after the delay.", tests.Success)
+
+			for i := 0; i < 2; i++ {
+				if _, err := uc.Authenticate(ctx, "unknown@example.com", "guess", "10.0.0.1", later); !errors.Is(err, usecases.ErrAuthenticationFailed) {
+					t.Fatalf("\t%s\tShould fail for an unknown account, but got: %v.", tests.Failed, err)
+				}
+			}
+			if _, err := uc.Authenticate(ctx, u.Email, "gophers", "10.0.0.1", later); !errors.As(err, &locked) {
+				t.Fatalf("\t%s\tShould lock the client IP address, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould lock the client IP address.", tests.Success)
+
+			if _, err := uc.Authenticate(ctx, u.Email, "gophers", "10.0.0.2", later); err != nil {
+				t.Fatalf("\t%s\tShould be able to authenticate from another client: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to authenticate from another client.", tests.Success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen the policies remember failures for different periods.", testID)
+		{
+			ctx := context.Background()
+			store := mem.New()
+			account := user.LockoutPolicy{FreeAttempts: 3, Delay: time.Hour, MaxDelay: time.Hour, ResetAfter: 24 * time.Hour}
+			ip := user.LockoutPolicy{FreeAttempts: 5, Delay: time.Minute, MaxDelay: 10 * time.Minute, ResetAfter: 10 * time.Minute}
+			uc := usecases.New(zap.NewNop().Sugar(), store, store, time.Minute, usecases.WithLockout(loginattempt.New(), account, ip))
+
+			u, _ := user.NewWithID("Test User", "test@example.com", "gophers", []string{user.RoleUser}, now)
+			if err := createMember(ctx, store, u); err != nil {
+				t.Fatalf("\t%s\tShould be able to store a user: %v.", tests.Failed, err)
+			}
+
+			for i := 0; i < 4; i++ {
+				if _, err := uc.Authenticate(ctx, u.Email, "guess", "10.0.0.1", now); !errors.Is(err, usecases.ErrAuthenticationFailed) {
+					t.Fatalf("\t%s\tShould fail with a wrong password, but got: %v.", tests.Failed, err)
+				}
+			}
+
+			later := now.Add(30 * time.Minute)
+			if _, err := uc.Authenticate(ctx, "unknown@example.com", "guess", "10.0.0.2", later); !errors.Is(err, usecases.ErrAuthenticationFailed) {
+				t.Fatalf("\t%s\tShould fail for an unknown account, but got: %v.", tests.Failed, err)
+			}
+
+			var locked *usecases.LockedError
+			if _, err := uc.Authenticate(ctx, u.Email, "gophers", "10.0.0.3", later); !errors.As(err, &locked) {
+				t.Fatalf("\t%s\tShould keep the account locked, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould keep failures of other keys while their policy remembers them.", tests.Success)
+		}
+	}
+}
+
+func TestTOTP(t *testing.T) {
+	t.Parallel()
+
+	t.Log("Given the need to protect admins with a second factor.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen enrolling and using a second factor for a single admin.", testID)
+		{
+			ctx := context.Background()
+			store := mem.New()
+			box, _ := encryption.NewFromPassphrase("totp key")
+			uc := usecases.New(zap.NewNop().Sugar(), store, store, time.Minute,
+				usecases.WithTOTP(store, box, "Sales"),
+				usecases.WithRequireAdminTOTP(),
+			)
+
+			u, _ := user.NewWithID("Test Admin", "admin@example.com", "gophers", []string{user.RoleAdmin, user.RoleUser}, now)
+			if err := createMember(ctx, store, u); err != nil {
+				t.Fatalf("\t%s\tShould be able to store a user: %v.", tests.Failed, err)
+			}
+
+			session, err := uc.Authenticate(ctx, u.Email, "gophers", "", now)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to authenticate: %v.", tests.Failed, err)
+			}
+			if session.UserHasRole(user.RoleAdmin) {
+				t.Fatalf("\t%s\tShould not get admin privileges without a second factor.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould not get admin privileges without a second factor.", tests.Success)
+
+			sctx := user.ContextWithSession(ctx, session)
+			uri, err := uc.EnrollTOTP(sctx, now)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to enroll: %v.", tests.Failed, err)
+			}
+			link, _ := url.Parse(uri)
+			secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(link.Query().Get("secret"))
+			if err != nil {
+				t.Fatalf("\t%s\tShould get the secret in an otpauth URI: %s.", tests.Failed, uri)
+			}
+			t.Logf("\t%s\tShould get the secret in an otpauth URI.", tests.Success)
+
+			stored, _ := store.QueryByID(user.ContextForSystem(ctx), u.ID)
+			if bytes.Contains(stored.TOTPSecret, secret) {
+				t.Fatalf("\t%s\tShould store the secret encrypted.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould store the secret encrypted.", tests.Success)
+
+			if _, err := uc.EnableTOTP(sctx, totp.Code(secret, now.Add(time.Hour)), now); !errors.Is(err, usecases.ErrInvalidTOTPCode) {
+				t.Fatalf("\t%s\tShould not enable with a wrong code, but got: %v.", tests.Failed, err)
+			}
+			codes, err := uc.EnableTOTP(sctx, totp.Code(secret, now), now)
+			if err != nil || len(codes) != user.RecoveryCodeCount {
+				t.Fatalf("\t%s\tShould enable with the first code and get recovery codes: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould enable with the first code and get recovery codes.", tests.Success)
+
+			login := func(at time.Time) string {
+				var challenge *usecases.ChallengeError
+				if _, err := uc.Authenticate(ctx, u.Email, "gophers", "", at); !errors.As(err, &challenge) {
+					t.Fatalf("\t%s\tShould get a challenge after the password, but got: %v.", tests.Failed, err)
+				}
+				return challenge.Challenge
+			}
+
+			later := now.Add(totp.Period)
+			challenge := login(later)
+			t.Logf("\t%s\tShould get a challenge after the password.", tests.Success)
+
+			if _, err := uc.CompleteChallenge(ctx, challenge, totp.Code(secret, now), "", later); !errors.Is(err, usecases.ErrInvalidTOTPCode) {
+				t.Fatalf("\t%s\tShould not accept a used code, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not accept a used code.", tests.Success)
+
+			session, err = uc.CompleteChallenge(ctx, challenge, totp.Code(secret, later), "", later)
+			if err != nil || !session.UserHasRole(user.RoleAdmin) {
+				t.Fatalf("\t%s\tShould get admin privileges with the second factor: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould get admin privileges with the second factor.", tests.Success)
+
+			if _, err := uc.CompleteChallenge(ctx, challenge, totp.Code(secret, later.Add(totp.Period)), "", later.Add(totp.Period)); !errors.Is(err, usecases.ErrInvalidChallenge) {
+				t.Fatalf("\t%s\tShould not accept a challenge twice, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not accept a challenge twice.", tests.Success)
+
+			if _, err := uc.CompleteChallenge(ctx, login(later), codes[0], "", later); err != nil {
+				t.Fatalf("\t%s\tShould accept a recovery code: %v.", tests.Failed, err)
+			}
+			if _, err := uc.CompleteChallenge(ctx, login(later), codes[0], "", later); !errors.Is(err, usecases.ErrInvalidTOTPCode) {
+				t.Fatalf("\t%s\tShould accept a recovery code once, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould accept a recovery code once.", tests.Success)
+
+			challenge = login(later)
+			for i := 0; i < 5; i++ {
+				if _, err := uc.CompleteChallenge(ctx, challenge, "wrong", "", later); !errors.Is(err, usecases.ErrInvalidTOTPCode) {
+					t.Fatalf("\t%s\tShould not accept a wrong code, but got: %v.", tests.Failed, err)
+				}
+			}
+			next := later.Add(totp.Period)
+			if _, err := uc.CompleteChallenge(ctx, challenge, totp.Code(secret, next), "", next); !errors.Is(err, usecases.ErrInvalidChallenge) {
+				t.Fatalf("\t%s\tShould invalidate a challenge after too many wrong codes, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould invalidate a challenge after too many wrong codes.", tests.Success)
+
+			if err := uc.DisableTOTP(user.ContextWithSession(ctx, session), codes[1], later); err != nil {
+				t.Fatalf("\t%s\tShould be able to disable with a recovery code: %v.", tests.Failed, err)
+			}
+			session, err = uc.Authenticate(ctx, u.Email, "gophers", "", later)
+			if err != nil || session.UserHasRole(user.RoleAdmin) {
+				t.Fatalf("\t%s\tShould lose admin privileges after disabling: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould lose admin privileges after disabling.", tests.Success)
+		}
+	}
+}
+
+func TestExternalIdentities(t *testing.T) {
+	t.Parallel()
+
+	t.Log("Given the need to authenticate users of an external identity provider.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen linking identities to existing users.", testID)
+		{
+			ctx := context.Background()
+			store := mem.New()
+			uc := usecases.New(zap.NewNop().Sugar(), store, store, time.Minute, usecases.WithExternalIdentities(store, false))
+
+			u, _ := user.NewWithID("Test User", "user@example.com", "gophers", []string{user.RoleUser}, now)
+			if err := createMember(ctx, store, u); err != nil {
+				t.Fatalf("\t%s\tShould be able to store a user: %v.", tests.Failed, err)
+			}
+
+			ext := usecases.ExternalIdentity{Provider: "idp", Subject: "1234", Email: u.Email}
+			if _, err := uc.AuthenticateExternal(ctx, ext, now); !errors.Is(err, usecases.ErrIdentityNotLinked) {
+				t.Fatalf("\t%s\tShould not link by an unverified email address, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not link by an unverified email address.", tests.Success)
+
+			ext.EmailVerified = true
+			session, err := uc.AuthenticateExternal(ctx, ext, now)
+			if err != nil || session.User.ID != u.ID {
+				t.Fatalf("\t%s\tShould link by a verified email address: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould link by a verified email address.", tests.Success)
+
+			// Once linked, the subject identifies the user, whatever the email.
+			ext.Email, ext.EmailVerified = "changed@example.com", false
+			session, err = uc.AuthenticateExternal(ctx, ext, now)
+			if err != nil || session.User.ID != u.ID {
+				t.Fatalf("\t%s\tShould authenticate a linked subject: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould authenticate a linked subject.", tests.Success)
+
+			other := usecases.ExternalIdentity{Provider: "idp", Subject: "5678", Email: "new@example.com", EmailVerified: true}
+			if _, err := uc.AuthenticateExternal(ctx, other, now); !errors.Is(err, usecases.ErrIdentityNotLinked) {
+				t.Fatalf("\t%s\tShould not provision users when disabled, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not provision users when disabled.", tests.Success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen provisioning users.", testID)
+		{
+			ctx := context.Background()
+			store := mem.New()
+			uc := usecases.New(zap.NewNop().Sugar(), store, store, time.Minute, usecases.WithExternalIdentities(store, true))
+
+			ext := usecases.ExternalIdentity{Provider: "idp", Subject: "1234", Email: "new@example.com", EmailVerified: true, Name: "New User"}
+			session, err := uc.AuthenticateExternal(ctx, ext, now)
+			if err != nil {
+				t.Fatalf("\t%s\tShould provision a user: %v.", tests.Failed, err)
+			}
+			u, err := store.QueryByEmail(user.ContextForSystem(ctx), ext.Email)
+			if err != nil || u.ID != session.User.ID || u.Name != ext.Name || !u.Verified {
+				t.Fatalf("\t%s\tShould provision a verified user: %v.", tests.Failed, err)
+			}
+			if session.UserHasRole(user.RoleAdmin) || !session.UserHasRole(user.RoleUser) {
+				t.Fatalf("\t%s\tShould provision a user with the USER role only: %v.", tests.Failed, session.User.Roles)
+			}
+			t.Logf("\t%s\tShould provision a verified user with the USER role only.", tests.Success)
+
+			again, err := uc.AuthenticateExternal(ctx, ext, now)
+			if err != nil || again.User.ID != u.ID {
+				t.Fatalf("\t%s\tShould authenticate the provisioned user again: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould authenticate the provisioned user again.", tests.Success)
+
+			ext = usecases.ExternalIdentity{Provider: "idp", Subject: "5678", Email: "unverified@example.com"}
+			if _, err := uc.AuthenticateExternal(ctx, ext, now); !errors.Is(err, usecases.ErrIdentityNotLinked) {
+				t.Fatalf("\t%s\tShould not provision users with an unverified email address, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not provision users with an unverified email address.", tests.Success)
+
+			d, _ := user.NewWithID("Deleted User", "deleted@example.com", "gophers", []string{user.RoleUser}, now)
+			if err := createMember(ctx, store, d); err != nil {
+				t.Fatalf("\t%s\tShould be able to store a user: %v.", tests.Failed, err)
+			}
+			if err := store.Delete(user.ContextForSystem(ctx), d.ID, now); err != nil {
+				t.Fatalf("\t%s\tShould be able to delete the user: %v.", tests.Failed, err)
+			}
+			ext = usecases.ExternalIdentity{Provider: "idp", Subject: "9012", Email: d.Email, EmailVerified: true}
+			if _, err := uc.AuthenticateExternal(ctx, ext, now); !errors.Is(err, usecases.ErrAuthenticationFailed) {
+				t.Fatalf("\t%s\tShould not provision users with the email address of a deleted user, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not provision users with the email address of a deleted user.", tests.Success)
+		}
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	t.Parallel()
+
+	t.Log("Given the need to authenticate machine clients with API keys.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen managing the API keys of a single user.", testID)
+		{
+			ctx := context.Background()
+			store := mem.New()
+			uc := usecases.New(zap.NewNop().Sugar(), store, store, time.Minute, usecases.WithAPIKeys(store, 24*time.Hour))
+
+			u, _ := user.NewWithID("Test User", "user@example.com", "gophers", []string{user.RoleAdmin, user.RoleUser}, now)
+			if err := createMember(ctx, store, u); err != nil {
+				t.Fatalf("\t%s\tShould be able to store a user: %v.", tests.Failed, err)
+			}
+			sctx := user.ContextWithSession(ctx, memberSession(u, now.Add(time.Minute)))
+
+			nk := usecases.NewAPIKey{Name: "batch", Scopes: []string{user.RoleUser}, Expires: now.Add(48 * time.Hour)}
+			if _, _, err := uc.CreateAPIKey(sctx, u.ID, nk, now); !errors.Is(err, usecases.ErrInvalidExpiry) {
+				t.Fatalf("\t%s\tShould not create a key beyond the maximum duration, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not create a key beyond the maximum duration.", tests.Success)
+
+			nk.Expires = now.Add(time.Hour)
+			nk.Scopes = []string{"OWNER"}
+			if _, _, err := uc.CreateAPIKey(sctx, u.ID, nk, now); !errors.Is(err, usecases.ErrInvalidScope) {
+				t.Fatalf("\t%s\tShould not create a key with scopes the user does not have, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not create a key with scopes the user does not have.", tests.Success)
+
+			nk.Scopes = []string{user.RoleUser}
+			ak, key, err := uc.CreateAPIKey(sctx, u.ID, nk, now)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to create a key: %v.", tests.Failed, err)
+			}
+			if ak.KeyHash == key || ak.KeyHash != user.HashToken(key) {
+				t.Fatalf("\t%s\tShould only store a hash of the key.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould be able to create a key.", tests.Success)
+
+			later := now.Add(2 * time.Minute)
+			session, err := uc.AuthenticateAPIKey(ctx, key, later)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to authenticate with the key: %v.", tests.Failed, err)
+			}
+			if session.User.ID != u.ID || session.APIKeyID != ak.ID || session.UserHasRole(user.RoleAdmin) {
+				t.Fatalf("\t%s\tShould get a session limited to the scopes of the key: %v.", tests.Failed, session.User.Roles)
+			}
+			t.Logf("\t%s\tShould get a session limited to the scopes of the key.", tests.Success)
+
+			keys, err := uc.QueryAPIKeys(sctx, u.ID)
+			if err != nil || len(keys) != 1 || !keys[0].DateLastUsed.Equal(later) {
+				t.Fatalf("\t%s\tShould record when the key was last used: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould record when the key was last used.", tests.Success)
+
+			kctx := user.ContextWithSession(ctx, session)
+			if _, _, err := uc.CreateAPIKey(kctx, u.ID, nk, later); !errors.Is(err, usecases.ErrUnauthorized) {
+				t.Fatalf("\t%s\tShould not create keys with a key, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not create keys with a key.", tests.Success)
+
+			if _, err := uc.AuthenticateAPIKey(ctx, key, now.Add(2*time.Hour)); !errors.Is(err, usecases.ErrAuthenticationFailed) {
+				t.Fatalf("\t%s\tShould not accept an expired key, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not accept an expired key.", tests.Success)
+
+			if err := uc.RevokeAPIKey(sctx, u.ID, ak.ID); err != nil {
+				t.Fatalf("\t%s\tShould be able to revoke the key: %v.", tests.Failed, err)
+			}
+			if _, err := uc.AuthenticateAPIKey(ctx, key, later); !errors.Is(err, usecases.ErrAuthenticationFailed) {
+				t.Fatalf("\t%s\tShould not accept a revoked key, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not accept a revoked key.", tests.Success)
+		}
+	}
+}
+
+// =============================================================================
+
+// mailRecorder is a mailer that keeps the messages it sends.
+type mailRecorder struct {
+	messages []mail.Message
+}
+
+func (m *mailRecorder) Send(ctx context.Context, msg mail.Message) error {
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// revocationRecorder is a token revoker that keeps the subjects it revokes.
+type revocationRecorder struct {
+	subjects []string
+}
+
+func (r *revocationRecorder) RevokeAll(ctx context.Context, subject string, now time.Time) error {
+	r.subjects = append(r.subjects, subject)
+	return nil
+}
+
+// createMember stores the user as a member of the default organization,
+// with the roles of the user.
+func createMember(ctx context.Context, store *mem.Store, u user.User) error {
+	if err := store.Create(ctx, u); err != nil {
+		return err
+	}
+
+	m, err := org.NewMembership(org.DefaultID, u.ID, u.Roles, u.DateCreated)
+	if err != nil {
+		return err
+	}
+
+	return store.CreateMembership(ctx, m)
+}
+
+// memberSession returns a session for the user in the default organization.
+func memberSession(u user.User, expires time.Time) user.Session {
+	s := user.NewSession(u, expires)
+	s.TenantID = org.DefaultID
+	return s
+}
+
+// tokenFromBody returns the opaque token, which is on a line of its own.
+func tokenFromBody(body string) string {
+	for _, line := range strings.Split(body, "\n") {
+		if len(line) == 43 && !strings.Contains(line, " ") {
+			return line
+		}
+	}
+	return ""
+}
+
+// linkTokenFromBody returns the token of the verification link in the body.
+func linkTokenFromBody(body string) string {
+	for _, line := range strings.Split(body, "\n") {
+		if u, err := url.Parse(line); err == nil && u.Query().Get("token") != "" {
+			return u.Query().Get("token")
+		}
+	}
+	return ""
+}
+
+func TestAuthorization(t *testing.T) {
+	t.Parallel()
+
+	t.Log("Given the need to authorize users by policy.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen a user manages its own account.", testID)
+		{
+			ctx := context.Background()
+			store := mem.New()
+			uc := usecases.New(zap.NewNop().Sugar(), store, store, time.Minute)
+
+			u, _ := user.NewWithID("Test User", "user@example.com", "gophers", []string{user.RoleUser}, now)
+			o, _ := user.NewWithID("Other User", "other@example.com", "gophers", []string{user.RoleUser}, now)
+			for _, usr := range []user.User{u, o} {
+				if err := createMember(ctx, store, usr); err != nil {
+					t.Fatalf("\t%s\tShould be able to store a user: %v.", tests.Failed, err)
+				}
+			}
+			sctx := user.ContextWithSession(ctx, memberSession(u, now.Add(time.Minute)))
+
+			if _, err := uc.QueryByID(sctx, u.ID); err != nil {
+				t.Fatalf("\t%s\tShould be able to read itself: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to read itself.", tests.Success)
+
+			if _, err := uc.QueryByID(sctx, o.ID); !errors.Is(err, usecases.ErrUnauthorized) {
+				t.Fatalf("\t%s\tShould not be able to read another user, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not be able to read another user.", tests.Success)
+
+			name := "Renamed User"
+			if _, err := uc.Update(sctx, u.ID, usecases.UpdateUser{Name: &name}, 0, now); err != nil {
+				t.Fatalf("\t%s\tShould be able to update itself: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to update itself.", tests.Success)
+
+			roles := []string{user.RoleAdmin, user.RoleUser}
+			if _, err := uc.Update(sctx, u.ID, usecases.UpdateUser{Roles: roles}, 0, now); !errors.Is(err, usecases.ErrUnauthorized) {
+				t.Fatalf("\t%s\tShould not be able to change its own roles, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not be able to change its own roles.", tests.Success)
+
+			if _, err := uc.Query(sctx, user.QueryFilter{}, 1, 10); !errors.Is(err, usecases.ErrUnauthorized) {
+				t.Fatalf("\t%s\tShould not be able to list users, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not be able to list users.", tests.Success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen an admin changes the roles of a user.", testID)
+		{
+			ctx := context.Background()
+			store := mem.New()
+			revocations := revocationRecorder{}
+			uc := usecases.New(zap.NewNop().Sugar(), store, store, time.Minute, usecases.WithTokenRevocation(&revocations))
+
+			a, _ := user.NewWithID("Admin User", "admin@example.com", "gophers", []string{user.RoleAdmin, user.RoleUser}, now)
+			u, _ := user.NewWithID("Test User", "user@example.com", "gophers", []string{user.RoleUser}, now)
+			for _, usr := range []user.User{a, u} {
+				if err := createMember(ctx, store, usr); err != nil {
+					t.Fatalf("\t%s\tShould be able to store a user: %v.", tests.Failed, err)
+				}
+			}
+			actx := user.ContextWithSession(ctx, memberSession(a, now.Add(time.Minute)))
+
+			if _, err := uc.Update(actx, u.ID, usecases.UpdateUser{Roles: []string{user.RoleUser}}, 0, now); err != nil {
+				t.Fatalf("\t%s\tShould be able to update the user: %v.", tests.Failed, err)
+			}
+			if len(revocations.subjects) != 0 {
+				t.Fatalf("\t%s\tShould not revoke tokens when the roles stay the same: %v.", tests.Failed, revocations.subjects)
+			}
+			t.Logf("\t%s\tShould keep the tokens when the roles stay the same.", tests.Success)
+
+			roles := []string{user.RoleAdmin, user.RoleUser}
+			if _, err := uc.Update(actx, u.ID, usecases.UpdateUser{Roles: roles}, 0, now); err != nil {
+				t.Fatalf("\t%s\tShould be able to change the roles of the user: %v.", tests.Failed, err)
+			}
+			if len(revocations.subjects) != 1 || revocations.subjects[0] != u.ID {
+				t.Fatalf("\t%s\tShould revoke the tokens with the old roles: %v.", tests.Failed, revocations.subjects)
+			}
+			t.Logf("\t%s\tShould revoke the tokens with the old roles.", tests.Success)
+		}
+	}
+}
+
+func TestOrganizations(t *testing.T) {
+	t.Parallel()
+
+	t.Log("Given the need to keep the data of organizations apart.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen a user creates and manages another organization.", testID)
+		{
+			ctx := context.Background()
+			store := mem.New()
+			uc := usecases.New(zap.NewNop().Sugar(), store, store, time.Minute)
+
+			u, _ := user.NewWithID("Test User", "user@example.com", "gophers", []string{user.RoleUser}, now)
+			o, _ := user.NewWithID("Other User", "other@example.com", "gophers", []string{user.RoleUser}, now)
+			for _, usr := range []user.User{u, o} {
+				if err := createMember(ctx, store, usr); err != nil {
+					t.Fatalf("\t%s\tShould be able to store a user: %v.", tests.Failed, err)
+				}
+			}
+			sctx := user.ContextWithSession(ctx, memberSession(u, now.Add(time.Minute)))
+
+			created, err := uc.CreateOrg(sctx, usecases.NewOrg{Name: "Garage Sale"}, now)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to create an organization: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to create an organization.", tests.Success)
+
+			orgs, err := uc.QueryOrgs(sctx)
+			if err != nil || len(orgs) != 2 {
+				t.Fatalf("\t%s\tShould be a member of both organizations, but got %d: %v.", tests.Failed, len(orgs), err)
+			}
+			t.Logf("\t%s\tShould be a member of both organizations.", tests.Success)
+
+			session, err := uc.SwitchOrg(sctx, created.ID, now)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to switch organizations: %v.", tests.Failed, err)
+			}
+			if session.TenantID != created.ID || !session.UserHasRole(user.RoleAdmin) {
+				t.Fatalf("\t%s\tShould be an admin of the new organization: %+v.", tests.Failed, session)
+			}
+			t.Logf("\t%s\tShould be an admin of the new organization.", tests.Success)
+			octx := user.ContextWithSession(ctx, session)
+
+			if _, err := uc.QueryByID(octx, o.ID); !errors.Is(err, usecases.ErrNotFound) {
+				t.Fatalf("\t%s\tShould not find users of another organization, but got: %v.", tests.Failed, err)
+			}
+			users, err := uc.Query(octx, user.QueryFilter{}, 1, 10)
+			if err != nil || len(users) != 1 {
+				t.Fatalf("\t%s\tShould only list members of the organization, but got %d: %v.", tests.Failed, len(users), err)
+			}
+			t.Logf("\t%s\tShould not see users of another organization.", tests.Success)
+
+			if _, err := store.QueryByID(ctx, o.ID); !errors.Is(err, user.ErrNoTenant) {
+				t.Fatalf("\t%s\tShould not find users without a session, but got: %v.", tests.Failed, err)
+			}
+			if _, err := store.QueryByID(user.ContextForSystem(ctx), o.ID); err != nil {
+				t.Fatalf("\t%s\tShould find users for the system: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould only find users without a session for the system.", tests.Success)
+
+			inv, err := uc.InviteMember(octx, usecases.NewMember{UserID: o.ID, Roles: []string{user.RoleUser}}, now)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to invite a user: %v.", tests.Failed, err)
+			}
+			if _, err := uc.InviteMember(octx, usecases.NewMember{UserID: o.ID, Roles: []string{user.RoleUser}}, now); !errors.Is(err, usecases.ErrUniqueInvitation) {
+				t.Fatalf("\t%s\tShould not be able to invite a user twice, but got: %v.", tests.Failed, err)
+			}
+			if _, err := uc.QueryByID(octx, o.ID); !errors.Is(err, usecases.ErrNotFound) {
+				t.Fatalf("\t%s\tShould not find an invited user before it accepts, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to invite a user.", tests.Success)
+
+			ctxOther := user.ContextWithSession(ctx, memberSession(o, now.Add(time.Minute)))
+			if _, err := uc.AcceptInvitation(sctx, inv.ID, now); !errors.Is(err, usecases.ErrInvitationNotFound) {
+				t.Fatalf("\t%s\tShould not be able to accept the invitation of another user, but got: %v.", tests.Failed, err)
+			}
+			invs, err := uc.QueryInvitations(ctxOther)
+			if err != nil || len(invs) != 1 || invs[0].OrgID != created.ID {
+				t.Fatalf("\t%s\tShould see the invitation, but got %+v: %v.", tests.Failed, invs, err)
+			}
+			if _, err := uc.AcceptInvitation(ctxOther, inv.ID, now); err != nil {
+				t.Fatalf("\t%s\tShould be able to accept an invitation: %v.", tests.Failed, err)
+			}
+			if _, err := uc.QueryByID(octx, o.ID); err != nil {
+				t.Fatalf("\t%s\tShould find the new member: %v.", tests.Failed, err)
+			}
+			if _, err := uc.InviteMember(octx, usecases.NewMember{UserID: o.ID, Roles: []string{user.RoleUser}}, now); !errors.Is(err, usecases.ErrUniqueMembership) {
+				t.Fatalf("\t%s\tShould not be able to invite a member, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould become a member after accepting the invitation.", tests.Success)
+
+			if _, err := uc.InviteMember(sctx, usecases.NewMember{UserID: o.ID, Roles: []string{user.RoleAdmin}}, now); !errors.Is(err, usecases.ErrUnauthorized) {
+				t.Fatalf("\t%s\tShould not be able to invite users without being an admin, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not be able to invite users without being an admin.", tests.Success)
+
+			pw := "correct horse battery"
+			if _, err := uc.Update(octx, o.ID, usecases.UpdateUser{Password: &pw}, 0, now); !errors.Is(err, usecases.ErrUnauthorized) {
+				t.Fatalf("\t%s\tShould not be able to change the password of a member of other organizations, but got: %v.", tests.Failed, err)
+			}
+			if err := uc.Delete(octx, o.ID, now); !errors.Is(err, usecases.ErrUnauthorized) {
+				t.Fatalf("\t%s\tShould not be able to delete a member of other organizations, but got: %v.", tests.Failed, err)
+			}
+			if err := uc.Restore(octx, o.ID, now); !errors.Is(err, usecases.ErrUnauthorized) {
+				t.Fatalf("\t%s\tShould not be able to restore a member of other organizations, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not be able to change the account of a member of other organizations.", tests.Success)
+
+			if err := uc.RemoveMember(octx, o.ID); err != nil {
+				t.Fatalf("\t%s\tShould be able to remove a member: %v.", tests.Failed, err)
+			}
+			if _, err := uc.SwitchOrg(ctxOther, created.ID, now); !errors.Is(err, usecases.ErrAuthenticationFailed) {
+				t.Fatalf("\t%s\tShould not be able to switch to an organization after removal, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to remove a member.", tests.Success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen a user invites another user to an organization it created.", testID)
+		{
+			ctx := context.Background()
+			store := mem.New()
+			revocations := revocationRecorder{}
+			uc := usecases.New(zap.NewNop().Sugar(), store, store, time.Minute, usecases.WithTokenRevocation(&revocations))
+
+			u, _ := user.NewWithID("Test User", "user@example.com", "gophers", []string{user.RoleUser}, now)
+			v, _ := user.NewWithID("Victim User", "victim@example.com", "gophers", []string{user.RoleUser}, now)
+			for _, usr := range []user.User{u, v} {
+				if err := createMember(ctx, store, usr); err != nil {
+					t.Fatalf("\t%s\tShould be able to store a user: %v.", tests.Failed, err)
+				}
+			}
+			sctx := user.ContextWithSession(ctx, memberSession(u, now.Add(time.Minute)))
+
+			created, err := uc.CreateOrg(sctx, usecases.NewOrg{Name: "Garage Sale"}, now)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to create an organization: %v.", tests.Failed, err)
+			}
+			session, err := uc.SwitchOrg(sctx, created.ID, now)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to switch organizations: %v.", tests.Failed, err)
+			}
+			octx := user.ContextWithSession(ctx, session)
+
+			if _, err := uc.InviteMember(octx, usecases.NewMember{UserID: v.ID, Roles: []string{user.RoleUser}}, now); err != nil {
+				t.Fatalf("\t%s\tShould be able to invite the user: %v.", tests.Failed, err)
+			}
+
+			pw := "correct horse battery"
+			email := "attacker@example.com"
+			if _, err := uc.Update(octx, v.ID, usecases.UpdateUser{Password: &pw}, 0, now); !errors.Is(err, usecases.ErrNotFound) {
+				t.Fatalf("\t%s\tShould not be able to change the password of the invited user, but got: %v.", tests.Failed, err)
+			}
+			if _, err := uc.Update(octx, v.ID, usecases.UpdateUser{Email: &email}, 0, now); !errors.Is(err, usecases.ErrNotFound) {
+				t.Fatalf("\t%s\tShould not be able to change the email of the invited user, but got: %v.", tests.Failed, err)
+			}
+			if err := uc.Delete(octx, v.ID, now); !errors.Is(err, usecases.ErrNotFound) {
+				t.Fatalf("\t%s\tShould not be able to delete the invited user, but got: %v.", tests.Failed, err)
+			}
+			if err := uc.RevokeTokens(octx, v.ID, now); !errors.Is(err, usecases.ErrNotFound) {
+				t.Fatalf("\t%s\tShould not be able to revoke the tokens of the invited user, but got: %v.", tests.Failed, err)
+			}
+			if len(revocations.subjects) != 0 {
+				t.Fatalf("\t%s\tShould not have revoked any tokens: %v.", tests.Failed, revocations.subjects)
+			}
+			if err := uc.RevokeTokens(sctx, u.ID, now); err != nil || len(revocations.subjects) != 1 {
+				t.Fatalf("\t%s\tShould be able to revoke its own tokens: %v.", tests.Failed, err)
+			}
+			if _, err := uc.Authenticate(ctx, v.Email, "gophers", "", now); err != nil {
+				t.Fatalf("\t%s\tShould leave the account of the invited user untouched: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not be able to take over the account of the invited user.", tests.Success)
+		}
+	}
+}
+
+func TestAudit(t *testing.T) {
+	t.Parallel()
+
+	t.Log("Given the need to record all changes in an audit trail.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen an admin changes a user.", testID)
+		{
+			ctx := context.Background()
+			store := mem.New()
+			trail := audits.New(zap.NewNop().Sugar(), auditmem.New())
+			uc := usecases.New(zap.NewNop().Sugar(), store, store, time.Minute, usecases.WithAudit(trail))
+
+			a, _ := user.NewWithID("Admin User", "admin@example.com", "gophers", []string{user.RoleAdmin, user.RoleUser}, now)
+			u, _ := user.NewWithID("Test User", "user@example.com", "gophers", []string{user.RoleUser}, now)
+			for _, usr := range []user.User{a, u} {
+				if err := createMember(ctx, store, usr); err != nil {
+					t.Fatalf("\t%s\tShould be able to store a user: %v.", tests.Failed, err)
+				}
+			}
+			actx := fctx.ContextWithValues(ctx, &fctx.Values{TraceID: "trace-id", Now: now})
+			actx = user.ContextWithSession(actx, memberSession(a, now.Add(time.Minute)))
+			uctx := user.ContextWithSession(ctx, memberSession(u, now.Add(time.Minute)))
+
+			name := "Renamed User"
+			if _, err := uc.Update(actx, u.ID, usecases.UpdateUser{Name: &name}, 0, now); err != nil {
+				t.Fatalf("\t%s\tShould be able to update a user: %v.", tests.Failed, err)
+			}
+			if err := uc.Delete(actx, u.ID, now); err != nil {
+				t.Fatalf("\t%s\tShould be able to delete a user: %v.", tests.Failed, err)
+			}
+
+			entries, err := trail.Query(actx, audit.Filter{EntityID: u.ID}, 1, 10)
+			if err != nil || len(entries) != 2 {
+				t.Fatalf("\t%s\tShould record both changes, but got %d: %v.", tests.Failed, len(entries), err)
+			}
+			t.Logf("\t%s\tShould record both changes.", tests.Success)
+
+			var update audit.Entry
+			for _, e := range entries {
+				if e.Usecase == "user.Update" {
+					update = e
+				}
+			}
+			exp := map[string]audit.Change{
+				"Name":    {Before: json.RawMessage(`"Test User"`), After: json.RawMessage(`"Renamed User"`)},
+				"Version": {Before: json.RawMessage(`1`), After: json.RawMessage(`2`)},
+			}
+			if update.ActorID != a.ID || update.OrgID != org.DefaultID || update.TraceID != "trace-id" {
+				t.Fatalf("\t%s\tShould record who made the change: %+v.", tests.Failed, update)
+			}
+			if diff := cmp.Diff(update.Changes, exp); diff != "" {
+				t.Fatalf("\t%s\tShould record what changed. Diff:\n%s", tests.Failed, diff)
+			}
+			t.Logf("\t%s\tShould record who made the change and what changed.", tests.Success)
+
+			if _, err := trail.Query(uctx, audit.Filter{}, 1, 10); !errors.Is(err, usecases.ErrUnauthorized) {
+				t.Fatalf("\t%s\tShould not be able to query the audit trail as a user, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not be able to query the audit trail as a user.", tests.Success)
+		}
+	}
+}
+
+func TestSoftDelete(t *testing.T) {
+	t.Parallel()
+
+	t.Log("Given the need to delete users and restore them before they are purged.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen a user is deleted.", testID)
+		{
+			ctx := context.Background()
+			store := mem.New()
+			revocations := revocationRecorder{}
+			uc := usecases.New(zap.NewNop().Sugar(), store, store, time.Minute, usecases.WithTokenRevocation(&revocations))
+
+			a, _ := user.NewWithID("Admin User", "admin@example.com", "gophers", []string{user.RoleAdmin, user.RoleUser}, now)
+			u, _ := user.NewWithID("Test User", "user@example.com", "gophers", []string{user.RoleUser}, now)
+			for _, usr := range []user.User{a, u} {
+				if err := createMember(ctx, store, usr); err != nil {
+					t.Fatalf("\t%s\tShould be able to store a user: %v.", tests.Failed, err)
+				}
+			}
+			actx := user.ContextWithSession(ctx, memberSession(a, now.Add(time.Minute)))
+			uctx := user.ContextWithSession(ctx, memberSession(u, now.Add(time.Minute)))
+
+			if err := uc.Delete(uctx, u.ID, now); err != nil {
+				t.Fatalf("\t%s\tShould be able to delete itself: %v.", tests.Failed, err)
+			}
+			if len(revocations.subjects) != 1 || revocations.subjects[0] != u.ID {
+				t.Fatalf("\t%s\tShould revoke the tokens of the deleted user: %v.", tests.Failed, revocations.subjects)
+			}
+			if err := uc.Delete(uctx, u.ID, now); !errors.Is(err, usecases.ErrNotFound) {
+				t.Fatalf("\t%s\tShould not be able to delete a deleted user, but got: %v.", tests.Failed, err)
+			}
+			if _, err := uc.QueryByID(actx, u.ID); !errors.Is(err, usecases.ErrNotFound) {
+				t.Fatalf("\t%s\tShould not find a deleted user, but got: %v.", tests.Failed, err)
+			}
+			if _, err := uc.Authenticate(ctx, u.Email, "gophers", "", now); !errors.Is(err, usecases.ErrAuthenticationFailed) {
+				t.Fatalf("\t%s\tShould not be able to authenticate a deleted user, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould leave out deleted users.", tests.Success)
+
+			deleted, err := uc.QueryDeleted(actx, 1, 10)
+			if err != nil || len(deleted) != 1 || deleted[0].ID != u.ID || !deleted[0].IsDeleted() {
+				t.Fatalf("\t%s\tShould list the deleted user, but got %+v: %v.", tests.Failed, deleted, err)
+			}
+			t.Logf("\t%s\tShould list the deleted user.", tests.Success)
+
+			if err := uc.Restore(uctx, u.ID, now); !errors.Is(err, usecases.ErrUnauthorized) {
+				t.Fatalf("\t%s\tShould not be able to restore without being an admin, but got: %v.", tests.Failed, err)
+			}
+			if err := uc.Restore(actx, u.ID, now); err != nil {
+				t.Fatalf("\t%s\tShould be able to restore the user: %v.", tests.Failed, err)
+			}
+			if err := uc.Restore(actx, u.ID, now); !errors.Is(err, usecases.ErrNotFound) {
+				t.Fatalf("\t%s\tShould not be able to restore a user that is not deleted, but got: %v.", tests.Failed, err)
+			}
+			if _, err := uc.Authenticate(ctx, u.Email, "gophers", "", now); err != nil {
+				t.Fatalf("\t%s\tShould be able to authenticate a restored user: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to restore the user as an admin.", tests.Success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen deleted users are purged.", testID)
+		{
+			ctx := context.Background()
+			store := mem.New()
+			uc := usecases.New(zap.NewNop().Sugar(), store, store, time.Minute)
+
+			a, _ := user.NewWithID("Admin User", "admin@example.com", "gophers", []string{user.RoleAdmin, user.RoleUser}, now)
+			u, _ := user.NewWithID("Test User", "user@example.com", "gophers", []string{user.RoleUser}, now)
+			for _, usr := range []user.User{a, u} {
+				if err := createMember(ctx, store, usr); err != nil {
+					t.Fatalf("\t%s\tShould be able to store a user: %v.", tests.Failed, err)
+				}
+			}
+			actx := user.ContextWithSession(ctx, memberSession(a, now.Add(time.Minute)))
+
+			if err := uc.Delete(actx, u.ID, now); err != nil {
+				t.Fatalf("\t%s\tShould be able to delete a user: %v.", tests.Failed, err)
+			}
+
+			ids, err := uc.PurgeDeleted(ctx, now)
+			if err != nil || len(ids) != 0 {
+				t.Fatalf("\t%s\tShould keep users within the retention period, but got %v: %v.", tests.Failed, ids, err)
+			}
+			t.Logf("\t%s\tShould keep users within the retention period.", tests.Success)
+
+			ids, err = uc.PurgeDeleted(ctx, now.Add(time.Hour))
+			if err != nil || len(ids) != 1 || ids[0] != u.ID {
+				t.Fatalf("\t%s\tShould purge the deleted user, but got %v: %v.", tests.Failed, ids, err)
+			}
+			if err := uc.Restore(actx, u.ID, now); !errors.Is(err, usecases.ErrNotFound) {
+				t.Fatalf("\t%s\tShould not be able to restore a purged user, but got: %v.", tests.Failed, err)
+			}
+			if _, err := store.QueryMembership(ctx, org.DefaultID, u.ID); !errors.Is(err, usecases.ErrMembershipNotFound) {
+				t.Fatalf("\t%s\tShould remove the memberships of a purged user, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould purge users after the retention period.", tests.Success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen the only user is deleted and someone registers.", testID)
+		{
+			ctx := context.Background()
+			store := mem.New()
+			uc := usecases.New(zap.NewNop().Sugar(), store, store, time.Minute)
+
+			a, err := uc.Register(ctx, usecases.NewUser{Name: "Admin User", Email: "admin@example.com", Password: "gophers", PasswordConfirm: "gophers"}, now)
+			if err != nil || !(user.QueryFilter{Role: user.RoleAdmin}).Match(a) {
+				t.Fatalf("\t%s\tShould register the first user as admin: %+v: %v.", tests.Failed, a, err)
+			}
+			actx := user.ContextWithSession(ctx, memberSession(a, now.Add(time.Minute)))
+			if err := uc.Delete(actx, a.ID, now); err != nil {
+				t.Fatalf("\t%s\tShould be able to delete itself: %v.", tests.Failed, err)
+			}
+
+			u, err := uc.Register(ctx, usecases.NewUser{Name: "Test User", Email: "user@example.com", Password: "gophers", PasswordConfirm: "gophers"}, now)
+			if err != nil || (user.QueryFilter{Role: user.RoleAdmin}).Match(u) {
+				t.Fatalf("\t%s\tShould not register the next user as admin: %+v: %v.", tests.Failed, u, err)
+			}
+			t.Logf("\t%s\tShould not register the next user as admin.", tests.Success)
+		}
+	}
+}
+
+func TestQueryFilter(t *testing.T) {
+	t.Parallel()
+
+	t.Log("Given the need to list users page by page.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen an admin filters and orders users.", testID)
+		{
+			ctx := context.Background()
+			store := mem.New()
+			uc := usecases.New(zap.NewNop().Sugar(), store, store, time.Minute)
+
+			a, _ := user.NewWithID("Admin User", "admin@example.com", "gophers", []string{user.RoleAdmin, user.RoleUser}, now)
+			if err := createMember(ctx, store, a); err != nil {
+				t.Fatalf("\t%s\tShould be able to store a user: %v.", tests.Failed, err)
+			}
+			for i, name := range []string{"Carol", "Alice", "Bob"} {
+				u, _ := user.NewWithID(name, strings.ToLower(name)+"@example.com", "gophers", []string{user.RoleUser}, now.Add(time.Duration(i+1)*time.Hour))
+				if err := createMember(ctx, store, u); err != nil {
+					t.Fatalf("\t%s\tShould be able to store a user: %v.", tests.Failed, err)
+				}
+			}
+			actx := user.ContextWithSession(ctx, memberSession(a, now.Add(time.Minute)))
+
+			f := user.QueryFilter{Role: user.RoleUser, Email: "@example", OrderBy: user.OrderByName}
+			users, err := uc.Query(actx, f, 1, 2)
+			if err != nil || len(users) != 2 || users[0].Name != "Admin User" || users[1].Name != "Alice" {
+				t.Fatalf("\t%s\tShould get the first page in order, but got %+v: %v.", tests.Failed, users, err)
+			}
+			users, err = uc.Query(actx, f, 2, 2)
+			if err != nil || len(users) != 2 || users[0].Name != "Bob" || users[1].Name != "Carol" {
+				t.Fatalf("\t%s\tShould get the second page in order, but got %+v: %v.", tests.Failed, users, err)
+			}
+			t.Logf("\t%s\tShould get the pages in order.", tests.Success)
+
+			f = user.QueryFilter{Role: user.RoleUser, Since: now.Add(time.Hour), Until: now.Add(3 * time.Hour)}
+			total, err := uc.Count(actx, f)
+			if err != nil || total != 2 {
+				t.Fatalf("\t%s\tShould count the users created in the range, but got %d: %v.", tests.Failed, total, err)
+			}
+			t.Logf("\t%s\tShould count the users created in the range.", tests.Success)
+
+			if _, err := uc.Query(actx, user.QueryFilter{OrderBy: "password_hash"}, 1, 2); !validation.IsValidationError(err) {
+				t.Fatalf("\t%s\tShould not be able to order by an unknown field, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not be able to order by an unknown field.", tests.Success)
+		}
+	}
+}
+
+func TestPartialUpdate(t *testing.T) {
+	t.Parallel()
+
+	t.Log("Given the need to update users partially without losing changes.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen a user updates its account.", testID)
+		{
+			ctx := context.Background()
+			store := mem.New()
+			uc := usecases.New(zap.NewNop().Sugar(), store, store, time.Minute)
+
+			u, _ := user.NewWithID("Test User", "user@example.com", "gophers", []string{user.RoleUser}, now)
+			if err := createMember(ctx, store, u); err != nil {
+				t.Fatalf("\t%s\tShould be able to store a user: %v.", tests.Failed, err)
+			}
+			u.Verified = true
+			if err := store.Update(user.ContextForSystem(ctx), u); err != nil {
+				t.Fatalf("\t%s\tShould be able to verify the user: %v.", tests.Failed, err)
+			}
+			sctx := user.ContextWithSession(ctx, memberSession(u, now.Add(time.Minute)))
+
+			email, password := "new@example.com", "new gophers"
+			later := now.Add(time.Hour)
+			got, err := uc.Update(sctx, u.ID, usecases.UpdateUser{Email: &email, Password: &password}, 2, later)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to update itself: %v.", tests.Failed, err)
+			}
+			if got.Name != u.Name || got.Email != email || got.Verified || got.Version != 3 || !got.DateUpdated.Equal(later) {
+				t.Fatalf("\t%s\tShould only change the provided fields: %+v.", tests.Failed, got)
+			}
+			stored, err := store.QueryByID(user.ContextForSystem(ctx), u.ID)
+			if err != nil || stored.Version != 3 {
+				t.Fatalf("\t%s\tShould store the new version, but got %d: %v.", tests.Failed, stored.Version, err)
+			}
+			t.Logf("\t%s\tShould only change the provided fields and bump the version.", tests.Success)
+
+			if _, err := uc.Authenticate(ctx, email, password, "", later); err != nil {
+				t.Fatalf("\t%s\tShould be able to authenticate with the new password: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould hash the new password.", tests.Success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen a user is changed concurrently.", testID)
+		{
+			ctx := context.Background()
+			store := mem.New()
+			uc := usecases.New(zap.NewNop().Sugar(), store, store, time.Minute)
+
+			u, _ := user.NewWithID("Test User", "user@example.com", "gophers", []string{user.RoleUser}, now)
+			if err := createMember(ctx, store, u); err != nil {
+				t.Fatalf("\t%s\tShould be able to store a user: %v.", tests.Failed, err)
+			}
+			sctx := user.ContextWithSession(ctx, memberSession(u, now.Add(time.Minute)))
+
+			name := "Renamed User"
+			if _, err := uc.Update(sctx, u.ID, usecases.UpdateUser{Name: &name}, 1, now); err != nil {
+				t.Fatalf("\t%s\tShould be able to update the version that was read: %v.", tests.Failed, err)
+			}
+			if _, err := uc.Update(sctx, u.ID, usecases.UpdateUser{Name: &name}, 1, now); !errors.Is(err, usecases.ErrConflict) {
+				t.Fatalf("\t%s\tShould not be able to update an old version, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not be able to update an old version.", tests.Success)
+
+			if err := store.Update(user.ContextForSystem(ctx), u); !errors.Is(err, usecases.ErrConflict) {
+				t.Fatalf("\t%s\tShould not be able to store a user that was read before a change, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not be able to store a user that was read before a change.", tests.Success)
+		}
+	}
+}
+
+func TestRehashPassword(t *testing.T) {
+	t.Parallel()
+
+	t.Log("Given the need to upgrade outdated password hashes.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen a user with a bcrypt hash authenticates.", testID)
+		{
+			ctx := context.Background()
+			store := mem.New()
+			uc := usecases.New(zap.NewNop().Sugar(), store, store, time.Minute)
+
+			u, _ := user.NewWithID("Test User", "user@example.com", "gophers", []string{user.RoleUser}, now)
+			u.PasswordHash, _ = passhash.Bcrypt("gophers", passhash.BcryptMinCost)
+			if err := createMember(ctx, store, u); err != nil {
+				t.Fatalf("\t%s\tShould be able to store a user: %v.", tests.Failed, err)
+			}
+
+			if _, err := uc.Authenticate(ctx, u.Email, "wrong", "", now); !errors.Is(err, usecases.ErrAuthenticationFailed) {
+				t.Fatalf("\t%s\tShould not authenticate with a wrong password, but got: %v.", tests.Failed, err)
+			}
+			if stored, _ := store.QueryByID(user.ContextForSystem(ctx), u.ID); passhash.Algorithm(stored.PasswordHash) != passhash.AlgorithmBcrypt {
+				t.Fatalf("\t%s\tShould keep the hash after a failed attempt.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould keep the hash after a failed attempt.", tests.Success)
+
+			if _, err := uc.Authenticate(ctx, u.Email, "gophers", "", now); err != nil {
+				t.Fatalf("\t%s\tShould be able to authenticate with a bcrypt hash: %v.", tests.Failed, err)
+			}
+			stored, err := store.QueryByID(user.ContextForSystem(ctx), u.ID)
+			if err != nil || passhash.Algorithm(stored.PasswordHash) != passhash.AlgorithmArgon2id {
+				t.Fatalf("\t%s\tShould replace the hash by an argon2id hash, but got %s: %v.", tests.Failed, stored.PasswordHash, err)
+			}
+			t.Logf("\t%s\tShould replace the hash by an argon2id hash.", tests.Success)
+
+			if _, err := uc.Authenticate(ctx, u.Email, "gophers", "", now); err != nil {
+				t.Fatalf("\t%s\tShould be able to authenticate with the new hash: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to authenticate with the new hash.", tests.Success)
+		}
+	}
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	t.Parallel()
+
+	list, err := breached.Load(strings.NewReader("5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493\n"))
+	if err != nil {
+		t.Fatalf("Should be able to load the breached passwords: %v", err)
+	}
+	policy := user.PasswordPolicy{MinLength: 8, RequireDigit: true, RejectPersonal: true, Breached: list}
+
+	t.Log("Given the need to reject weak passwords.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen registering a user.", testID)
+		{
+			ctx := context.Background()
+			store := mem.New()
+			uc := usecases.New(zap.NewNop().Sugar(), store, store, time.Minute, usecases.WithPasswordPolicy(policy))
+
+			n := usecases.NewUser{Name: "Test User", Email: "test@example.com", Password: "a", PasswordConfirm: "a"}
+			var ve validation.ValidationError
+			if _, err := uc.Register(ctx, n, now); !errors.As(err, &ve) || ve.Fields["Password"] == "" {
+				t.Fatalf("\t%s\tShould reject a weak password with a field error, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould reject a weak password with a field error.", tests.Success)
+
+			n.Password, n.PasswordConfirm = "password", "password"
+			if _, err := uc.Register(ctx, n, now); !errors.As(err, &ve) {
+				t.Fatalf("\t%s\tShould reject a breached password, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould reject a breached password.", tests.Success)
+
+			n.Password, n.PasswordConfirm = "2 gophers", "2 gophers"
+			if _, err := uc.Register(ctx, n, now); err != nil {
+				t.Fatalf("\t%s\tShould accept a strong password: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould accept a strong password.", tests.Success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen changing a password.", testID)
+		{
+			ctx := context.Background()
+			store := mem.New()
+			mailer := &mailRecorder{}
+			uc := usecases.New(zap.NewNop().Sugar(), store, store, time.Minute,
+				usecases.WithPasswordPolicy(policy), usecases.WithPasswordReset(store, mailer, time.Hour))
+
+			u, _ := user.NewWithID("Test User", "test@example.com", "gophers", []string{user.RoleUser}, now)
+			if err := createMember(ctx, store, u); err != nil {
+				t.Fatalf("\t%s\tShould be able to store a user: %v.", tests.Failed, err)
+			}
+			sctx := user.ContextWithSession(ctx, memberSession(u, now.Add(time.Minute)))
+
+			password := "test-1234"
+			if _, err := uc.Update(sctx, u.ID, usecases.UpdateUser{Password: &password}, 0, now); !validation.IsValidationError(err) {
+				t.Fatalf("\t%s\tShould reject a password with the email address, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould reject a password with the email address.", tests.Success)
+
+			if err := uc.RequestPasswordReset(ctx, u.Email, now); err != nil {
+				t.Fatalf("\t%s\tShould be able to request a password reset: %v.", tests.Failed, err)
+			}
+			token := tokenFromBody(mailer.messages[0].Body)
+
+			if _, err := uc.ResetPassword(ctx, token, "gophers", now); !validation.IsValidationError(err) {
+				t.Fatalf("\t%s\tShould reject a weak new password, but got: %v.", tests.Failed, err)
+			}
+			if _, err := uc.ResetPassword(ctx, token, "2 gophers", now); err != nil {
+				t.Fatalf("\t%s\tShould keep the token valid after a rejected password: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould keep the token valid after a rejected password.", tests.Success)
+		}
+	}
+}
+
+func TestLoginSessions(t *testing.T) {
+	t.Parallel()
+
+	t.Log("Given the need to track and end login sessions.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen a user is logged in on two devices.", testID)
+		{
+			ctx := context.Background()
+			store := mem.New()
+			uc := usecases.New(zap.NewNop().Sugar(), store, store, time.Minute,
+				usecases.WithRefreshTokens(store, time.Hour), usecases.WithLoginSessions(store))
+
+			u, _ := user.NewWithID("Test User", "test@example.com", "gophers", []string{user.RoleUser}, now)
+			if err := createMember(ctx, store, u); err != nil {
+				t.Fatalf("\t%s\tShould be able to store a user: %v.", tests.Failed, err)
+			}
+
+			login := func(userAgent string) (user.Session, string) {
+				s, err := uc.Authenticate(ctx, u.Email, "gophers", "", now)
+				if err != nil {
+					t.Fatalf("\t%s\tShould be able to authenticate: %v.", tests.Failed, err)
+				}
+				s, err = uc.StartSession(ctx, s, "192.0.2.1", userAgent, now)
+				if err != nil {
+					t.Fatalf("\t%s\tShould be able to start a login session: %v.", tests.Failed, err)
+				}
+				token, err := uc.IssueRefreshToken(ctx, s, now)
+				if err != nil {
+					t.Fatalf("\t%s\tShould be able to issue a refresh token: %v.", tests.Failed, err)
+				}
+				return s, token
+			}
+			laptop, laptopToken := login("Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/118.0")
+			phone, _ := login("curl/8.4.0")
+			if laptop.LoginSessionID == "" || laptop.LoginSessionID == phone.LoginSessionID {
+				t.Fatalf("\t%s\tShould start a login session per authentication.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould start a login session per authentication.", tests.Success)
+
+			later := now.Add(2 * time.Minute)
+			if _, err := uc.ResumeSession(ctx, laptop, laptop.LoginSessionID, "198.51.100.1", later); err != nil {
+				t.Fatalf("\t%s\tShould be able to resume an active login session: %v.", tests.Failed, err)
+			}
+			sctx := user.ContextWithSession(ctx, laptop)
+			sessions, err := uc.QuerySessions(sctx, u.ID, later)
+			if err != nil || len(sessions) != 2 {
+				t.Fatalf("\t%s\tShould list both login sessions, but got %d: %v.", tests.Failed, len(sessions), err)
+			}
+			if sessions[0].ID != laptop.LoginSessionID || sessions[0].IP != "198.51.100.1" || sessions[0].Device != "Firefox on Linux" {
+				t.Fatalf("\t%s\tShould list the login session seen last first: %+v.", tests.Failed, sessions[0])
+			}
+			t.Logf("\t%s\tShould list the login sessions with their devices.", tests.Success)
+
+			if err := uc.RevokeSession(sctx, u.ID, laptop.LoginSessionID); err != nil {
+				t.Fatalf("\t%s\tShould be able to revoke a login session: %v.", tests.Failed, err)
+			}
+			if _, err := uc.ResumeSession(ctx, laptop, laptop.LoginSessionID, "", later); !errors.Is(err, usecases.ErrAuthenticationFailed) {
+				t.Fatalf("\t%s\tShould refuse tokens of a revoked login session, but got: %v.", tests.Failed, err)
+			}
+			if _, _, err := uc.Refresh(ctx, laptopToken, "", later); !errors.Is(err, usecases.ErrAuthenticationFailed) {
+				t.Fatalf("\t%s\tShould refuse refresh tokens of a revoked login session, but got: %v.", tests.Failed, err)
+			}
+			if _, err := uc.ResumeSession(ctx, phone, phone.LoginSessionID, "", later); err != nil {
+				t.Fatalf("\t%s\tShould keep other login sessions active: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould only end the revoked login session.", tests.Success)
+
+			if err := uc.RevokeSessions(user.ContextWithSession(ctx, phone), u.ID); err != nil {
+				t.Fatalf("\t%s\tShould be able to revoke all login sessions: %v.", tests.Failed, err)
+			}
+			if _, err := uc.ResumeSession(ctx, phone, phone.LoginSessionID, "", later); !errors.Is(err, usecases.ErrAuthenticationFailed) {
+				t.Fatalf("\t%s\tShould refuse tokens of all login sessions, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould end all login sessions.", tests.Success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen the password of a user changes.", testID)
+		{
+			ctx := context.Background()
+			store := mem.New()
+			mailer := &mailRecorder{}
+			uc := usecases.New(zap.NewNop().Sugar(), store, store, time.Minute,
+				usecases.WithLoginSessions(store), usecases.WithPasswordReset(store, mailer, time.Hour))
+
+			u, _ := user.NewWithID("Test User", "test@example.com", "gophers", []string{user.RoleUser}, now)
+			if err := createMember(ctx, store, u); err != nil {
+				t.Fatalf("\t%s\tShould be able to store a user: %v.", tests.Failed, err)
+			}
+
+			login := func(password string) user.Session {
+				s, err := uc.Authenticate(ctx, u.Email, password, "", now)
+				if err != nil {
+					t.Fatalf("\t%s\tShould be able to authenticate: %v.", tests.Failed, err)
+				}
+				s, err = uc.StartSession(ctx, s, "192.0.2.1", "curl/8.4.0", now)
+				if err != nil {
+					t.Fatalf("\t%s\tShould be able to start a login session: %v.", tests.Failed, err)
+				}
+				return s
+			}
+
+			s := login("gophers")
+			password := "new gophers"
+			if _, err := uc.Update(user.ContextWithSession(ctx, s), u.ID, usecases.UpdateUser{Password: &password}, 0, now); err != nil {
+				t.Fatalf("\t%s\tShould be able to change the password: %v.", tests.Failed, err)
+			}
+			if _, err := uc.ResumeSession(ctx, s, s.LoginSessionID, "", now); !errors.Is(err, usecases.ErrAuthenticationFailed) {
+				t.Fatalf("\t%s\tShould end the login sessions after a password change, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould end the login sessions after a password change.", tests.Success)
+
+			s = login(password)
+			if err := uc.RequestPasswordReset(ctx, u.Email, now); err != nil {
+				t.Fatalf("\t%s\tShould be able to request a password reset: %v.", tests.Failed, err)
+			}
+			if _, err := uc.ResetPassword(ctx, tokenFromBody(mailer.messages[0].Body), "reset gophers", now); err != nil {
+				t.Fatalf("\t%s\tShould be able to reset the password: %v.", tests.Failed, err)
+			}
+			if _, err := uc.ResumeSession(ctx, s, s.LoginSessionID, "", now); !errors.Is(err, usecases.ErrAuthenticationFailed) {
+				t.Fatalf("\t%s\tShould end the login sessions after a password reset, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould end the login sessions after a password reset.", tests.Success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen another user lists the login sessions of a user.", testID)
+		{
+			ctx := context.Background()
+			store := mem.New()
+			uc := usecases.New(zap.NewNop().Sugar(), store, store, time.Minute, usecases.WithLoginSessions(store))
+
+			u, _ := user.NewWithID("Test User", "test@example.com", "gophers", []string{user.RoleUser}, now)
+			admin, _ := user.NewWithID("Admin", "admin@example.com", "gophers", []string{user.RoleAdmin}, now)
+			sctx := user.ContextWithSession(ctx, memberSession(admin, now.Add(time.Minute)))
+
+			if _, err := uc.QuerySessions(sctx, u.ID, now); !errors.Is(err, usecases.ErrUnauthorized) {
+				t.Fatalf("\t%s\tShould not be able to list the login sessions of others, but got: %v.", tests.Failed, err)
+			}
+			if err := uc.RevokeSessions(sctx, u.ID); !errors.Is(err, usecases.ErrUnauthorized) {
+				t.Fatalf("\t%s\tShould not be able to revoke the login sessions of others, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould only manage its own login sessions.", tests.Success)
+		}
+	}
+}
+
+func TestImpersonation(t *testing.T) {
+	t.Parallel()
+
+	t.Log("Given the need for admins to impersonate users.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen an admin impersonates a user.", testID)
+		{
+			ctx := context.Background()
+			store := mem.New()
+			trail := audits.New(zap.NewNop().Sugar(), auditmem.New())
+			uc := usecases.New(zap.NewNop().Sugar(), store, store, time.Hour,
+				usecases.WithImpersonation(15*time.Minute), usecases.WithAudit(trail))
+
+			a, _ := user.NewWithID("Admin User", "admin@example.com", "gophers", []string{user.RoleAdmin, user.RoleUser}, now)
+			u, _ := user.NewWithID("Test User", "user@example.com", "gophers", []string{user.RoleUser}, now)
+			for _, usr := range []user.User{a, u} {
+				if err := createMember(ctx, store, usr); err != nil {
+					t.Fatalf("\t%s\tShould be able to store a user: %v.", tests.Failed, err)
+				}
+			}
+			actx := user.ContextWithSession(ctx, memberSession(a, now.Add(time.Hour)))
+			uctx := user.ContextWithSession(ctx, memberSession(u, now.Add(time.Hour)))
+
+			if _, err := uc.Impersonate(uctx, a.ID, "", "", now); !errors.Is(err, usecases.ErrUnauthorized) {
+				t.Fatalf("\t%s\tShould not be able to impersonate as a user, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not be able to impersonate as a user.", tests.Success)
+
+			s, err := uc.Impersonate(actx, u.ID, "", "", now)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to impersonate a user: %v.", tests.Failed, err)
+			}
+			if s.User.ID != u.ID || s.ImpersonatorID != a.ID || !s.Expires.Equal(now.Add(15*time.Minute)) {
+				t.Fatalf("\t%s\tShould get a short-lived session of the user marked with the admin: %+v.", tests.Failed, s)
+			}
+			t.Logf("\t%s\tShould get a short-lived session of the user marked with the admin.", tests.Success)
+
+			ictx := user.ContextWithSession(ctx, s)
+			name := "Renamed User"
+			if _, err := uc.Update(ictx, u.ID, usecases.UpdateUser{Name: &name}, 0, now); err != nil {
+				t.Fatalf("\t%s\tShould be able to act as the user: %v.", tests.Failed, err)
+			}
+			password := "new gophers"
+			if _, err := uc.Update(ictx, u.ID, usecases.UpdateUser{Password: &password}, 0, now); !errors.Is(err, usecases.ErrUnauthorized) {
+				t.Fatalf("\t%s\tShould not be able to change the password of the user, but got: %v.", tests.Failed, err)
+			}
+			if _, err := uc.Impersonate(ictx, a.ID, "", "", now); !errors.Is(err, usecases.ErrUnauthorized) {
+				t.Fatalf("\t%s\tShould not be able to impersonate from an impersonated session, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould refuse sensitive actions while impersonating.", tests.Success)
+
+			entries, err := trail.Query(actx, audit.Filter{EntityID: u.ID}, 1, 10)
+			if err != nil || len(entries) != 2 {
+				t.Fatalf("\t%s\tShould record the impersonation and the change, but got %d: %v.", tests.Failed, len(entries), err)
+			}
+			for _, e := range entries {
+				switch e.Usecase {
+				case "user.Impersonate":
+					if e.ActorID != a.ID || e.ImpersonatorID != "" {
+						t.Fatalf("\t%s\tShould record the admin as actor of the impersonation: %+v.", tests.Failed, e)
+					}
+				case "user.Update":
+					if e.ActorID != u.ID || e.ImpersonatorID != a.ID {
+						t.Fatalf("\t%s\tShould record the admin as impersonator of the change: %+v.", tests.Failed, e)
+					}
+				}
+			}
+			t.Logf("\t%s\tShould record the admin in the audit trail.", tests.Success)
+
+			if _, err := uc.ResumeImpersonation(ctx, memberSession(u, s.Expires), a.ID); err != nil {
+				t.Fatalf("\t%s\tShould be able to resume the impersonation: %v.", tests.Failed, err)
+			}
+			if err := store.DeleteMembership(ctx, org.DefaultID, a.ID); err != nil {
+				t.Fatalf("\t%s\tShould be able to remove the admin from the organization: %v.", tests.Failed, err)
+			}
+			if _, err := uc.ResumeImpersonation(ctx, memberSession(u, s.Expires), a.ID); !errors.Is(err, usecases.ErrAuthenticationFailed) {
+				t.Fatalf("\t%s\tShould refuse the impersonation of a former admin, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould only resume impersonations of admins.", tests.Success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen a user that is impersonated signs out everywhere.", testID)
+		{
+			ctx := context.Background()
+			store := mem.New()
+			uc := usecases.New(zap.NewNop().Sugar(), store, store, time.Hour,
+				usecases.WithImpersonation(15*time.Minute), usecases.WithLoginSessions(store))
+
+			a, _ := user.NewWithID("Admin User", "admin@example.com", "gophers", []string{user.RoleAdmin, user.RoleUser}, now)
+			u, _ := user.NewWithID("Test User", "user@example.com", "gophers", []string{user.RoleUser}, now)
+			for _, usr := range []user.User{a, u} {
+				if err := createMember(ctx, store, usr); err != nil {
+					t.Fatalf("\t%s\tShould be able to store a user: %v.", tests.Failed, err)
+				}
+			}
+			actx := user.ContextWithSession(ctx, memberSession(a, now.Add(time.Hour)))
+
+			s, err := uc.Impersonate(actx, u.ID, "203.0.113.7", "curl/8.0", now)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to impersonate a user: %v.", tests.Failed, err)
+			}
+			if s.LoginSessionID == "" {
+				t.Fatalf("\t%s\tShould start a login session for the impersonation: %+v.", tests.Failed, s)
+			}
+			if _, err := uc.ResumeSession(ctx, s, s.LoginSessionID, "203.0.113.7", now); err != nil {
+				t.Fatalf("\t%s\tShould be able to resume the impersonation: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould start a login session for the impersonation.", tests.Success)
+
+			uctx := user.ContextWithSession(ctx, memberSession(u, now.Add(time.Hour)))
+			if err := uc.RevokeSessions(uctx, u.ID); err != nil {
+				t.Fatalf("\t%s\tShould be able to sign out everywhere: %v.", tests.Failed, err)
+			}
+			if _, err := uc.ResumeSession(ctx, s, s.LoginSessionID, "203.0.113.7", now); !errors.Is(err, usecases.ErrAuthenticationFailed) {
+				t.Fatalf("\t%s\tShould end the impersonation, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould end the impersonation when the user signs out everywhere.", tests.Success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen impersonation is not enabled.", testID)
+		{
+			ctx := context.Background()
+			store := mem.New()
+			uc := usecases.New(zap.NewNop().Sugar(), store, store, time.Hour)
+
+			a, _ := user.NewWithID("Admin User", "admin@example.com", "gophers", []string{user.RoleAdmin, user.RoleUser}, now)
+			actx := user.ContextWithSession(ctx, memberSession(a, now.Add(time.Hour)))
+			if _, err := uc.Impersonate(actx, a.ID, "", "", now); !errors.Is(err, usecases.ErrImpersonationDisabled) {
+				t.Fatalf("\t%s\tShould not be able to impersonate, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not be able to impersonate.", tests.Success)
+		}
+	}
+}
+
+func TestImportUsers(t *testing.T) {
+	t.Parallel()
+
+	t.Log("Given the need to import users in batches.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen importing new, existing and invalid users.", testID)
+		{
+			ctx := context.Background()
+			store := mem.New()
+			uc := usecases.New(zap.NewNop().Sugar(), store, store, time.Hour)
+
+			u, _ := user.NewWithID("Test User", "test@example.com", "gophers", []string{user.RoleUser}, now)
+			if err := createMember(ctx, store, u); err != nil {
+				t.Fatalf("\t%s\tShould be able to store a user: %v.", tests.Failed, err)
+			}
+
+			batch := []usecases.ImportUser{
+				{Name: "New User", Email: "new@example.com", Roles: []string{user.RoleUser}, Password: "gophers"},
+				{Name: "Renamed User", Email: "test@example.com", Roles: []string{user.RoleAdmin, user.RoleUser}},
+				{Name: "No Password", Email: "nopassword@example.com", Roles: []string{user.RoleUser}},
+				{Name: "Bad Email", Email: "not an email", Roles: []string{user.RoleUser}, Password: "gophers"},
+				{Name: "Duplicate", Email: "new@example.com", Roles: []string{user.RoleUser}, Password: "gophers"},
+			}
+			later := now.Add(time.Hour)
+
+			results, err := uc.ImportUsers(ctx, org.DefaultID, batch, true, later)
+			if err != nil || len(results) != len(batch) {
+				t.Fatalf("\t%s\tShould be able to validate the batch: %v.", tests.Failed, err)
+			}
+			if _, err := store.QueryByEmail(user.ContextForSystem(ctx), "new@example.com"); !errors.Is(err, usecases.ErrNotFound) {
+				t.Fatalf("\t%s\tShould not store users in a dry run, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not store users in a dry run.", tests.Success)
+
+			results, err = uc.ImportUsers(ctx, org.DefaultID, batch, false, later)
+			if err != nil || len(results) != len(batch) {
+				t.Fatalf("\t%s\tShould be able to import the batch: %v.", tests.Failed, err)
+			}
+			if !results[0].Created || results[0].Err != nil || results[1].Created || results[1].Err != nil || results[1].UserID != u.ID {
+				t.Fatalf("\t%s\tShould create new users and update existing ones: %+v.", tests.Failed, results[:2])
+			}
+			if !validation.IsValidationError(results[2].Err) || !validation.IsValidationError(results[3].Err) {
+				t.Fatalf("\t%s\tShould report invalid users: %+v.", tests.Failed, results[2:4])
+			}
+			if !errors.Is(results[4].Err, usecases.ErrDuplicateImport) {
+				t.Fatalf("\t%s\tShould report duplicate email addresses, but got: %v.", tests.Failed, results[4].Err)
+			}
+			t.Logf("\t%s\tShould report the result of every user.", tests.Success)
+
+			updated, err := store.QueryByEmail(user.ContextForSystem(ctx), "test@example.com")
+			if err != nil || updated.Name != "Renamed User" || updated.Version != 2 || !updated.HasPassword("gophers") {
+				t.Fatalf("\t%s\tShould update the existing user and keep its password: %+v: %v.", tests.Failed, updated, err)
+			}
+			m, err := store.QueryMembership(ctx, org.DefaultID, results[0].UserID)
+			if err != nil || len(m.Roles) != 1 || m.Roles[0] != user.RoleUser {
+				t.Fatalf("\t%s\tShould make new users members of the organization: %+v: %v.", tests.Failed, m, err)
+			}
+			m, err = store.QueryMembership(ctx, org.DefaultID, u.ID)
+			if err != nil || len(m.Roles) != 2 {
+				t.Fatalf("\t%s\tShould update the roles of existing members: %+v: %v.", tests.Failed, m, err)
+			}
+			t.Logf("\t%s\tShould store the valid users as members.", tests.Success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen importing users that were deleted or changed.", testID)
+		{
+			ctx := context.Background()
+			store := mem.New()
+			uc := usecases.New(zap.NewNop().Sugar(), store, store, time.Hour)
+
+			d, _ := user.NewWithID("Deleted User", "deleted@example.com", "gophers", []string{user.RoleUser}, now)
+			u, _ := user.NewWithID("Test User", "test@example.com", "gophers", []string{user.RoleUser}, now)
+			for _, usr := range []user.User{d, u} {
+				if err := createMember(ctx, store, usr); err != nil {
+					t.Fatalf("\t%s\tShould be able to store a user: %v.", tests.Failed, err)
+				}
+			}
+			if err := store.Delete(user.ContextForSystem(ctx), d.ID, now); err != nil {
+				t.Fatalf("\t%s\tShould be able to delete a user: %v.", tests.Failed, err)
+			}
+
+			batch := []usecases.ImportUser{
+				{Name: "Deleted User", Email: d.Email, Roles: []string{user.RoleUser}, Password: "correct horse battery"},
+				{Name: "Renamed User", Email: u.Email},
+			}
+			results, err := uc.ImportUsers(ctx, org.DefaultID, batch, false, now)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to import the other users: %v.", tests.Failed, err)
+			}
+			if !errors.Is(results[0].Err, usecases.ErrDeletedImport) || results[1].Err != nil {
+				t.Fatalf("\t%s\tShould only fail the deleted user, but got: %+v.", tests.Failed, results)
+			}
+			t.Logf("\t%s\tShould report deleted users per user.", tests.Success)
+
+			if err := store.Import(ctx, []user.User{u}, nil); !errors.Is(err, usecases.ErrConflict) {
+				t.Fatalf("\t%s\tShould not replace a user that was changed since, but got: %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not replace a user that was changed since.", tests.Success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen exporting users by role.", testID)
+		{
+			ctx := context.Background()
+			store := mem.New()
+			uc := usecases.New(zap.NewNop().Sugar(), store, store, time.Hour)
+
+			a, _ := user.NewWithID("Admin User", "admin@example.com", "gophers", []string{user.RoleAdmin, user.RoleUser}, now)
+			u, _ := user.NewWithID("Test User", "test@example.com", "gophers", []string{user.RoleUser}, now)
+			for _, usr := range []user.User{a, u} {
+				if err := createMember(ctx, store, usr); err != nil {
+					t.Fatalf("\t%s\tShould be able to store a user: %v.", tests.Failed, err)
+				}
+			}
+
+			export := func(roles ...string) []string {
+				var emails []string
+				err := uc.ExportUsers(ctx, roles, func(u user.User) error {
+					emails = append(emails, u.Email)
+					return nil
+				})
+				if err != nil {
+					t.Fatalf("\t%s\tShould be able to export users: %v.", tests.Failed, err)
+				}
+				return emails
+			}
+
+			if emails := export(); len(emails) != 2 {
+				t.Fatalf("\t%s\tShould export all users without roles: %v.", tests.Failed, emails)
+			}
+			if emails := export(user.RoleAdmin); len(emails) != 1 || emails[0] != a.Email {
+				t.Fatalf("\t%s\tShould export the users with the role: %v.", tests.Failed, emails)
+			}
+			if emails := export(user.RoleAdmin, "AUDITOR"); len(emails) != 1 || emails[0] != a.Email {
+				t.Fatalf("\t%s\tShould export the users with one of the roles: %v.", tests.Failed, emails)
+			}
+			t.Logf("\t%s\tShould export the users with one of the roles.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/appinesshq/caservice/business/user"
+	"github.com/appinesshq/caservice/foundation/mail"
+)
+
+// RequestVerification sends a new verification link to the user with the
+// provided email address. To not disclose which email addresses are
+// registered, no error is returned for unknown or already verified email
+// addresses.
+func (uc UserUseCases) RequestVerification(ctx context.Context, email string, now time.Time) error {
+	if uc.VerificationKey == nil {
+		return ErrVerificationDisabled
+	}
+	ctx = user.ContextForSystem(ctx)
+
+	u, err := uc.Repo.QueryByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if u.Verified {
+		return nil
+	}
+
+	return uc.mailVerification(ctx, u, now)
+}
+
+// VerifyEmail marks the email address of the user a verification token was
+// issued for as verified. The verified user is returned.
+func (uc UserUseCases) VerifyEmail(ctx context.Context, token string, now time.Time) (user.User, error) {
+	if uc.VerificationKey == nil {
+		return user.User{}, ErrVerificationDisabled
+	}
+	ctx = user.ContextForSystem(ctx)
+
+	id, ok := user.ParseVerificationToken(token)
+	if !ok {
+		return user.User{}, ErrInvalidVerificationToken
+	}
+
+	u, err := uc.Repo.QueryByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return user.User{}, ErrInvalidVerificationToken
+		}
+		return user.User{}, err
+	}
+
+	if !user.CheckVerificationToken(uc.VerificationKey, token, u, now) {
+		return user.User{}, ErrInvalidVerificationToken
+	}
+
+	if u.Verified {
+		return u, nil
+	}
+
+	before := u
+	u.Verified = true
+	u.DateUpdated = now
+
+	if err := uc.Repo.Update(ctx, u); err != nil {
+		return user.User{}, err
+	}
+
+	if err := uc.record(ctx, "user.VerifyEmail", u.ID, before, u); err != nil {
+		return user.User{}, err
+	}
+
+	return u, nil
+}
+
+// sendVerification sends a verification link to a new user, if email
+// verification is enabled. Failures are only logged: the user exists
+// already and can request a new link.
+func (uc UserUseCases) sendVerification(ctx context.Context, u user.User, now time.Time) {
+	if uc.VerificationKey == nil || u.Verified {
+		return
+	}
+
+	if err := uc.mailVerification(ctx, u, now); err != nil {
+		uc.Log.Errorw("verification", "status", "sending verification link", "userID", u.ID, "ERROR", err)
+	}
+}
+
+// mailVerification sends a verification link to the email address of the user.
+func (uc UserUseCases) mailVerification(ctx context.Context, u user.User, now time.Time) error {
+	expires := now.Add(uc.VerificationDuration)
+
+	link, err := url.Parse(uc.VerificationURL)
+	if err != nil {
+		return fmt.Errorf("parsing verification url: %w", err)
+	}
+	q := link.Query()
+	q.Set("token", user.NewVerificationToken(uc.VerificationKey, u, expires))
+	link.RawQuery = q.Encode()
+
+	msg := mail.Message{
+		To:      u.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Open the following link to verify your email address. The link expires at %s.\n\n"+
+			"%s\n\n"+
+			"If you did not create an account, you can ignore this email.\n",
+			u.Name, expires.Format(time.RFC1123), link),
+	}
+	if err := uc.Mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("sending verification: %w", err)
+	}
+
+	return nil
+}
@@ -5,9 +5,9 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/appinesshq/caservice/foundation/passhash"
 	"github.com/appinesshq/caservice/foundation/validation"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
 const (
@@ -16,17 +16,24 @@ const (
 )
 
 type User struct {
-	ID           string    `validate:"required,uuid"`
-	Name         string    `validate:"required"`
-	Email        string    `validate:"required,email"`
-	PasswordHash []byte    `validate:"required,notEmptyPassword"`
-	Roles        []string  `validate:"required,min=1"`
-	DateCreated  time.Time `validate:"required"`
-	DateUpdated  time.Time `validate:"required"`
+	ID            string   `validate:"required,uuid"`
+	Name          string   `validate:"required"`
+	Email         string   `validate:"required,email"`
+	PasswordHash  []byte   `json:"-" validate:"required,notEmptyPassword"`
+	Roles         []string `validate:"required,min=1"`
+	Verified      bool
+	TOTPEnabled   bool
+	TOTPSecret    []byte     `json:"-"`
+	TOTPLastStep  int64      `json:"-"`
+	RecoveryCodes []string   `json:"-"`
+	DateCreated   time.Time  `validate:"required"`
+	DateUpdated   time.Time  `validate:"required"`
+	DateDeleted   *time.Time `json:",omitempty"`
+	Version       int
 }
 
 func New(id, name, email, password string, roles []string, now time.Time) (User, error) {
-	b, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	b, err := DefaultPasswordHasher.Hash(password)
 	if err != nil {
 		return User{}, fmt.Errorf("encrypting password: %w", err)
 	}
@@ -39,6 +46,7 @@ func New(id, name, email, password string, roles []string, now time.Time) (User,
 		Roles:        roles,
 		DateCreated:  now,
 		DateUpdated:  now,
+		Version:      1,
 	}
 
 	if err := u.Validate(); err != nil {
@@ -59,9 +67,35 @@ func (u User) Validate() error {
 	return nil
 }
 
+// IsDeleted returns true if the user has been deleted.
+func (u User) IsDeleted() bool {
+	return u.DateDeleted != nil
+}
+
+// SetPassword replaces the password hash of the user with
+// a hash of the provided password.
+func (u *User) SetPassword(password string) error {
+	b, err := DefaultPasswordHasher.Hash(password)
+	if err != nil {
+		return fmt.Errorf("encrypting password: %w", err)
+	}
+	u.PasswordHash = b
+
+	return nil
+}
+
+// HasPassword returns true if the provided password matches the password
+// hash of the user, whichever supported algorithm it was hashed with.
 func (u User) HasPassword(s string) bool {
-	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(s)); err != nil {
+	if err := passhash.Compare(u.PasswordHash, s); err != nil {
 		return false
 	}
 	return true
 }
+
+// PasswordNeedsRehash returns true if the password hash of the user was not
+// made by the DefaultPasswordHasher, so it should be replaced by a new hash
+// once the password is known.
+func (u User) PasswordNeedsRehash() bool {
+	return DefaultPasswordHasher.NeedsRehash(u.PasswordHash)
+}
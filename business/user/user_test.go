@@ -98,6 +98,7 @@ func testValidUser(t *testing.T) {
 	exp.Roles = roles
 	exp.DateCreated = now
 	exp.DateUpdated = now
+	exp.Version = 1
 
 	if diff := cmp.Diff(got, exp); diff != "" {
 		t.Fatalf("\t%s\tShould get the expected result. Diff:\n%s", tests.Failed, diff)
@@ -0,0 +1,82 @@
+package user
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewVerificationToken returns a signed token that verifies the current
+// email address of the user until it expires. The token holds the user ID
+// and expiry, signed together with the email address. Nothing needs to be
+// stored: changing the email address invalidates the token.
+func NewVerificationToken(key []byte, u User, expires time.Time) string {
+	payload := u.ID + "." + strconv.FormatInt(expires.Unix(), 10)
+	enc := base64.RawURLEncoding
+
+	return enc.EncodeToString([]byte(payload)) + "." + enc.EncodeToString(signVerification(key, payload, u.Email))
+}
+
+// ParseVerificationToken returns the user ID a verification token was issued
+// for and whether the token is well-formed. The signature is not checked,
+// the user is needed for that.
+func ParseVerificationToken(token string) (string, bool) {
+	userID, _, _, ok := splitVerificationToken(token)
+	return userID, ok
+}
+
+// CheckVerificationToken reports whether the token was signed with the key
+// for the current email address of the user and has not expired.
+func CheckVerificationToken(key []byte, token string, u User, now time.Time) bool {
+	userID, expires, sig, ok := splitVerificationToken(token)
+	if !ok || userID != u.ID {
+		return false
+	}
+
+	payload := userID + "." + strconv.FormatInt(expires.Unix(), 10)
+	if !hmac.Equal(sig, signVerification(key, payload, u.Email)) {
+		return false
+	}
+
+	return now.Before(expires)
+}
+
+// splitVerificationToken decodes the parts of a verification token.
+func splitVerificationToken(token string) (string, time.Time, []byte, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return "", time.Time{}, nil, false
+	}
+
+	enc := base64.RawURLEncoding
+	payload, err := enc.DecodeString(parts[0])
+	if err != nil {
+		return "", time.Time{}, nil, false
+	}
+	sig, err := enc.DecodeString(parts[1])
+	if err != nil {
+		return "", time.Time{}, nil, false
+	}
+
+	fields := strings.Split(string(payload), ".")
+	if len(fields) != 2 {
+		return "", time.Time{}, nil, false
+	}
+	unix, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return "", time.Time{}, nil, false
+	}
+
+	return fields[0], time.Unix(unix, 0), sig, true
+}
+
+// signVerification returns the signature of a verification token payload
+// for the email address.
+func signVerification(key []byte, payload, email string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("email-verification\x00" + payload + "\x00" + email))
+	return mac.Sum(nil)
+}
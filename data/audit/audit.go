@@ -0,0 +1,27 @@
+// Package audit provides audit trail storage functionality.
+package audit
+
+import (
+	"context"
+
+	"github.com/appinesshq/caservice/business/audit"
+)
+
+// AuditStorage is an interface to be implemented by audit trail storages.
+type AuditStorage interface {
+	Create(context.Context, audit.Entry) error
+	Query(context.Context, audit.Filter, int, int) ([]audit.Entry, error)
+}
+
+// AuditRepository implements the usecases' repository.
+type AuditRepository struct {
+	Storage AuditStorage
+}
+
+func (r AuditRepository) Create(ctx context.Context, e audit.Entry) error {
+	return r.Storage.Create(ctx, e)
+}
+
+func (r AuditRepository) Query(ctx context.Context, f audit.Filter, pageNumber int, rowsPerPage int) ([]audit.Entry, error) {
+	return r.Storage.Query(ctx, f, pageNumber, rowsPerPage)
+}
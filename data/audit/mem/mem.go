@@ -0,0 +1,62 @@
+// Package mem provides memory storage functionality for the audit trail.
+// Like the database storage, it scopes queries to the organization of the
+// session in the context.
+package mem
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/appinesshq/caservice/business/audit"
+	"github.com/appinesshq/caservice/business/user"
+)
+
+type Store struct {
+	mu      sync.RWMutex
+	entries []audit.Entry
+}
+
+func New() *Store {
+	return &Store{}
+}
+
+func (m *Store) Create(ctx context.Context, e audit.Entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.entries = append(m.entries, e)
+	return nil
+}
+
+func (m *Store) Query(ctx context.Context, f audit.Filter, pageNumber int, rowsPerPage int) ([]audit.Entry, error) {
+	orgID, err := user.GetTenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entries := make([]audit.Entry, 0, len(m.entries))
+	for _, e := range m.entries {
+		if e.OrgID == orgID && f.Match(e) {
+			entries = append(entries, e)
+		}
+	}
+
+	// Newest first, like the database storage does.
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].DateCreated.After(entries[j].DateCreated) })
+
+	offset := (pageNumber - 1) * rowsPerPage
+	if offset < 0 || rowsPerPage <= 0 || offset >= len(entries) {
+		return []audit.Entry{}, nil
+	}
+
+	end := offset + rowsPerPage
+	if end > len(entries) {
+		end = len(entries)
+	}
+
+	return entries[offset:end], nil
+}
@@ -0,0 +1,78 @@
+package pg
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/appinesshq/caservice/business/audit"
+)
+
+// Entry represent the structure we need for moving audit entries
+// between the app and the database.
+type Entry struct {
+	ID             string    `db:"entry_id"`
+	OrgID          string    `db:"org_id"`
+	ActorID        string    `db:"actor_id"`
+	ImpersonatorID string    `db:"impersonator_id"`
+	Usecase        string    `db:"usecase"`
+	EntityID       string    `db:"entity_id"`
+	TraceID        string    `db:"trace_id"`
+	Changes        []byte    `db:"changes"`
+	DateCreated    time.Time `db:"date_created"`
+}
+
+// =============================================================================
+
+// The changes are stored as JSON, so unlike other models entries can't be
+// converted in place.
+
+func toEntity(dbEntry Entry) (audit.Entry, error) {
+	var changes map[string]audit.Change
+	if err := json.Unmarshal(dbEntry.Changes, &changes); err != nil {
+		return audit.Entry{}, fmt.Errorf("decoding changes of entryID[%s]: %w", dbEntry.ID, err)
+	}
+
+	return audit.Entry{
+		ID:             dbEntry.ID,
+		OrgID:          dbEntry.OrgID,
+		ActorID:        dbEntry.ActorID,
+		ImpersonatorID: dbEntry.ImpersonatorID,
+		Usecase:        dbEntry.Usecase,
+		EntityID:       dbEntry.EntityID,
+		TraceID:        dbEntry.TraceID,
+		Changes:        changes,
+		DateCreated:    dbEntry.DateCreated,
+	}, nil
+}
+
+func toEntitySlice(dbEntries []Entry) ([]audit.Entry, error) {
+	entries := make([]audit.Entry, len(dbEntries))
+	for i, dbEntry := range dbEntries {
+		e, err := toEntity(dbEntry)
+		if err != nil {
+			return nil, err
+		}
+		entries[i] = e
+	}
+	return entries, nil
+}
+
+func toEntry(e audit.Entry) (Entry, error) {
+	changes, err := json.Marshal(e.Changes)
+	if err != nil {
+		return Entry{}, fmt.Errorf("encoding changes of entryID[%s]: %w", e.ID, err)
+	}
+
+	return Entry{
+		ID:             e.ID,
+		OrgID:          e.OrgID,
+		ActorID:        e.ActorID,
+		ImpersonatorID: e.ImpersonatorID,
+		Usecase:        e.Usecase,
+		EntityID:       e.EntityID,
+		TraceID:        e.TraceID,
+		Changes:        changes,
+		DateCreated:    e.DateCreated,
+	}, nil
+}
@@ -0,0 +1,114 @@
+// Package pg provides postgres storage functionality for the audit trail.
+//
+// The audit trail is append-only: entries are inserted and queried, never
+// updated or deleted. Queries are scoped to the organization of the session
+// in the context.
+package pg
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/appinesshq/caservice/business/audit"
+	"github.com/appinesshq/caservice/business/user"
+	database "github.com/appinesshq/caservice/data/core/pg"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// Store manages the set of APIs for audit trail access.
+type Store struct {
+	log *zap.SugaredLogger
+	db  sqlx.ExtContext
+}
+
+// NewStore constructs a data for api access.
+func NewStore(log *zap.SugaredLogger, db *sqlx.DB) Store {
+	return Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// Create inserts a new entry into the database.
+func (s Store) Create(ctx context.Context, e audit.Entry) error {
+	const q = `
+	INSERT INTO audit_entries
+		(entry_id, org_id, actor_id, impersonator_id, usecase, entity_id, trace_id, changes, date_created)
+	VALUES
+		(:entry_id, :org_id, :actor_id, :impersonator_id, :usecase, :entity_id, :trace_id, :changes, :date_created)`
+
+	dbEntry, err := toEntry(e)
+	if err != nil {
+		return err
+	}
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, dbEntry); err != nil {
+		return fmt.Errorf("inserting audit entry: %w", err)
+	}
+
+	return nil
+}
+
+// Query retrieves the entries of the organization of the session that match
+// the filter from the database, newest first.
+func (s Store) Query(ctx context.Context, f audit.Filter, pageNumber int, rowsPerPage int) ([]audit.Entry, error) {
+	orgID, err := user.GetTenantID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("scoping audit entries: %w", err)
+	}
+
+	data := struct {
+		OrgID       string    `db:"org_id"`
+		ActorID     string    `db:"actor_id"`
+		EntityID    string    `db:"entity_id"`
+		Since       time.Time `db:"since"`
+		Until       time.Time `db:"until"`
+		Offset      int       `db:"offset"`
+		RowsPerPage int       `db:"rows_per_page"`
+	}{
+		OrgID:       orgID,
+		ActorID:     f.ActorID,
+		EntityID:    f.EntityID,
+		Since:       f.Since,
+		Until:       f.Until,
+		Offset:      (pageNumber - 1) * rowsPerPage,
+		RowsPerPage: rowsPerPage,
+	}
+
+	q := `
+	SELECT
+		*
+	FROM
+		audit_entries
+	WHERE
+		org_id = :org_id`
+	if f.ActorID != "" {
+		q += ` AND
+		actor_id = :actor_id`
+	}
+	if f.EntityID != "" {
+		q += ` AND
+		entity_id = :entity_id`
+	}
+	if !f.Since.IsZero() {
+		q += ` AND
+		date_created >= :since`
+	}
+	if !f.Until.IsZero() {
+		q += ` AND
+		date_created < :until`
+	}
+	q += `
+	ORDER BY
+		date_created DESC
+	OFFSET :offset ROWS FETCH NEXT :rows_per_page ROWS ONLY`
+
+	var dbEntries []Entry
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbEntries); err != nil {
+		return nil, fmt.Errorf("selecting audit entries: %w", err)
+	}
+
+	return toEntitySlice(dbEntries)
+}
@@ -1,3 +1,13 @@
+DELETE FROM audit_entries;
+DELETE FROM memberships;
+DELETE FROM api_keys;
+DELETE FROM identities;
+DELETE FROM login_failures;
+DELETE FROM revoked_subjects;
+DELETE FROM revoked_tokens;
+DELETE FROM one_time_tokens;
+DELETE FROM refresh_tokens;
 DELETE FROM sales;
 DELETE FROM products;
-DELETE FROM users;
\ No newline at end of file
+DELETE FROM users;
+DELETE FROM organizations WHERE org_id != '06726073-2051-4458-87e2-da9956ac6531';
\ No newline at end of file
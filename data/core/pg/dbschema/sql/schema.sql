@@ -41,3 +41,220 @@ CREATE TABLE sales (
 	FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE,
 	FOREIGN KEY (product_id) REFERENCES products(product_id) ON DELETE CASCADE
 );
+
+-- Version: 1.4
+-- Description: Create table refresh_tokens
+CREATE TABLE refresh_tokens (
+	token_id     UUID,
+	family_id    UUID,
+	user_id      UUID,
+	token_hash   TEXT UNIQUE,
+	used         BOOLEAN DEFAULT FALSE,
+	revoked      BOOLEAN DEFAULT FALSE,
+	date_created TIMESTAMP,
+	date_expires TIMESTAMP,
+
+	PRIMARY KEY (token_id),
+	FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+-- Version: 1.5
+-- Description: Create tables for revoked tokens
+CREATE TABLE revoked_tokens (
+	token_id     TEXT,
+	date_expires TIMESTAMP,
+
+	PRIMARY KEY (token_id)
+);
+
+CREATE TABLE revoked_subjects (
+	subject      TEXT,
+	date_revoked TIMESTAMP,
+
+	PRIMARY KEY (subject)
+);
+
+-- Version: 1.6
+-- Description: Create table one_time_tokens
+CREATE TABLE one_time_tokens (
+	token_id     UUID,
+	user_id      UUID,
+	purpose      TEXT,
+	token_hash   TEXT UNIQUE,
+	used         BOOLEAN DEFAULT FALSE,
+	date_created TIMESTAMP,
+	date_expires TIMESTAMP,
+
+	PRIMARY KEY (token_id),
+	FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+-- Version: 1.7
+-- Description: Add verified column to users
+ALTER TABLE users ADD COLUMN verified BOOLEAN DEFAULT FALSE;
+
+-- Accounts created before email verification was introduced are trusted.
+UPDATE users SET verified = TRUE;
+
+-- Version: 1.8
+-- Description: Create table login_failures
+CREATE TABLE login_failures (
+	key               TEXT,
+	failures          INT,
+	date_last_failure TIMESTAMP,
+
+	PRIMARY KEY (key)
+);
+
+-- Version: 1.9
+-- Description: Add two-factor authentication columns to users
+ALTER TABLE users
+	ADD COLUMN totp_secret    BYTEA,
+	ADD COLUMN totp_enabled   BOOLEAN DEFAULT FALSE,
+	ADD COLUMN totp_last_step BIGINT DEFAULT 0,
+	ADD COLUMN recovery_codes TEXT[] DEFAULT '{}';
+
+-- Version: 1.10
+-- Description: Create table identities
+CREATE TABLE identities (
+	identity_id  UUID,
+	user_id      UUID NOT NULL,
+	provider     TEXT NOT NULL,
+	subject      TEXT NOT NULL,
+	email        TEXT,
+	date_created TIMESTAMP,
+
+	PRIMARY KEY (identity_id),
+	UNIQUE (provider, subject),
+	FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+-- Version: 1.11
+-- Description: Create table api_keys
+CREATE TABLE api_keys (
+	key_id         UUID,
+	user_id        UUID NOT NULL,
+	name           TEXT,
+	key_hash       TEXT UNIQUE,
+	scopes         TEXT[],
+	revoked        BOOLEAN DEFAULT FALSE,
+	date_created   TIMESTAMP,
+	date_expires   TIMESTAMP,
+	date_last_used TIMESTAMP,
+
+	PRIMARY KEY (key_id),
+	FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+-- Version: 1.12
+-- Description: Create tables organizations and memberships, scope data to organizations
+CREATE TABLE organizations (
+	org_id       UUID,
+	name         TEXT,
+	date_created TIMESTAMP,
+	date_updated TIMESTAMP,
+
+	PRIMARY KEY (org_id)
+);
+
+CREATE TABLE memberships (
+	org_id       UUID,
+	user_id      UUID,
+	roles        TEXT[],
+	date_created TIMESTAMP,
+
+	PRIMARY KEY (org_id, user_id),
+	FOREIGN KEY (org_id) REFERENCES organizations(org_id) ON DELETE CASCADE,
+	FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+-- Existing users and their data belong to the default organization.
+INSERT INTO organizations (org_id, name, date_created, date_updated) VALUES
+	('06726073-2051-4458-87e2-da9956ac6531', 'Default', NOW(), NOW());
+
+INSERT INTO memberships (org_id, user_id, roles, date_created)
+	SELECT '06726073-2051-4458-87e2-da9956ac6531', user_id, roles, date_created FROM users;
+
+ALTER TABLE products ADD COLUMN org_id UUID NOT NULL DEFAULT '06726073-2051-4458-87e2-da9956ac6531' REFERENCES organizations(org_id) ON DELETE CASCADE;
+ALTER TABLE sales ADD COLUMN org_id UUID NOT NULL DEFAULT '06726073-2051-4458-87e2-da9956ac6531' REFERENCES organizations(org_id) ON DELETE CASCADE;
+ALTER TABLE refresh_tokens ADD COLUMN org_id UUID NOT NULL DEFAULT '06726073-2051-4458-87e2-da9956ac6531' REFERENCES organizations(org_id) ON DELETE CASCADE;
+ALTER TABLE api_keys ADD COLUMN org_id UUID NOT NULL DEFAULT '06726073-2051-4458-87e2-da9956ac6531' REFERENCES organizations(org_id) ON DELETE CASCADE;
+
+-- New rows must name their organization.
+ALTER TABLE products ALTER COLUMN org_id DROP DEFAULT;
+ALTER TABLE sales ALTER COLUMN org_id DROP DEFAULT;
+ALTER TABLE refresh_tokens ALTER COLUMN org_id DROP DEFAULT;
+ALTER TABLE api_keys ALTER COLUMN org_id DROP DEFAULT;
+
+-- Version: 1.13
+-- Description: Create table audit_entries
+-- Entries outlive the users and organizations they refer to, so there are no
+-- foreign keys. Entries made without a session have no actor and organization.
+CREATE TABLE audit_entries (
+	entry_id     UUID,
+	org_id       TEXT NOT NULL,
+	actor_id     TEXT NOT NULL,
+	usecase      TEXT NOT NULL,
+	entity_id    TEXT NOT NULL,
+	trace_id     TEXT NOT NULL,
+	changes      JSONB NOT NULL,
+	date_created TIMESTAMP,
+
+	PRIMARY KEY (entry_id)
+);
+
+-- Version: 1.14
+-- Description: Add soft deletion of users
+-- Deleted users are kept until they are purged after the retention period.
+ALTER TABLE users ADD COLUMN date_deleted TIMESTAMP;
+
+-- Version: 1.15
+-- Description: Add versions of users
+-- Every write bumps the version, so concurrent writes can be detected.
+ALTER TABLE users ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+-- Version: 1.16
+-- Description: Create table login_sessions
+-- The refresh tokens of a login session are the family with its id.
+CREATE TABLE login_sessions (
+	session_id     UUID,
+	user_id        UUID NOT NULL,
+	device         TEXT,
+	ip             TEXT,
+	user_agent     TEXT,
+	revoked        BOOLEAN DEFAULT FALSE,
+	date_created   TIMESTAMP,
+	date_last_seen TIMESTAMP,
+	date_expires   TIMESTAMP,
+
+	PRIMARY KEY (session_id),
+	FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+-- Version: 1.17
+-- Description: Add impersonators to audit_entries
+-- Entries made in an impersonated session record the admin as impersonator.
+ALTER TABLE audit_entries ADD COLUMN impersonator_id TEXT NOT NULL DEFAULT '';
+
+-- Version: 1.18
+-- Description: Create table org_invitations
+-- Users only become a member of an organization once they accept.
+CREATE TABLE org_invitations (
+	invitation_id UUID,
+	org_id        UUID NOT NULL,
+	user_id       UUID NOT NULL,
+	roles         TEXT[],
+	invited_by    UUID NOT NULL,
+	date_created  TIMESTAMP,
+
+	PRIMARY KEY (invitation_id),
+	UNIQUE (org_id, user_id),
+	FOREIGN KEY (org_id) REFERENCES organizations(org_id) ON DELETE CASCADE,
+	FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE,
+	FOREIGN KEY (invited_by) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+-- Version: 1.19
+-- Description: Add attempts to one_time_tokens
+-- Challenges are invalidated after a few wrong codes.
+ALTER TABLE one_time_tokens ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
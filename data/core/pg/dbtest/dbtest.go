@@ -5,6 +5,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
 	"fmt"
@@ -13,8 +14,10 @@ import (
 
 	// TODO: Refactor. Should not require auth on this level.
 	"github.com/appinesshq/caservice/app/services/sales-api/web/auth"
+	"github.com/appinesshq/caservice/business/org"
 	database "github.com/appinesshq/caservice/data/core/pg"
 	"github.com/appinesshq/caservice/data/core/pg/dbschema"
+	revocationpg "github.com/appinesshq/caservice/data/revocation/pg"
 	dbUser "github.com/appinesshq/caservice/data/user/pg"
 	"github.com/appinesshq/caservice/foundation/docker"
 	"github.com/appinesshq/caservice/foundation/keystore"
@@ -148,7 +151,8 @@ func NewIntegration(t *testing.T, c *docker.Container, dbName string) *Test {
 	}
 
 	// Build an authenticator using this private key and id for the key store.
-	auth, err := auth.New(keyID, keystore.NewMap(map[string]*rsa.PrivateKey{keyID: privateKey}))
+	ks := keystore.NewMap(map[string]crypto.Signer{keyID: privateKey})
+	auth, err := auth.New(keyID, ks, auth.WithRevocationStore(revocationpg.NewStore(log, db)))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -181,7 +185,8 @@ func (test *Test) Token(email, pass string) string {
 			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(time.Hour)),
 			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
 		},
-		Roles: dbUsr.Roles,
+		Roles:    dbUsr.Roles,
+		TenantID: org.DefaultID,
 	}
 
 	token, err := test.Auth.GenerateToken(claims)
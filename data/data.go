@@ -1,8 +1,23 @@
 // Package data provides functionality for data interaction.
 package data
 
-import user "github.com/appinesshq/caservice/business/user/usecases"
+import (
+	audit "github.com/appinesshq/caservice/business/audit/usecases"
+	product "github.com/appinesshq/caservice/business/product/usecases"
+	sale "github.com/appinesshq/caservice/business/sale/usecases"
+	user "github.com/appinesshq/caservice/business/user/usecases"
+)
 
 type Repositories struct {
-	UserRepo user.UserRepository
+	UserRepo         user.UserRepository
+	OrgRepo          user.OrgRepository
+	RefreshTokenRepo user.RefreshTokenRepository
+	OneTimeTokenRepo user.OneTimeTokenRepository
+	LoginAttemptRepo user.LoginAttemptRepository
+	IdentityRepo     user.IdentityRepository
+	APIKeyRepo       user.APIKeyRepository
+	LoginSessionRepo user.LoginSessionRepository
+	ProductRepo      product.ProductRepository
+	SaleRepo         sale.SaleRepository
+	AuditRepo        audit.AuditRepository
 }
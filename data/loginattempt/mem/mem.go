@@ -0,0 +1,62 @@
+// Package mem provides memory storage functionality for failed login attempts.
+package mem
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// failures holds the number of failures of a key and the time of the last one.
+type failures struct {
+	count int
+	last  time.Time
+}
+
+type Store struct {
+	mu       sync.Mutex
+	failures map[string]failures
+}
+
+func New() *Store {
+	return &Store{
+		failures: make(map[string]failures),
+	}
+}
+
+// RecordLoginFailure counts a failure of the key at now and returns the number
+// of failures of the key. Failures of the key before since are forgotten.
+// Other keys are left alone, their policies may remember failures longer.
+func (m *Store) RecordLoginFailure(ctx context.Context, key string, now, since time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f := m.failures[key]
+	if f.last.Before(since) {
+		f.count = 0
+	}
+	f.count++
+	f.last = now
+	m.failures[key] = f
+
+	return f.count, nil
+}
+
+// QueryLoginFailures returns the number of failures of the key and the time
+// of the last failure.
+func (m *Store) QueryLoginFailures(ctx context.Context, key string) (int, time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f := m.failures[key]
+	return f.count, f.last, nil
+}
+
+// ResetLoginFailures forgets the failures of the key.
+func (m *Store) ResetLoginFailures(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.failures, key)
+	return nil
+}
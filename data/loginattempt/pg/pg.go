@@ -0,0 +1,117 @@
+// Package pg provides postgres storage functionality for failed login attempts.
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	database "github.com/appinesshq/caservice/data/core/pg"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// Store manages the set of APIs for failed login attempt access.
+type Store struct {
+	log *zap.SugaredLogger
+	db  sqlx.ExtContext
+}
+
+// NewStore constructs a data for api access.
+func NewStore(log *zap.SugaredLogger, db *sqlx.DB) Store {
+	return Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// RecordLoginFailure counts a failure of the key at now and returns the number
+// of failures of the key. Failures of the key before since are forgotten.
+// Other keys are left alone, their policies may remember failures longer.
+func (s Store) RecordLoginFailure(ctx context.Context, key string, now, since time.Time) (int, error) {
+	data := struct {
+		Key   string    `db:"key"`
+		Now   time.Time `db:"now"`
+		Since time.Time `db:"since"`
+	}{
+		Key:   key,
+		Now:   now.UTC(),
+		Since: since.UTC(),
+	}
+
+	const q = `
+	INSERT INTO login_failures
+		(key, failures, date_last_failure)
+	VALUES
+		(:key, 1, :now)
+	ON CONFLICT (key) DO UPDATE SET
+		failures = CASE
+			WHEN login_failures.date_last_failure < :since THEN 1
+			ELSE login_failures.failures + 1
+		END,
+		date_last_failure = EXCLUDED.date_last_failure
+	RETURNING
+		failures`
+
+	var result struct {
+		Failures int `db:"failures"`
+	}
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &result); err != nil {
+		return 0, fmt.Errorf("recording login failure for key[%s]: %w", key, err)
+	}
+
+	return result.Failures, nil
+}
+
+// QueryLoginFailures returns the number of failures of the key and the time
+// of the last failure.
+func (s Store) QueryLoginFailures(ctx context.Context, key string) (int, time.Time, error) {
+	data := struct {
+		Key string `db:"key"`
+	}{
+		Key: key,
+	}
+
+	const q = `
+	SELECT
+		failures, date_last_failure
+	FROM
+		login_failures
+	WHERE
+		key = :key`
+
+	var result struct {
+		Failures        int       `db:"failures"`
+		DateLastFailure time.Time `db:"date_last_failure"`
+	}
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &result); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return 0, time.Time{}, nil
+		}
+		return 0, time.Time{}, fmt.Errorf("querying login failures for key[%s]: %w", key, err)
+	}
+
+	return result.Failures, result.DateLastFailure, nil
+}
+
+// ResetLoginFailures forgets the failures of the key.
+func (s Store) ResetLoginFailures(ctx context.Context, key string) error {
+	data := struct {
+		Key string `db:"key"`
+	}{
+		Key: key,
+	}
+
+	const q = `
+	DELETE FROM
+		login_failures
+	WHERE
+		key = :key`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("resetting login failures for key[%s]: %w", key, err)
+	}
+
+	return nil
+}
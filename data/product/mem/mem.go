@@ -0,0 +1,182 @@
+// Package mem provides memory storage functionality for products.
+// Like the database storage, it scopes all queries to the organization of
+// the session in the context.
+package mem
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/appinesshq/caservice/business/product"
+	"github.com/appinesshq/caservice/business/product/usecases"
+	"github.com/appinesshq/caservice/business/user"
+)
+
+type Store struct {
+	mu       sync.RWMutex
+	products map[string]product.Product
+}
+
+func New() *Store {
+	return &Store{products: make(map[string]product.Product)}
+}
+
+func (m *Store) Create(ctx context.Context, p product.Product) error {
+	orgID, err := user.GetTenantID(ctx)
+	if err != nil {
+		return err
+	}
+	p.OrgID = orgID
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Return an error if the ID already exists.
+	if _, exists := m.products[p.ID]; exists {
+		return usecases.ErrUniqueID
+	}
+
+	m.products[p.ID] = p
+	return nil
+}
+
+func (m *Store) Query(ctx context.Context, pageNumber int, rowsPerPage int) ([]product.Product, error) {
+	orgID, err := user.GetTenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	prds := make([]product.Product, 0, len(m.products))
+	for _, p := range m.products {
+		if p.OrgID == orgID {
+			prds = append(prds, p)
+		}
+	}
+
+	// Order by id, like the database storage does.
+	sort.Slice(prds, func(i, j int) bool { return prds[i].ID < prds[j].ID })
+
+	return paginate(prds, pageNumber, rowsPerPage), nil
+}
+
+func (m *Store) QueryByID(ctx context.Context, id string) (product.Product, error) {
+	orgID, err := user.GetTenantID(ctx)
+	if err != nil {
+		return product.Product{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	p, ok := m.products[id]
+	if !ok || p.OrgID != orgID {
+		return product.Product{}, usecases.ErrNotFound
+	}
+
+	return p, nil
+}
+
+func (m *Store) QueryByUserID(ctx context.Context, userID string) ([]product.Product, error) {
+	orgID, err := user.GetTenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	prds := []product.Product{}
+	for _, p := range m.products {
+		if p.UserID == userID && p.OrgID == orgID {
+			prds = append(prds, p)
+		}
+	}
+	sort.Slice(prds, func(i, j int) bool { return prds[i].ID < prds[j].ID })
+
+	return prds, nil
+}
+
+func (m *Store) Update(ctx context.Context, p product.Product, quantity int) error {
+	orgID, err := user.GetTenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, exists := m.products[p.ID]
+	if !exists || current.OrgID != orgID {
+		return usecases.ErrNotFound
+	}
+	if current.Quantity != quantity {
+		return usecases.ErrConflict
+	}
+
+	p.OrgID = orgID
+	m.products[p.ID] = p
+	return nil
+}
+
+func (m *Store) Delete(ctx context.Context, id string) error {
+	orgID, err := user.GetTenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Delete returns a nil error in case of not found.
+	if p, exists := m.products[id]; exists && p.OrgID == orgID {
+		delete(m.products, id)
+	}
+	return nil
+}
+
+// DecrementQuantity lowers the stock of a product by the provided quantity
+// and returns the product as updated. It returns false, and leaves the stock
+// untouched, when the product has less stock than the provided quantity.
+func (m *Store) DecrementQuantity(ctx context.Context, id string, quantity int, now time.Time) (product.Product, bool, error) {
+	orgID, err := user.GetTenantID(ctx)
+	if err != nil {
+		return product.Product{}, false, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, exists := m.products[id]
+	if !exists || p.OrgID != orgID {
+		return product.Product{}, false, usecases.ErrNotFound
+	}
+	if p.Quantity < quantity {
+		return product.Product{}, false, nil
+	}
+
+	p.Quantity -= quantity
+	p.DateUpdated = now
+	m.products[id] = p
+
+	return p, true, nil
+}
+
+// paginate returns the requested page of the provided products.
+func paginate(prds []product.Product, pageNumber int, rowsPerPage int) []product.Product {
+	offset := (pageNumber - 1) * rowsPerPage
+	if offset < 0 || rowsPerPage <= 0 || offset >= len(prds) {
+		return []product.Product{}
+	}
+
+	end := offset + rowsPerPage
+	if end > len(prds) {
+		end = len(prds)
+	}
+
+	return prds[offset:end]
+}
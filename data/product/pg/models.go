@@ -0,0 +1,41 @@
+package pg
+
+import (
+	"time"
+	"unsafe"
+
+	"github.com/appinesshq/caservice/business/product"
+)
+
+// Product represent the structure we need for moving data
+// between the app and the database.
+type Product struct {
+	ID          string    `db:"product_id"`
+	Name        string    `db:"name"`
+	Cost        int       `db:"cost"`
+	Quantity    int       `db:"quantity"`
+	UserID      string    `db:"user_id"`
+	OrgID       string    `db:"org_id"`
+	DateCreated time.Time `db:"date_created"`
+	DateUpdated time.Time `db:"date_updated"`
+}
+
+// =============================================================================
+
+func toEntity(dbPrd Product) product.Product {
+	pp := (*product.Product)(unsafe.Pointer(&dbPrd))
+	return *pp
+}
+
+func toEntitySlice(dbPrds []Product) []product.Product {
+	prds := make([]product.Product, len(dbPrds))
+	for i, dbPrd := range dbPrds {
+		prds[i] = toEntity(dbPrd)
+	}
+	return prds
+}
+
+func toProduct(p product.Product) Product {
+	pp := (*Product)(unsafe.Pointer(&p))
+	return *pp
+}
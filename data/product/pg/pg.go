@@ -0,0 +1,266 @@
+// Package pg provides postgres storage functionality for products.
+//
+// Products belong to an organization. All queries are scoped to the
+// organization of the session in the context, so products of other
+// organizations can't be reached.
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/appinesshq/caservice/business/product"
+	"github.com/appinesshq/caservice/business/product/usecases"
+	"github.com/appinesshq/caservice/business/user"
+	database "github.com/appinesshq/caservice/data/core/pg"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// Store manages the set of APIs for product access.
+type Store struct {
+	log          *zap.SugaredLogger
+	tr           database.Transactor
+	db           sqlx.ExtContext
+	isWithinTran bool
+}
+
+// NewStore constructs a data for api access.
+func NewStore(log *zap.SugaredLogger, db *sqlx.DB) Store {
+	return Store{
+		log: log,
+		tr:  db,
+		db:  db,
+	}
+}
+
+// WithinTran runs passed function and do commit/rollback at the end.
+func (s Store) WithinTran(ctx context.Context, fn func(sqlx.ExtContext) error) error {
+	if s.isWithinTran {
+		return fn(s.db)
+	}
+	return database.WithinTran(ctx, s.log, s.tr, fn)
+}
+
+// Tran return new Store with transaction in it.
+func (s Store) Tran(tx sqlx.ExtContext) Store {
+	return Store{
+		log:          s.log,
+		tr:           s.tr,
+		db:           tx,
+		isWithinTran: true,
+	}
+}
+
+// Create inserts a new product into the database, within the organization
+// of the session.
+func (s Store) Create(ctx context.Context, p product.Product) error {
+	orgID, err := user.GetTenantID(ctx)
+	if err != nil {
+		return fmt.Errorf("scoping product: %w", err)
+	}
+
+	const q = `
+	INSERT INTO products
+		(product_id, org_id, name, cost, quantity, user_id, date_created, date_updated)
+	VALUES
+		(:product_id, :org_id, :name, :cost, :quantity, :user_id, :date_created, :date_updated)`
+
+	// Convert entity to DB product model.
+	prd := toProduct(p)
+	prd.OrgID = orgID
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, prd); err != nil {
+		if errors.Is(err, database.ErrDBDuplicatedEntry) {
+			return usecases.ErrUniqueID
+		}
+		return fmt.Errorf("inserting product: %w", err)
+	}
+
+	return nil
+}
+
+// Update replaces a product document in the database, if its stock still is
+// the provided quantity.
+func (s Store) Update(ctx context.Context, p product.Product, quantity int) error {
+	orgID, err := user.GetTenantID(ctx)
+	if err != nil {
+		return fmt.Errorf("scoping product: %w", err)
+	}
+
+	const q = `
+	UPDATE
+		products
+	SET
+		"name" = :name,
+		"cost" = :cost,
+		"quantity" = :quantity,
+		"date_updated" = :date_updated
+	WHERE
+		product_id = :product_id AND
+		org_id = :org_id AND
+		quantity = :old_quantity
+	RETURNING
+		product_id`
+
+	// Convert entity to DB product model.
+	data := struct {
+		Product
+		OldQuantity int `db:"old_quantity"`
+	}{
+		Product:     toProduct(p),
+		OldQuantity: quantity,
+	}
+	data.OrgID = orgID
+
+	var updated struct {
+		ID string `db:"product_id"`
+	}
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &updated); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			// No product was updated: it is gone or its stock changed.
+			if _, err := s.QueryByID(ctx, p.ID); err != nil {
+				return err
+			}
+			return usecases.ErrConflict
+		}
+		return fmt.Errorf("updating productID[%s]: %w", p.ID, err)
+	}
+
+	return nil
+}
+
+// Delete removes a product from the database.
+func (s Store) Delete(ctx context.Context, productID string) error {
+	orgID, err := user.GetTenantID(ctx)
+	if err != nil {
+		return fmt.Errorf("scoping product: %w", err)
+	}
+
+	data := struct {
+		ProductID string `db:"product_id"`
+		OrgID     string `db:"org_id"`
+	}{
+		ProductID: productID,
+		OrgID:     orgID,
+	}
+
+	const q = `
+	DELETE FROM
+		products
+	WHERE
+		product_id = :product_id AND
+		org_id = :org_id`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("deleting productID[%s]: %w", productID, err)
+	}
+
+	return nil
+}
+
+// Query retrieves a list of existing products from the database.
+func (s Store) Query(ctx context.Context, pageNumber int, rowsPerPage int) ([]product.Product, error) {
+	orgID, err := user.GetTenantID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("scoping products: %w", err)
+	}
+
+	data := struct {
+		OrgID       string `db:"org_id"`
+		Offset      int    `db:"offset"`
+		RowsPerPage int    `db:"rows_per_page"`
+	}{
+		OrgID:       orgID,
+		Offset:      (pageNumber - 1) * rowsPerPage,
+		RowsPerPage: rowsPerPage,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		products
+	WHERE
+		org_id = :org_id
+	ORDER BY
+		product_id
+	OFFSET :offset ROWS FETCH NEXT :rows_per_page ROWS ONLY`
+
+	var prds []Product
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &prds); err != nil {
+		return nil, fmt.Errorf("selecting products: %w", err)
+	}
+
+	return toEntitySlice(prds), nil
+}
+
+// QueryByID gets the specified product from the database.
+func (s Store) QueryByID(ctx context.Context, productID string) (product.Product, error) {
+	orgID, err := user.GetTenantID(ctx)
+	if err != nil {
+		return product.Product{}, fmt.Errorf("scoping product: %w", err)
+	}
+
+	data := struct {
+		ProductID string `db:"product_id"`
+		OrgID     string `db:"org_id"`
+	}{
+		ProductID: productID,
+		OrgID:     orgID,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		products
+	WHERE
+		product_id = :product_id AND
+		org_id = :org_id`
+
+	var prd Product
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &prd); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return product.Product{}, usecases.ErrNotFound
+		}
+		return product.Product{}, fmt.Errorf("selecting productID[%q]: %w", productID, err)
+	}
+
+	return toEntity(prd), nil
+}
+
+// QueryByUserID gets the products listed by the specified user from the database.
+func (s Store) QueryByUserID(ctx context.Context, userID string) ([]product.Product, error) {
+	orgID, err := user.GetTenantID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("scoping products: %w", err)
+	}
+
+	data := struct {
+		UserID string `db:"user_id"`
+		OrgID  string `db:"org_id"`
+	}{
+		UserID: userID,
+		OrgID:  orgID,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		products
+	WHERE
+		user_id = :user_id AND
+		org_id = :org_id
+	ORDER BY
+		product_id`
+
+	var prds []Product
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &prds); err != nil {
+		return nil, fmt.Errorf("selecting products userID[%q]: %w", userID, err)
+	}
+
+	return toEntitySlice(prds), nil
+}
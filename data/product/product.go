@@ -0,0 +1,47 @@
+// Package product provides product storage functionality.
+package product
+
+import (
+	"context"
+
+	"github.com/appinesshq/caservice/business/product"
+)
+
+// ProductStorage is an interface to be implemented by product storages.
+type ProductStorage interface {
+	Create(context.Context, product.Product) error
+	Query(context.Context, int, int) ([]product.Product, error)
+	QueryByID(context.Context, string) (product.Product, error)
+	QueryByUserID(context.Context, string) ([]product.Product, error)
+	Update(context.Context, product.Product, int) error
+	Delete(context.Context, string) error
+}
+
+// ProductRepository implements the usecases' repository.
+type ProductRepository struct {
+	Storage ProductStorage
+}
+
+func (r ProductRepository) Create(ctx context.Context, p product.Product) error {
+	return r.Storage.Create(ctx, p)
+}
+
+func (r ProductRepository) Query(ctx context.Context, pageNumber int, rowsPerPage int) ([]product.Product, error) {
+	return r.Storage.Query(ctx, pageNumber, rowsPerPage)
+}
+
+func (r ProductRepository) QueryByID(ctx context.Context, id string) (product.Product, error) {
+	return r.Storage.QueryByID(ctx, id)
+}
+
+func (r ProductRepository) QueryByUserID(ctx context.Context, userID string) ([]product.Product, error) {
+	return r.Storage.QueryByUserID(ctx, userID)
+}
+
+func (r ProductRepository) Update(ctx context.Context, p product.Product, quantity int) error {
+	return r.Storage.Update(ctx, p, quantity)
+}
+
+func (r ProductRepository) Delete(ctx context.Context, id string) error {
+	return r.Storage.Delete(ctx, id)
+}
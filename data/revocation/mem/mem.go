@@ -0,0 +1,63 @@
+// Package mem provides memory storage functionality for revoked tokens.
+package mem
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type Store struct {
+	mu       sync.RWMutex
+	tokens   map[string]time.Time
+	subjects map[string]time.Time
+}
+
+func New() *Store {
+	return &Store{
+		tokens:   make(map[string]time.Time),
+		subjects: make(map[string]time.Time),
+	}
+}
+
+// Revoke stores the id of a revoked token until the token expires.
+// Tokens without expiry are stored forever. Tokens that are expired already are removed from the store.
+func (m *Store) Revoke(ctx context.Context, tokenID string, expires time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for id, exp := range m.tokens {
+		if !exp.IsZero() && exp.Before(now) {
+			delete(m.tokens, id)
+		}
+	}
+
+	m.tokens[tokenID] = expires
+	return nil
+}
+
+// RevokeSubject revokes all tokens of the subject issued up to before.
+func (m *Store) RevokeSubject(ctx context.Context, subject string, before time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.subjects[subject] = before
+	return nil
+}
+
+// IsRevoked reports whether the token has been revoked by its id or subject.
+func (m *Store) IsRevoked(ctx context.Context, tokenID string, subject string, issuedAt time.Time) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, exists := m.tokens[tokenID]; exists && tokenID != "" {
+		return true, nil
+	}
+
+	if before, exists := m.subjects[subject]; exists && !issuedAt.After(before) {
+		return true, nil
+	}
+
+	return false, nil
+}
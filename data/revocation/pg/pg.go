@@ -0,0 +1,118 @@
+// Package pg provides postgres storage functionality for revoked tokens.
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	database "github.com/appinesshq/caservice/data/core/pg"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// Store manages the set of APIs for revoked token access.
+type Store struct {
+	log *zap.SugaredLogger
+	db  sqlx.ExtContext
+}
+
+// NewStore constructs a data for api access.
+func NewStore(log *zap.SugaredLogger, db *sqlx.DB) Store {
+	return Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// Revoke stores the id of a revoked token until the token expires.
+// Tokens without expiry are stored forever. Tokens that are expired already
+// are removed from the database.
+func (s Store) Revoke(ctx context.Context, tokenID string, expires time.Time) error {
+	data := struct {
+		TokenID     string       `db:"token_id"`
+		DateExpires sql.NullTime `db:"date_expires"`
+		Now         time.Time    `db:"now"`
+	}{
+		TokenID:     tokenID,
+		DateExpires: sql.NullTime{Time: expires.UTC(), Valid: !expires.IsZero()},
+		Now:         time.Now().UTC(),
+	}
+
+	const qd = `
+	DELETE FROM
+		revoked_tokens
+	WHERE
+		date_expires < :now`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, qd, data); err != nil {
+		return fmt.Errorf("deleting expired tokens: %w", err)
+	}
+
+	const q = `
+	INSERT INTO revoked_tokens
+		(token_id, date_expires)
+	VALUES
+		(:token_id, :date_expires)
+	ON CONFLICT (token_id) DO NOTHING`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("inserting revoked tokenID[%s]: %w", tokenID, err)
+	}
+
+	return nil
+}
+
+// RevokeSubject revokes all tokens of the subject issued up to before.
+func (s Store) RevokeSubject(ctx context.Context, subject string, before time.Time) error {
+	data := struct {
+		Subject     string    `db:"subject"`
+		DateRevoked time.Time `db:"date_revoked"`
+	}{
+		Subject:     subject,
+		DateRevoked: before.UTC(),
+	}
+
+	const q = `
+	INSERT INTO revoked_subjects
+		(subject, date_revoked)
+	VALUES
+		(:subject, :date_revoked)
+	ON CONFLICT (subject) DO UPDATE SET
+		date_revoked = EXCLUDED.date_revoked`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("revoking subject[%s]: %w", subject, err)
+	}
+
+	return nil
+}
+
+// IsRevoked reports whether the token has been revoked by its id or subject.
+func (s Store) IsRevoked(ctx context.Context, tokenID string, subject string, issuedAt time.Time) (bool, error) {
+	data := struct {
+		TokenID  string    `db:"token_id"`
+		Subject  string    `db:"subject"`
+		IssuedAt time.Time `db:"issued_at"`
+	}{
+		TokenID:  tokenID,
+		Subject:  subject,
+		IssuedAt: issuedAt.UTC(),
+	}
+
+	const q = `
+	SELECT
+		EXISTS (SELECT 1 FROM revoked_tokens WHERE token_id = :token_id AND token_id <> '') OR
+		EXISTS (SELECT 1 FROM revoked_subjects WHERE subject = :subject AND date_revoked >= :issued_at)
+		AS revoked`
+
+	var result struct {
+		Revoked bool `db:"revoked"`
+	}
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &result); err != nil {
+		return false, fmt.Errorf("checking revocation of tokenID[%s]: %w", tokenID, err)
+	}
+
+	return result.Revoked, nil
+}
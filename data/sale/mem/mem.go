@@ -0,0 +1,109 @@
+// Package mem provides memory storage functionality for sales.
+// Like the database storage, it scopes all queries to the organization of
+// the session in the context.
+package mem
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	products "github.com/appinesshq/caservice/business/product/usecases"
+	"github.com/appinesshq/caservice/business/sale"
+	"github.com/appinesshq/caservice/business/sale/usecases"
+	"github.com/appinesshq/caservice/business/user"
+	productmem "github.com/appinesshq/caservice/data/product/mem"
+)
+
+type Store struct {
+	mu       sync.RWMutex
+	sales    map[string]sale.Sale
+	products *productmem.Store
+}
+
+// New returns a sale store which decrements the stock
+// of sold products in the provided product store.
+func New(products *productmem.Store) *Store {
+	return &Store{sales: make(map[string]sale.Sale), products: products}
+}
+
+func (m *Store) Create(ctx context.Context, s sale.Sale) (sale.Sale, error) {
+	orgID, err := user.GetTenantID(ctx)
+	if err != nil {
+		return sale.Sale{}, err
+	}
+	s.OrgID = orgID
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Return an error if the ID already exists.
+	if _, exists := m.sales[s.ID]; exists {
+		return sale.Sale{}, usecases.ErrUniqueID
+	}
+
+	// Decrement the stock while holding the lock, so the sale is only
+	// recorded if the stock was available, at the cost it was taken at.
+	p, ok, err := m.products.DecrementQuantity(ctx, s.ProductID, s.Quantity, s.DateCreated)
+	if err != nil {
+		if errors.Is(err, products.ErrNotFound) {
+			return sale.Sale{}, usecases.ErrProductNotFound
+		}
+		return sale.Sale{}, err
+	}
+	if !ok {
+		return sale.Sale{}, usecases.ErrInsufficientStock
+	}
+	s.Paid = s.Quantity * p.Cost
+
+	m.sales[s.ID] = s
+	return s, nil
+}
+
+func (m *Store) QueryByID(ctx context.Context, id string) (sale.Sale, error) {
+	orgID, err := user.GetTenantID(ctx)
+	if err != nil {
+		return sale.Sale{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, ok := m.sales[id]
+	if !ok || s.OrgID != orgID {
+		return sale.Sale{}, usecases.ErrNotFound
+	}
+
+	return s, nil
+}
+
+func (m *Store) QueryByProductID(ctx context.Context, productID string) ([]sale.Sale, error) {
+	return m.filter(ctx, func(s sale.Sale) bool { return s.ProductID == productID })
+}
+
+func (m *Store) QueryByUserID(ctx context.Context, userID string) ([]sale.Sale, error) {
+	return m.filter(ctx, func(s sale.Sale) bool { return s.UserID == userID })
+}
+
+// filter returns the sales of the organization of the session matching fn,
+// ordered by creation date like the database storage does.
+func (m *Store) filter(ctx context.Context, fn func(sale.Sale) bool) ([]sale.Sale, error) {
+	orgID, err := user.GetTenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sales := []sale.Sale{}
+	for _, s := range m.sales {
+		if s.OrgID == orgID && fn(s) {
+			sales = append(sales, s)
+		}
+	}
+	sort.Slice(sales, func(i, j int) bool { return sales[i].DateCreated.Before(sales[j].DateCreated) })
+
+	return sales, nil
+}
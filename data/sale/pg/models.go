@@ -0,0 +1,57 @@
+package pg
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/appinesshq/caservice/business/sale"
+)
+
+// Sale represent the structure we need for moving data
+// between the app and the database.
+//
+// Sales recorded before buyers were tracked have no user_id,
+// so it can't be converted with an unsafe pointer cast.
+type Sale struct {
+	ID          string         `db:"sale_id"`
+	UserID      sql.NullString `db:"user_id"`
+	ProductID   string         `db:"product_id"`
+	OrgID       string         `db:"org_id"`
+	Quantity    int            `db:"quantity"`
+	Paid        int            `db:"paid"`
+	DateCreated time.Time      `db:"date_created"`
+}
+
+// =============================================================================
+
+func toEntity(dbSale Sale) sale.Sale {
+	return sale.Sale{
+		ID:          dbSale.ID,
+		UserID:      dbSale.UserID.String,
+		ProductID:   dbSale.ProductID,
+		OrgID:       dbSale.OrgID,
+		Quantity:    dbSale.Quantity,
+		Paid:        dbSale.Paid,
+		DateCreated: dbSale.DateCreated,
+	}
+}
+
+func toEntitySlice(dbSales []Sale) []sale.Sale {
+	sales := make([]sale.Sale, len(dbSales))
+	for i, dbSale := range dbSales {
+		sales[i] = toEntity(dbSale)
+	}
+	return sales
+}
+
+func toSale(s sale.Sale) Sale {
+	return Sale{
+		ID:          s.ID,
+		UserID:      sql.NullString{String: s.UserID, Valid: s.UserID != ""},
+		ProductID:   s.ProductID,
+		OrgID:       s.OrgID,
+		Quantity:    s.Quantity,
+		Paid:        s.Paid,
+		DateCreated: s.DateCreated,
+	}
+}
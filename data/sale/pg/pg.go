@@ -0,0 +1,243 @@
+// Package pg provides postgres storage functionality for sales.
+//
+// Sales belong to an organization. All queries are scoped to the
+// organization of the session in the context, so sales of other
+// organizations can't be reached.
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/appinesshq/caservice/business/sale"
+	"github.com/appinesshq/caservice/business/sale/usecases"
+	"github.com/appinesshq/caservice/business/user"
+	database "github.com/appinesshq/caservice/data/core/pg"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// Store manages the set of APIs for sale access.
+type Store struct {
+	log          *zap.SugaredLogger
+	tr           database.Transactor
+	db           sqlx.ExtContext
+	isWithinTran bool
+}
+
+// NewStore constructs a data for api access.
+func NewStore(log *zap.SugaredLogger, db *sqlx.DB) Store {
+	return Store{
+		log: log,
+		tr:  db,
+		db:  db,
+	}
+}
+
+// WithinTran runs passed function and do commit/rollback at the end.
+func (s Store) WithinTran(ctx context.Context, fn func(sqlx.ExtContext) error) error {
+	if s.isWithinTran {
+		return fn(s.db)
+	}
+	return database.WithinTran(ctx, s.log, s.tr, fn)
+}
+
+// Tran return new Store with transaction in it.
+func (s Store) Tran(tx sqlx.ExtContext) Store {
+	return Store{
+		log:          s.log,
+		tr:           s.tr,
+		db:           tx,
+		isWithinTran: true,
+	}
+}
+
+// Create inserts a new sale into the database and decrements the stock of
+// the sold product within the same transaction. The sale is paid at the cost
+// the guarded update returns, so it's the cost of the stock that was sold.
+//
+// The stock is decremented with a guarded update, which locks the product
+// row. Concurrent purchases of the same product are serialized by that lock
+// and re-check the remaining stock, so a product can't be oversold. Only
+// products of the organization of the session can be sold.
+func (s Store) Create(ctx context.Context, sl sale.Sale) (sale.Sale, error) {
+	orgID, err := user.GetTenantID(ctx)
+	if err != nil {
+		return sale.Sale{}, fmt.Errorf("scoping sale: %w", err)
+	}
+
+	const qStock = `
+	UPDATE
+		products
+	SET
+		"quantity" = quantity - :quantity,
+		"date_updated" = :date_created
+	WHERE
+		product_id = :product_id AND
+		org_id = :org_id AND
+		quantity >= :quantity
+	RETURNING
+		cost`
+
+	const qExists = `
+	SELECT
+		product_id
+	FROM
+		products
+	WHERE
+		product_id = :product_id AND
+		org_id = :org_id`
+
+	const qSale = `
+	INSERT INTO sales
+		(sale_id, org_id, user_id, product_id, quantity, paid, date_created)
+	VALUES
+		(:sale_id, :org_id, :user_id, :product_id, :quantity, :paid, :date_created)`
+
+	// Convert entity to DB sale model.
+	dbSale := toSale(sl)
+	dbSale.OrgID = orgID
+
+	f := func(tx sqlx.ExtContext) error {
+		var stock struct {
+			Cost int `db:"cost"`
+		}
+		if err := database.NamedQueryStruct(ctx, s.log, tx, qStock, dbSale, &stock); err != nil {
+			if !errors.Is(err, database.ErrDBNotFound) {
+				return fmt.Errorf("decrementing stock productID[%s]: %w", dbSale.ProductID, err)
+			}
+
+			// Nothing was updated, find out if the product exists at all.
+			var prd struct {
+				ID string `db:"product_id"`
+			}
+			if err := database.NamedQueryStruct(ctx, s.log, tx, qExists, dbSale, &prd); err != nil {
+				if errors.Is(err, database.ErrDBNotFound) {
+					return usecases.ErrProductNotFound
+				}
+				return fmt.Errorf("selecting productID[%s]: %w", dbSale.ProductID, err)
+			}
+			return usecases.ErrInsufficientStock
+		}
+		dbSale.Paid = dbSale.Quantity * stock.Cost
+
+		if err := database.NamedExecContext(ctx, s.log, tx, qSale, dbSale); err != nil {
+			if errors.Is(err, database.ErrDBDuplicatedEntry) {
+				return usecases.ErrUniqueID
+			}
+			return fmt.Errorf("inserting sale: %w", err)
+		}
+
+		return nil
+	}
+
+	if err := s.WithinTran(ctx, f); err != nil {
+		return sale.Sale{}, err
+	}
+
+	return toEntity(dbSale), nil
+}
+
+// QueryByID gets the specified sale from the database.
+func (s Store) QueryByID(ctx context.Context, saleID string) (sale.Sale, error) {
+	orgID, err := user.GetTenantID(ctx)
+	if err != nil {
+		return sale.Sale{}, fmt.Errorf("scoping sale: %w", err)
+	}
+
+	data := struct {
+		SaleID string `db:"sale_id"`
+		OrgID  string `db:"org_id"`
+	}{
+		SaleID: saleID,
+		OrgID:  orgID,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		sales
+	WHERE
+		sale_id = :sale_id AND
+		org_id = :org_id`
+
+	var dbSale Sale
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbSale); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return sale.Sale{}, usecases.ErrNotFound
+		}
+		return sale.Sale{}, fmt.Errorf("selecting saleID[%q]: %w", saleID, err)
+	}
+
+	return toEntity(dbSale), nil
+}
+
+// QueryByProductID gets the sales of the specified product from the database.
+func (s Store) QueryByProductID(ctx context.Context, productID string) ([]sale.Sale, error) {
+	orgID, err := user.GetTenantID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("scoping sales: %w", err)
+	}
+
+	data := struct {
+		ProductID string `db:"product_id"`
+		OrgID     string `db:"org_id"`
+	}{
+		ProductID: productID,
+		OrgID:     orgID,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		sales
+	WHERE
+		product_id = :product_id AND
+		org_id = :org_id
+	ORDER BY
+		date_created`
+
+	var dbSales []Sale
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbSales); err != nil {
+		return nil, fmt.Errorf("selecting sales productID[%q]: %w", productID, err)
+	}
+
+	return toEntitySlice(dbSales), nil
+}
+
+// QueryByUserID gets the purchases of the specified user from the database.
+func (s Store) QueryByUserID(ctx context.Context, userID string) ([]sale.Sale, error) {
+	orgID, err := user.GetTenantID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("scoping sales: %w", err)
+	}
+
+	data := struct {
+		UserID string `db:"user_id"`
+		OrgID  string `db:"org_id"`
+	}{
+		UserID: userID,
+		OrgID:  orgID,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		sales
+	WHERE
+		user_id = :user_id AND
+		org_id = :org_id
+	ORDER BY
+		date_created`
+
+	var dbSales []Sale
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbSales); err != nil {
+		return nil, fmt.Errorf("selecting sales userID[%q]: %w", userID, err)
+	}
+
+	return toEntitySlice(dbSales), nil
+}
@@ -0,0 +1,37 @@
+// Package sale provides sale storage functionality.
+package sale
+
+import (
+	"context"
+
+	"github.com/appinesshq/caservice/business/sale"
+)
+
+// SaleStorage is an interface to be implemented by sale storages.
+type SaleStorage interface {
+	Create(context.Context, sale.Sale) (sale.Sale, error)
+	QueryByID(context.Context, string) (sale.Sale, error)
+	QueryByProductID(context.Context, string) ([]sale.Sale, error)
+	QueryByUserID(context.Context, string) ([]sale.Sale, error)
+}
+
+// SaleRepository implements the usecases' repository.
+type SaleRepository struct {
+	Storage SaleStorage
+}
+
+func (r SaleRepository) Create(ctx context.Context, s sale.Sale) (sale.Sale, error) {
+	return r.Storage.Create(ctx, s)
+}
+
+func (r SaleRepository) QueryByID(ctx context.Context, id string) (sale.Sale, error) {
+	return r.Storage.QueryByID(ctx, id)
+}
+
+func (r SaleRepository) QueryByProductID(ctx context.Context, productID string) ([]sale.Sale, error) {
+	return r.Storage.QueryByProductID(ctx, productID)
+}
+
+func (r SaleRepository) QueryByUserID(ctx context.Context, userID string) ([]sale.Sale, error) {
+	return r.Storage.QueryByUserID(ctx, userID)
+}
@@ -1,23 +1,88 @@
 // Package mem provides memory storage functionality for users.
+//
+// Like the database storage, calls with a session in the context are scoped
+// to the members of the organization of the session and calls without one
+// fail, unless the context is marked for the system.
 package mem
 
 import (
 	"context"
 	"errors"
+	"sort"
 	"sync"
+	"time"
 
+	"github.com/appinesshq/caservice/business/org"
 	"github.com/appinesshq/caservice/business/user"
 	"github.com/appinesshq/caservice/business/user/usecases"
 )
 
 type Store struct {
-	mu      sync.RWMutex
-	users   map[string]user.User
-	indexes map[string]string
+	mu            sync.RWMutex
+	users         map[string]user.User
+	indexes       map[string]string
+	refreshTokens map[string]user.RefreshToken
+	oneTimeTokens map[string]user.OneTimeToken
+	identities    map[string]user.Identity
+	apiKeys       map[string]user.APIKey
+	loginSessions map[string]user.LoginSession
+	orgs          map[string]org.Org
+	memberships   map[string]org.Membership
+	invitations   map[string]org.Invitation
 }
 
+// New returns an empty store with only the default organization, like a
+// freshly migrated database.
 func New() *Store {
-	return &Store{users: make(map[string]user.User), indexes: make(map[string]string)}
+	return &Store{
+		users:         make(map[string]user.User),
+		indexes:       make(map[string]string),
+		refreshTokens: make(map[string]user.RefreshToken),
+		oneTimeTokens: make(map[string]user.OneTimeToken),
+		identities:    make(map[string]user.Identity),
+		apiKeys:       make(map[string]user.APIKey),
+		loginSessions: make(map[string]user.LoginSession),
+		orgs:          map[string]org.Org{org.DefaultID: {ID: org.DefaultID, Name: "Default"}},
+		memberships:   make(map[string]org.Membership),
+		invitations:   make(map[string]org.Invitation),
+	}
+}
+
+// scope returns the organization calls on behalf of the session in the
+// context are scoped to. Calls the system makes itself are not scoped if
+// their context is marked for the system. Any other call without a session
+// fails.
+func scope(ctx context.Context) (string, bool, error) {
+	if _, err := user.GetSession(ctx); err != nil {
+		if user.IsSystem(ctx) {
+			return "", false, nil
+		}
+		return "", false, user.ErrNoTenant
+	}
+
+	orgID, err := user.GetTenantID(ctx)
+	if err != nil {
+		return "", false, err
+	}
+
+	return orgID, true, nil
+}
+
+// member returns the user as a member of the organization, with its roles
+// within it. It returns false if the user is not a member.
+func (m *Store) member(orgID string, u user.User) (user.User, bool) {
+	ms, ok := m.memberships[membershipKey(orgID, u.ID)]
+	if !ok {
+		return user.User{}, false
+	}
+
+	u.Roles = ms.Roles
+	return u, true
+}
+
+// membershipKey returns the key a membership is stored under.
+func membershipKey(orgID, userID string) string {
+	return orgID + "/" + userID
 }
 
 func (m *Store) hasID(id string) bool {
@@ -55,24 +120,99 @@ func (m *Store) Create(ctx context.Context, u user.User) error {
 	return nil
 }
 
-func (m *Store) Query(ctx context.Context, pageNumber int, rowsPerPage int) ([]user.User, error) {
-	// TODO: Implement pagination.
+// Query returns a page of the users selected by the filter, in the order of
+// the filter.
+func (m *Store) Query(ctx context.Context, f user.QueryFilter, pageNumber int, rowsPerPage int) ([]user.User, error) {
+	users, err := m.filter(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(users, func(i, j int) bool { return f.Less(users[i], users[j]) })
+
+	return page(users, pageNumber, rowsPerPage), nil
+}
+
+// Count returns the number of users selected by the filter.
+func (m *Store) Count(ctx context.Context, f user.QueryFilter) (int, error) {
+	users, err := m.filter(ctx, f)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(users), nil
+}
+
+// CountAll returns the number of users, including deleted users.
+func (m *Store) CountAll(ctx context.Context) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.users), nil
+}
+
+// filter returns the users that are not deleted and are selected by the
+// filter, in no particular order.
+func (m *Store) filter(ctx context.Context, f user.QueryFilter) ([]user.User, error) {
+	orgID, scoped, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	users := []user.User{}
 	for _, u := range m.users {
-		users = append(users, u)
+		if u.IsDeleted() {
+			continue
+		}
+		if scoped {
+			var ok bool
+			if u, ok = m.member(orgID, u); !ok {
+				continue
+			}
+		}
+		if f.Match(u) {
+			users = append(users, u)
+		}
 	}
 
 	return users, nil
 }
 
+// page returns a page of the users, like the OFFSET and FETCH clauses of the
+// database storage.
+func page(users []user.User, pageNumber int, rowsPerPage int) []user.User {
+	offset := (pageNumber - 1) * rowsPerPage
+	if offset < 0 || rowsPerPage <= 0 || offset >= len(users) {
+		return []user.User{}
+	}
+
+	end := offset + rowsPerPage
+	if end > len(users) {
+		end = len(users)
+	}
+
+	return users[offset:end]
+}
+
 func (m *Store) QueryByID(ctx context.Context, id string) (user.User, error) {
+	orgID, scoped, err := scope(ctx)
+	if err != nil {
+		return user.User{}, err
+	}
+
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	u, ok := m.users[id]
+	if ok && u.IsDeleted() {
+		ok = false
+	}
+	if ok && scoped {
+		u, ok = m.member(orgID, u)
+	}
 	if !ok {
 		return user.User{}, usecases.ErrNotFound
 	}
@@ -81,6 +221,11 @@ func (m *Store) QueryByID(ctx context.Context, id string) (user.User, error) {
 }
 
 func (m *Store) QueryByEmail(ctx context.Context, email string) (user.User, error) {
+	orgID, scoped, err := scope(ctx)
+	if err != nil {
+		return user.User{}, err
+	}
+
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -104,39 +249,720 @@ func (m *Store) QueryByEmail(ctx context.Context, email string) (user.User, erro
 		u = m.users[id]
 	}
 
+	if u.IsDeleted() {
+		return user.User{}, usecases.ErrNotFound
+	}
+
+	if scoped {
+		var ok bool
+		if u, ok = m.member(orgID, u); !ok {
+			return user.User{}, usecases.ErrNotFound
+		}
+	}
+
 	return u, nil
 
 }
+
+// QueryDeletedByEmail returns the deleted user with the email address.
+func (m *Store) QueryDeletedByEmail(ctx context.Context, email string) (user.User, error) {
+	orgID, scoped, err := scope(ctx)
+	if err != nil {
+		return user.User{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, u := range m.users {
+		if u.Email != email || !u.IsDeleted() {
+			continue
+		}
+		if scoped {
+			var ok bool
+			if u, ok = m.member(orgID, u); !ok {
+				break
+			}
+		}
+		return u, nil
+	}
+
+	return user.User{}, usecases.ErrNotFound
+}
+
+// Update replaces the user, if it is still the version the user was read
+// as. Within an organization, the roles of the user
+// are its roles in the organization, so these are updated instead.
 func (m *Store) Update(ctx context.Context, u user.User) error {
-	current, err := m.QueryByID(ctx, u.ID)
+	orgID, scoped, err := scope(ctx)
 	if err != nil {
 		return err
 	}
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
+
+	current, ok := m.users[u.ID]
+	if ok && current.IsDeleted() {
+		ok = false
+	}
+	if ok && scoped {
+		_, ok = m.member(orgID, current)
+	}
+	if !ok {
+		return usecases.ErrNotFound
+	}
+	if current.Version != u.Version {
+		return usecases.ErrConflict
+	}
+	u.Version++
+
+	if scoped {
+		ms := m.memberships[membershipKey(orgID, u.ID)]
+		ms.Roles = u.Roles
+		m.memberships[membershipKey(orgID, u.ID)] = ms
+		u.Roles = current.Roles
+	}
+
 	m.users[u.ID] = u
 	if u.Email != current.Email {
 		delete(m.indexes, "email:"+current.Email)
-		m.indexes[u.Email] = u.ID
+		m.indexes["email:"+u.Email] = u.ID
+	}
+
+	return nil
+}
+
+// Delete marks the user as deleted. The user and its memberships are kept
+// until the user is purged.
+func (m *Store) Delete(ctx context.Context, id string, now time.Time) error {
+	if _, err := m.QueryByID(ctx, id); err != nil {
+		if errors.Is(err, usecases.ErrNotFound) {
+			// Delete should return a nil error in case of not found.
+			return nil
+		}
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u := m.users[id]
+	u.DateDeleted = &now
+	u.Version++
+	m.users[id] = u
+
+	return nil
+}
+
+// QueryDeleted returns the deleted users, the most recently deleted first.
+func (m *Store) QueryDeleted(ctx context.Context, pageNumber int, rowsPerPage int) ([]user.User, error) {
+	orgID, scoped, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	users := []user.User{}
+	for _, u := range m.users {
+		if !u.IsDeleted() {
+			continue
+		}
+		if scoped {
+			var ok bool
+			if u, ok = m.member(orgID, u); !ok {
+				continue
+			}
+		}
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].DateDeleted.After(*users[j].DateDeleted) })
+
+	return page(users, pageNumber, rowsPerPage), nil
+}
+
+// Restore removes the deleted mark of the user.
+func (m *Store) Restore(ctx context.Context, id string, now time.Time) error {
+	orgID, scoped, err := scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.users[id]
+	if ok && !u.IsDeleted() {
+		ok = false
 	}
+	if ok && scoped {
+		_, ok = m.member(orgID, u)
+	}
+	if !ok {
+		return usecases.ErrNotFound
+	}
+
+	u.DateDeleted = nil
+	u.DateUpdated = now
+	u.Version++
+	m.users[id] = u
+
+	return nil
+}
+
+// Purge removes the users deleted before the provided time, together with
+// their memberships and invitations.
+func (m *Store) Purge(ctx context.Context, before time.Time) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids := []string{}
+	for id, u := range m.users {
+		if !u.IsDeleted() || !u.DateDeleted.Before(before) {
+			continue
+		}
+
+		delete(m.users, id)
+		delete(m.indexes, "email:"+u.Email)
+		for key, ms := range m.memberships {
+			if ms.UserID == id {
+				delete(m.memberships, key)
+			}
+		}
+		for key, i := range m.invitations {
+			if i.UserID == id {
+				delete(m.invitations, key)
+			}
+		}
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids, nil
+}
+
+// Import inserts or replaces a batch of users and memberships. Nothing is
+// stored if an email address belongs to another user.
+func (m *Store) Import(ctx context.Context, us []user.User, ms []org.Membership) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	emails := make(map[string]string, len(us))
+	for _, u := range us {
+		if current, exists := m.users[u.ID]; exists && (current.Version != u.Version || current.IsDeleted()) {
+			return usecases.ErrConflict
+		}
+		if id, exists := m.indexes["email:"+u.Email]; exists && id != u.ID {
+			return usecases.ErrUniqueEmail
+		}
+		if id, exists := emails[u.Email]; exists && id != u.ID {
+			return usecases.ErrUniqueEmail
+		}
+		emails[u.Email] = u.ID
+	}
+
+	for _, u := range us {
+		if current, exists := m.users[u.ID]; exists {
+			u.Version = current.Version + 1
+			delete(m.indexes, "email:"+current.Email)
+		}
+		m.users[u.ID] = u
+		m.indexes["email:"+u.Email] = u.ID
+	}
+	for _, ms := range ms {
+		if current, exists := m.memberships[membershipKey(ms.OrgID, ms.UserID)]; exists {
+			ms.DateCreated = current.DateCreated
+		}
+		m.memberships[membershipKey(ms.OrgID, ms.UserID)] = ms
+	}
+
+	return nil
+}
+
+func (m *Store) CreateRefreshToken(ctx context.Context, rt user.RefreshToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
+	// Refresh tokens are stored by their hash, which is how they are looked up.
+	m.refreshTokens[rt.TokenHash] = rt
 	return nil
 }
 
-func (m *Store) Delete(ctx context.Context, id string) error {
-	current, err := m.QueryByID(ctx, id)
-	if err != nil && errors.Is(err, usecases.ErrNotFound) {
-		// Delete should return a nil error in case of not found.
+func (m *Store) QueryRefreshTokenByHash(ctx context.Context, hash string) (user.RefreshToken, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	rt, ok := m.refreshTokens[hash]
+	if !ok {
+		return user.RefreshToken{}, usecases.ErrRefreshTokenNotFound
+	}
+
+	return rt, nil
+}
+
+func (m *Store) UseRefreshToken(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for hash, rt := range m.refreshTokens {
+		if rt.ID != id {
+			continue
+		}
+		if rt.Used {
+			return usecases.ErrRefreshTokenUsed
+		}
+		rt.Used = true
+		m.refreshTokens[hash] = rt
 		return nil
-	} else if err != nil {
+	}
+
+	return usecases.ErrRefreshTokenNotFound
+}
+
+func (m *Store) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for hash, rt := range m.refreshTokens {
+		if rt.FamilyID == familyID {
+			rt.Revoked = true
+			m.refreshTokens[hash] = rt
+		}
+	}
+
+	return nil
+}
+
+func (m *Store) RevokeUserRefreshTokens(ctx context.Context, userID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for hash, rt := range m.refreshTokens {
+		if rt.UserID == userID {
+			rt.Revoked = true
+			m.refreshTokens[hash] = rt
+		}
+	}
+
+	return nil
+}
+
+func (m *Store) CreateOneTimeToken(ctx context.Context, ot user.OneTimeToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// One-time tokens are stored by their hash, which is how they are looked up.
+	m.oneTimeTokens[ot.TokenHash] = ot
+	return nil
+}
+
+func (m *Store) QueryOneTimeTokenByHash(ctx context.Context, hash string) (user.OneTimeToken, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ot, ok := m.oneTimeTokens[hash]
+	if !ok {
+		return user.OneTimeToken{}, usecases.ErrOneTimeTokenNotFound
+	}
+
+	return ot, nil
+}
+
+func (m *Store) UseOneTimeToken(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for hash, ot := range m.oneTimeTokens {
+		if ot.ID != id {
+			continue
+		}
+		if ot.Used {
+			return usecases.ErrOneTimeTokenUsed
+		}
+		ot.Used = true
+		m.oneTimeTokens[hash] = ot
+		return nil
+	}
+
+	return usecases.ErrOneTimeTokenNotFound
+}
+
+func (m *Store) AttemptOneTimeToken(ctx context.Context, id string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for hash, ot := range m.oneTimeTokens {
+		if ot.ID != id {
+			continue
+		}
+		ot.Attempts++
+		m.oneTimeTokens[hash] = ot
+		return ot.Attempts, nil
+	}
+
+	return 0, usecases.ErrOneTimeTokenNotFound
+}
+
+func (m *Store) CreateIdentity(ctx context.Context, id user.Identity) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Identities are stored by provider and subject, which is how they are looked up.
+	key := id.Provider + "\x00" + id.Subject
+	if _, exists := m.identities[key]; exists {
+		return usecases.ErrUniqueIdentity
+	}
+	m.identities[key] = id
+	return nil
+}
+
+func (m *Store) QueryIdentity(ctx context.Context, provider, subject string) (user.Identity, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	id, ok := m.identities[provider+"\x00"+subject]
+	if !ok {
+		return user.Identity{}, usecases.ErrIdentityNotFound
+	}
+
+	return id, nil
+}
+
+func (m *Store) CreateAPIKey(ctx context.Context, ak user.APIKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// API keys are stored by their hash, which is how they are looked up.
+	m.apiKeys[ak.KeyHash] = ak
+	return nil
+}
+
+func (m *Store) QueryAPIKeyByHash(ctx context.Context, hash string) (user.APIKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ak, ok := m.apiKeys[hash]
+	if !ok {
+		return user.APIKey{}, usecases.ErrAPIKeyNotFound
+	}
+
+	return ak, nil
+}
+
+func (m *Store) QueryAPIKeysByUserID(ctx context.Context, userID string) ([]user.APIKey, error) {
+	orgID, scoped, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := []user.APIKey{}
+	for _, ak := range m.apiKeys {
+		if ak.UserID == userID && (!scoped || ak.OrgID == orgID) {
+			keys = append(keys, ak)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].DateCreated.Before(keys[j].DateCreated)
+	})
+
+	return keys, nil
+}
+
+func (m *Store) RevokeAPIKey(ctx context.Context, userID, id string) error {
+	orgID, scoped, err := scope(ctx)
+	if err != nil {
 		return err
 	}
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	delete(m.users, id)
-	delete(m.indexes, "email:"+current.Email)
+	for hash, ak := range m.apiKeys {
+		if ak.ID == id && ak.UserID == userID && (!scoped || ak.OrgID == orgID) {
+			ak.Revoked = true
+			m.apiKeys[hash] = ak
+			return nil
+		}
+	}
+
+	return usecases.ErrAPIKeyNotFound
+}
+
+func (m *Store) UseAPIKey(ctx context.Context, id string, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for hash, ak := range m.apiKeys {
+		if ak.ID == id {
+			ak.DateLastUsed = now
+			m.apiKeys[hash] = ak
+			return nil
+		}
+	}
+
+	return usecases.ErrAPIKeyNotFound
+}
+
+func (m *Store) CreateLoginSession(ctx context.Context, ls user.LoginSession) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.loginSessions[ls.ID] = ls
+	return nil
+}
+
+func (m *Store) QueryLoginSessionByID(ctx context.Context, id string) (user.LoginSession, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ls, ok := m.loginSessions[id]
+	if !ok {
+		return user.LoginSession{}, usecases.ErrLoginSessionNotFound
+	}
+
+	return ls, nil
+}
+
+func (m *Store) QueryLoginSessionsByUserID(ctx context.Context, userID string) ([]user.LoginSession, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sessions := []user.LoginSession{}
+	for _, ls := range m.loginSessions {
+		if ls.UserID == userID {
+			sessions = append(sessions, ls)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].DateLastSeen.After(sessions[j].DateLastSeen)
+	})
+
+	return sessions, nil
+}
+
+func (m *Store) SeeLoginSession(ctx context.Context, id, ip string, now, expires time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ls, ok := m.loginSessions[id]
+	if !ok {
+		return usecases.ErrLoginSessionNotFound
+	}
+
+	if ip != "" {
+		ls.IP = ip
+	}
+	ls.DateLastSeen = now
+	if expires.After(ls.DateExpires) {
+		ls.DateExpires = expires
+	}
+	m.loginSessions[id] = ls
+
+	return nil
+}
+
+func (m *Store) RevokeLoginSession(ctx context.Context, userID, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ls, ok := m.loginSessions[id]
+	if !ok || ls.UserID != userID {
+		return usecases.ErrLoginSessionNotFound
+	}
+
+	ls.Revoked = true
+	m.loginSessions[id] = ls
+
+	return nil
+}
+
+func (m *Store) RevokeUserLoginSessions(ctx context.Context, userID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, ls := range m.loginSessions {
+		if ls.UserID == userID {
+			ls.Revoked = true
+			m.loginSessions[id] = ls
+		}
+	}
+
+	return nil
+}
+
+func (m *Store) CreateOrg(ctx context.Context, o org.Org, ms org.Membership) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.orgs[o.ID]; exists {
+		return usecases.ErrUniqueID
+	}
+	if u, exists := m.users[ms.UserID]; !exists || u.IsDeleted() {
+		return usecases.ErrNotFound
+	}
+
+	m.orgs[o.ID] = o
+	m.memberships[membershipKey(ms.OrgID, ms.UserID)] = ms
+	return nil
+}
+
+func (m *Store) QueryOrgsByUserID(ctx context.Context, userID string) ([]org.Org, error) {
+	ms, err := m.QueryMembershipsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	orgs := make([]org.Org, len(ms))
+	for i, ms := range ms {
+		orgs[i] = m.orgs[ms.OrgID]
+	}
+
+	return orgs, nil
+}
+
+func (m *Store) CreateMembership(ctx context.Context, ms org.Membership) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if u, exists := m.users[ms.UserID]; !exists || u.IsDeleted() {
+		return usecases.ErrNotFound
+	}
+	if _, exists := m.memberships[membershipKey(ms.OrgID, ms.UserID)]; exists {
+		return usecases.ErrUniqueMembership
+	}
+
+	m.memberships[membershipKey(ms.OrgID, ms.UserID)] = ms
+	return nil
+}
+
+func (m *Store) QueryMembership(ctx context.Context, orgID, userID string) (org.Membership, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ms, ok := m.memberships[membershipKey(orgID, userID)]
+	if !ok {
+		return org.Membership{}, usecases.ErrMembershipNotFound
+	}
+
+	return ms, nil
+}
+
+func (m *Store) QueryMembershipsByUserID(ctx context.Context, userID string) ([]org.Membership, error) {
+	return m.filterMemberships(func(ms org.Membership) bool { return ms.UserID == userID }), nil
+}
+
+func (m *Store) QueryMembershipsByOrgID(ctx context.Context, orgID string) ([]org.Membership, error) {
+	return m.filterMemberships(func(ms org.Membership) bool { return ms.OrgID == orgID }), nil
+}
+
+func (m *Store) DeleteMembership(ctx context.Context, orgID, userID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.memberships[membershipKey(orgID, userID)]; !exists {
+		return usecases.ErrMembershipNotFound
+	}
+
+	delete(m.memberships, membershipKey(orgID, userID))
+	return nil
+}
+
+// filterMemberships returns the memberships matching fn, ordered by
+// creation date like the database storage does.
+func (m *Store) filterMemberships(fn func(org.Membership) bool) []org.Membership {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	mss := []org.Membership{}
+	for _, ms := range m.memberships {
+		if fn(ms) {
+			mss = append(mss, ms)
+		}
+	}
+	sort.Slice(mss, func(i, j int) bool { return mss[i].DateCreated.Before(mss[j].DateCreated) })
+
+	return mss
+}
+
+func (m *Store) CreateInvitation(ctx context.Context, i org.Invitation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if u, exists := m.users[i.UserID]; !exists || u.IsDeleted() {
+		return usecases.ErrNotFound
+	}
+	for _, other := range m.invitations {
+		if other.OrgID == i.OrgID && other.UserID == i.UserID {
+			return usecases.ErrUniqueInvitation
+		}
+	}
+
+	m.invitations[i.ID] = i
+	return nil
+}
+
+func (m *Store) QueryInvitationByID(ctx context.Context, id string) (org.Invitation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	i, ok := m.invitations[id]
+	if !ok {
+		return org.Invitation{}, usecases.ErrInvitationNotFound
+	}
+
+	return i, nil
+}
+
+func (m *Store) QueryInvitationsByUserID(ctx context.Context, userID string) ([]org.Invitation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	is := []org.Invitation{}
+	for _, i := range m.invitations {
+		if i.UserID == userID {
+			is = append(is, i)
+		}
+	}
+	sort.Slice(is, func(i, j int) bool { return is[i].DateCreated.Before(is[j].DateCreated) })
+
+	return is, nil
+}
+
+func (m *Store) AcceptInvitation(ctx context.Context, id string, ms org.Membership) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.invitations[id]; !exists {
+		return usecases.ErrInvitationNotFound
+	}
+	if u, exists := m.users[ms.UserID]; !exists || u.IsDeleted() {
+		return usecases.ErrNotFound
+	}
+	if _, exists := m.memberships[membershipKey(ms.OrgID, ms.UserID)]; exists {
+		return usecases.ErrUniqueMembership
+	}
+
+	m.memberships[membershipKey(ms.OrgID, ms.UserID)] = ms
+	delete(m.invitations, id)
+	return nil
+}
+
+func (m *Store) DeleteInvitation(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.invitations[id]; !exists {
+		return usecases.ErrInvitationNotFound
+	}
+
+	delete(m.invitations, id)
 	return nil
 }
@@ -0,0 +1,163 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/appinesshq/caservice/business/user"
+	"github.com/appinesshq/caservice/business/user/usecases"
+	database "github.com/appinesshq/caservice/data/core/pg"
+)
+
+// CreateAPIKey inserts a new API key into the database.
+func (s Store) CreateAPIKey(ctx context.Context, ak user.APIKey) error {
+	const q = `
+	INSERT INTO api_keys
+		(key_id, user_id, org_id, name, key_hash, scopes, revoked, date_created, date_expires, date_last_used)
+	VALUES
+		(:key_id, :user_id, :org_id, :name, :key_hash, :scopes, :revoked, :date_created, :date_expires, :date_last_used)`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, toAPIKey(ak)); err != nil {
+		return fmt.Errorf("inserting api key: %w", err)
+	}
+
+	return nil
+}
+
+// QueryAPIKeyByHash gets the API key with the specified hash from the database.
+func (s Store) QueryAPIKeyByHash(ctx context.Context, hash string) (user.APIKey, error) {
+	data := struct {
+		KeyHash string `db:"key_hash"`
+	}{
+		KeyHash: hash,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		api_keys
+	WHERE
+		key_hash = :key_hash`
+
+	var ak APIKey
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &ak); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return user.APIKey{}, usecases.ErrAPIKeyNotFound
+		}
+		return user.APIKey{}, fmt.Errorf("selecting api key: %w", err)
+	}
+
+	return toAPIKeyEntity(ak), nil
+}
+
+// QueryAPIKeysByUserID gets all API keys of a user from the database.
+func (s Store) QueryAPIKeysByUserID(ctx context.Context, userID string) ([]user.APIKey, error) {
+	orgID, scoped, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	data := struct {
+		UserID string `db:"user_id"`
+		OrgID  string `db:"org_id"`
+	}{
+		UserID: userID,
+		OrgID:  orgID,
+	}
+
+	q := `
+	SELECT
+		*
+	FROM
+		api_keys
+	WHERE
+		user_id = :user_id`
+	if scoped {
+		q += ` AND
+		org_id = :org_id`
+	}
+	q += `
+	ORDER BY
+		date_created`
+
+	var aks []APIKey
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &aks); err != nil {
+		return nil, fmt.Errorf("selecting api keys of userID[%s]: %w", userID, err)
+	}
+
+	return toAPIKeyEntitySlice(aks), nil
+}
+
+// RevokeAPIKey revokes an API key of a user.
+func (s Store) RevokeAPIKey(ctx context.Context, userID, keyID string) error {
+	orgID, scoped, err := scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	data := struct {
+		UserID string `db:"user_id"`
+		KeyID  string `db:"key_id"`
+		OrgID  string `db:"org_id"`
+	}{
+		UserID: userID,
+		KeyID:  keyID,
+		OrgID:  orgID,
+	}
+
+	q := `
+	UPDATE
+		api_keys
+	SET
+		"revoked" = TRUE
+	WHERE
+		key_id = :key_id AND
+		user_id = :user_id`
+	if scoped {
+		q += ` AND
+		org_id = :org_id`
+	}
+	q += `
+	RETURNING
+		key_id`
+
+	var revoked struct {
+		KeyID string `db:"key_id"`
+	}
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &revoked); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return usecases.ErrAPIKeyNotFound
+		}
+		return fmt.Errorf("revoking api keyID[%s]: %w", keyID, err)
+	}
+
+	return nil
+}
+
+// UseAPIKey records the time an API key was last used.
+func (s Store) UseAPIKey(ctx context.Context, keyID string, now time.Time) error {
+	data := struct {
+		KeyID        string    `db:"key_id"`
+		DateLastUsed time.Time `db:"date_last_used"`
+	}{
+		KeyID:        keyID,
+		DateLastUsed: now,
+	}
+
+	const q = `
+	UPDATE
+		api_keys
+	SET
+		"date_last_used" = :date_last_used
+	WHERE
+		key_id = :key_id`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("using api keyID[%s]: %w", keyID, err)
+	}
+
+	return nil
+}
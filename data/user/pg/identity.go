@@ -0,0 +1,59 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/appinesshq/caservice/business/user"
+	"github.com/appinesshq/caservice/business/user/usecases"
+	database "github.com/appinesshq/caservice/data/core/pg"
+)
+
+// CreateIdentity inserts a new identity into the database.
+func (s Store) CreateIdentity(ctx context.Context, id user.Identity) error {
+	const q = `
+	INSERT INTO identities
+		(identity_id, user_id, provider, subject, email, date_created)
+	VALUES
+		(:identity_id, :user_id, :provider, :subject, :email, :date_created)`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, toIdentity(id)); err != nil {
+		if errors.Is(err, database.ErrDBDuplicatedEntry) {
+			return usecases.ErrUniqueIdentity
+		}
+		return fmt.Errorf("inserting identity: %w", err)
+	}
+
+	return nil
+}
+
+// QueryIdentity gets the identity of the subject at the provider from the database.
+func (s Store) QueryIdentity(ctx context.Context, provider, subject string) (user.Identity, error) {
+	data := struct {
+		Provider string `db:"provider"`
+		Subject  string `db:"subject"`
+	}{
+		Provider: provider,
+		Subject:  subject,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		identities
+	WHERE
+		provider = :provider AND
+		subject = :subject`
+
+	var id Identity
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &id); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return user.Identity{}, usecases.ErrIdentityNotFound
+		}
+		return user.Identity{}, fmt.Errorf("selecting identity provider[%s]: %w", provider, err)
+	}
+
+	return toIdentityEntity(id), nil
+}
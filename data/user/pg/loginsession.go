@@ -0,0 +1,179 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/appinesshq/caservice/business/user"
+	"github.com/appinesshq/caservice/business/user/usecases"
+	database "github.com/appinesshq/caservice/data/core/pg"
+)
+
+// CreateLoginSession inserts a new login session into the database.
+func (s Store) CreateLoginSession(ctx context.Context, ls user.LoginSession) error {
+	const q = `
+	INSERT INTO login_sessions
+		(session_id, user_id, device, ip, user_agent, revoked, date_created, date_last_seen, date_expires)
+	VALUES
+		(:session_id, :user_id, :device, :ip, :user_agent, :revoked, :date_created, :date_last_seen, :date_expires)`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, toLoginSession(ls)); err != nil {
+		return fmt.Errorf("inserting login session: %w", err)
+	}
+
+	return nil
+}
+
+// QueryLoginSessionByID gets the login session with the specified id from
+// the database.
+func (s Store) QueryLoginSessionByID(ctx context.Context, sessionID string) (user.LoginSession, error) {
+	data := struct {
+		SessionID string `db:"session_id"`
+	}{
+		SessionID: sessionID,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		login_sessions
+	WHERE
+		session_id = :session_id`
+
+	var ls LoginSession
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &ls); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return user.LoginSession{}, usecases.ErrLoginSessionNotFound
+		}
+		return user.LoginSession{}, fmt.Errorf("selecting login sessionID[%s]: %w", sessionID, err)
+	}
+
+	return toLoginSessionEntity(ls), nil
+}
+
+// QueryLoginSessionsByUserID gets all login sessions of a user from the
+// database.
+func (s Store) QueryLoginSessionsByUserID(ctx context.Context, userID string) ([]user.LoginSession, error) {
+	data := struct {
+		UserID string `db:"user_id"`
+	}{
+		UserID: userID,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		login_sessions
+	WHERE
+		user_id = :user_id
+	ORDER BY
+		date_last_seen DESC`
+
+	var lss []LoginSession
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &lss); err != nil {
+		return nil, fmt.Errorf("selecting login sessions of userID[%s]: %w", userID, err)
+	}
+
+	return toLoginSessionEntitySlice(lss), nil
+}
+
+// SeeLoginSession records the client IP address and time a login session
+// was last seen, and extends it until expires if that is later.
+func (s Store) SeeLoginSession(ctx context.Context, sessionID, ip string, now, expires time.Time) error {
+	data := struct {
+		SessionID    string    `db:"session_id"`
+		IP           string    `db:"ip"`
+		DateLastSeen time.Time `db:"date_last_seen"`
+		DateExpires  time.Time `db:"date_expires"`
+	}{
+		SessionID:    sessionID,
+		IP:           ip,
+		DateLastSeen: now,
+		DateExpires:  expires,
+	}
+
+	const q = `
+	UPDATE
+		login_sessions
+	SET
+		"ip" = COALESCE(NULLIF(:ip, ''), ip),
+		"date_last_seen" = :date_last_seen,
+		"date_expires" = GREATEST(date_expires, :date_expires)
+	WHERE
+		session_id = :session_id
+	RETURNING
+		session_id`
+
+	var seen struct {
+		SessionID string `db:"session_id"`
+	}
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &seen); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return usecases.ErrLoginSessionNotFound
+		}
+		return fmt.Errorf("seeing login sessionID[%s]: %w", sessionID, err)
+	}
+
+	return nil
+}
+
+// RevokeLoginSession revokes a login session of a user.
+func (s Store) RevokeLoginSession(ctx context.Context, userID, sessionID string) error {
+	data := struct {
+		UserID    string `db:"user_id"`
+		SessionID string `db:"session_id"`
+	}{
+		UserID:    userID,
+		SessionID: sessionID,
+	}
+
+	const q = `
+	UPDATE
+		login_sessions
+	SET
+		"revoked" = TRUE
+	WHERE
+		session_id = :session_id AND
+		user_id = :user_id
+	RETURNING
+		session_id`
+
+	var revoked struct {
+		SessionID string `db:"session_id"`
+	}
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &revoked); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return usecases.ErrLoginSessionNotFound
+		}
+		return fmt.Errorf("revoking login sessionID[%s]: %w", sessionID, err)
+	}
+
+	return nil
+}
+
+// RevokeUserLoginSessions revokes all login sessions of a user.
+func (s Store) RevokeUserLoginSessions(ctx context.Context, userID string) error {
+	data := struct {
+		UserID string `db:"user_id"`
+	}{
+		UserID: userID,
+	}
+
+	const q = `
+	UPDATE
+		login_sessions
+	SET
+		"revoked" = TRUE
+	WHERE
+		user_id = :user_id`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("revoking login sessions of userID[%s]: %w", userID, err)
+	}
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 	"unsafe"
 
+	"github.com/appinesshq/caservice/business/org"
 	"github.com/appinesshq/caservice/business/user"
 	"github.com/lib/pq"
 )
@@ -11,13 +12,34 @@ import (
 // User represent the structure we need for moving data
 // between the app and the database.
 type User struct {
-	ID           string         `db:"user_id"`
-	Name         string         `db:"name"`
-	Email        string         `db:"email"`
-	PasswordHash []byte         `db:"password_hash"`
-	Roles        pq.StringArray `db:"roles"`
-	DateCreated  time.Time      `db:"date_created"`
-	DateUpdated  time.Time      `db:"date_updated"`
+	ID            string         `db:"user_id"`
+	Name          string         `db:"name"`
+	Email         string         `db:"email"`
+	PasswordHash  []byte         `db:"password_hash"`
+	Roles         pq.StringArray `db:"roles"`
+	Verified      bool           `db:"verified"`
+	TOTPEnabled   bool           `db:"totp_enabled"`
+	TOTPSecret    []byte         `db:"totp_secret"`
+	TOTPLastStep  int64          `db:"totp_last_step"`
+	RecoveryCodes pq.StringArray `db:"recovery_codes"`
+	DateCreated   time.Time      `db:"date_created"`
+	DateUpdated   time.Time      `db:"date_updated"`
+	DateDeleted   *time.Time     `db:"date_deleted"`
+	Version       int            `db:"version"`
+}
+
+// RefreshToken represent the structure we need for moving refresh tokens
+// between the app and the database.
+type RefreshToken struct {
+	ID          string    `db:"token_id"`
+	FamilyID    string    `db:"family_id"`
+	UserID      string    `db:"user_id"`
+	OrgID       string    `db:"org_id"`
+	TokenHash   string    `db:"token_hash"`
+	Used        bool      `db:"used"`
+	Revoked     bool      `db:"revoked"`
+	DateCreated time.Time `db:"date_created"`
+	DateExpires time.Time `db:"date_expires"`
 }
 
 // =============================================================================
@@ -40,6 +62,16 @@ func toUser(u user.User) User {
 	return *pu
 }
 
+func toRefreshTokenEntity(dbRT RefreshToken) user.RefreshToken {
+	prt := (*user.RefreshToken)(unsafe.Pointer(&dbRT))
+	return *prt
+}
+
+func toRefreshToken(rt user.RefreshToken) RefreshToken {
+	prt := (*RefreshToken)(unsafe.Pointer(&rt))
+	return *prt
+}
+
 // func toUserSlice(usrs []user.User) []User {
 // 	users := make([]User, len(usrs))
 // 	for i, u := range usrs {
@@ -47,3 +79,188 @@ func toUser(u user.User) User {
 // 	}
 // 	return users
 // }
+
+// OneTimeToken represents a one-time token in the database.
+type OneTimeToken struct {
+	ID          string    `db:"token_id"`
+	UserID      string    `db:"user_id"`
+	Purpose     string    `db:"purpose"`
+	TokenHash   string    `db:"token_hash"`
+	Used        bool      `db:"used"`
+	Attempts    int       `db:"attempts"`
+	DateCreated time.Time `db:"date_created"`
+	DateExpires time.Time `db:"date_expires"`
+}
+
+func toOneTimeTokenEntity(dbOT OneTimeToken) user.OneTimeToken {
+	pot := (*user.OneTimeToken)(unsafe.Pointer(&dbOT))
+	return *pot
+}
+
+func toOneTimeToken(ot user.OneTimeToken) OneTimeToken {
+	pot := (*OneTimeToken)(unsafe.Pointer(&ot))
+	return *pot
+}
+
+// Identity represents an identity at an external provider in the database.
+type Identity struct {
+	ID          string    `db:"identity_id"`
+	UserID      string    `db:"user_id"`
+	Provider    string    `db:"provider"`
+	Subject     string    `db:"subject"`
+	Email       string    `db:"email"`
+	DateCreated time.Time `db:"date_created"`
+}
+
+func toIdentityEntity(dbID Identity) user.Identity {
+	pid := (*user.Identity)(unsafe.Pointer(&dbID))
+	return *pid
+}
+
+func toIdentity(id user.Identity) Identity {
+	pid := (*Identity)(unsafe.Pointer(&id))
+	return *pid
+}
+
+// APIKey represents an API key in the database.
+type APIKey struct {
+	ID           string         `db:"key_id"`
+	UserID       string         `db:"user_id"`
+	OrgID        string         `db:"org_id"`
+	Name         string         `db:"name"`
+	KeyHash      string         `db:"key_hash"`
+	Scopes       pq.StringArray `db:"scopes"`
+	Revoked      bool           `db:"revoked"`
+	DateCreated  time.Time      `db:"date_created"`
+	DateExpires  time.Time      `db:"date_expires"`
+	DateLastUsed time.Time      `db:"date_last_used"`
+}
+
+func toAPIKeyEntity(dbAK APIKey) user.APIKey {
+	pak := (*user.APIKey)(unsafe.Pointer(&dbAK))
+	return *pak
+}
+
+func toAPIKeyEntitySlice(dbAKs []APIKey) []user.APIKey {
+	keys := make([]user.APIKey, len(dbAKs))
+	for i, dbAK := range dbAKs {
+		keys[i] = toAPIKeyEntity(dbAK)
+	}
+	return keys
+}
+
+func toAPIKey(ak user.APIKey) APIKey {
+	pak := (*APIKey)(unsafe.Pointer(&ak))
+	return *pak
+}
+
+// LoginSession represents a login session in the database.
+type LoginSession struct {
+	ID           string    `db:"session_id"`
+	UserID       string    `db:"user_id"`
+	Device       string    `db:"device"`
+	IP           string    `db:"ip"`
+	UserAgent    string    `db:"user_agent"`
+	Revoked      bool      `db:"revoked"`
+	DateCreated  time.Time `db:"date_created"`
+	DateLastSeen time.Time `db:"date_last_seen"`
+	DateExpires  time.Time `db:"date_expires"`
+}
+
+func toLoginSessionEntity(dbLS LoginSession) user.LoginSession {
+	pls := (*user.LoginSession)(unsafe.Pointer(&dbLS))
+	return *pls
+}
+
+func toLoginSessionEntitySlice(dbLSs []LoginSession) []user.LoginSession {
+	sessions := make([]user.LoginSession, len(dbLSs))
+	for i, dbLS := range dbLSs {
+		sessions[i] = toLoginSessionEntity(dbLS)
+	}
+	return sessions
+}
+
+func toLoginSession(ls user.LoginSession) LoginSession {
+	pls := (*LoginSession)(unsafe.Pointer(&ls))
+	return *pls
+}
+
+// Org represents an organization in the database.
+type Org struct {
+	ID          string    `db:"org_id"`
+	Name        string    `db:"name"`
+	DateCreated time.Time `db:"date_created"`
+	DateUpdated time.Time `db:"date_updated"`
+}
+
+func toOrgEntity(dbOrg Org) org.Org {
+	po := (*org.Org)(unsafe.Pointer(&dbOrg))
+	return *po
+}
+
+func toOrgEntitySlice(dbOrgs []Org) []org.Org {
+	orgs := make([]org.Org, len(dbOrgs))
+	for i, dbOrg := range dbOrgs {
+		orgs[i] = toOrgEntity(dbOrg)
+	}
+	return orgs
+}
+
+func toOrg(o org.Org) Org {
+	po := (*Org)(unsafe.Pointer(&o))
+	return *po
+}
+
+// Membership represents a membership of an organization in the database.
+type Membership struct {
+	OrgID       string         `db:"org_id"`
+	UserID      string         `db:"user_id"`
+	Roles       pq.StringArray `db:"roles"`
+	DateCreated time.Time      `db:"date_created"`
+}
+
+func toMembershipEntity(dbM Membership) org.Membership {
+	pm := (*org.Membership)(unsafe.Pointer(&dbM))
+	return *pm
+}
+
+func toMembershipEntitySlice(dbMs []Membership) []org.Membership {
+	ms := make([]org.Membership, len(dbMs))
+	for i, dbM := range dbMs {
+		ms[i] = toMembershipEntity(dbM)
+	}
+	return ms
+}
+
+func toMembership(m org.Membership) Membership {
+	pm := (*Membership)(unsafe.Pointer(&m))
+	return *pm
+}
+
+// Invitation represents an invitation to an organization in the database.
+type Invitation struct {
+	ID          string         `db:"invitation_id"`
+	OrgID       string         `db:"org_id"`
+	UserID      string         `db:"user_id"`
+	Roles       pq.StringArray `db:"roles"`
+	InvitedBy   string         `db:"invited_by"`
+	DateCreated time.Time      `db:"date_created"`
+}
+
+func toInvitationEntity(dbI Invitation) org.Invitation {
+	pi := (*org.Invitation)(unsafe.Pointer(&dbI))
+	return *pi
+}
+
+func toInvitationEntitySlice(dbIs []Invitation) []org.Invitation {
+	is := make([]org.Invitation, len(dbIs))
+	for i, dbI := range dbIs {
+		is[i] = toInvitationEntity(dbI)
+	}
+	return is
+}
+
+func toInvitation(i org.Invitation) Invitation {
+	pi := (*Invitation)(unsafe.Pointer(&i))
+	return *pi
+}
@@ -0,0 +1,335 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/appinesshq/caservice/business/org"
+	"github.com/appinesshq/caservice/business/user/usecases"
+	database "github.com/appinesshq/caservice/data/core/pg"
+	"github.com/jmoiron/sqlx"
+)
+
+// CreateOrg inserts a new organization and the membership of its first
+// member into the database.
+func (s Store) CreateOrg(ctx context.Context, o org.Org, m org.Membership) error {
+	const q = `
+	INSERT INTO organizations
+		(org_id, name, date_created, date_updated)
+	VALUES
+		(:org_id, :name, :date_created, :date_updated)`
+
+	f := func(tx sqlx.ExtContext) error {
+		if err := database.NamedExecContext(ctx, s.log, tx, q, toOrg(o)); err != nil {
+			return fmt.Errorf("inserting organization: %w", err)
+		}
+		return s.Tran(tx).CreateMembership(ctx, m)
+	}
+
+	return s.WithinTran(ctx, f)
+}
+
+// QueryOrgsByUserID gets the organizations a user is a member of from the
+// database.
+func (s Store) QueryOrgsByUserID(ctx context.Context, userID string) ([]org.Org, error) {
+	data := struct {
+		UserID string `db:"user_id"`
+	}{
+		UserID: userID,
+	}
+
+	const q = `
+	SELECT
+		o.*
+	FROM
+		organizations AS o
+	JOIN
+		memberships AS m ON m.org_id = o.org_id
+	WHERE
+		m.user_id = :user_id
+	ORDER BY
+		m.date_created`
+
+	var orgs []Org
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &orgs); err != nil {
+		return nil, fmt.Errorf("selecting organizations of userID[%s]: %w", userID, err)
+	}
+
+	return toOrgEntitySlice(orgs), nil
+}
+
+// CreateMembership inserts a new membership into the database.
+func (s Store) CreateMembership(ctx context.Context, m org.Membership) error {
+	const q = `
+	INSERT INTO memberships
+		(org_id, user_id, roles, date_created)
+	SELECT
+		:org_id, user_id, :roles, :date_created
+	FROM
+		users
+	WHERE
+		user_id = :user_id AND
+		date_deleted IS NULL
+	RETURNING
+		user_id`
+
+	var created struct {
+		UserID string `db:"user_id"`
+	}
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, toMembership(m), &created); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return usecases.ErrNotFound
+		}
+		if errors.Is(err, database.ErrDBDuplicatedEntry) {
+			return usecases.ErrUniqueMembership
+		}
+		return fmt.Errorf("inserting membership: %w", err)
+	}
+
+	return nil
+}
+
+// QueryMembership gets the membership of a user in an organization from the
+// database.
+func (s Store) QueryMembership(ctx context.Context, orgID, userID string) (org.Membership, error) {
+	data := struct {
+		OrgID  string `db:"org_id"`
+		UserID string `db:"user_id"`
+	}{
+		OrgID:  orgID,
+		UserID: userID,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		memberships
+	WHERE
+		org_id = :org_id AND
+		user_id = :user_id`
+
+	var m Membership
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &m); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return org.Membership{}, usecases.ErrMembershipNotFound
+		}
+		return org.Membership{}, fmt.Errorf("selecting membership of userID[%s]: %w", userID, err)
+	}
+
+	return toMembershipEntity(m), nil
+}
+
+// QueryMembershipsByUserID gets all memberships of a user from the database.
+func (s Store) QueryMembershipsByUserID(ctx context.Context, userID string) ([]org.Membership, error) {
+	data := struct {
+		UserID string `db:"user_id"`
+	}{
+		UserID: userID,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		memberships
+	WHERE
+		user_id = :user_id
+	ORDER BY
+		date_created`
+
+	var ms []Membership
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &ms); err != nil {
+		return nil, fmt.Errorf("selecting memberships of userID[%s]: %w", userID, err)
+	}
+
+	return toMembershipEntitySlice(ms), nil
+}
+
+// QueryMembershipsByOrgID gets all memberships of an organization from the
+// database.
+func (s Store) QueryMembershipsByOrgID(ctx context.Context, orgID string) ([]org.Membership, error) {
+	data := struct {
+		OrgID string `db:"org_id"`
+	}{
+		OrgID: orgID,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		memberships
+	WHERE
+		org_id = :org_id
+	ORDER BY
+		date_created`
+
+	var ms []Membership
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &ms); err != nil {
+		return nil, fmt.Errorf("selecting memberships of orgID[%s]: %w", orgID, err)
+	}
+
+	return toMembershipEntitySlice(ms), nil
+}
+
+// DeleteMembership removes the membership of a user in an organization from
+// the database.
+func (s Store) DeleteMembership(ctx context.Context, orgID, userID string) error {
+	data := struct {
+		OrgID  string `db:"org_id"`
+		UserID string `db:"user_id"`
+	}{
+		OrgID:  orgID,
+		UserID: userID,
+	}
+
+	const q = `
+	DELETE FROM
+		memberships
+	WHERE
+		org_id = :org_id AND
+		user_id = :user_id
+	RETURNING
+		user_id`
+
+	var deleted struct {
+		UserID string `db:"user_id"`
+	}
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &deleted); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return usecases.ErrMembershipNotFound
+		}
+		return fmt.Errorf("deleting membership of userID[%s]: %w", userID, err)
+	}
+
+	return nil
+}
+
+// CreateInvitation inserts a new invitation into the database.
+func (s Store) CreateInvitation(ctx context.Context, i org.Invitation) error {
+	const q = `
+	INSERT INTO org_invitations
+		(invitation_id, org_id, user_id, roles, invited_by, date_created)
+	SELECT
+		:invitation_id, :org_id, user_id, :roles, :invited_by, :date_created
+	FROM
+		users
+	WHERE
+		user_id = :user_id AND
+		date_deleted IS NULL
+	RETURNING
+		invitation_id`
+
+	var created struct {
+		ID string `db:"invitation_id"`
+	}
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, toInvitation(i), &created); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return usecases.ErrNotFound
+		}
+		if errors.Is(err, database.ErrDBDuplicatedEntry) {
+			return usecases.ErrUniqueInvitation
+		}
+		return fmt.Errorf("inserting invitation: %w", err)
+	}
+
+	return nil
+}
+
+// QueryInvitationByID gets the specified invitation from the database.
+func (s Store) QueryInvitationByID(ctx context.Context, id string) (org.Invitation, error) {
+	data := struct {
+		ID string `db:"invitation_id"`
+	}{
+		ID: id,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		org_invitations
+	WHERE
+		invitation_id = :invitation_id`
+
+	var i Invitation
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &i); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return org.Invitation{}, usecases.ErrInvitationNotFound
+		}
+		return org.Invitation{}, fmt.Errorf("selecting invitationID[%s]: %w", id, err)
+	}
+
+	return toInvitationEntity(i), nil
+}
+
+// QueryInvitationsByUserID gets all invitations of a user from the database.
+func (s Store) QueryInvitationsByUserID(ctx context.Context, userID string) ([]org.Invitation, error) {
+	data := struct {
+		UserID string `db:"user_id"`
+	}{
+		UserID: userID,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		org_invitations
+	WHERE
+		user_id = :user_id
+	ORDER BY
+		date_created`
+
+	var is []Invitation
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &is); err != nil {
+		return nil, fmt.Errorf("selecting invitations of userID[%s]: %w", userID, err)
+	}
+
+	return toInvitationEntitySlice(is), nil
+}
+
+// AcceptInvitation deletes the invitation and inserts the membership it
+// was accepted as into the database at once.
+func (s Store) AcceptInvitation(ctx context.Context, id string, m org.Membership) error {
+	f := func(tx sqlx.ExtContext) error {
+		store := s.Tran(tx)
+		if err := store.DeleteInvitation(ctx, id); err != nil {
+			return err
+		}
+		return store.CreateMembership(ctx, m)
+	}
+
+	return s.WithinTran(ctx, f)
+}
+
+// DeleteInvitation removes the invitation from the database.
+func (s Store) DeleteInvitation(ctx context.Context, id string) error {
+	data := struct {
+		ID string `db:"invitation_id"`
+	}{
+		ID: id,
+	}
+
+	const q = `
+	DELETE FROM
+		org_invitations
+	WHERE
+		invitation_id = :invitation_id
+	RETURNING
+		invitation_id`
+
+	var deleted struct {
+		ID string `db:"invitation_id"`
+	}
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &deleted); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return usecases.ErrInvitationNotFound
+		}
+		return fmt.Errorf("deleting invitationID[%s]: %w", id, err)
+	}
+
+	return nil
+}
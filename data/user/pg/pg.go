@@ -1,11 +1,20 @@
 // Package pg provides memory storage functionality for users.
+//
+// Calls with a session in the context are made on behalf of its user and are
+// scoped to the members of the organization of the session. Users of other
+// organizations can't be reached with such a context. Calls without a session
+// fail, unless the context is marked for the system.
 package pg
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/appinesshq/caservice/business/org"
 	"github.com/appinesshq/caservice/business/user"
+	"github.com/appinesshq/caservice/business/user/usecases"
 	database "github.com/appinesshq/caservice/data/core/pg"
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
@@ -50,22 +59,32 @@ func (s Store) Tran(tx sqlx.ExtContext) Store {
 func (s Store) Create(ctx context.Context, u user.User) error {
 	const q = `
 	INSERT INTO users
-		(user_id, name, email, password_hash, roles, date_created, date_updated)
+		(user_id, name, email, password_hash, roles, verified, totp_secret, totp_enabled, totp_last_step, recovery_codes, date_created, date_updated, version)
 	VALUES
-		(:user_id, :name, :email, :password_hash, :roles, :date_created, :date_updated)`
+		(:user_id, :name, :email, :password_hash, :roles, :verified, :totp_secret, :totp_enabled, :totp_last_step, :recovery_codes, :date_created, :date_updated, :version)`
 
 	// Convert entity to DB user model.
 	usr := toUser(u)
 
 	if err := database.NamedExecContext(ctx, s.log, s.db, q, usr); err != nil {
+		if errors.Is(err, database.ErrDBDuplicatedEntry) {
+			return usecases.ErrUniqueEmail
+		}
 		return fmt.Errorf("inserting user: %w", err)
 	}
 
 	return nil
 }
 
-// Update replaces a user document in the database.
+// Update replaces a user document in the database, if it is still the
+// version the user was read as. Within an organization, the roles of the
+// user are its roles in the organization, so these are updated instead.
 func (s Store) Update(ctx context.Context, u user.User) error {
+	orgID, scoped, err := scope(ctx)
+	if err != nil {
+		return err
+	}
+
 	const q = `
 	UPDATE
 		users
@@ -74,33 +93,126 @@ func (s Store) Update(ctx context.Context, u user.User) error {
 		"email" = :email,
 		"roles" = :roles,
 		"password_hash" = :password_hash,
-		"date_updated" = :date_updated
+		"verified" = :verified,
+		"totp_secret" = :totp_secret,
+		"totp_enabled" = :totp_enabled,
+		"totp_last_step" = :totp_last_step,
+		"recovery_codes" = :recovery_codes,
+		"date_updated" = :date_updated,
+		"version" = version + 1
+	WHERE
+		user_id = :user_id AND
+		version = :version AND
+		date_deleted IS NULL
+	RETURNING
+		user_id`
+
+	const qMember = `
+	UPDATE
+		users
+	SET 
+		"name" = :name,
+		"email" = :email,
+		"password_hash" = :password_hash,
+		"verified" = :verified,
+		"totp_secret" = :totp_secret,
+		"totp_enabled" = :totp_enabled,
+		"totp_last_step" = :totp_last_step,
+		"recovery_codes" = :recovery_codes,
+		"date_updated" = :date_updated,
+		"version" = version + 1
+	WHERE
+		user_id = :user_id AND
+		version = :version AND
+		date_deleted IS NULL AND
+		user_id IN (SELECT user_id FROM memberships WHERE org_id = :org_id)
+	RETURNING
+		user_id`
+
+	const qRoles = `
+	UPDATE
+		memberships
+	SET
+		"roles" = :roles
 	WHERE
+		org_id = :org_id AND
 		user_id = :user_id`
 
 	// Convert entity to DB user model.
-	usr := toUser(u)
+	data := struct {
+		User
+		OrgID string `db:"org_id"`
+	}{
+		User:  toUser(u),
+		OrgID: orgID,
+	}
 
-	if err := database.NamedExecContext(ctx, s.log, s.db, q, usr); err != nil {
-		return fmt.Errorf("updating userID[%s]: %w", usr.ID, err)
+	f := func(tx sqlx.ExtContext) error {
+		var updated struct {
+			UserID string `db:"user_id"`
+		}
+		if !scoped {
+			return database.NamedQueryStruct(ctx, s.log, tx, q, data, &updated)
+		}
+		if err := database.NamedQueryStruct(ctx, s.log, tx, qMember, data, &updated); err != nil {
+			return err
+		}
+		return database.NamedExecContext(ctx, s.log, tx, qRoles, data)
+	}
+
+	if scoped {
+		err = s.WithinTran(ctx, f)
+	} else {
+		err = f(s.db)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrDBDuplicatedEntry):
+			return usecases.ErrUniqueEmail
+		case errors.Is(err, database.ErrDBNotFound):
+			// No user was updated: it is gone or has a newer version.
+			if _, err := s.QueryByID(ctx, u.ID); err != nil {
+				return err
+			}
+			return usecases.ErrConflict
+		}
+		return fmt.Errorf("updating userID[%s]: %w", u.ID, err)
 	}
 
 	return nil
 }
 
-// Delete removes a user from the database.
-func (s Store) Delete(ctx context.Context, userID string) error {
+// Delete marks a user as deleted in the database. The user is kept until it
+// is purged.
+func (s Store) Delete(ctx context.Context, userID string, now time.Time) error {
+	orgID, scoped, err := scope(ctx)
+	if err != nil {
+		return err
+	}
+
 	data := struct {
-		UserID string `db:"user_id"`
+		UserID      string    `db:"user_id"`
+		OrgID       string    `db:"org_id"`
+		DateDeleted time.Time `db:"date_deleted"`
 	}{
-		UserID: userID,
+		UserID:      userID,
+		OrgID:       orgID,
+		DateDeleted: now,
 	}
 
-	const q = `
-	DELETE FROM
+	q := `
+	UPDATE
 		users
+	SET
+		"date_deleted" = :date_deleted,
+		"version" = version + 1
 	WHERE
-		user_id = :user_id`
+		user_id = :user_id AND
+		date_deleted IS NULL`
+	if scoped {
+		q += ` AND
+		user_id IN (SELECT user_id FROM memberships WHERE org_id = :org_id)`
+	}
 
 	if err := database.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
 		return fmt.Errorf("deleting userID[%s]: %w", userID, err)
@@ -109,28 +221,257 @@ func (s Store) Delete(ctx context.Context, userID string) error {
 	return nil
 }
 
-// Query retrieves a list of existing users from the database.
-func (s Store) Query(ctx context.Context, pageNumber int, rowsPerPage int) ([]user.User, error) {
+// Restore removes the deleted mark of a user in the database.
+func (s Store) Restore(ctx context.Context, userID string, now time.Time) error {
+	orgID, scoped, err := scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	data := struct {
+		UserID      string    `db:"user_id"`
+		OrgID       string    `db:"org_id"`
+		DateUpdated time.Time `db:"date_updated"`
+	}{
+		UserID:      userID,
+		OrgID:       orgID,
+		DateUpdated: now,
+	}
+
+	q := `
+	UPDATE
+		users
+	SET
+		"date_deleted" = NULL,
+		"date_updated" = :date_updated,
+		"version" = version + 1
+	WHERE
+		user_id = :user_id AND
+		date_deleted IS NOT NULL`
+	if scoped {
+		q += ` AND
+		user_id IN (SELECT user_id FROM memberships WHERE org_id = :org_id)`
+	}
+	q += `
+	RETURNING
+		user_id`
+
+	var restored struct {
+		UserID string `db:"user_id"`
+	}
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &restored); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return usecases.ErrNotFound
+		}
+		return fmt.Errorf("restoring userID[%s]: %w", userID, err)
+	}
+
+	return nil
+}
+
+// Purge removes the users deleted before the provided time from the
+// database, together with their products and sales.
+func (s Store) Purge(ctx context.Context, before time.Time) ([]string, error) {
 	data := struct {
+		Before time.Time `db:"before"`
+	}{
+		Before: before,
+	}
+
+	const q = `
+	DELETE FROM
+		users
+	WHERE
+		date_deleted < :before
+	RETURNING
+		user_id`
+
+	var purged []struct {
+		UserID string `db:"user_id"`
+	}
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &purged); err != nil {
+		return nil, fmt.Errorf("purging users: %w", err)
+	}
+
+	ids := make([]string, len(purged))
+	for i, p := range purged {
+		ids[i] = p.UserID
+	}
+
+	return ids, nil
+}
+
+// Import inserts or replaces a batch of users and their memberships in the
+// database within a single transaction. Nothing is stored if an email
+// address belongs to another user.
+func (s Store) Import(ctx context.Context, us []user.User, ms []org.Membership) error {
+	const qUser = `
+	INSERT INTO users
+		(user_id, name, email, password_hash, roles, verified, totp_secret, totp_enabled, totp_last_step, recovery_codes, date_created, date_updated, version)
+	VALUES
+		(:user_id, :name, :email, :password_hash, :roles, :verified, :totp_secret, :totp_enabled, :totp_last_step, :recovery_codes, :date_created, :date_updated, :version)
+	ON CONFLICT (user_id) DO UPDATE SET
+		"name" = EXCLUDED.name,
+		"email" = EXCLUDED.email,
+		"password_hash" = EXCLUDED.password_hash,
+		"roles" = EXCLUDED.roles,
+		"date_updated" = EXCLUDED.date_updated,
+		"version" = users.version + 1
+	WHERE
+		users.version = EXCLUDED.version AND
+		users.date_deleted IS NULL
+	RETURNING
+		user_id`
+
+	const qMember = `
+	INSERT INTO memberships
+		(org_id, user_id, roles, date_created)
+	VALUES
+		(:org_id, :user_id, :roles, :date_created)
+	ON CONFLICT (org_id, user_id) DO UPDATE SET
+		"roles" = EXCLUDED.roles`
+
+	f := func(tx sqlx.ExtContext) error {
+		for _, u := range us {
+			// Nothing is returned if the user was changed or deleted since
+			// it was read.
+			var imported struct {
+				UserID string `db:"user_id"`
+			}
+			if err := database.NamedQueryStruct(ctx, s.log, tx, qUser, toUser(u), &imported); err != nil {
+				if errors.Is(err, database.ErrDBNotFound) {
+					return usecases.ErrConflict
+				}
+				if errors.Is(err, database.ErrDBDuplicatedEntry) {
+					return usecases.ErrUniqueEmail
+				}
+				return fmt.Errorf("importing userID[%s]: %w", u.ID, err)
+			}
+		}
+		for _, m := range ms {
+			if err := database.NamedExecContext(ctx, s.log, tx, qMember, toMembership(m)); err != nil {
+				return fmt.Errorf("importing membership of userID[%s]: %w", m.UserID, err)
+			}
+		}
+		return nil
+	}
+
+	return s.WithinTran(ctx, f)
+}
+
+// Query retrieves a page of the users selected by the filter from the
+// database.
+func (s Store) Query(ctx context.Context, f user.QueryFilter, pageNumber int, rowsPerPage int) ([]user.User, error) {
+	orgID, scoped, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	data := struct {
+		filterData
 		Offset      int `db:"offset"`
 		RowsPerPage int `db:"rows_per_page"`
 	}{
+		filterData:  toFilterData(orgID, f),
 		Offset:      (pageNumber - 1) * rowsPerPage,
 		RowsPerPage: rowsPerPage,
 	}
 
+	q := selectFiltered(scoped, f) + `
+	ORDER BY
+		` + orderBy(f) + `
+	OFFSET :offset ROWS FETCH NEXT :rows_per_page ROWS ONLY`
+
+	var usrs []User
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &usrs); err != nil {
+		return nil, fmt.Errorf("selecting users: %w", err)
+	}
+
+	return toEntitySlice(usrs), nil
+}
+
+// Count returns the number of users selected by the filter in the database.
+func (s Store) Count(ctx context.Context, f user.QueryFilter) (int, error) {
+	orgID, scoped, err := scope(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	q := `
+	SELECT
+		count(*) AS count
+	FROM (` + selectFiltered(scoped, f) + `
+	) AS filtered`
+
+	var result struct {
+		Count int `db:"count"`
+	}
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, toFilterData(orgID, f), &result); err != nil {
+		return 0, fmt.Errorf("counting users: %w", err)
+	}
+
+	return result.Count, nil
+}
+
+// CountAll returns the number of users in the database, including deleted
+// users.
+func (s Store) CountAll(ctx context.Context) (int, error) {
 	const q = `
+	SELECT
+		count(*) AS count
+	FROM
+		users`
+
+	var result struct {
+		Count int `db:"count"`
+	}
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, struct{}{}, &result); err != nil {
+		return 0, fmt.Errorf("counting all users: %w", err)
+	}
+
+	return result.Count, nil
+}
+
+// QueryDeleted retrieves a list of deleted users from the database.
+func (s Store) QueryDeleted(ctx context.Context, pageNumber int, rowsPerPage int) ([]user.User, error) {
+	orgID, scoped, err := scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	data := struct {
+		OrgID       string `db:"org_id"`
+		Offset      int    `db:"offset"`
+		RowsPerPage int    `db:"rows_per_page"`
+	}{
+		OrgID:       orgID,
+		Offset:      (pageNumber - 1) * rowsPerPage,
+		RowsPerPage: rowsPerPage,
+	}
+
+	q := `
 	SELECT
 		*
 	FROM
 		users
+	WHERE
+		date_deleted IS NOT NULL
 	ORDER BY
-		user_id
+		date_deleted DESC
 	OFFSET :offset ROWS FETCH NEXT :rows_per_page ROWS ONLY`
+	if scoped {
+		q = selectMembers + `
+	WHERE
+		m.org_id = :org_id AND
+		u.date_deleted IS NOT NULL
+	ORDER BY
+		u.date_deleted DESC
+	OFFSET :offset ROWS FETCH NEXT :rows_per_page ROWS ONLY`
+	}
 
 	var usrs []User
 	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &usrs); err != nil {
-		return nil, fmt.Errorf("selecting users: %w", err)
+		return nil, fmt.Errorf("selecting deleted users: %w", err)
 	}
 
 	return toEntitySlice(usrs), nil
@@ -138,22 +479,40 @@ func (s Store) Query(ctx context.Context, pageNumber int, rowsPerPage int) ([]us
 
 // QueryByID gets the specified user from the database.
 func (s Store) QueryByID(ctx context.Context, userID string) (user.User, error) {
+	orgID, scoped, err := scope(ctx)
+	if err != nil {
+		return user.User{}, err
+	}
+
 	data := struct {
 		UserID string `db:"user_id"`
+		OrgID  string `db:"org_id"`
 	}{
 		UserID: userID,
+		OrgID:  orgID,
 	}
 
-	const q = `
+	q := `
 	SELECT
 		*
 	FROM
 		users
 	WHERE 
-		user_id = :user_id`
+		user_id = :user_id AND
+		date_deleted IS NULL`
+	if scoped {
+		q = selectMembers + `
+	WHERE
+		m.org_id = :org_id AND
+		u.user_id = :user_id AND
+		u.date_deleted IS NULL`
+	}
 
 	var usr User
 	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &usr); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return user.User{}, usecases.ErrNotFound
+		}
 		return user.User{}, fmt.Errorf("selecting userID[%q]: %w", userID, err)
 	}
 
@@ -162,22 +521,40 @@ func (s Store) QueryByID(ctx context.Context, userID string) (user.User, error)
 
 // QueryByEmail gets the specified user from the database by email.
 func (s Store) QueryByEmail(ctx context.Context, email string) (user.User, error) {
+	orgID, scoped, err := scope(ctx)
+	if err != nil {
+		return user.User{}, err
+	}
+
 	data := struct {
 		Email string `db:"email"`
+		OrgID string `db:"org_id"`
 	}{
 		Email: email,
+		OrgID: orgID,
 	}
 
-	const q = `
+	q := `
 	SELECT
 		*
 	FROM
 		users
 	WHERE
-		email = :email`
+		email = :email AND
+		date_deleted IS NULL`
+	if scoped {
+		q = selectMembers + `
+	WHERE
+		m.org_id = :org_id AND
+		u.email = :email AND
+		u.date_deleted IS NULL`
+	}
 
 	var usr User
 	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &usr); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return user.User{}, usecases.ErrNotFound
+		}
 		return user.User{}, fmt.Errorf("selecting email[%q]: %w", email, err)
 	}
 
@@ -185,3 +562,170 @@ func (s Store) QueryByEmail(ctx context.Context, email string) (user.User, error
 
 	return u, nil
 }
+
+// QueryDeletedByEmail gets the deleted user with the email address from the
+// database.
+func (s Store) QueryDeletedByEmail(ctx context.Context, email string) (user.User, error) {
+	orgID, scoped, err := scope(ctx)
+	if err != nil {
+		return user.User{}, err
+	}
+
+	data := struct {
+		Email string `db:"email"`
+		OrgID string `db:"org_id"`
+	}{
+		Email: email,
+		OrgID: orgID,
+	}
+
+	q := `
+	SELECT
+		*
+	FROM
+		users
+	WHERE
+		email = :email AND
+		date_deleted IS NOT NULL`
+	if scoped {
+		q = selectMembers + `
+	WHERE
+		m.org_id = :org_id AND
+		u.email = :email AND
+		u.date_deleted IS NOT NULL`
+	}
+
+	var usr User
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &usr); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return user.User{}, usecases.ErrNotFound
+		}
+		return user.User{}, fmt.Errorf("selecting deleted email[%q]: %w", email, err)
+	}
+
+	return toEntity(usr), nil
+}
+
+// selectMembers selects users as members of an organization, with their
+// roles within it. Queries must add the organization to the conditions.
+const selectMembers = `
+	SELECT
+		u.user_id, u.name, u.email, u.password_hash, m.roles, u.verified, u.totp_enabled,
+		u.totp_secret, u.totp_last_step, u.recovery_codes, u.date_created, u.date_updated,
+		u.date_deleted, u.version
+	FROM
+		users AS u
+	JOIN
+		memberships AS m ON m.user_id = u.user_id`
+
+// filterData holds the values of a filter for the queries of selectFiltered.
+type filterData struct {
+	OrgID string    `db:"org_id"`
+	Name  string    `db:"name"`
+	Email string    `db:"email"`
+	Role  string    `db:"role"`
+	Since time.Time `db:"since"`
+	Until time.Time `db:"until"`
+}
+
+func toFilterData(orgID string, f user.QueryFilter) filterData {
+	return filterData{
+		OrgID: orgID,
+		Name:  f.Name,
+		Email: f.Email,
+		Role:  f.Role,
+		Since: f.Since,
+		Until: f.Until,
+	}
+}
+
+// selectFiltered returns a query selecting the users that are not deleted
+// and are selected by the filter. Scoped queries select the members of the
+// organization, with their roles within it.
+func selectFiltered(scoped bool, f user.QueryFilter) string {
+	q := `
+	SELECT
+		*
+	FROM
+		users
+	WHERE
+		date_deleted IS NULL`
+	if scoped {
+		q = `
+	SELECT
+		*
+	FROM (` + selectMembers + `
+	WHERE
+		m.org_id = :org_id
+	) AS users
+	WHERE
+		date_deleted IS NULL`
+	}
+
+	// Like the memory storage, names and email addresses match if they
+	// contain the value regardless of case.
+	if f.Name != "" {
+		q += ` AND
+		strpos(lower(name), lower(:name)) > 0`
+	}
+	if f.Email != "" {
+		q += ` AND
+		strpos(lower(email), lower(:email)) > 0`
+	}
+	if f.Role != "" {
+		q += ` AND
+		:role = ANY(roles)`
+	}
+	if !f.Since.IsZero() {
+		q += ` AND
+		date_created >= :since`
+	}
+	if !f.Until.IsZero() {
+		q += ` AND
+		date_created < :until`
+	}
+
+	return q
+}
+
+// orderBy returns the order of the users selected by the filter. Text is
+// compared byte by byte, so users are in the same order as in the memory
+// storage, and users are ordered by id last.
+func orderBy(f user.QueryFilter) string {
+	direction := "ASC"
+	if f.Direction == user.DirectionDesc {
+		direction = "DESC"
+	}
+
+	var by string
+	switch f.OrderBy {
+	case user.OrderByName:
+		by = `name COLLATE "C" ` + direction + `, `
+	case user.OrderByEmail:
+		by = `email COLLATE "C" ` + direction + `, `
+	case user.OrderByDateCreated:
+		by = `date_created ` + direction + `, `
+	}
+
+	return by + `user_id ` + direction
+}
+
+// scope returns the organization calls on behalf of the session in the
+// context are scoped to. Calls the system makes itself, for example to
+// authenticate a user, are not scoped if their context is marked for the
+// system. Any other call without a session fails.
+func scope(ctx context.Context) (string, bool, error) {
+	if _, err := user.GetSession(ctx); err != nil {
+		if user.IsSystem(ctx) {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("scoping users: %w", user.ErrNoTenant)
+	}
+
+	orgID, err := user.GetTenantID(ctx)
+	if err != nil {
+		return "", false, fmt.Errorf("scoping users: %w", err)
+	}
+
+	return orgID, true, nil
+}
@@ -0,0 +1,239 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/appinesshq/caservice/business/user"
+	"github.com/appinesshq/caservice/business/user/usecases"
+	database "github.com/appinesshq/caservice/data/core/pg"
+)
+
+// CreateRefreshToken inserts a new refresh token into the database.
+func (s Store) CreateRefreshToken(ctx context.Context, rt user.RefreshToken) error {
+	const q = `
+	INSERT INTO refresh_tokens
+		(token_id, family_id, user_id, org_id, token_hash, used, revoked, date_created, date_expires)
+	VALUES
+		(:token_id, :family_id, :user_id, :org_id, :token_hash, :used, :revoked, :date_created, :date_expires)`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, toRefreshToken(rt)); err != nil {
+		return fmt.Errorf("inserting refresh token: %w", err)
+	}
+
+	return nil
+}
+
+// QueryRefreshTokenByHash gets the refresh token with the specified hash from the database.
+func (s Store) QueryRefreshTokenByHash(ctx context.Context, hash string) (user.RefreshToken, error) {
+	data := struct {
+		TokenHash string `db:"token_hash"`
+	}{
+		TokenHash: hash,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		refresh_tokens
+	WHERE
+		token_hash = :token_hash`
+
+	var rt RefreshToken
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &rt); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return user.RefreshToken{}, usecases.ErrRefreshTokenNotFound
+		}
+		return user.RefreshToken{}, fmt.Errorf("selecting refresh token: %w", err)
+	}
+
+	return toRefreshTokenEntity(rt), nil
+}
+
+// UseRefreshToken marks a refresh token as used. The update only matches an
+// unused token, so of two concurrent calls only one can succeed.
+func (s Store) UseRefreshToken(ctx context.Context, tokenID string) error {
+	data := struct {
+		TokenID string `db:"token_id"`
+	}{
+		TokenID: tokenID,
+	}
+
+	const q = `
+	UPDATE
+		refresh_tokens
+	SET
+		"used" = TRUE
+	WHERE
+		token_id = :token_id AND
+		used = FALSE
+	RETURNING
+		token_id`
+
+	var used struct {
+		TokenID string `db:"token_id"`
+	}
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &used); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return usecases.ErrRefreshTokenUsed
+		}
+		return fmt.Errorf("using refresh tokenID[%s]: %w", tokenID, err)
+	}
+
+	return nil
+}
+
+// RevokeRefreshTokenFamily revokes all refresh tokens of a family.
+func (s Store) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	data := struct {
+		FamilyID string `db:"family_id"`
+	}{
+		FamilyID: familyID,
+	}
+
+	const q = `
+	UPDATE
+		refresh_tokens
+	SET
+		"revoked" = TRUE
+	WHERE
+		family_id = :family_id`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("revoking refresh token familyID[%s]: %w", familyID, err)
+	}
+
+	return nil
+}
+
+// RevokeUserRefreshTokens revokes all refresh tokens of a user.
+func (s Store) RevokeUserRefreshTokens(ctx context.Context, userID string) error {
+	data := struct {
+		UserID string `db:"user_id"`
+	}{
+		UserID: userID,
+	}
+
+	const q = `
+	UPDATE
+		refresh_tokens
+	SET
+		"revoked" = TRUE
+	WHERE
+		user_id = :user_id`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("revoking refresh tokens of userID[%s]: %w", userID, err)
+	}
+
+	return nil
+}
+
+// CreateOneTimeToken inserts a new one-time token into the database.
+func (s Store) CreateOneTimeToken(ctx context.Context, ot user.OneTimeToken) error {
+	const q = `
+	INSERT INTO one_time_tokens
+		(token_id, user_id, purpose, token_hash, used, date_created, date_expires)
+	VALUES
+		(:token_id, :user_id, :purpose, :token_hash, :used, :date_created, :date_expires)`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, toOneTimeToken(ot)); err != nil {
+		return fmt.Errorf("inserting one-time token: %w", err)
+	}
+
+	return nil
+}
+
+// QueryOneTimeTokenByHash gets the one-time token with the specified hash from the database.
+func (s Store) QueryOneTimeTokenByHash(ctx context.Context, hash string) (user.OneTimeToken, error) {
+	data := struct {
+		TokenHash string `db:"token_hash"`
+	}{
+		TokenHash: hash,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		one_time_tokens
+	WHERE
+		token_hash = :token_hash`
+
+	var ot OneTimeToken
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &ot); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return user.OneTimeToken{}, usecases.ErrOneTimeTokenNotFound
+		}
+		return user.OneTimeToken{}, fmt.Errorf("selecting one-time token: %w", err)
+	}
+
+	return toOneTimeTokenEntity(ot), nil
+}
+
+// UseOneTimeToken marks a one-time token as used. The update only matches an
+// unused token, so of two concurrent calls only one can succeed.
+func (s Store) UseOneTimeToken(ctx context.Context, tokenID string) error {
+	data := struct {
+		TokenID string `db:"token_id"`
+	}{
+		TokenID: tokenID,
+	}
+
+	const q = `
+	UPDATE
+		one_time_tokens
+	SET
+		"used" = TRUE
+	WHERE
+		token_id = :token_id AND
+		used = FALSE
+	RETURNING
+		token_id`
+
+	var used struct {
+		TokenID string `db:"token_id"`
+	}
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &used); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return usecases.ErrOneTimeTokenUsed
+		}
+		return fmt.Errorf("using one-time tokenID[%s]: %w", tokenID, err)
+	}
+
+	return nil
+}
+
+// AttemptOneTimeToken counts an attempt to use the one-time token in the
+// database and returns the number of attempts.
+func (s Store) AttemptOneTimeToken(ctx context.Context, tokenID string) (int, error) {
+	data := struct {
+		TokenID string `db:"token_id"`
+	}{
+		TokenID: tokenID,
+	}
+
+	const q = `
+	UPDATE
+		one_time_tokens
+	SET
+		"attempts" = attempts + 1
+	WHERE
+		token_id = :token_id
+	RETURNING
+		attempts`
+
+	var result struct {
+		Attempts int `db:"attempts"`
+	}
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &result); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return 0, usecases.ErrOneTimeTokenNotFound
+		}
+		return 0, fmt.Errorf("attempting one-time tokenID[%s]: %w", tokenID, err)
+	}
+
+	return result.Attempts, nil
+}
@@ -3,18 +3,27 @@ package user
 
 import (
 	"context"
+	"time"
 
+	"github.com/appinesshq/caservice/business/org"
 	"github.com/appinesshq/caservice/business/user"
 )
 
 // UserStorage is an interface to be implemented by user storages.
 type UserStorage interface {
 	Create(context.Context, user.User) error
-	Query(context.Context, int, int) ([]user.User, error)
+	Query(context.Context, user.QueryFilter, int, int) ([]user.User, error)
+	Count(context.Context, user.QueryFilter) (int, error)
+	CountAll(context.Context) (int, error)
+	QueryDeleted(context.Context, int, int) ([]user.User, error)
 	QueryByID(context.Context, string) (user.User, error)
 	QueryByEmail(context.Context, string) (user.User, error)
+	QueryDeletedByEmail(context.Context, string) (user.User, error)
 	Update(context.Context, user.User) error
-	Delete(context.Context, string) error
+	Delete(context.Context, string, time.Time) error
+	Restore(context.Context, string, time.Time) error
+	Purge(context.Context, time.Time) ([]string, error)
+	Import(context.Context, []user.User, []org.Membership) error
 }
 
 // UserRepository implements the usecases' repository.
@@ -33,8 +42,20 @@ func (r UserRepository) Create(ctx context.Context, u user.User) error {
 	return r.Storage.Create(ctx, u)
 }
 
-func (r UserRepository) Query(ctx context.Context, pageNumber int, rowsPerPage int) ([]user.User, error) {
-	return r.Storage.Query(ctx, pageNumber, rowsPerPage)
+func (r UserRepository) Query(ctx context.Context, f user.QueryFilter, pageNumber int, rowsPerPage int) ([]user.User, error) {
+	return r.Storage.Query(ctx, f, pageNumber, rowsPerPage)
+}
+
+func (r UserRepository) Count(ctx context.Context, f user.QueryFilter) (int, error) {
+	return r.Storage.Count(ctx, f)
+}
+
+func (r UserRepository) CountAll(ctx context.Context) (int, error) {
+	return r.Storage.CountAll(ctx)
+}
+
+func (r UserRepository) QueryDeleted(ctx context.Context, pageNumber int, rowsPerPage int) ([]user.User, error) {
+	return r.Storage.QueryDeleted(ctx, pageNumber, rowsPerPage)
 }
 
 func (r UserRepository) QueryByID(ctx context.Context, id string) (user.User, error) {
@@ -45,10 +66,251 @@ func (r UserRepository) QueryByEmail(ctx context.Context, email string) (user.Us
 	return r.Storage.QueryByEmail(ctx, email)
 }
 
+func (r UserRepository) QueryDeletedByEmail(ctx context.Context, email string) (user.User, error) {
+	return r.Storage.QueryDeletedByEmail(ctx, email)
+}
+
 func (r UserRepository) Update(ctx context.Context, u user.User) error {
 	return r.Storage.Update(ctx, u)
 }
 
-func (r UserRepository) Delete(ctx context.Context, id string) error {
-	return r.Storage.Delete(ctx, id)
+func (r UserRepository) Delete(ctx context.Context, id string, now time.Time) error {
+	return r.Storage.Delete(ctx, id, now)
+}
+
+func (r UserRepository) Restore(ctx context.Context, id string, now time.Time) error {
+	return r.Storage.Restore(ctx, id, now)
+}
+
+func (r UserRepository) Purge(ctx context.Context, before time.Time) ([]string, error) {
+	return r.Storage.Purge(ctx, before)
+}
+
+func (r UserRepository) Import(ctx context.Context, us []user.User, ms []org.Membership) error {
+	return r.Storage.Import(ctx, us, ms)
+}
+
+// RefreshTokenStorage is an interface to be implemented by refresh token storages.
+type RefreshTokenStorage interface {
+	CreateRefreshToken(context.Context, user.RefreshToken) error
+	QueryRefreshTokenByHash(context.Context, string) (user.RefreshToken, error)
+	UseRefreshToken(context.Context, string) error
+	RevokeRefreshTokenFamily(context.Context, string) error
+	RevokeUserRefreshTokens(context.Context, string) error
+}
+
+// RefreshTokenRepository implements the usecases' refresh token repository.
+type RefreshTokenRepository struct {
+	Storage RefreshTokenStorage
+}
+
+func (r RefreshTokenRepository) CreateRefreshToken(ctx context.Context, rt user.RefreshToken) error {
+	return r.Storage.CreateRefreshToken(ctx, rt)
+}
+
+func (r RefreshTokenRepository) QueryRefreshTokenByHash(ctx context.Context, hash string) (user.RefreshToken, error) {
+	return r.Storage.QueryRefreshTokenByHash(ctx, hash)
+}
+
+func (r RefreshTokenRepository) UseRefreshToken(ctx context.Context, id string) error {
+	return r.Storage.UseRefreshToken(ctx, id)
+}
+
+func (r RefreshTokenRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	return r.Storage.RevokeRefreshTokenFamily(ctx, familyID)
+}
+
+func (r RefreshTokenRepository) RevokeUserRefreshTokens(ctx context.Context, userID string) error {
+	return r.Storage.RevokeUserRefreshTokens(ctx, userID)
+}
+
+// OneTimeTokenStorage is an interface to be implemented by one-time token storages.
+type OneTimeTokenStorage interface {
+	CreateOneTimeToken(context.Context, user.OneTimeToken) error
+	QueryOneTimeTokenByHash(context.Context, string) (user.OneTimeToken, error)
+	UseOneTimeToken(context.Context, string) error
+	AttemptOneTimeToken(context.Context, string) (int, error)
+}
+
+// OneTimeTokenRepository implements the usecases' one-time token repository.
+type OneTimeTokenRepository struct {
+	Storage OneTimeTokenStorage
+}
+
+func (r OneTimeTokenRepository) CreateOneTimeToken(ctx context.Context, ot user.OneTimeToken) error {
+	return r.Storage.CreateOneTimeToken(ctx, ot)
+}
+
+func (r OneTimeTokenRepository) QueryOneTimeTokenByHash(ctx context.Context, hash string) (user.OneTimeToken, error) {
+	return r.Storage.QueryOneTimeTokenByHash(ctx, hash)
+}
+
+func (r OneTimeTokenRepository) UseOneTimeToken(ctx context.Context, id string) error {
+	return r.Storage.UseOneTimeToken(ctx, id)
+}
+
+func (r OneTimeTokenRepository) AttemptOneTimeToken(ctx context.Context, id string) (int, error) {
+	return r.Storage.AttemptOneTimeToken(ctx, id)
+}
+
+// IdentityStorage is an interface to be implemented by identity storages.
+type IdentityStorage interface {
+	CreateIdentity(context.Context, user.Identity) error
+	QueryIdentity(context.Context, string, string) (user.Identity, error)
+}
+
+// IdentityRepository implements the usecases' identity repository.
+type IdentityRepository struct {
+	Storage IdentityStorage
+}
+
+func (r IdentityRepository) CreateIdentity(ctx context.Context, id user.Identity) error {
+	return r.Storage.CreateIdentity(ctx, id)
+}
+
+func (r IdentityRepository) QueryIdentity(ctx context.Context, provider, subject string) (user.Identity, error) {
+	return r.Storage.QueryIdentity(ctx, provider, subject)
+}
+
+// APIKeyStorage is an interface to be implemented by API key storages.
+type APIKeyStorage interface {
+	CreateAPIKey(context.Context, user.APIKey) error
+	QueryAPIKeyByHash(context.Context, string) (user.APIKey, error)
+	QueryAPIKeysByUserID(context.Context, string) ([]user.APIKey, error)
+	RevokeAPIKey(context.Context, string, string) error
+	UseAPIKey(context.Context, string, time.Time) error
+}
+
+// APIKeyRepository implements the usecases' API key repository.
+type APIKeyRepository struct {
+	Storage APIKeyStorage
+}
+
+func (r APIKeyRepository) CreateAPIKey(ctx context.Context, ak user.APIKey) error {
+	return r.Storage.CreateAPIKey(ctx, ak)
+}
+
+func (r APIKeyRepository) QueryAPIKeyByHash(ctx context.Context, hash string) (user.APIKey, error) {
+	return r.Storage.QueryAPIKeyByHash(ctx, hash)
+}
+
+func (r APIKeyRepository) QueryAPIKeysByUserID(ctx context.Context, userID string) ([]user.APIKey, error) {
+	return r.Storage.QueryAPIKeysByUserID(ctx, userID)
+}
+
+func (r APIKeyRepository) RevokeAPIKey(ctx context.Context, userID, id string) error {
+	return r.Storage.RevokeAPIKey(ctx, userID, id)
+}
+
+func (r APIKeyRepository) UseAPIKey(ctx context.Context, id string, now time.Time) error {
+	return r.Storage.UseAPIKey(ctx, id, now)
+}
+
+// LoginSessionStorage is an interface to be implemented by login session
+// storages.
+type LoginSessionStorage interface {
+	CreateLoginSession(context.Context, user.LoginSession) error
+	QueryLoginSessionByID(context.Context, string) (user.LoginSession, error)
+	QueryLoginSessionsByUserID(context.Context, string) ([]user.LoginSession, error)
+	SeeLoginSession(context.Context, string, string, time.Time, time.Time) error
+	RevokeLoginSession(context.Context, string, string) error
+	RevokeUserLoginSessions(context.Context, string) error
+}
+
+// LoginSessionRepository implements the usecases' login session repository.
+type LoginSessionRepository struct {
+	Storage LoginSessionStorage
+}
+
+func (r LoginSessionRepository) CreateLoginSession(ctx context.Context, ls user.LoginSession) error {
+	return r.Storage.CreateLoginSession(ctx, ls)
+}
+
+func (r LoginSessionRepository) QueryLoginSessionByID(ctx context.Context, id string) (user.LoginSession, error) {
+	return r.Storage.QueryLoginSessionByID(ctx, id)
+}
+
+func (r LoginSessionRepository) QueryLoginSessionsByUserID(ctx context.Context, userID string) ([]user.LoginSession, error) {
+	return r.Storage.QueryLoginSessionsByUserID(ctx, userID)
+}
+
+func (r LoginSessionRepository) SeeLoginSession(ctx context.Context, id, ip string, now, expires time.Time) error {
+	return r.Storage.SeeLoginSession(ctx, id, ip, now, expires)
+}
+
+func (r LoginSessionRepository) RevokeLoginSession(ctx context.Context, userID, id string) error {
+	return r.Storage.RevokeLoginSession(ctx, userID, id)
+}
+
+func (r LoginSessionRepository) RevokeUserLoginSessions(ctx context.Context, userID string) error {
+	return r.Storage.RevokeUserLoginSessions(ctx, userID)
+}
+
+// OrgStorage is an interface to be implemented by organization storages.
+type OrgStorage interface {
+	CreateOrg(context.Context, org.Org, org.Membership) error
+	QueryOrgsByUserID(context.Context, string) ([]org.Org, error)
+	CreateMembership(context.Context, org.Membership) error
+	QueryMembership(ctx context.Context, orgID, userID string) (org.Membership, error)
+	QueryMembershipsByUserID(context.Context, string) ([]org.Membership, error)
+	QueryMembershipsByOrgID(context.Context, string) ([]org.Membership, error)
+	DeleteMembership(ctx context.Context, orgID, userID string) error
+	CreateInvitation(context.Context, org.Invitation) error
+	QueryInvitationByID(context.Context, string) (org.Invitation, error)
+	QueryInvitationsByUserID(context.Context, string) ([]org.Invitation, error)
+	AcceptInvitation(ctx context.Context, id string, m org.Membership) error
+	DeleteInvitation(context.Context, string) error
+}
+
+// OrgRepository implements the usecases' organization repository.
+type OrgRepository struct {
+	Storage OrgStorage
+}
+
+func (r OrgRepository) CreateOrg(ctx context.Context, o org.Org, m org.Membership) error {
+	return r.Storage.CreateOrg(ctx, o, m)
+}
+
+func (r OrgRepository) QueryOrgsByUserID(ctx context.Context, userID string) ([]org.Org, error) {
+	return r.Storage.QueryOrgsByUserID(ctx, userID)
+}
+
+func (r OrgRepository) CreateMembership(ctx context.Context, m org.Membership) error {
+	return r.Storage.CreateMembership(ctx, m)
+}
+
+func (r OrgRepository) QueryMembership(ctx context.Context, orgID, userID string) (org.Membership, error) {
+	return r.Storage.QueryMembership(ctx, orgID, userID)
+}
+
+func (r OrgRepository) QueryMembershipsByUserID(ctx context.Context, userID string) ([]org.Membership, error) {
+	return r.Storage.QueryMembershipsByUserID(ctx, userID)
+}
+
+func (r OrgRepository) QueryMembershipsByOrgID(ctx context.Context, orgID string) ([]org.Membership, error) {
+	return r.Storage.QueryMembershipsByOrgID(ctx, orgID)
+}
+
+func (r OrgRepository) DeleteMembership(ctx context.Context, orgID, userID string) error {
+	return r.Storage.DeleteMembership(ctx, orgID, userID)
+}
+
+func (r OrgRepository) CreateInvitation(ctx context.Context, i org.Invitation) error {
+	return r.Storage.CreateInvitation(ctx, i)
+}
+
+func (r OrgRepository) QueryInvitationByID(ctx context.Context, id string) (org.Invitation, error) {
+	return r.Storage.QueryInvitationByID(ctx, id)
+}
+
+func (r OrgRepository) QueryInvitationsByUserID(ctx context.Context, userID string) ([]org.Invitation, error) {
+	return r.Storage.QueryInvitationsByUserID(ctx, userID)
+}
+
+func (r OrgRepository) AcceptInvitation(ctx context.Context, id string, m org.Membership) error {
+	return r.Storage.AcceptInvitation(ctx, id, m)
+}
+
+func (r OrgRepository) DeleteInvitation(ctx context.Context, id string) error {
+	return r.Storage.DeleteInvitation(ctx, id)
 }
@@ -0,0 +1,101 @@
+// Package breached provides support for checking passwords against a local
+// list of breached passwords. The list holds the uppercase hexadecimal SHA-1
+// hashes of the passwords, one per line, optionally followed by a colon and
+// the number of times the password was seen, like the lists of Have I Been
+// Pwned. Hashes are grouped by the first five characters, so the list can be
+// queried by range without revealing the full hash, like with k-anonymity.
+package breached
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// PrefixLength is the number of characters of a hash that selects a range.
+const PrefixLength = 5
+
+// List is a list of breached password hashes.
+type List struct {
+	ranges map[string]map[string]struct{}
+	size   int
+}
+
+// Load reads a list of breached password hashes.
+func Load(r io.Reader) (*List, error) {
+	l := List{ranges: make(map[string]map[string]struct{})}
+
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			line = line[:i]
+		}
+
+		hash := strings.ToUpper(line)
+		if len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("line %d: hash must be %d characters", n, sha1.Size*2)
+		}
+		if _, err := hex.DecodeString(hash); err != nil {
+			return nil, fmt.Errorf("line %d: hash must be hexadecimal", n)
+		}
+
+		prefix, suffix := hash[:PrefixLength], hash[PrefixLength:]
+		suffixes, ok := l.ranges[prefix]
+		if !ok {
+			suffixes = make(map[string]struct{})
+			l.ranges[prefix] = suffixes
+		}
+		if _, ok := suffixes[suffix]; !ok {
+			suffixes[suffix] = struct{}{}
+			l.size++
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("reading list: %w", err)
+	}
+
+	return &l, nil
+}
+
+// LoadFile reads a list of breached password hashes from the file.
+func LoadFile(path string) (*List, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Load(f)
+}
+
+// Len returns the number of hashes in the list.
+func (l *List) Len() int {
+	return l.size
+}
+
+// Range returns the suffixes of the hashes that start with the prefix.
+func (l *List) Range(prefix string) []string {
+	suffixes := l.ranges[strings.ToUpper(prefix)]
+	r := make([]string, 0, len(suffixes))
+	for s := range suffixes {
+		r = append(r, s)
+	}
+	return r
+}
+
+// Contains returns true if the password is in the list.
+func (l *List) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	_, ok := l.ranges[hash[:PrefixLength]][hash[PrefixLength:]]
+	return ok
+}
@@ -0,0 +1,64 @@
+package breached_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/appinesshq/caservice/foundation/breached"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// list holds the hashes of "password" and "123456", with and without counts.
+const list = `# breached passwords
+5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493
+7c4a8d09ca3762af61e59520943dc26494f8941b
+`
+
+func Test_List(t *testing.T) {
+	t.Log("Given the need to reject breached passwords.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen loading a list of hashes.", testID)
+		{
+			l, err := breached.Load(strings.NewReader(list))
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to load the list: %v", failed, testID, err)
+			}
+			if l.Len() != 2 {
+				t.Fatalf("\t%s\tTest %d:\tShould load 2 hashes, but got %d.", failed, testID, l.Len())
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to load the list.", success, testID)
+
+			if !l.Contains("password") || !l.Contains("123456") {
+				t.Fatalf("\t%s\tTest %d:\tShould contain the listed passwords.", failed, testID)
+			}
+			if l.Contains("gophers") {
+				t.Fatalf("\t%s\tTest %d:\tShould not contain other passwords.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould only contain the listed passwords.", success, testID)
+
+			r := l.Range("5baa6")
+			if len(r) != 1 || r[0] != "1E4C9B93F3F0682250B6CF8331B7EE68FD8" {
+				t.Fatalf("\t%s\tTest %d:\tShould return the suffixes of the range, but got %v.", failed, testID, r)
+			}
+			t.Logf("\t%s\tTest %d:\tShould return the suffixes of the range.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen loading a malformed list.", testID)
+		{
+			if _, err := breached.Load(strings.NewReader("5BAA61E4C9B93F3F\n")); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould not accept a short hash.", failed, testID)
+			}
+			if _, err := breached.Load(strings.NewReader(strings.Repeat("Z", 40) + "\n")); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould not accept a non-hexadecimal hash.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not accept malformed hashes.", success, testID)
+		}
+	}
+}
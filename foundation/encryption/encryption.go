@@ -0,0 +1,71 @@
+// Package encryption provides support for encrypting small secrets at rest,
+// like the secrets of second factors, with AES-GCM.
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+// ErrDecrypt is returned for ciphertexts that were not encrypted with the
+// key of the Box or were modified.
+var ErrDecrypt = errors.New("unable to decrypt")
+
+// Box encrypts and decrypts data with a single key.
+type Box struct {
+	aead cipher.AEAD
+}
+
+// New returns a Box for the provided key, which must be 16, 24 or 32 bytes
+// long to select AES-128, AES-192 or AES-256.
+func New(key []byte) (*Box, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("creating cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("creating gcm: %w", err)
+	}
+
+	return &Box{aead: aead}, nil
+}
+
+// NewFromPassphrase returns a Box with an AES-256 key derived from the
+// passphrase with SHA-256. The passphrase should be long and random, like
+// a generated key from a secret store.
+func NewFromPassphrase(passphrase string) (*Box, error) {
+	key := sha256.Sum256([]byte(passphrase))
+	return New(key[:])
+}
+
+// Encrypt returns the ciphertext of the plaintext. The random nonce is
+// prepended to the ciphertext.
+func (b *Box) Encrypt(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generating nonce: %w", err)
+	}
+
+	return b.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt returns the plaintext of a ciphertext returned by Encrypt.
+func (b *Box) Decrypt(ciphertext []byte) ([]byte, error) {
+	n := b.aead.NonceSize()
+	if len(ciphertext) < n {
+		return nil, ErrDecrypt
+	}
+
+	plaintext, err := b.aead.Open(nil, ciphertext[:n], ciphertext[n:], nil)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	return plaintext, nil
+}
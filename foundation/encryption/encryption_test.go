@@ -0,0 +1,58 @@
+package encryption_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/appinesshq/caservice/foundation/encryption"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+func Test_Box(t *testing.T) {
+	t.Log("Given the need to encrypt secrets at rest.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen encrypting a single secret.", testID)
+		{
+			box, err := encryption.NewFromPassphrase("correct horse battery staple")
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create a box: %v", failed, testID, err)
+			}
+
+			secret := []byte("gophers")
+			ct, err := box.Encrypt(secret)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to encrypt: %v", failed, testID, err)
+			}
+			if bytes.Contains(ct, secret) {
+				t.Fatalf("\t%s\tTest %d:\tShould not hold the plaintext.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to encrypt.", success, testID)
+
+			pt, err := box.Decrypt(ct)
+			if err != nil || !bytes.Equal(pt, secret) {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to decrypt: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to decrypt.", success, testID)
+
+			ct[len(ct)-1] ^= 1
+			if _, err := box.Decrypt(ct); !errors.Is(err, encryption.ErrDecrypt) {
+				t.Fatalf("\t%s\tTest %d:\tShould not decrypt a modified ciphertext: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not decrypt a modified ciphertext.", success, testID)
+
+			other, _ := encryption.NewFromPassphrase("another passphrase")
+			ct, _ = box.Encrypt(secret)
+			if _, err := other.Decrypt(ct); !errors.Is(err, encryption.ErrDecrypt) {
+				t.Fatalf("\t%s\tTest %d:\tShould not decrypt with another key: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not decrypt with another key.", success, testID)
+		}
+	}
+}
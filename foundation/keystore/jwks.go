@@ -0,0 +1,170 @@
+package keystore
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// JWK represents a public key in the JSON Web Key format (RFC 7517). RSA
+// keys use N and E, EC and OKP (Ed25519) keys use Curve, X and Y.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+// JWKS represents a JSON Web Key Set, as published at /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewJWK constructs a JWK for verifying signatures with the public key. RSA
+// keys are used with RS256, ECDSA P-256 keys with ES256 and Ed25519 keys
+// with EdDSA.
+func NewJWK(kid string, publicKey crypto.PublicKey) (JWK, error) {
+	enc := base64.RawURLEncoding
+
+	switch pk := publicKey.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			KeyType:   "RSA",
+			Use:       "sig",
+			Algorithm: "RS256",
+			KeyID:     kid,
+			N:         enc.EncodeToString(pk.N.Bytes()),
+			E:         enc.EncodeToString(big.NewInt(int64(pk.E)).Bytes()),
+		}, nil
+
+	case *ecdsa.PublicKey:
+		if pk.Curve != elliptic.P256() {
+			return JWK{}, fmt.Errorf("unsupported curve %s", pk.Curve.Params().Name)
+		}
+
+		// Coordinates are padded to the size of the curve.
+		x, y := make([]byte, 32), make([]byte, 32)
+		pk.X.FillBytes(x)
+		pk.Y.FillBytes(y)
+
+		return JWK{
+			KeyType:   "EC",
+			Use:       "sig",
+			Algorithm: "ES256",
+			KeyID:     kid,
+			Curve:     "P-256",
+			X:         enc.EncodeToString(x),
+			Y:         enc.EncodeToString(y),
+		}, nil
+
+	case ed25519.PublicKey:
+		return JWK{
+			KeyType:   "OKP",
+			Use:       "sig",
+			Algorithm: "EdDSA",
+			KeyID:     kid,
+			Curve:     "Ed25519",
+			X:         enc.EncodeToString(pk),
+		}, nil
+	}
+
+	return JWK{}, fmt.Errorf("unsupported key type %T", publicKey)
+}
+
+// PublicKey decodes the public key of the JWK.
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	enc := base64.RawURLEncoding
+
+	switch k.KeyType {
+	case "RSA":
+		n, err := enc.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("decoding modulus: %w", err)
+		}
+
+		e, err := enc.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("decoding exponent: %w", err)
+		}
+
+		exponent := new(big.Int).SetBytes(e)
+		if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() < 2 || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid public key")
+		}
+
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+
+	case "EC":
+		if k.Curve != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+
+		x, err := enc.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("decoding x coordinate: %w", err)
+		}
+
+		y, err := enc.DecodeString(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("decoding y coordinate: %w", err)
+		}
+
+		pk := ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pk.Curve.IsOnCurve(pk.X, pk.Y) {
+			return nil, errors.New("invalid public key")
+		}
+
+		return &pk, nil
+
+	case "OKP":
+		if k.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+
+		x, err := enc.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("decoding public key: %w", err)
+		}
+
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid public key")
+		}
+
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+}
+
+// JWKS returns the public keys of all keys in the store as a JSON Web Key
+// Set, ordered by kid. Keys of an unsupported type are left out.
+func (ks *KeyStore) JWKS() JWKS {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	jwks := JWKS{Keys: make([]JWK, 0, len(ks.store))}
+	for kid, privateKey := range ks.store {
+		jwk, err := NewJWK(kid, privateKey.Public())
+		if err != nil {
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	sort.Slice(jwks.Keys, func(i, j int) bool {
+		return jwks.Keys[i].KeyID < jwks.Keys[j].KeyID
+	})
+
+	return jwks
+}
@@ -1,9 +1,27 @@
 // Package keystore implements the auth.KeyLookup interface. This implements
-// an in-memory keystore for JWT support.
+// an in-memory keystore for JWT support and a keystore for the public keys
+// of a remote JSON Web Key Set.
+//
+// Keys in the in-memory keystore have a state. The active key is used to sign
+// new tokens, verify keys are still valid to verify tokens and retired keys
+// are not valid anymore. A keystore constructed with NewFS can be reloaded,
+// which allows rotating keys without a restart:
+//
+//  1. Add the PEM file of the new key. It is loaded as a verify key, so it
+//     is published to other services before it is used.
+//  2. Write the kid of the new key to the file named active. The new key
+//     becomes the active key, the previous active key a verify key.
+//  3. Remove the PEM file of the previous key after all tokens signed by it
+//     have expired. The key is retired.
 package keystore
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"io"
@@ -11,37 +29,114 @@ import (
 	"path"
 	"strings"
 	"sync"
+)
 
-	"github.com/golang-jwt/jwt/v4"
+// These are the states a key in the KeyStore can have.
+const (
+	StateActive  = "active"
+	StateVerify  = "verify"
+	StateRetired = "retired"
 )
 
+// activeFile is the name of the file that holds the kid of the active key.
+const activeFile = "active"
+
 // KeyStore represents an in memory store implementation of the
 // KeyLookup interface for use with the auth package.
 type KeyStore struct {
-	mu    sync.RWMutex
-	store map[string]*rsa.PrivateKey
+	mu     sync.RWMutex
+	fsys   fs.FS
+	store  map[string]crypto.Signer
+	states map[string]string
 }
 
 // New constructs an empty KeyStore ready for use.
 func New() *KeyStore {
 	return &KeyStore{
-		store: make(map[string]*rsa.PrivateKey),
+		store:  make(map[string]crypto.Signer),
+		states: make(map[string]string),
 	}
 }
 
-// NewMap constructs a KeyStore with an initial set of keys.
-func NewMap(store map[string]*rsa.PrivateKey) *KeyStore {
-	return &KeyStore{
-		store: store,
+// NewMap constructs a KeyStore with an initial set of keys. All keys are
+// verify keys.
+func NewMap(store map[string]crypto.Signer) *KeyStore {
+	ks := New()
+	for kid, privateKey := range store {
+		ks.store[kid] = privateKey
+		ks.states[kid] = StateVerify
 	}
+	return ks
 }
 
 // NewFS constructs a KeyStore based on a set of PEM files rooted inside
 // of a directory. The name of each PEM file will be used as the key id.
+// The PEM files can hold RSA, ECDSA or Ed25519 private keys.
+// The optional file named active holds the kid of the active key, all
+// other keys are verify keys.
 // Example: keystore.NewFS(os.DirFS("/zarf/keys/"))
 // Example: /zarf/keys/54bb2165-71e1-41a6-af3e-7da4a0e1e2c1.pem
 func NewFS(fsys fs.FS) (*KeyStore, error) {
 	ks := New()
+	ks.fsys = fsys
+
+	if err := ks.Reload(); err != nil {
+		return nil, err
+	}
+
+	return ks, nil
+}
+
+// Reload scans the directory of a KeyStore constructed with NewFS again.
+// New keys are added as verify keys and keys that are no longer present are
+// retired. If the file named active holds a kid, that key becomes the active
+// key. The KeyStore is left unchanged if the directory can't be read, or if
+// the active key would be retired.
+func (ks *KeyStore) Reload() error {
+	if ks.fsys == nil {
+		return errors.New("key store has no directory to reload")
+	}
+
+	store, err := readPEMs(ks.fsys)
+	if err != nil {
+		return err
+	}
+
+	activeKID, err := readActive(ks.fsys)
+	if err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if activeKID == "" {
+		activeKID = ks.activeKID()
+	}
+	if _, exists := store[activeKID]; activeKID != "" && !exists {
+		return fmt.Errorf("active kid[%s] does not exist in directory", activeKID)
+	}
+
+	for kid := range ks.store {
+		if _, exists := store[kid]; !exists {
+			ks.states[kid] = StateRetired
+			delete(ks.store, kid)
+		}
+	}
+	for kid, privateKey := range store {
+		ks.store[kid] = privateKey
+		ks.states[kid] = StateVerify
+	}
+	if activeKID != "" {
+		ks.states[activeKID] = StateActive
+	}
+
+	return nil
+}
+
+// readPEMs reads all PEM files in the directory.
+func readPEMs(fsys fs.FS) (map[string]crypto.Signer, error) {
+	store := make(map[string]crypto.Signer)
 
 	fn := func(fileName string, dirEntry fs.DirEntry, err error) error {
 		if err != nil {
@@ -70,12 +165,12 @@ func NewFS(fsys fs.FS) (*KeyStore, error) {
 			return fmt.Errorf("reading auth private key: %w", err)
 		}
 
-		privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(privatePEM)
+		privateKey, err := ParsePrivateKeyPEM(privatePEM)
 		if err != nil {
 			return fmt.Errorf("parsing auth private key: %w", err)
 		}
 
-		ks.store[strings.TrimSuffix(dirEntry.Name(), ".pem")] = privateKey
+		store[strings.TrimSuffix(dirEntry.Name(), ".pem")] = privateKey
 		return nil
 	}
 
@@ -83,15 +178,68 @@ func NewFS(fsys fs.FS) (*KeyStore, error) {
 		return nil, fmt.Errorf("walking directory: %w", err)
 	}
 
-	return ks, nil
+	return store, nil
+}
+
+// ParsePrivateKeyPEM parses an RSA, ECDSA or Ed25519 private key in PEM form.
+// PKCS #1 RSA keys, SEC 1 EC keys and PKCS #8 keys are supported.
+func ParsePrivateKeyPEM(privatePEM []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(privatePEM)
+	if block == nil {
+		return nil, errors.New("key must be PEM encoded")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		switch key := key.(type) {
+		case *rsa.PrivateKey:
+			return key, nil
+		case *ecdsa.PrivateKey:
+			return key, nil
+		case ed25519.PrivateKey:
+			return key, nil
+		}
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+
+	return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+}
+
+// readActive reads the kid of the active key from the directory. It returns
+// an empty kid if there is no file named active.
+func readActive(fsys fs.FS) (string, error) {
+	file, err := fsys.Open(activeFile)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", nil
+		}
+		return "", fmt.Errorf("opening active file: %w", err)
+	}
+	defer file.Close()
+
+	kid, err := io.ReadAll(io.LimitReader(file, 1024))
+	if err != nil {
+		return "", fmt.Errorf("reading active file: %w", err)
+	}
+
+	return strings.TrimSpace(string(kid)), nil
 }
 
-// Add adds a private key and combination kid to the store.
-func (ks *KeyStore) Add(privateKey *rsa.PrivateKey, kid string) {
+// Add adds a private key and combination kid to the store as a verify key.
+func (ks *KeyStore) Add(privateKey crypto.Signer, kid string) {
 	ks.mu.Lock()
 	defer ks.mu.Unlock()
 
 	ks.store[kid] = privateKey
+	ks.states[kid] = StateVerify
 }
 
 // Remove removes a private key and combination kid to the store.
@@ -100,11 +248,75 @@ func (ks *KeyStore) Remove(kid string) {
 	defer ks.mu.Unlock()
 
 	delete(ks.store, kid)
+	delete(ks.states, kid)
+}
+
+// Retire retires the key for the kid. The key can't be used for signing or
+// verifying tokens anymore. The active key can't be retired.
+func (ks *KeyStore) Retire(kid string) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	switch ks.states[kid] {
+	case "":
+		return errors.New("kid lookup failed")
+	case StateActive:
+		return errors.New("active key can't be retired")
+	}
+
+	ks.states[kid] = StateRetired
+	delete(ks.store, kid)
+	return nil
+}
+
+// SetActive makes the key for the kid the active key. The previous active
+// key becomes a verify key.
+func (ks *KeyStore) SetActive(kid string) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if _, found := ks.store[kid]; !found {
+		return errors.New("kid lookup failed")
+	}
+
+	if active := ks.activeKID(); active != "" {
+		ks.states[active] = StateVerify
+	}
+	ks.states[kid] = StateActive
+	return nil
+}
+
+// ActiveKID returns the kid of the active key, or an empty string if there
+// is no active key.
+func (ks *KeyStore) ActiveKID() string {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	return ks.activeKID()
+}
+
+// activeKID returns the kid of the active key. The caller must hold the lock.
+func (ks *KeyStore) activeKID() string {
+	for kid, state := range ks.states {
+		if state == StateActive {
+			return kid
+		}
+	}
+	return ""
+}
+
+// State returns the state of the key for the kid, or an empty string if the
+// kid is unknown.
+func (ks *KeyStore) State(kid string) string {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	return ks.states[kid]
 }
 
 // PrivateKey searches the key store for a given kid and returns
-// the private key.
-func (ks *KeyStore) PrivateKey(kid string) (*rsa.PrivateKey, error) {
+// the private key. Retired keys are not returned.
+func (ks *KeyStore) PrivateKey(kid string) (crypto.Signer, error) {
 	ks.mu.RLock()
 	defer ks.mu.RUnlock()
 
@@ -116,8 +328,8 @@ func (ks *KeyStore) PrivateKey(kid string) (*rsa.PrivateKey, error) {
 }
 
 // PublicKey searches the key store for a given kid and returns
-// the public key.
-func (ks *KeyStore) PublicKey(kid string) (*rsa.PublicKey, error) {
+// the public key. Retired keys are not returned.
+func (ks *KeyStore) PublicKey(kid string) (crypto.PublicKey, error) {
 	ks.mu.RLock()
 	defer ks.mu.RUnlock()
 
@@ -125,5 +337,5 @@ func (ks *KeyStore) PublicKey(kid string) (*rsa.PublicKey, error) {
 	if !found {
 		return nil, errors.New("kid lookup failed")
 	}
-	return &privateKey.PublicKey, nil
+	return privateKey.Public(), nil
 }
@@ -1,8 +1,21 @@
 package keystore_test
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
 	"embed" // Calls init function.
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/appinesshq/caservice/foundation/keystore"
 )
@@ -35,10 +48,189 @@ func Test_Read(t *testing.T) {
 			}
 			t.Logf("\t%s\tTest %d:\tShould be able to find key in store.", success, testID)
 
-			if err := pk.Validate(); err != nil {
+			rsaKey, ok := pk.(*rsa.PrivateKey)
+			if !ok {
+				t.Fatalf("\t%s\tTest %d:\tShould be an RSA key: %T", failed, testID, pk)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be an RSA key.", success, testID)
+
+			if err := rsaKey.Validate(); err != nil {
 				t.Fatalf("\t%s\tTest %d:\tShould be able to validate the key: %v", failed, testID, err)
 			}
 			t.Logf("\t%s\tTest %d:\tShould be able to validate the key.", success, testID)
 		}
 	}
 }
+
+func Test_Remote(t *testing.T) {
+	t.Log("Given the need to look up public keys from a remote key set.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen handling a key set published by a key store.", testID)
+		{
+			ks, err := keystore.NewFS(keyDocs)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to construct key store: %v", failed, testID, err)
+			}
+
+			var requests int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				json.NewEncoder(w).Encode(ks.JWKS())
+			}))
+			defer srv.Close()
+
+			remote := keystore.NewRemote(srv.URL, srv.Client(), time.Hour)
+
+			const keyID = "test"
+			publicKey, err := remote.PublicKey(keyID)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to find key in remote key set: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to find key in remote key set.", success, testID)
+
+			want, _ := ks.PublicKey(keyID)
+			if !want.(*rsa.PublicKey).Equal(publicKey) {
+				t.Fatalf("\t%s\tTest %d:\tShould get the public key of the key store.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould get the public key of the key store.", success, testID)
+
+			if _, err := remote.PublicKey(keyID); err != nil || requests != 1 {
+				t.Fatalf("\t%s\tTest %d:\tShould use the cached key set, requests %d: %v", failed, testID, requests, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould use the cached key set.", success, testID)
+
+			if _, err := remote.PublicKey("unknown"); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould not find an unknown key.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not find an unknown key.", success, testID)
+
+			if _, err := remote.PrivateKey(keyID); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould not provide private keys.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not provide private keys.", success, testID)
+		}
+	}
+}
+
+func Test_Reload(t *testing.T) {
+	t.Log("Given the need to rotate keys without a restart.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen reloading a directory of keyfile(s).", testID)
+		{
+			newPEM := func() *fstest.MapFile {
+				privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to create a private key: %v", failed, testID, err)
+				}
+				block := pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)}
+				return &fstest.MapFile{Data: pem.EncodeToMemory(&block)}
+			}
+
+			fsys := fstest.MapFS{
+				"old.pem": newPEM(),
+				"active":  &fstest.MapFile{Data: []byte("old\n")},
+			}
+
+			ks, err := keystore.NewFS(fsys)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to construct key store: %v", failed, testID, err)
+			}
+			if kid := ks.ActiveKID(); kid != "old" {
+				t.Fatalf("\t%s\tTest %d:\tShould have the active key from the active file, got %q.", failed, testID, kid)
+			}
+			t.Logf("\t%s\tTest %d:\tShould have the active key from the active file.", success, testID)
+
+			fsys["new.pem"] = newPEM()
+			if err := ks.Reload(); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to reload the key store: %v", failed, testID, err)
+			}
+			if state := ks.State("new"); state != keystore.StateVerify {
+				t.Fatalf("\t%s\tTest %d:\tShould add new keys as verify keys, got %q.", failed, testID, state)
+			}
+			t.Logf("\t%s\tTest %d:\tShould add new keys as verify keys.", success, testID)
+
+			delete(fsys, "old.pem")
+			if err := ks.Reload(); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould not retire the active key.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not retire the active key.", success, testID)
+
+			fsys["active"] = &fstest.MapFile{Data: []byte("new")}
+			if err := ks.Reload(); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to reload the key store: %v", failed, testID, err)
+			}
+			if kid := ks.ActiveKID(); kid != "new" {
+				t.Fatalf("\t%s\tTest %d:\tShould switch the active key, got %q.", failed, testID, kid)
+			}
+			t.Logf("\t%s\tTest %d:\tShould switch the active key.", success, testID)
+
+			if state := ks.State("old"); state != keystore.StateRetired {
+				t.Fatalf("\t%s\tTest %d:\tShould retire removed keys, got %q.", failed, testID, state)
+			}
+			if _, err := ks.PublicKey("old"); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould not provide retired keys.", failed, testID)
+			}
+			if len(ks.JWKS().Keys) != 1 {
+				t.Fatalf("\t%s\tTest %d:\tShould not publish retired keys.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould retire removed keys.", success, testID)
+		}
+	}
+}
+
+func Test_JWK(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 1024)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	ecDER, _ := x509.MarshalECPrivateKey(ecKey)
+	edDER, _ := x509.MarshalPKCS8PrivateKey(edKey)
+
+	tt := []struct {
+		name  string
+		alg   string
+		block pem.Block
+	}{
+		{"rsa", "RS256", pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}},
+		{"ecdsa", "ES256", pem.Block{Type: "EC PRIVATE KEY", Bytes: ecDER}},
+		{"ed25519", "EdDSA", pem.Block{Type: "PRIVATE KEY", Bytes: edDER}},
+	}
+
+	t.Log("Given the need to publish public keys of different types.")
+	{
+		for testID, tst := range tt {
+			tf := func(t *testing.T) {
+				t.Logf("\tTest %d:\tWhen handling a %s key.", testID, tst.name)
+				{
+					privateKey, err := keystore.ParsePrivateKeyPEM(pem.EncodeToMemory(&tst.block))
+					if err != nil {
+						t.Fatalf("\t%s\tTest %d:\tShould be able to parse the PEM file: %v", failed, testID, err)
+					}
+					t.Logf("\t%s\tTest %d:\tShould be able to parse the PEM file.", success, testID)
+
+					jwk, err := keystore.NewJWK("kid", privateKey.Public())
+					if err != nil {
+						t.Fatalf("\t%s\tTest %d:\tShould be able to construct a JWK: %v", failed, testID, err)
+					}
+					if jwk.Algorithm != tst.alg {
+						t.Fatalf("\t%s\tTest %d:\tShould have algorithm %s, got %s.", failed, testID, tst.alg, jwk.Algorithm)
+					}
+					t.Logf("\t%s\tTest %d:\tShould have algorithm %s.", success, testID, tst.alg)
+
+					publicKey, err := jwk.PublicKey()
+					if err != nil {
+						t.Fatalf("\t%s\tTest %d:\tShould be able to decode the JWK: %v", failed, testID, err)
+					}
+					want := privateKey.Public().(interface{ Equal(crypto.PublicKey) bool })
+					if !want.Equal(publicKey) {
+						t.Fatalf("\t%s\tTest %d:\tShould decode to the same public key.", failed, testID)
+					}
+					t.Logf("\t%s\tTest %d:\tShould decode to the same public key.", success, testID)
+				}
+			}
+			t.Run(tst.name, tf)
+		}
+	}
+}
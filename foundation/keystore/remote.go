@@ -0,0 +1,125 @@
+package keystore
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// minRefreshInterval limits how often an unknown kid causes the remote key
+// set to be fetched again.
+const minRefreshInterval = 10 * time.Second
+
+// Remote represents a KeyLookup for use with the auth package that fetches
+// the public keys from a remote JSON Web Key Set. It has no private keys, so
+// an auth.Auth using it can only verify tokens.
+type Remote struct {
+	url           string
+	client        *http.Client
+	cacheDuration time.Duration
+
+	mu      sync.RWMutex
+	store   map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+// NewRemote constructs a Remote key store for the JWKS at the url. Fetched
+// keys are cached for the cache duration. If the client is nil, a client with
+// a timeout of 10 seconds is used.
+func NewRemote(url string, client *http.Client, cacheDuration time.Duration) *Remote {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &Remote{
+		url:           url,
+		client:        client,
+		cacheDuration: cacheDuration,
+		store:         make(map[string]crypto.PublicKey),
+	}
+}
+
+// PrivateKey always fails, since a remote key set only holds public keys.
+func (r *Remote) PrivateKey(kid string) (crypto.Signer, error) {
+	return nil, errors.New("remote key store has no private keys")
+}
+
+// PublicKey searches the remote key set for a given kid and returns the
+// public key. The key set is fetched again when the cache expired or when
+// the kid is unknown, for example after the remote rotated its keys. If
+// fetching fails, a previously fetched key is still used.
+func (r *Remote) PublicKey(kid string) (crypto.PublicKey, error) {
+	r.mu.RLock()
+	publicKey, found := r.store[kid]
+	age := time.Since(r.fetched)
+	r.mu.RUnlock()
+
+	switch {
+	case found && age < r.cacheDuration:
+		return publicKey, nil
+	case !found && age < minRefreshInterval:
+		return nil, errors.New("kid lookup failed")
+	}
+
+	if err := r.refresh(); err != nil {
+		if found {
+			return publicKey, nil
+		}
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	publicKey, found = r.store[kid]
+	if !found {
+		return nil, errors.New("kid lookup failed")
+	}
+	return publicKey, nil
+}
+
+// refresh fetches the remote key set and replaces the cached keys.
+func (r *Remote) refresh() error {
+	resp, err := r.client.Get(r.url)
+	if err != nil {
+		return fmt.Errorf("fetching key set: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching key set: status %d", resp.StatusCode)
+	}
+
+	// limit the key set to 1 megabyte, like PEM files.
+	var jwks JWKS
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1024*1024)).Decode(&jwks); err != nil {
+		return fmt.Errorf("decoding key set: %w", err)
+	}
+
+	// Keys that are not meant for signatures or that are not supported
+	// are skipped.
+	store := make(map[string]crypto.PublicKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		publicKey, err := jwk.PublicKey()
+		if err != nil {
+			continue
+		}
+		store[jwk.KeyID] = publicKey
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.store = store
+	r.fetched = time.Now()
+
+	return nil
+}
@@ -0,0 +1,56 @@
+// Package mail provides support for sending emails.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Message represents a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer declares the behavior for sending emails.
+type Mailer interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// Bytes returns the message in the Internet Message Format (RFC 5322), sent
+// from the provided address at the provided time.
+func (m Message) Bytes(from string, now time.Time) ([]byte, error) {
+
+	// Line breaks in header values would allow injecting headers.
+	for _, v := range []string{from, m.To, m.Subject} {
+		if strings.ContainsAny(v, "\r\n") {
+			return nil, errors.New("header values must not contain line breaks")
+		}
+	}
+	if m.To == "" {
+		return nil, errors.New("missing recipient")
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", m.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", m.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+
+	// Normalize line endings of the body.
+	body := strings.ReplaceAll(m.Body, "\r\n", "\n")
+	for _, line := range strings.Split(body, "\n") {
+		b.WriteString(line)
+		b.WriteString("\r\n")
+	}
+
+	return b.Bytes(), nil
+}
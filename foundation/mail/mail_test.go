@@ -0,0 +1,152 @@
+package mail_test
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/appinesshq/caservice/foundation/mail"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+func Test_Outbox(t *testing.T) {
+	t.Log("Given the need to write emails to an outbox.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen writing a single message.", testID)
+		{
+			var buf bytes.Buffer
+			outbox := mail.NewOutbox(&buf, "sales@example.com")
+
+			msg := mail.Message{To: "user@example.com", Subject: "Hello", Body: "Hello Gopher"}
+			if err := outbox.Send(context.Background(), msg); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to send the message: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to send the message.", success, testID)
+
+			for _, want := range []string{"From: sales@example.com", "To: user@example.com", "Subject: Hello", "Hello Gopher"} {
+				if !strings.Contains(buf.String(), want) {
+					t.Fatalf("\t%s\tTest %d:\tShould write %q to the outbox: %s", failed, testID, want, buf.String())
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould write the message to the outbox.", success, testID)
+
+			msg.Subject = "Hello\r\nBcc: attacker@example.com"
+			if err := outbox.Send(context.Background(), msg); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould not allow line breaks in headers.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not allow line breaks in headers.", success, testID)
+		}
+	}
+}
+
+func Test_SMTP(t *testing.T) {
+	t.Log("Given the need to send emails through an SMTP server.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen sending a single message.", testID)
+		{
+			addr, received := startSMTP(t)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			mailer := mail.NewSMTP(addr, "sales@example.com", nil)
+			msg := mail.Message{To: "user@example.com", Subject: "Hello", Body: "Hello Gopher\n.\nBye"}
+			if err := mailer.Send(ctx, msg); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to send the message: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to send the message.", success, testID)
+
+			env := <-received
+			if env.from != "sales@example.com" || env.to != "user@example.com" {
+				t.Fatalf("\t%s\tTest %d:\tShould use the sender and recipient, got %q and %q.", failed, testID, env.from, env.to)
+			}
+			t.Logf("\t%s\tTest %d:\tShould use the sender and recipient.", success, testID)
+
+			if !strings.Contains(env.data, "Subject: Hello") || !strings.Contains(env.data, "Hello Gopher\n.\nBye") {
+				t.Fatalf("\t%s\tTest %d:\tShould deliver the message: %s", failed, testID, env.data)
+			}
+			t.Logf("\t%s\tTest %d:\tShould deliver the message.", success, testID)
+		}
+	}
+}
+
+// =============================================================================
+
+// envelope is a message received by the SMTP stand-in.
+type envelope struct {
+	from string
+	to   string
+	data string
+}
+
+// startSMTP starts a minimal SMTP server that accepts a single message.
+func startSMTP(t *testing.T) (string, <-chan envelope) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	received := make(chan envelope, 1)
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		tc := textproto.NewConn(conn)
+		tc.PrintfLine("220 localhost ESMTP")
+
+		var env envelope
+		for {
+			line, err := tc.ReadLine()
+			if err != nil {
+				return
+			}
+
+			cmd := strings.ToUpper(line)
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"):
+				tc.PrintfLine("250-localhost")
+				tc.PrintfLine("250 8BITMIME")
+			case strings.HasPrefix(cmd, "HELO"):
+				tc.PrintfLine("250 localhost")
+			case strings.HasPrefix(cmd, "MAIL FROM:"):
+				env.from = strings.Trim(strings.Fields(line[len("MAIL FROM:"):])[0], "<>")
+				tc.PrintfLine("250 OK")
+			case strings.HasPrefix(cmd, "RCPT TO:"):
+				env.to = strings.Trim(strings.Fields(line[len("RCPT TO:"):])[0], "<>")
+				tc.PrintfLine("250 OK")
+			case cmd == "DATA":
+				tc.PrintfLine("354 end data with <CR><LF>.<CR><LF>")
+				data, err := tc.ReadDotBytes()
+				if err != nil {
+					return
+				}
+				env.data = string(data)
+				tc.PrintfLine("250 OK")
+				received <- env
+			case cmd == "QUIT":
+				tc.PrintfLine("221 bye")
+				return
+			default:
+				tc.PrintfLine("502 not implemented")
+			}
+		}
+	}()
+
+	return l.Addr().String(), received
+}
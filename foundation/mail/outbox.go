@@ -0,0 +1,43 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Outbox is a Mailer that writes messages to a writer, like a file or the
+// log output, instead of sending them. It is meant for development and
+// testing.
+type Outbox struct {
+	mu   sync.Mutex
+	w    io.Writer
+	from string
+}
+
+// NewOutbox constructs an Outbox that writes messages to w.
+func NewOutbox(w io.Writer, from string) *Outbox {
+	return &Outbox{
+		w:    w,
+		from: from,
+	}
+}
+
+// Send writes the message to the outbox.
+func (o *Outbox) Send(ctx context.Context, m Message) error {
+	b, err := m.Bytes(o.from, time.Now())
+	if err != nil {
+		return fmt.Errorf("formatting message: %w", err)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if _, err := fmt.Fprintf(o.w, "%s\r\n", b); err != nil {
+		return fmt.Errorf("writing message: %w", err)
+	}
+
+	return nil
+}
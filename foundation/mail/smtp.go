@@ -0,0 +1,89 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// SMTP is a Mailer that sends messages through an SMTP server.
+type SMTP struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTP constructs an SMTP mailer for the server at addr (host:port). The
+// auth is optional and only used if the server supports authentication.
+func NewSMTP(addr string, from string, auth smtp.Auth) *SMTP {
+	return &SMTP{
+		addr: addr,
+		from: from,
+		auth: auth,
+	}
+}
+
+// Send sends the message through the SMTP server. The connection is upgraded
+// to TLS if the server supports it.
+func (s *SMTP) Send(ctx context.Context, m Message) error {
+	msg, err := m.Bytes(s.from, time.Now())
+	if err != nil {
+		return fmt.Errorf("formatting message: %w", err)
+	}
+
+	host, _, err := net.SplitHostPort(s.addr)
+	if err != nil {
+		return fmt.Errorf("parsing address: %w", err)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("connecting: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("greeting: %w", err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("starting tls: %w", err)
+		}
+	}
+
+	if ok, _ := c.Extension("AUTH"); ok && s.auth != nil {
+		if err := c.Auth(s.auth); err != nil {
+			return fmt.Errorf("authenticating: %w", err)
+		}
+	}
+
+	if err := c.Mail(s.from); err != nil {
+		return fmt.Errorf("sender: %w", err)
+	}
+	if err := c.Rcpt(m.To); err != nil {
+		return fmt.Errorf("recipient: %w", err)
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("data: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("writing message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("sending message: %w", err)
+	}
+
+	return c.Quit()
+}
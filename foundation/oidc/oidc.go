@@ -0,0 +1,232 @@
+// Package oidc provides support for signing in with an OpenID Connect
+// provider, using the authorization code flow with PKCE (RFC 7636).
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/appinesshq/caservice/foundation/keystore"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Errors of the authorization code flow, caused by the code or the tokens
+// the client received rather than by problems reaching the provider.
+var (
+	ErrCodeRejected   = errors.New("authorization code rejected")
+	ErrInvalidIDToken = errors.New("invalid id token")
+)
+
+// algorithms are the signing algorithms accepted for ID tokens.
+var algorithms = []string{"RS256", "ES256", "EdDSA"}
+
+// Config contains the settings of the client at the provider.
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Claims represents the claims of an ID token that identify the user.
+type Claims struct {
+	jwt.RegisteredClaims
+	Nonce         string `json:"nonce"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+}
+
+// discovery is the provider metadata published at
+// /.well-known/openid-configuration.
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider represents an OpenID Connect provider.
+type Provider struct {
+	cfg       Config
+	client    *http.Client
+	discovery discovery
+	keys      *keystore.Remote
+	parser    *jwt.Parser
+}
+
+// NewProvider constructs a Provider from the metadata the issuer publishes.
+// If the client is nil, a client with a timeout of 10 seconds is used.
+func NewProvider(ctx context.Context, cfg Config, client *http.Client) (*Provider, error) {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+
+	u := strings.TrimSuffix(cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating discovery request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching discovery: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching discovery: status %d", resp.StatusCode)
+	}
+
+	var d discovery
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1024*1024)).Decode(&d); err != nil {
+		return nil, fmt.Errorf("decoding discovery: %w", err)
+	}
+
+	// The issuer must be exactly the issuer the metadata was fetched for,
+	// since ID tokens are checked against it.
+	if d.Issuer != cfg.Issuer {
+		return nil, fmt.Errorf("discovery issuer %q does not match %q", d.Issuer, cfg.Issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, errors.New("discovery is missing endpoints")
+	}
+
+	p := Provider{
+		cfg:       cfg,
+		client:    client,
+		discovery: d,
+		keys:      keystore.NewRemote(d.JWKSURI, client, time.Hour),
+		parser:    jwt.NewParser(jwt.WithValidMethods(algorithms)),
+	}
+
+	return &p, nil
+}
+
+// AuthCodeURL returns the URL of the provider to send the user to. The state
+// and nonce must be random and are checked when the user comes back, the
+// verifier is sent later when exchanging the code.
+func (p *Provider) AuthCodeURL(state, nonce, verifier string) string {
+	v := url.Values{}
+	v.Set("response_type", "code")
+	v.Set("client_id", p.cfg.ClientID)
+	v.Set("redirect_uri", p.cfg.RedirectURL)
+	v.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	v.Set("state", state)
+	v.Set("nonce", nonce)
+	v.Set("code_challenge", Challenge(verifier))
+	v.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(p.discovery.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return p.discovery.AuthorizationEndpoint + sep + v.Encode()
+}
+
+// Exchange exchanges the authorization code for tokens and returns the
+// verified claims of the ID token. The nonce and verifier must be the ones
+// passed to AuthCodeURL.
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (Claims, error) {
+	v := url.Values{}
+	v.Set("grant_type", "authorization_code")
+	v.Set("code", code)
+	v.Set("redirect_uri", p.cfg.RedirectURL)
+	v.Set("code_verifier", verifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.discovery.TokenEndpoint, strings.NewReader(v.Encode()))
+	if err != nil {
+		return Claims{}, fmt.Errorf("creating token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return Claims{}, fmt.Errorf("exchanging code: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1024*1024)).Decode(&body); err != nil {
+		return Claims{}, fmt.Errorf("decoding token response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return Claims{}, fmt.Errorf("%w: status %d: %s %s", ErrCodeRejected, resp.StatusCode, body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return Claims{}, fmt.Errorf("%w: no id token", ErrInvalidIDToken)
+	}
+
+	return p.Verify(body.IDToken, nonce)
+}
+
+// Verify verifies the signature, issuer, audience, expiry and nonce of an
+// ID token and returns its claims.
+func (p *Provider) Verify(idToken, nonce string) (Claims, error) {
+	var claims Claims
+	keyFunc := func(t *jwt.Token) (any, error) {
+		kid, ok := t.Header["kid"].(string)
+		if !ok {
+			return nil, errors.New("missing key id (kid) in token header")
+		}
+		return p.keys.PublicKey(kid)
+	}
+
+	if _, err := p.parser.ParseWithClaims(idToken, &claims, keyFunc); err != nil {
+		return Claims{}, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	switch {
+	case claims.Issuer != p.discovery.Issuer:
+		return Claims{}, fmt.Errorf("%w: issuer %q", ErrInvalidIDToken, claims.Issuer)
+	case !claims.VerifyAudience(p.cfg.ClientID, true):
+		return Claims{}, fmt.Errorf("%w: audience %v", ErrInvalidIDToken, claims.Audience)
+	case claims.ExpiresAt == nil:
+		return Claims{}, fmt.Errorf("%w: missing expiry", ErrInvalidIDToken)
+	case claims.Subject == "":
+		return Claims{}, fmt.Errorf("%w: missing subject", ErrInvalidIDToken)
+	case claims.Nonce != nonce:
+		return Claims{}, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	return claims, nil
+}
+
+// =============================================================================
+
+// RandomString returns a random URL safe string, for use as state, nonce or
+// PKCE verifier.
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating random string: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Challenge returns the S256 PKCE code challenge of the verifier.
+func Challenge(verifier string) string {
+	h := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(h[:])
+}
@@ -0,0 +1,130 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/appinesshq/caservice/foundation/oidc"
+	"github.com/appinesshq/caservice/foundation/oidc/oidctest"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+func Test_Flow(t *testing.T) {
+	iss, err := oidctest.NewIssuer("client", "secret")
+	if err != nil {
+		t.Fatalf("starting issuer: %s", err)
+	}
+	defer iss.Close()
+
+	cfg := oidc.Config{
+		Issuer:       iss.URL(),
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost/callback",
+	}
+
+	// start begins a flow and returns the code the issuer redirected back
+	// with for the identity.
+	start := func(p *oidc.Provider, nonce, verifier string) string {
+		redirect, err := iss.Authorize(p.AuthCodeURL("state", nonce, verifier), oidctest.Identity{
+			Subject:       "1234",
+			Email:         "user@example.com",
+			EmailVerified: true,
+			Name:          "User",
+		})
+		if err != nil {
+			t.Fatalf("authorizing: %s", err)
+		}
+		u, err := url.Parse(redirect)
+		if err != nil {
+			t.Fatalf("parsing redirect: %s", err)
+		}
+		if u.Query().Get("state") != "state" {
+			t.Fatalf("state not returned: %s", redirect)
+		}
+		return u.Query().Get("code")
+	}
+
+	t.Log("Given the need to sign in with an OpenID Connect provider.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen exchanging a code.", testID)
+		{
+			ctx := context.Background()
+			p, err := oidc.NewProvider(ctx, cfg, nil)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to discover the provider: %s.", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to discover the provider.", success, testID)
+
+			verifier, err := oidc.RandomString()
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to generate a verifier: %s.", failed, testID, err)
+			}
+
+			code := start(p, "nonce", verifier)
+			claims, err := p.Exchange(ctx, code, verifier, "nonce")
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to exchange the code: %s.", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to exchange the code.", success, testID)
+
+			if claims.Subject != "1234" || claims.Email != "user@example.com" || !claims.EmailVerified {
+				t.Fatalf("\t%s\tTest %d:\tShould get the claims of the identity: %+v.", failed, testID, claims)
+			}
+			t.Logf("\t%s\tTest %d:\tShould get the claims of the identity.", success, testID)
+
+			if _, err := p.Exchange(ctx, code, verifier, "nonce"); !errors.Is(err, oidc.ErrCodeRejected) {
+				t.Fatalf("\t%s\tTest %d:\tShould not be able to exchange the code twice.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not be able to exchange the code twice.", success, testID)
+
+			code = start(p, "nonce", verifier)
+			if _, err := p.Exchange(ctx, code, "other", "nonce"); !errors.Is(err, oidc.ErrCodeRejected) {
+				t.Fatalf("\t%s\tTest %d:\tShould not be able to exchange a code with the wrong verifier.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not be able to exchange a code with the wrong verifier.", success, testID)
+
+			code = start(p, "nonce", verifier)
+			if _, err := p.Exchange(ctx, code, verifier, "other"); !errors.Is(err, oidc.ErrInvalidIDToken) {
+				t.Fatalf("\t%s\tTest %d:\tShould refuse an ID token with the wrong nonce: %v.", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould refuse an ID token with the wrong nonce.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen using another client id.", testID)
+		{
+			ctx := context.Background()
+			other := cfg
+			other.ClientID = "other"
+			p, err := oidc.NewProvider(ctx, other, nil)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to discover the provider: %s.", failed, testID, err)
+			}
+
+			if _, err := iss.Authorize(p.AuthCodeURL("state", "nonce", "verifier"), oidctest.Identity{Subject: "1234"}); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould not be authorized.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not be authorized.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen the issuer does not match.", testID)
+		{
+			other := cfg
+			other.Issuer = iss.URL() + "/"
+			if _, err := oidc.NewProvider(context.Background(), other, nil); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould not be able to discover the provider.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not be able to discover the provider.", success, testID)
+		}
+	}
+}
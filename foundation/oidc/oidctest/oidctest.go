@@ -0,0 +1,242 @@
+// Package oidctest provides an in-process OpenID Connect issuer for testing
+// the oidc package and the services using it.
+package oidctest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/appinesshq/caservice/foundation/keystore"
+	"github.com/appinesshq/caservice/foundation/oidc"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// kid is the key id of the signing key of the issuer.
+const kid = "oidctest"
+
+// Identity represents the user signing in at the issuer.
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// authorization is an issued authorization code waiting to be exchanged.
+type authorization struct {
+	identity    Identity
+	clientID    string
+	redirectURI string
+	nonce       string
+	challenge   string
+}
+
+// Issuer is an OpenID Connect issuer backed by an httptest.Server. Users are
+// not asked to sign in: Authorize issues a code for a given identity.
+type Issuer struct {
+	Server       *httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	key   *ecdsa.PrivateKey
+	mu    sync.Mutex
+	codes map[string]authorization
+}
+
+// NewIssuer starts an issuer accepting the client. Close must be called when
+// the issuer is no longer needed.
+func NewIssuer(clientID, clientSecret string) (*Issuer, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generating key: %w", err)
+	}
+
+	iss := Issuer{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		key:          key,
+		codes:        make(map[string]authorization),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", iss.discovery)
+	mux.HandleFunc("/jwks", iss.jwks)
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "use Issuer.Authorize", http.StatusNotImplemented)
+	})
+	mux.HandleFunc("/token", iss.token)
+	iss.Server = httptest.NewServer(mux)
+
+	return &iss, nil
+}
+
+// URL returns the issuer identifier.
+func (iss *Issuer) URL() string {
+	return iss.Server.URL
+}
+
+// Close shuts down the issuer.
+func (iss *Issuer) Close() {
+	iss.Server.Close()
+}
+
+// Authorize completes the authorization request of the auth code URL for the
+// identity, as if the user signed in and consented. It returns the redirect
+// URL the user agent is sent back to, with the code and state.
+func (iss *Issuer) Authorize(authCodeURL string, id Identity) (string, error) {
+	u, err := url.Parse(authCodeURL)
+	if err != nil {
+		return "", fmt.Errorf("parsing auth code url: %w", err)
+	}
+	q := u.Query()
+
+	switch {
+	case q.Get("response_type") != "code":
+		return "", errors.New("unsupported response type")
+	case q.Get("client_id") != iss.ClientID:
+		return "", errors.New("unknown client")
+	case q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "":
+		return "", errors.New("missing S256 code challenge")
+	}
+
+	code, err := oidc.RandomString()
+	if err != nil {
+		return "", err
+	}
+
+	iss.mu.Lock()
+	iss.codes[code] = authorization{
+		identity:    id,
+		clientID:    q.Get("client_id"),
+		redirectURI: q.Get("redirect_uri"),
+		nonce:       q.Get("nonce"),
+		challenge:   q.Get("code_challenge"),
+	}
+	iss.mu.Unlock()
+
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil {
+		return "", fmt.Errorf("parsing redirect uri: %w", err)
+	}
+	rq := redirect.Query()
+	rq.Set("code", code)
+	rq.Set("state", q.Get("state"))
+	redirect.RawQuery = rq.Encode()
+
+	return redirect.String(), nil
+}
+
+// discovery serves the provider metadata.
+func (iss *Issuer) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                iss.URL(),
+		"authorization_endpoint":                iss.URL() + "/authorize",
+		"token_endpoint":                        iss.URL() + "/token",
+		"jwks_uri":                              iss.URL() + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"ES256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+// jwks serves the public key of the issuer.
+func (iss *Issuer) jwks(w http.ResponseWriter, r *http.Request) {
+	jwk, err := keystore.NewJWK(kid, &iss.key.PublicKey)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, keystore.JWKS{Keys: []keystore.JWK{jwk}})
+}
+
+// token exchanges an authorization code for an ID token.
+func (iss *Issuer) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		id, secret = r.PostFormValue("client_id"), r.PostFormValue("client_secret")
+	}
+	id, _ = url.QueryUnescape(id)
+	secret, _ = url.QueryUnescape(secret)
+	if id != iss.ClientID || secret != iss.ClientSecret {
+		tokenError(w, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+
+	if r.PostFormValue("grant_type") != "authorization_code" {
+		tokenError(w, http.StatusBadRequest, "unsupported_grant_type")
+		return
+	}
+
+	// Codes can be exchanged once, whether the exchange succeeds or not.
+	code := r.PostFormValue("code")
+	iss.mu.Lock()
+	a, found := iss.codes[code]
+	delete(iss.codes, code)
+	iss.mu.Unlock()
+
+	switch {
+	case !found,
+		a.clientID != id,
+		a.redirectURI != r.PostFormValue("redirect_uri"),
+		a.challenge != oidc.Challenge(r.PostFormValue("code_verifier")):
+		tokenError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	claims := oidc.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    iss.URL(),
+			Subject:   a.identity.Subject,
+			Audience:  jwt.ClaimStrings{a.clientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(5 * time.Minute)),
+		},
+		Nonce:         a.nonce,
+		Email:         a.identity.Email,
+		EmailVerified: a.identity.EmailVerified,
+		Name:          a.identity.Name,
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = kid
+	idToken, err := token.SignedString(iss.key)
+	if err != nil {
+		tokenError(w, http.StatusInternalServerError, "server_error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": "oidctest",
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+// tokenError responds with an error of the token endpoint.
+func tokenError(w http.ResponseWriter, status int, code string) {
+	writeJSON(w, status, map[string]string{"error": code})
+}
+
+// writeJSON responds with the value as JSON.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
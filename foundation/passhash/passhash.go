@@ -0,0 +1,148 @@
+// Package passhash provides support for hashing passwords with argon2id and
+// bcrypt. Hashes are strings in the PHC string format, which hold the
+// algorithm and its parameters, so hashes of either algorithm can be
+// verified and outdated hashes can be detected.
+package passhash
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrMismatch is returned if the password does not match the hash.
+	ErrMismatch = errors.New("password does not match")
+
+	// ErrUnknownFormat is returned for hashes of an unsupported algorithm.
+	ErrUnknownFormat = errors.New("unknown hash format")
+)
+
+// These are the algorithms a hash can be made with.
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+// These are the lowest and highest costs of bcrypt.
+const (
+	BcryptMinCost = bcrypt.MinCost
+	BcryptMaxCost = bcrypt.MaxCost
+)
+
+// Argon2idParams are the parameters of argon2id. Memory is in KiB.
+type Argon2idParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// Argon2id returns an argon2id hash of the password with a random salt, like
+// $argon2id$v=19$m=65536,t=1,p=4$<salt>$<key>.
+func Argon2id(password string, p Argon2idParams) ([]byte, error) {
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("generating salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+
+	hash := fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		AlgorithmArgon2id, argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+
+	return []byte(hash), nil
+}
+
+// Bcrypt returns a bcrypt hash of the password with the cost.
+func Bcrypt(password string, cost int) ([]byte, error) {
+	return bcrypt.GenerateFromPassword([]byte(password), cost)
+}
+
+// Algorithm returns the algorithm of the hash, or an empty string if the
+// algorithm is not supported.
+func Algorithm(hash []byte) string {
+	switch {
+	case bytes.HasPrefix(hash, []byte("$"+AlgorithmArgon2id+"$")):
+		return AlgorithmArgon2id
+	case bytes.HasPrefix(hash, []byte("$2a$")), bytes.HasPrefix(hash, []byte("$2b$")), bytes.HasPrefix(hash, []byte("$2y$")):
+		return AlgorithmBcrypt
+	}
+	return ""
+}
+
+// Compare returns nil if the password matches the hash, which can be made
+// with any of the supported algorithms.
+func Compare(hash []byte, password string) error {
+	switch Algorithm(hash) {
+	case AlgorithmArgon2id:
+		p, salt, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return err
+		}
+		other := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+		if subtle.ConstantTimeCompare(key, other) != 1 {
+			return ErrMismatch
+		}
+		return nil
+
+	case AlgorithmBcrypt:
+		err := bcrypt.CompareHashAndPassword(hash, []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrMismatch
+		}
+		return err
+	}
+
+	return ErrUnknownFormat
+}
+
+// Argon2idParamsOf returns the parameters an argon2id hash was made with.
+func Argon2idParamsOf(hash []byte) (Argon2idParams, error) {
+	p, _, _, err := decodeArgon2id(hash)
+	return p, err
+}
+
+// BcryptCostOf returns the cost a bcrypt hash was made with.
+func BcryptCostOf(hash []byte) (int, error) {
+	return bcrypt.Cost(hash)
+}
+
+// decodeArgon2id returns the parameters, salt and key of an argon2id hash.
+func decodeArgon2id(hash []byte) (Argon2idParams, []byte, []byte, error) {
+	parts := bytes.Split(hash, []byte("$"))
+	if len(parts) != 6 || string(parts[1]) != AlgorithmArgon2id {
+		return Argon2idParams{}, nil, nil, ErrUnknownFormat
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(string(parts[2]), "v=%d", &version); err != nil || version != argon2.Version {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("unsupported argon2id version: %w", ErrUnknownFormat)
+	}
+
+	var p Argon2idParams
+	if _, err := fmt.Sscanf(string(parts[3]), "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("decoding argon2id parameters: %w", err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(string(parts[4]))
+	if err != nil {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("decoding argon2id salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(string(parts[5]))
+	if err != nil {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("decoding argon2id key: %w", err)
+	}
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+
+	return p, salt, key, nil
+}
@@ -0,0 +1,89 @@
+package passhash_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/appinesshq/caservice/foundation/passhash"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+var params = passhash.Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func Test_Compare(t *testing.T) {
+	argon, err := passhash.Argon2id("gophers", params)
+	if err != nil {
+		t.Fatalf("Should be able to hash with argon2id: %v", err)
+	}
+	bcrypt, err := passhash.Bcrypt("gophers", passhash.BcryptMinCost)
+	if err != nil {
+		t.Fatalf("Should be able to hash with bcrypt: %v", err)
+	}
+
+	table := []struct {
+		algorithm string
+		hash      []byte
+	}{
+		{passhash.AlgorithmArgon2id, argon},
+		{passhash.AlgorithmBcrypt, bcrypt},
+	}
+
+	t.Log("Given the need to verify passwords of any supported algorithm.")
+	{
+		for testID, tt := range table {
+			t.Logf("\tTest %d:\tWhen handling a %s hash.", testID, tt.algorithm)
+			{
+				if got := passhash.Algorithm(tt.hash); got != tt.algorithm {
+					t.Fatalf("\t%s\tTest %d:\tShould identify the algorithm %s, but got %q.", failed, testID, tt.algorithm, got)
+				}
+				t.Logf("\t%s\tTest %d:\tShould identify the algorithm.", success, testID)
+
+				if err := passhash.Compare(tt.hash, "gophers"); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould match the password: %v", failed, testID, err)
+				}
+				if err := passhash.Compare(tt.hash, ""); !errors.Is(err, passhash.ErrMismatch) {
+					t.Fatalf("\t%s\tTest %d:\tShould not match another password, but got: %v", failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould only match the password.", success, testID)
+			}
+		}
+
+		t.Logf("\tTest %d:\tWhen handling an unknown hash.", len(table))
+		{
+			if err := passhash.Compare([]byte("$md5$gophers"), "gophers"); !errors.Is(err, passhash.ErrUnknownFormat) {
+				t.Fatalf("\t%s\tTest %d:\tShould not accept an unknown format, but got: %v", failed, len(table), err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not accept an unknown format.", success, len(table))
+		}
+	}
+}
+
+func Test_Argon2idParams(t *testing.T) {
+	t.Log("Given the need to detect outdated hashes.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen reading the parameters of an argon2id hash.", testID)
+		{
+			hash, err := passhash.Argon2id("gophers", params)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to hash: %v", failed, testID, err)
+			}
+			if !strings.HasPrefix(string(hash), "$argon2id$v=19$m=1024,t=1,p=1$") {
+				t.Fatalf("\t%s\tTest %d:\tShould use the PHC string format: %s", failed, testID, hash)
+			}
+			t.Logf("\t%s\tTest %d:\tShould use the PHC string format.", success, testID)
+
+			got, err := passhash.Argon2idParamsOf(hash)
+			if err != nil || got != params {
+				t.Fatalf("\t%s\tTest %d:\tShould get the parameters, but got %+v: %v", failed, testID, got, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould get the parameters.", success, testID)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Package totp provides support for time-based one-time passwords as
+// specified in RFC 6238, compatible with common authenticator apps: HMAC-SHA1,
+// six digits and a period of thirty seconds.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Parameters of the generated codes.
+const (
+	Digits = 6
+	Period = 30 * time.Second
+)
+
+// secretSize is the size of generated secrets in bytes, the size of
+// a SHA-1 digest as recommended by RFC 4226.
+const secretSize = 20
+
+// encoding is the encoding of secrets in URIs: base32 without padding.
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns a new random secret.
+func GenerateSecret() ([]byte, error) {
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("generating secret: %w", err)
+	}
+	return secret, nil
+}
+
+// EncodeSecret returns the secret in the base32 form users can enter in
+// authenticator apps manually.
+func EncodeSecret(secret []byte) string {
+	return encoding.EncodeToString(secret)
+}
+
+// URI returns the otpauth:// URI for the secret, which authenticator apps
+// read from a QR code. The issuer and account name label the entry in the app.
+func URI(issuer, account string, secret []byte) string {
+	v := url.Values{}
+	v.Set("secret", EncodeSecret(secret))
+	v.Set("issuer", issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprint(Digits))
+	v.Set("period", fmt.Sprint(int(Period/time.Second)))
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: v.Encode(),
+	}
+	return u.String()
+}
+
+// Step returns the time step of t: the number of periods since the Unix epoch.
+func Step(t time.Time) int64 {
+	return t.Unix() / int64(Period/time.Second)
+}
+
+// Code returns the code for the secret at time t.
+func Code(secret []byte, t time.Time) string {
+	return codeFor(secret, Step(t))
+}
+
+// Validate reports whether the code is valid for the secret at time t. Codes
+// of up to skew steps before or after t are accepted to allow for clock
+// drift. The step of the matching code is returned, so callers can refuse
+// codes of the same or earlier steps to prevent replays.
+func Validate(secret []byte, code string, t time.Time, skew int) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != Digits {
+		return 0, false
+	}
+
+	step := Step(t)
+	for i := -int64(skew); i <= int64(skew); i++ {
+		want := codeFor(secret, step+i)
+		if subtle.ConstantTimeCompare([]byte(want), []byte(code)) == 1 {
+			return step + i, true
+		}
+	}
+
+	return 0, false
+}
+
+// codeFor returns the code for the secret at the time step, as specified by
+// the HOTP algorithm of RFC 4226 with the time step as counter.
+func codeFor(secret []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation.
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < Digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", Digits, value%mod)
+}
@@ -0,0 +1,103 @@
+package totp_test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/appinesshq/caservice/foundation/totp"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+func Test_Code(t *testing.T) {
+	// The SHA-1 test vectors of RFC 6238, truncated to six digits.
+	secret := []byte("12345678901234567890")
+	table := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	t.Log("Given the need to generate codes.")
+	{
+		for testID, tt := range table {
+			t.Logf("\tTest %d:\tWhen generating the code at %d.", testID, tt.unix)
+			{
+				if got := totp.Code(secret, time.Unix(tt.unix, 0)); got != tt.code {
+					t.Fatalf("\t%s\tTest %d:\tShould generate %s, but got %s.", failed, testID, tt.code, got)
+				}
+				t.Logf("\t%s\tTest %d:\tShould generate %s.", success, testID, tt.code)
+			}
+		}
+	}
+}
+
+func Test_Validate(t *testing.T) {
+	t.Log("Given the need to validate codes.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen validating codes of a single secret.", testID)
+		{
+			secret, err := totp.GenerateSecret()
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to generate a secret: %v", failed, testID, err)
+			}
+
+			now := time.Now()
+			step, ok := totp.Validate(secret, totp.Code(secret, now), now, 1)
+			if !ok || step != totp.Step(now) {
+				t.Fatalf("\t%s\tTest %d:\tShould accept the current code.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould accept the current code.", success, testID)
+
+			if _, ok := totp.Validate(secret, totp.Code(secret, now.Add(-totp.Period)), now, 1); !ok {
+				t.Fatalf("\t%s\tTest %d:\tShould accept the previous code.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould accept the previous code.", success, testID)
+
+			if _, ok := totp.Validate(secret, totp.Code(secret, now.Add(-3*totp.Period)), now, 1); ok {
+				t.Fatalf("\t%s\tTest %d:\tShould not accept an old code.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not accept an old code.", success, testID)
+
+			if _, ok := totp.Validate(secret, "12345", now, 1); ok {
+				t.Fatalf("\t%s\tTest %d:\tShould not accept a short code.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not accept a short code.", success, testID)
+		}
+	}
+}
+
+func Test_URI(t *testing.T) {
+	t.Log("Given the need to enroll authenticator apps.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen building an otpauth URI.", testID)
+		{
+			secret := []byte("12345678901234567890")
+			u, err := url.Parse(totp.URI("Sales", "user@example.com", secret))
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould build a valid URI: %v", failed, testID, err)
+			}
+
+			if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/Sales:user@example.com" {
+				t.Fatalf("\t%s\tTest %d:\tShould label the account: %s", failed, testID, u)
+			}
+			if got := u.Query().Get("secret"); got != "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" {
+				t.Fatalf("\t%s\tTest %d:\tShould hold the base32 secret, but got %s.", failed, testID, got)
+			}
+			t.Logf("\t%s\tTest %d:\tShould build a valid URI.", success, testID)
+		}
+	}
+}
@@ -2,11 +2,11 @@
 package validation
 
 import (
+	"github.com/appinesshq/caservice/foundation/passhash"
 	"github.com/go-playground/locales/en"
 	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
 	en_translations "github.com/go-playground/validator/v10/translations/en"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // ValueValidator is an interface for value validation
@@ -69,9 +69,10 @@ func NewStandardValidationProvider() ValidationProvider {
 // DefaultValidationProvider is a singleton StandardValidationProvider.
 var DefaultValidationProvider = NewStandardValidationProvider()
 
-// isValidUUID returns true if the field does NOT contain a hashed empty password.
+// isNotEmptyPassword returns true if the field does NOT contain a hashed
+// empty password, of any of the supported hash algorithms.
 func isNotEmptyPassword(f validator.FieldLevel) bool {
-	if err := bcrypt.CompareHashAndPassword(f.Field().Bytes(), []byte("")); err == nil {
+	if err := passhash.Compare(f.Field().Bytes(), ""); err == nil {
 		return false
 	}
 